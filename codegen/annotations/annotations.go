@@ -1,12 +1,16 @@
 // Package annotations finds and parses annotations in the comments to an interface or method.
 // An annotation must have the following format: @Name{"key1":"value1", "key2": "value2", ...}, i.e.
 // it begins with an @ characater, followed by a name followed by a valid json object.
+//
+// Multiple annotations with the same name are merged into a single one, so that a large annotation can be split into several blocks,
+// see mergeAnnotations.
 package annotations
 
 import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/dkinzler/kit/codegen/parse"
 )
@@ -21,6 +25,29 @@ type InterfaceAnnotation struct {
 	// Slice has the same length as parse.Interface.Methods.
 	// Contain empty string for methods without an annotation.
 	MethodAnnotations []string
+	// If true, generators should report unknown keys in annotations as errors, see ParseJSON.
+	// Set e.g. when annotations are linted.
+	Strict bool
+	// Options that apply to all interfaces, e.g. set with command line flags.
+	// Generators use them as defaults for keys that are not set in the annotation.
+	Options map[string]string
+}
+
+// Returns the value of the option with the given key or def if the option is not set.
+func (a InterfaceAnnotation) Option(key, def string) string {
+	if v, ok := a.Options[key]; ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// Parses an annotation on the interface or one of its methods with ParseJSONAnnotationStrict if a.Strict is true
+// and with ParseJSONAnnotation otherwise.
+func (a InterfaceAnnotation) ParseJSON(annotation string, result interface{}) error {
+	if a.Strict {
+		return ParseJSONAnnotationStrict(annotation, result)
+	}
+	return ParseJSONAnnotation(annotation, result)
 }
 
 // Returns a map of annotations found in the comments of the given interface.
@@ -60,6 +87,13 @@ func ParseInterfaceAnnotations(i parse.Interface) (map[string]InterfaceAnnotatio
 	return result, nil
 }
 
+// Returns a map of all annotations found in the comments of the given method.
+// The map keys are the annotation names and the values the JSON objects of the annotations.
+// Useful for annotations that only make sense on methods, e.g. to configure a code generator that is triggered by another annotation on the interface.
+func ParseMethodAnnotations(m parse.Method) (map[string]string, error) {
+	return parseAnnotations(m.Comments)
+}
+
 // Parses the annotation as JSON and stores the result in the "result" parameter, which should usually be a pointer to a struct or map.
 func ParseJSONAnnotation(annotation string, result interface{}) error {
 	err := json.Unmarshal([]byte(annotation), result)
@@ -69,6 +103,14 @@ func ParseJSONAnnotation(annotation string, result interface{}) error {
 	return nil
 }
 
+// Like ParseJSONAnnotation, but returns an error if the annotation contains a key that does not match any field of the result struct (or nested structs).
+// This helps to find typos in annotations, that would otherwise be silently ignored.
+func ParseJSONAnnotationStrict(annotation string, result interface{}) error {
+	d := json.NewDecoder(strings.NewReader(annotation))
+	d.DisallowUnknownFields()
+	return d.Decode(result)
+}
+
 func parseAnnotations(comments []string) (map[string]string, error) {
 	result := make(map[string]string)
 
@@ -105,8 +147,12 @@ func parseAnnotations(comments []string) (map[string]string, error) {
 					json += "}"
 					closedBrackets++
 					if openBrackets == closedBrackets {
-						if _, ok := result[name]; ok {
-							return nil, errors.New(fmt.Sprintf("multiple annotations found with name: %v", name))
+						if existing, ok := result[name]; ok {
+							merged, err := mergeAnnotations(existing, json)
+							if err != nil {
+								return nil, errors.New(fmt.Sprintf("could not merge multiple annotations with name %v: %v", name, err))
+							}
+							json = merged
 						}
 						result[name] = json
 						name = ""
@@ -129,3 +175,63 @@ func parseAnnotations(comments []string) (map[string]string, error) {
 
 	return result, nil
 }
+
+// Deep-merges the JSON objects of two annotations with the same name.
+// Nested objects are merged recursively and arrays are concatenated, any other key must only be set in one of the annotations.
+func mergeAnnotations(a, b string) (string, error) {
+	var x, y map[string]interface{}
+	if err := decodeJSONObject(a, &x); err != nil {
+		return "", err
+	}
+	if err := decodeJSONObject(b, &y); err != nil {
+		return "", err
+	}
+	merged, err := mergeJSONObjects(x, y, "")
+	if err != nil {
+		return "", err
+	}
+	result, err := json.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+func decodeJSONObject(s string, result *map[string]interface{}) error {
+	d := json.NewDecoder(strings.NewReader(s))
+	// keep numbers as they are written in the annotation
+	d.UseNumber()
+	return d.Decode(result)
+}
+
+func mergeJSONObjects(x, y map[string]interface{}, path string) (map[string]interface{}, error) {
+	for key, yv := range y {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		xv, ok := x[key]
+		if !ok {
+			x[key] = yv
+			continue
+		}
+		switch xt := xv.(type) {
+		case map[string]interface{}:
+			if yt, ok := yv.(map[string]interface{}); ok {
+				merged, err := mergeJSONObjects(xt, yt, keyPath)
+				if err != nil {
+					return nil, err
+				}
+				x[key] = merged
+				continue
+			}
+		case []interface{}:
+			if yt, ok := yv.([]interface{}); ok {
+				x[key] = append(xt, yt...)
+				continue
+			}
+		}
+		return nil, errors.New(fmt.Sprintf("key %v is set in more than one annotation", keyPath))
+	}
+	return x, nil
+}
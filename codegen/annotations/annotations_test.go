@@ -53,6 +53,35 @@ func TestParseAnnotations(t *testing.T) {
 			Result:       map[string]string{},
 			ReturnsError: true,
 		},
+		{
+			// annotations with the same name are merged
+			Comments: []string{
+				`@Kit{"abc":"xyz", "efg": {"a": 123, "b": [1]}}`,
+				`@Mock{}`,
+				`@Kit{"cde": 1.50, "efg": {"b": [2, 3], "c": {"d": "e"}}}`,
+			},
+			Result: map[string]string{
+				"Kit":  `{"abc":"xyz","cde":1.50,"efg":{"a":123,"b":[1,2,3],"c":{"d":"e"}}}`,
+				"Mock": `{}`,
+			},
+			ReturnsError: false,
+		},
+		{
+			Comments: []string{
+				`@Kit{"efg": {"a": 123}}`,
+				`@Kit{"efg": {"a": {"b": 1}}}`,
+			},
+			Result:       map[string]string{},
+			ReturnsError: true,
+		},
+		{
+			Comments: []string{
+				`@Kit{"abc": 1}`,
+				`@Kit{"abc" 1}`,
+			},
+			Result:       map[string]string{},
+			ReturnsError: true,
+		},
 	}
 
 	for i, c := range cases {
@@ -138,6 +167,43 @@ func TestParseInterfaceAnnotations(t *testing.T) {
 	}, kitParsed)
 }
 
+func TestParseMethodAnnotations(t *testing.T) {
+	a := assert.New(t)
+
+	m := parse.Method{
+		Comments: []string{
+			`@Kit{"k1":"v1"}`,
+			`@Validate{"a": {"required": true}}`,
+		},
+	}
+	annotations, err := ParseMethodAnnotations(m)
+	a.Nil(err)
+	a.Equal(map[string]string{
+		"Kit":      `{"k1":"v1"}`,
+		"Validate": `{"a": {"required": true}}`,
+	}, annotations)
+
+	annotations, err = ParseMethodAnnotations(parse.Method{})
+	a.Nil(err)
+	a.Empty(annotations)
+}
+
+func TestParseJSONAnnotationStrict(t *testing.T) {
+	a := assert.New(t)
+
+	var result testKitAnnotation
+	a.Nil(ParseJSONAnnotationStrict(`{"abc": "xyz", "efg": {"c": {"d": "e"}}}`, &result))
+	a.Equal("e", result.Efg.C.D)
+
+	// unknown keys are only ignored in non-strict mode, also in nested objects
+	for _, annotation := range []string{`{"abc": "xyz", "xyz": 1}`, `{"efg": {"c": {"x": "e"}}}`} {
+		a.Nil(ParseJSONAnnotation(annotation, &result))
+		a.NotNil(ParseJSONAnnotationStrict(annotation, &result))
+		a.NotNil(InterfaceAnnotation{Strict: true}.ParseJSON(annotation, &result))
+		a.Nil(InterfaceAnnotation{}.ParseJSON(annotation, &result))
+	}
+}
+
 type testKitAnnotation struct {
 	Abc string
 	Cde int
@@ -153,3 +219,13 @@ type testKitAnnotationInner struct {
 type testKitAnnotationInnerInner struct {
 	D string
 }
+
+func TestInterfaceAnnotationOption(t *testing.T) {
+	a := assert.New(t)
+
+	annotation := InterfaceAnnotation{Options: map[string]string{"abc": "xyz", "empty": ""}}
+	a.Equal("xyz", annotation.Option("abc", "default"))
+	a.Equal("default", annotation.Option("empty", "default"))
+	a.Equal("default", annotation.Option("efg", "default"))
+	a.Equal("default", InterfaceAnnotation{}.Option("abc", "default"))
+}
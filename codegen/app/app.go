@@ -0,0 +1,245 @@
+// Package app implements the command line interface of the code generator.
+//
+// It can be used to build a custom code generator binary that supports additional annotations:
+//
+//	func main() {
+//		r := app.DefaultRegistry()
+//		r.Register("Foo", fooGenerator)
+//		if err := app.New(r).Run(os.Args); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/dkinzler/kit/codegen/gen"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// Returns a new registry with the generators for the built-in annotations "Kit", "Mock" and "Firestore".
+func DefaultRegistry() *gen.Registry {
+	r := gen.NewRegistry()
+	r.Register("Kit", generateKit)
+	r.Register("Mock", generateMock)
+	r.Register("Firestore", generateFirestore)
+	return r
+}
+
+// Returns the command line app of the code generator, that uses the given registry to generate code for annotations.
+func New(registry *gen.Registry) *cli.App {
+	return &cli.App{
+		Name:    "Codegen",
+		Usage:   "generates code, how wonderful",
+		Version: Version,
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:    "fail-on-error",
+				Value:   true,
+				Aliases: []string{"e"},
+				Usage:   "If true code generation is aborted on first error. Otherwise code is generated for all interfaces without errors and all errors are reported at the end.",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "If true no files are written, instead a diff against the existing files is printed. Exits with a non-zero code if the generated code differs, e.g. to check in CI that generated code is up to date.",
+			},
+			&cli.BoolFlag{
+				Name:  "stdout",
+				Usage: "If true no files are written, instead the generated code is printed to stdout, e.g. for editor integrations or to post-process it.",
+			},
+			&cli.StringFlag{
+				Name:  "single-file",
+				Usage: "If set all generated Go code is written to this file instead of the output files configured in the annotations. The code must belong to a single package.",
+			},
+			&cli.BoolFlag{
+				Name:  "verify",
+				Usage: "If true the imports of the written Go files are fixed and their packages are type checked after writing them, exits with a non-zero code if the generated code does not compile.",
+			},
+			&cli.BoolFlag{
+				Name:  "cache",
+				Usage: "If true hashes of source and output files are stored in " + CacheFileName + " in the module root and code is only generated again for interfaces in changed files. Output files with unchanged content are never rewritten.",
+			},
+			&cli.BoolFlag{
+				Name:  "check-version",
+				Usage: "If true existing files that were generated by a newer version of the code generator are not overwritten and an error is returned instead.",
+			},
+			&cli.StringFlag{
+				Name:  "generateCommand",
+				Value: "go run github.com/dkinzler/kit/codegen",
+				Usage: "Command written as //go:generate directive to the header of one generated file per package, to generate the package again with \"go generate\". No directive is written if empty.",
+			},
+			&cli.BoolFlag{
+				Name:  "watch",
+				Usage: "If true the input directory is watched for changes and code is generated again whenever a go file changes.",
+			},
+		}, append(moduleFlags(), optionFlags()...)...),
+		Commands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "Lists the annotated interfaces in the input directory with their endpoints, http routes and the files that would be written, without writing any files.",
+				UsageText: "codegen list [--inputDir dir] [--moduleName name --modulePath path]",
+				Flags:     moduleFlags(),
+				Action: func(ctx *cli.Context) error {
+					config, err := configFromFlags(ctx, registry)
+					if err != nil {
+						return err
+					}
+					return List(config, os.Stdout)
+				},
+			},
+			{
+				Name:      "scaffold",
+				Usage:     "Generates a main.go for interfaces with a Kit annotation, that creates the endpoints and http handlers and runs a http server. Existing files are not overwritten.",
+				UsageText: "codegen scaffold [--inputDir dir] [--interface name] [--output file] [--force]",
+				Flags: append(append(moduleFlags(), optionFlags()...),
+					&cli.StringFlag{
+						Name:  "interface",
+						Usage: "Name of the interface to generate the scaffold for. If empty, a scaffold is generated for every interface with a Kit annotation.",
+					},
+					&cli.StringFlag{
+						Name:        "output",
+						Usage:       "Output file, can only be used if there is a single interface.",
+						DefaultText: "default: cmd/<interface name>/main.go in the module root",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "If true existing files are overwritten.",
+					},
+				),
+				Action: func(ctx *cli.Context) error {
+					config, err := configFromFlags(ctx, registry)
+					if err != nil {
+						return err
+					}
+					output := ctx.String("output")
+					if output != "" {
+						output, err = filepath.Abs(output)
+						if err != nil {
+							return err
+						}
+					}
+					return Scaffold(config, ctx.String("interface"), output, ctx.Bool("force"))
+				},
+			},
+			{
+				Name:      "lint",
+				Usage:     "Validates the annotations in the input directory without generating code, e.g. as a pre-commit hook. Reports unknown annotations and unknown keys in annotations and exits with a non-zero code if there are any problems.",
+				UsageText: "codegen lint [--inputDir dir] [--moduleName name --modulePath path]",
+				Flags:     append(moduleFlags(), optionFlags()...),
+				Action: func(ctx *cli.Context) error {
+					config, err := configFromFlags(ctx, registry)
+					if err != nil {
+						return err
+					}
+					return Lint(config)
+				},
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			config, err := configFromFlags(ctx, registry)
+			if err != nil {
+				return err
+			}
+			config.FailOnError = ctx.Bool("fail-on-error")
+			config.DryRun = ctx.Bool("dry-run")
+			config.Stdout = ctx.Bool("stdout")
+			if singleFile := ctx.String("single-file"); singleFile != "" {
+				config.SingleFile, err = filepath.Abs(singleFile)
+				if err != nil {
+					return err
+				}
+			}
+			config.Cache = ctx.Bool("cache")
+			config.CheckVersion = ctx.Bool("check-version")
+			config.Verify = ctx.Bool("verify")
+			config.GenerateCommand = ctx.String("generateCommand")
+			if ctx.Bool("watch") {
+				if config.DryRun || config.Stdout {
+					return errors.New("cannot use --watch together with --dry-run or --stdout")
+				}
+				return Watch(config)
+			}
+			if config.DryRun && config.Stdout {
+				return errors.New("cannot use --dry-run together with --stdout")
+			}
+			return Generate(config)
+		},
+	}
+}
+
+// Flags to locate the input directory and module, shared by all commands.
+func moduleFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "moduleName",
+			Usage: "Name of the module the input directory belongs to, e.g. github.com/user/example .",
+		},
+		&cli.StringFlag{
+			Name:  "modulePath",
+			Usage: "Path to the root directory of the module the input directory belongs to. If empty will attempt to find the module by looking for a go.mod file in the input directory and its ancestors.",
+		},
+		&cli.StringFlag{
+			Name:        "inputDir",
+			Value:       ".",
+			Usage:       "Directory to search for code generator annotations.",
+			DefaultText: "default: current working directory",
+		},
+		&cli.StringSliceFlag{
+			Name:  "exclude",
+			Usage: "Glob pattern of files and directories in the input directory that are not parsed, e.g. \"internal/legacy\" or \"*_old.go\". Can be repeated. Directories named vendor or testdata are always skipped.",
+		},
+	}
+}
+
+// Flags for options that are passed to all generators, see GeneratorConfig.Options.
+// The flag names are the names of the options.
+func optionFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "endpointHelperPackage",
+			Usage:       "Import path of the helper package used by generated endpoint code, if not set in the Kit annotation.",
+			DefaultText: "default: github.com/dkinzler/kit/endpoint",
+		},
+		&cli.StringFlag{
+			Name:        "httpHelperPackage",
+			Usage:       "Import path of the helper package used by generated http code, if not set in the Kit annotation.",
+			DefaultText: "default: github.com/dkinzler/kit/transport/http",
+		},
+	}
+}
+
+func configFromFlags(ctx *cli.Context, registry *gen.Registry) (GeneratorConfig, error) {
+	inputDir, err := filepath.Abs(ctx.String("inputDir"))
+	if err != nil {
+		return GeneratorConfig{}, err
+	}
+
+	modulePath := ctx.String("modulePath")
+	if modulePath != "" {
+		modulePath, err = filepath.Abs(modulePath)
+		if err != nil {
+			return GeneratorConfig{}, err
+		}
+	}
+
+	options := make(map[string]string)
+	for _, f := range optionFlags() {
+		name := f.Names()[0]
+		if ctx.IsSet(name) {
+			options[name] = ctx.String(name)
+		}
+	}
+
+	return GeneratorConfig{
+		InputDir:   inputDir,
+		ModuleName: ctx.String("moduleName"),
+		ModulePath: modulePath,
+		Exclude:    ctx.StringSlice("exclude"),
+		Registry:   registry,
+		Options:    options,
+	}, nil
+}
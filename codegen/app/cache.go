@@ -0,0 +1,167 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+)
+
+// Name of the cache file, stored in the root directory of the module.
+const CacheFileName = ".codegen-cache.json"
+
+// Cache of file hashes for incremental code generation.
+// Generators are only run for interfaces in source files that changed since the last run,
+// or that contribute to an output file that was changed or deleted.
+// All paths are relative to the module root.
+type generatorCache struct {
+	// Version of the code generator that wrote the cache, the cache is discarded if the version changes.
+	// Contains a hash of the executable, so that the cache is also discarded if e.g. a custom generator changed.
+	Version string `json:"version"`
+	// Generator options of the run that wrote the cache, the cache is discarded if the options change.
+	Options map[string]string `json:"options,omitempty"`
+	// Hashes of the source files that contain annotated interfaces.
+	Sources map[string]string `json:"sources"`
+	// Hashes of the output files and the source files that contributed to them.
+	Outputs map[string]cachedOutput `json:"outputs"`
+}
+
+type cachedOutput struct {
+	Hash    string   `json:"hash"`
+	Sources []string `json:"sources"`
+}
+
+func newGeneratorCache(version string, options map[string]string) *generatorCache {
+	return &generatorCache{
+		Version: version,
+		Options: options,
+		Sources: make(map[string]string),
+		Outputs: make(map[string]cachedOutput),
+	}
+}
+
+// Loads the cache from the module root. A missing or invalid cache file results in an empty cache, i.e. everything is generated.
+func loadCache(module parse.Module, options map[string]string) *generatorCache {
+	version := cacheVersion()
+	data, err := os.ReadFile(filepath.Join(module.Path, CacheFileName))
+	if err != nil {
+		return newGeneratorCache(version, options)
+	}
+	var c generatorCache
+	if err := json.Unmarshal(data, &c); err != nil || c.Version != version || !maps.Equal(c.Options, options) || c.Sources == nil || c.Outputs == nil {
+		return newGeneratorCache(version, options)
+	}
+	return &c
+}
+
+func (c *generatorCache) save(module parse.Module) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(module.Path, CacheFileName), data, 0644)
+}
+
+// Returns the source files (relative to the module root) that need to be generated again, i.e.
+//   - source files that are not in the cache or whose content changed
+//   - source files that contributed to an output file that was modified or deleted since the last run
+func (c *generatorCache) dirtySources(module parse.Module, sources []string) map[string]bool {
+	result := make(map[string]bool)
+	for _, s := range sources {
+		hash, err := hashFile(filepath.Join(module.Path, s))
+		if err != nil || c.Sources[s] != hash {
+			result[s] = true
+		}
+	}
+	for output, o := range c.Outputs {
+		hash, err := hashFile(filepath.Join(module.Path, output))
+		if err != nil || hash != o.Hash {
+			for _, s := range o.Sources {
+				result[s] = true
+			}
+		}
+	}
+	return result
+}
+
+// Returns the source files that contributed to the given output files or other output files in the same directories in the last run.
+// Since output files are written as a whole, these sources need to be generated again as well.
+// The sources of the other files in a directory are needed to write the //go:generate directives of the package, see gen.FileHeader.
+func (c *generatorCache) sourcesOfOutputs(outputs []string) []string {
+	dirs := make(map[string]bool)
+	for _, output := range outputs {
+		dirs[filepath.Dir(output)] = true
+	}
+	var result []string
+	for output, cached := range c.Outputs {
+		if dirs[filepath.Dir(output)] {
+			result = append(result, cached.Sources...)
+		}
+	}
+	return result
+}
+
+// Records the hashes of the source files and the output files that were generated from them.
+func (c *generatorCache) update(module parse.Module, sources []string, outputs map[string]string, outputSources map[string][]string) {
+	for _, s := range sources {
+		hash, err := hashFile(filepath.Join(module.Path, s))
+		if err != nil {
+			delete(c.Sources, s)
+		} else {
+			c.Sources[s] = hash
+		}
+	}
+	for output, hash := range outputs {
+		s := outputSources[output]
+		sort.Strings(s)
+		c.Outputs[output] = cachedOutput{Hash: hash, Sources: s}
+	}
+}
+
+func cacheVersion() string {
+	executable, err := os.Executable()
+	if err != nil {
+		return Version
+	}
+	hash, err := hashFile(executable)
+	if err != nil {
+		return Version
+	}
+	return Version + "-" + hash
+}
+
+func hashFile(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return hashBytes(data), nil
+}
+
+func hashBytes(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// Path of the file relative to the module root.
+func moduleRelativePath(module parse.Module, file string) string {
+	if rel, err := filepath.Rel(module.Path, file); err == nil {
+		return rel
+	}
+	return file
+}
+
+// Output files (relative to the module root) of the results.
+func outputFiles(module parse.Module, results []gen.GenResult) []string {
+	var result []string
+	for _, r := range results {
+		result = append(result, moduleRelativePath(module, r.OutputFile))
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratorCache(t *testing.T) {
+	a := assert.New(t)
+
+	module := parse.Module{Path: t.TempDir(), Name: "example.com/example"}
+	write := func(file, content string) {
+		a.Nil(os.MkdirAll(filepath.Dir(filepath.Join(module.Path, file)), 0755))
+		a.Nil(os.WriteFile(filepath.Join(module.Path, file), []byte(content), 0644))
+	}
+	write("a/a.go", "package a")
+	write("b/b.go", "package b")
+	write("gen/out.go", "package gen")
+
+	c := loadCache(module, nil)
+	a.Empty(c.Sources)
+	sources := []string{"a/a.go", "b/b.go"}
+	// everything is dirty initially
+	a.Equal(map[string]bool{"a/a.go": true, "b/b.go": true}, c.dirtySources(module, sources))
+
+	c.update(module, sources, map[string]string{"gen/out.go": hashBytes([]byte("package gen"))}, map[string][]string{"gen/out.go": {"b/b.go", "a/a.go"}})
+	a.Nil(c.save(module))
+	c = loadCache(module, nil)
+	a.Empty(c.dirtySources(module, sources))
+	a.Equal([]string{"a/a.go", "b/b.go"}, c.sourcesOfOutputs([]string{"gen/out.go"}))
+	// sources of other output files in the same directory are included, they contribute to the //go:generate directives
+	c.Outputs["gen/other.go"] = cachedOutput{Sources: []string{"c/c.go"}}
+	c.Outputs["other/out.go"] = cachedOutput{Sources: []string{"d/d.go"}}
+	a.ElementsMatch([]string{"a/a.go", "b/b.go", "c/c.go"}, c.sourcesOfOutputs([]string{"gen/new.go"}))
+	delete(c.Outputs, "gen/other.go")
+	delete(c.Outputs, "other/out.go")
+
+	write("a/a.go", "package a // changed")
+	a.Equal(map[string]bool{"a/a.go": true}, c.dirtySources(module, sources))
+
+	// a modified output file makes all of its sources dirty
+	write("a/a.go", "package a")
+	write("gen/out.go", "package gen // changed")
+	a.Equal(map[string]bool{"a/a.go": true, "b/b.go": true}, c.dirtySources(module, sources))
+
+	// the cache is discarded if the generator options change
+	a.Empty(loadCache(module, map[string]string{"httpHelperPackage": "example.com/http"}).Outputs)
+
+	// invalid cache files are ignored
+	write(CacheFileName, "{")
+	a.Empty(loadCache(module, nil).Outputs)
+}
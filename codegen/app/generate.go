@@ -0,0 +1,572 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dkinzler/kit/codegen/annotations"
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/internal/firestore"
+	"github.com/dkinzler/kit/codegen/internal/kit"
+	"github.com/dkinzler/kit/codegen/internal/mock"
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type GeneratorConfig struct {
+	InputDir string
+
+	// Generators for the annotations found on interfaces.
+	// If nil, DefaultRegistry() is used.
+	Registry *gen.Registry
+
+	ModuleName string
+	ModulePath string
+
+	// Glob patterns of files and directories in InputDir that are not parsed, see parse.ParseDir.
+	Exclude []string
+
+	// Whether or not to stop generating on the first error.
+	// If false, code is generated for all interfaces without errors and all errors are returned together.
+	FailOnError bool
+
+	// If true, no files are written. Instead a diff between the generated code and the existing files is printed.
+	DryRun bool
+
+	// If true, no files are written. Instead the generated code is printed to stdout.
+	// If more than one file is generated, every file is preceded by a comment line with its path.
+	Stdout bool
+
+	// If not empty, all generated Go code is written to this file instead of the output files configured in the annotations.
+	// The generated code must belong to a single package. Files that do not contain Go code are written as usual.
+	SingleFile string
+
+	// If true, hashes of source and output files are stored in a cache file in the module root (see CacheFileName).
+	// Generators are then only run for interfaces in files that changed since the last run.
+	// Ignored if DryRun, Stdout or SingleFile is set.
+	Cache bool
+
+	// Command written as //go:generate directive to the header of generated files, see gen.FileHeader.
+	// If empty, no directive is written.
+	GenerateCommand string
+
+	// If true, existing files are not overwritten if they were generated by a newer version of the code generator.
+	CheckVersion bool
+
+	// If true, the imports of the written Go files are fixed and their packages are type checked after writing them,
+	// so that generated code that does not compile results in an error, see verifyGeneratedCode.
+	// Ignored if DryRun or Stdout is true.
+	Verify bool
+
+	// Options passed to all generators, see annotations.InterfaceAnnotation.Options.
+	// E.g. the kit generator supports the options "endpointHelperPackage" and "httpHelperPackage".
+	Options map[string]string
+
+	// Hooks that post-process the generated code before it is written, see Hook.
+	Hooks []Hook
+}
+
+// Generates code for all annotated interfaces in the input directory and writes it to the output files.
+func Generate(config GeneratorConfig) error {
+	registry := config.Registry
+	if registry == nil {
+		registry = DefaultRegistry()
+	}
+
+	module, err := getModule(config)
+	if err != nil {
+		return err
+	}
+
+	is, err := parse.ParseDir(config.InputDir, module, config.Exclude...)
+	if err != nil && config.FailOnError {
+		return err
+	}
+	// errors are collected and returned together if FailOnError is false
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if config.DryRun || config.Stdout || config.SingleFile != "" {
+		generatedCode, generatorErrs := runGenerators(is, module, registry, config.FailOnError, generatorOptions{options: config.Options})
+		if len(generatorErrs) > 0 && config.FailOnError {
+			return generatorErrs[0]
+		}
+		errs = append(errs, generatorErrs...)
+		generatedCode, err = runHooks(config.Hooks, module, generatedCode)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		if config.SingleFile != "" {
+			generatedCode, err = toSingleFile(generatedCode, config.SingleFile)
+			if err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+		}
+		switch {
+		case config.DryRun:
+			errs = append(errs, diffGeneratedCode(generatedCode, fileHeader(config)))
+		case config.Stdout:
+			errs = append(errs, printGeneratedCode(os.Stdout, generatedCode, fileHeader(config)))
+		default:
+			hashes, outputErrs := outputGeneratedCode(generatedCode, fileHeader(config), config.CheckVersion)
+			errs = append(errs, outputErrs...)
+			errs = append(errs, verifyOutput(config, hashes))
+		}
+		return errors.Join(errs...)
+	}
+
+	if !config.Cache {
+		generatedCode, generatorErrs := runGenerators(is, module, registry, config.FailOnError, generatorOptions{options: config.Options})
+		if len(generatorErrs) > 0 && config.FailOnError {
+			return generatorErrs[0]
+		}
+		errs = append(errs, generatorErrs...)
+		generatedCode, err = runHooks(config.Hooks, module, generatedCode)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		hashes, outputErrs := outputGeneratedCode(generatedCode, fileHeader(config), config.CheckVersion)
+		errs = append(errs, outputErrs...)
+		errs = append(errs, verifyOutput(config, hashes))
+		return errors.Join(errs...)
+	}
+
+	cache := loadCache(module, config.Options)
+	r := runGeneratorsIncremental(is, module, registry, cache, config.FailOnError, generatorOptions{options: config.Options})
+	if len(r.errs) > 0 && config.FailOnError {
+		return r.errs[0]
+	}
+	errs = append(errs, r.errs...)
+	r.generatedCode, err = runHooks(config.Hooks, module, r.generatedCode)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	hashes, outputErrs := outputGeneratedCode(r.generatedCode, fileHeader(config), config.CheckVersion)
+	errs = append(errs, outputErrs...)
+	// imports fixed during verification change the hashes of the output files
+	errs = append(errs, verifyOutput(config, hashes))
+	outputs := make(map[string]string)
+	for file, hash := range hashes {
+		outputs[moduleRelativePath(module, file)] = hash
+	}
+	cache.update(module, r.succeeded, outputs, r.outputSources)
+	if err := cache.save(module); err != nil {
+		log.Printf("could not save cache, got error: %v\n", err)
+	}
+	return errors.Join(errs...)
+}
+
+type incrementalResult struct {
+	generatedCode []gen.GenResult
+	// source files that contributed to each output file, relative to the module root
+	outputSources map[string][]string
+	// source files for which all generators ran without errors
+	succeeded []string
+	errs      []error
+}
+
+// Runs the generators only for the interfaces in source files that are dirty according to the cache, see generatorCache.dirtySources.
+// Since an output file can contain code for interfaces from multiple source files, the generators are also run again for
+// all source files that contributed to an output file that is generated.
+func runGeneratorsIncremental(is []parse.Interface, module parse.Module, registry *gen.Registry, cache *generatorCache, failOnError bool, opts generatorOptions) incrementalResult {
+	// interfaces are sorted by file, hence sources are sorted as well
+	var sources []string
+	bySource := make(map[string][]parse.Interface)
+	for _, i := range is {
+		s := moduleRelativePath(module, i.File)
+		if _, ok := bySource[s]; !ok {
+			sources = append(sources, s)
+		}
+		bySource[s] = append(bySource[s], i)
+	}
+
+	result := incrementalResult{outputSources: make(map[string][]string)}
+	dirty := cache.dirtySources(module, sources)
+	done := make(map[string]bool)
+	for {
+		var next []string
+		for _, s := range sources {
+			if dirty[s] && !done[s] {
+				next = append(next, s)
+			}
+		}
+		if len(next) == 0 {
+			// only routes of interfaces the generators were run for are checked
+			if routeErrs := gen.CheckRoutes(result.generatedCode); len(routeErrs) > 0 {
+				if failOnError {
+					routeErrs = routeErrs[:1]
+				}
+				result.errs = append(result.errs, routeErrs...)
+				// the generators are run again for all sources next time, so that the conflict is reported again
+				result.succeeded = nil
+			}
+			return result
+		}
+
+		var batch []parse.Interface
+		for _, s := range next {
+			done[s] = true
+			batch = append(batch, bySource[s]...)
+		}
+		results := runGeneratorsParallel(batch, module, registry, opts)
+
+		// group the results by source file, interfaces of the same file are next to each other in the batch
+		j := 0
+		for _, s := range next {
+			var generatedCode []gen.GenResult
+			var errs []error
+			for range bySource[s] {
+				generatedCode = append(generatedCode, results[j].generatedCode...)
+				errs = append(errs, results[j].errs...)
+				j++
+			}
+			if len(errs) > 0 {
+				if failOnError {
+					result.errs = append(result.errs, errs[0])
+					return result
+				}
+				result.errs = append(result.errs, errs...)
+			} else {
+				result.succeeded = append(result.succeeded, s)
+			}
+			result.generatedCode = append(result.generatedCode, generatedCode...)
+
+			outputs := outputFiles(module, generatedCode)
+			for _, o := range outputs {
+				if !slices.Contains(result.outputSources[o], s) {
+					result.outputSources[o] = append(result.outputSources[o], s)
+				}
+			}
+			for _, other := range cache.sourcesOfOutputs(outputs) {
+				dirty[other] = true
+			}
+		}
+	}
+}
+
+// Runs the registered generators for the annotations on the interfaces, nothing is written to files.
+// If failOnError is true, only the error of the first interface (in the order of the given slice) with an error is returned,
+// since generators run in parallel this does not mean that the generators for later interfaces were not run.
+// Conflicting http routes of the generated code are reported as errors as well, see gen.CheckRoutes.
+func runGenerators(is []parse.Interface, module parse.Module, registry *gen.Registry, failOnError bool, opts generatorOptions) ([]gen.GenResult, []error) {
+	var generatedCode []gen.GenResult
+	var errs []error
+	for _, r := range runGeneratorsParallel(is, module, registry, opts) {
+		if len(r.errs) > 0 {
+			if failOnError {
+				return nil, r.errs[:1]
+			}
+			errs = append(errs, r.errs...)
+		}
+		generatedCode = append(generatedCode, r.generatedCode...)
+	}
+	if routeErrs := gen.CheckRoutes(generatedCode); len(routeErrs) > 0 {
+		if failOnError {
+			return nil, routeErrs[:1]
+		}
+		errs = append(errs, routeErrs...)
+	}
+	return generatedCode, errs
+}
+
+// Options passed to the generators for every interface.
+type generatorOptions struct {
+	// If true, unknown keys in annotations (see annotations.InterfaceAnnotation.Strict) and annotations
+	// without a registered generator are reported as errors.
+	strict bool
+	// see annotations.InterfaceAnnotation.Options
+	options map[string]string
+}
+
+// Results of the generators for a single interface.
+type interfaceResult struct {
+	generatedCode []gen.GenResult
+	errs          []error
+}
+
+// Runs the generators for the interfaces with a bounded number of goroutines (GOMAXPROCS).
+// Generator functions must therefore be safe for concurrent use, see gen.GeneratorFunc.
+// The results are returned in the same order as the interfaces, so that output files and errors are deterministic.
+func runGeneratorsParallel(is []parse.Interface, module parse.Module, registry *gen.Registry, opts generatorOptions) []interfaceResult {
+	result := make([]interfaceResult, len(is))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for j := range is {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(j int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result[j] = runInterfaceGenerators(is[j], module, registry, opts)
+		}(j)
+	}
+	wg.Wait()
+	return result
+}
+
+func runInterfaceGenerators(i parse.Interface, module parse.Module, registry *gen.Registry, opts generatorOptions) interfaceResult {
+	var result interfaceResult
+
+	a, err := annotations.ParseInterfaceAnnotations(i)
+	if err != nil {
+		result.errs = append(result.errs, i.WrapError(err))
+		return result
+	}
+
+	// iterate over annotations in a fixed order, so that errors are reported deterministically
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		generator, ok := registry.Get(name)
+		if !ok {
+			if opts.strict {
+				result.errs = append(result.errs, i.WrapError(errors.New(fmt.Sprintf("unknown annotation %v", name))))
+			} else {
+				log.Printf("unknown annotation %v on interface %v\n", name, i.Name)
+			}
+			continue
+		}
+		annotation := a[name]
+		annotation.Strict = opts.strict
+		annotation.Options = opts.options
+		files, err := generator(i, module, annotation)
+		if err != nil {
+			result.errs = append(result.errs, i.WrapError(err))
+		} else {
+			for k := range files {
+				if files[k].Source == "" {
+					files[k].Source = i.Package + "." + i.Name
+					files[k].SourceFile = i.File
+				}
+			}
+			result.generatedCode = append(result.generatedCode, files...)
+		}
+	}
+	return result
+}
+
+func getModule(config GeneratorConfig) (parse.Module, error) {
+	if config.ModuleName != "" && config.ModulePath != "" {
+		return parse.Module{
+			Path: config.ModulePath,
+			Name: config.ModuleName,
+		}, nil
+	} else {
+		log.Println("searching for go module...")
+		module, err := parse.NewModuleFromDir(config.InputDir)
+		if err != nil {
+			return parse.Module{}, err
+		}
+		log.Println("found go module", module.Name, "with root dir", module.Path)
+		return module, nil
+	}
+}
+
+func generateKit(i parse.Interface, module parse.Module, annotations annotations.InterfaceAnnotation) ([]gen.GenResult, error) {
+	spec, err := kit.SpecFromAnnotations(i, module, annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := kit.NewKitGenerator(spec).Generate()
+	return files, err
+}
+
+func generateMock(i parse.Interface, module parse.Module, annotations annotations.InterfaceAnnotation) ([]gen.GenResult, error) {
+	spec, err := mock.SpecFromAnnotations(i, module, annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := mock.NewMockGenerator(spec).Generate()
+	return files, err
+}
+
+func generateFirestore(i parse.Interface, module parse.Module, annotations annotations.InterfaceAnnotation) ([]gen.GenResult, error) {
+	spec, err := firestore.SpecFromAnnotations(i, module, annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := firestore.NewFirestoreGenerator(spec).Generate()
+	return files, err
+}
+
+// Returns the header written to generated files.
+func fileHeader(config GeneratorConfig) gen.FileHeader {
+	return gen.FileHeader{
+		Version: Version,
+		Command: config.GenerateCommand,
+	}
+}
+
+// Writes the generated code to the output files, files whose content did not change are not written to keep modification times stable.
+// Errors are logged, returns the hashes of the content of all output files that could be rendered.
+// If checkVersion is true, files that were generated by a newer version of the code generator are not overwritten
+// and an error is returned for each of them, see checkFileVersion.
+func outputGeneratedCode(c []gen.GenResult, header gen.FileHeader, checkVersion bool) (map[string]string, []error) {
+	generatedFiles := gen.MergeResults(c, header)
+	sort.Slice(generatedFiles, func(i, j int) bool {
+		return generatedFiles[i].Path < generatedFiles[j].Path
+	})
+
+	hashes := make(map[string]string)
+	var errs []error
+	for _, gf := range generatedFiles {
+		var buf bytes.Buffer
+		err := gf.Render(&buf)
+		if err != nil {
+			log.Printf("could not render file %v, got error: %v\n", gf.Path, err)
+			continue
+		}
+		hashes[gf.Path] = hashBytes(buf.Bytes())
+
+		existing, err := os.ReadFile(gf.Path)
+		if err == nil && bytes.Equal(existing, buf.Bytes()) {
+			continue
+		}
+		if err == nil && checkVersion {
+			if err := checkFileVersion(existing, gf.Path, header.Version); err != nil {
+				errs = append(errs, err)
+				delete(hashes, gf.Path)
+				continue
+			}
+		}
+		err = saveFile(buf.Bytes(), gf.Path)
+		if err != nil {
+			log.Printf("could not save file %v, got error: %v\n", gf.Path, err)
+			delete(hashes, gf.Path)
+		}
+	}
+
+	return hashes, errs
+}
+
+// Changes the output file of all generated Go code to the given file.
+// Returns an error if the code belongs to more than one package, since it could not be written to a single file.
+func toSingleFile(c []gen.GenResult, file string) ([]gen.GenResult, error) {
+	var packages []string
+	result := make([]gen.GenResult, len(c))
+	for i, r := range c {
+		if r.Content == nil {
+			if !slices.Contains(packages, r.PackagePath) {
+				packages = append(packages, r.PackagePath)
+			}
+			r.OutputFile = file
+		}
+		result[i] = r
+	}
+	if len(packages) > 1 {
+		sort.Strings(packages)
+		return nil, errors.New(fmt.Sprintf("cannot write generated code to single file %v, code belongs to multiple packages: %v", file, strings.Join(packages, ", ")))
+	}
+	return result, nil
+}
+
+// Prints the generated code to w instead of writing it to the output files.
+// If there is more than one file, each file is preceded by a comment line with its path.
+func printGeneratedCode(w io.Writer, c []gen.GenResult, header gen.FileHeader) error {
+	generatedFiles := gen.MergeResults(c, header)
+	sort.Slice(generatedFiles, func(i, j int) bool {
+		return generatedFiles[i].Path < generatedFiles[j].Path
+	})
+
+	for _, gf := range generatedFiles {
+		if len(generatedFiles) > 1 {
+			if _, err := fmt.Fprintf(w, "// file: %v\n", gf.Path); err != nil {
+				return err
+			}
+		}
+		if err := gf.Render(w); err != nil {
+			return fmt.Errorf("could not render file %v: %w", gf.Path, err)
+		}
+	}
+	return nil
+}
+
+// Prints a unified diff between the generated code and the existing files to stdout.
+// Returns an error if any of the files differ, i.e. if the generated code is out of date.
+func diffGeneratedCode(c []gen.GenResult, header gen.FileHeader) error {
+	generatedFiles := gen.MergeResults(c, header)
+	sort.Slice(generatedFiles, func(i, j int) bool {
+		return generatedFiles[i].Path < generatedFiles[j].Path
+	})
+
+	outOfDate := 0
+	for _, gf := range generatedFiles {
+		d, err := diffFile(gf)
+		if err != nil {
+			return err
+		}
+		if d != "" {
+			fmt.Print(d)
+			outOfDate++
+		}
+	}
+
+	if outOfDate > 0 {
+		return errors.New(fmt.Sprintf("generated code differs from %v existing file(s)", outOfDate))
+	}
+	return nil
+}
+
+// Returns a unified diff between the existing file and the rendered code file, or an empty string if they are equal.
+// A file that does not exist yet is treated as empty.
+func diffFile(gf gen.GeneratedFile) (string, error) {
+	filename := gf.Path
+	var buf bytes.Buffer
+	err := gf.Render(&buf)
+	if err != nil {
+		return "", fmt.Errorf("could not render file %v: %w", filename, err)
+	}
+
+	existing, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("could not read file %v: %w", filename, err)
+	}
+
+	if bytes.Equal(existing, buf.Bytes()) {
+		return "", nil
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(existing)),
+		B:        difflib.SplitLines(buf.String()),
+		FromFile: filename,
+		ToFile:   filename + " (generated)",
+		Context:  3,
+	})
+}
+
+func saveFile(content []byte, filename string) error {
+	dir := filepath.Dir(filename)
+	err := makeDir(dir)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, content, 0644)
+}
+
+func makeDir(d string) error {
+	return os.MkdirAll(d, os.ModePerm)
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dkinzler/kit/codegen/gen"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSingleFileAndStdout(t *testing.T) {
+	a := assert.New(t)
+
+	code := func(name string) *jen.Group {
+		f := jen.NewFile("x")
+		f.Type().Id(name).Struct()
+		return f.Group
+	}
+
+	results := []gen.GenResult{
+		{Code: code("A"), PackagePath: "example.com/a", PackageName: "a", OutputFile: "/a/a.gen.go"},
+		{Code: code("B"), PackagePath: "example.com/a", PackageName: "a", OutputFile: "/a/b.gen.go"},
+		{Content: []byte("{}\n"), OutputFile: "/schema/a.json"},
+	}
+
+	single, err := toSingleFile(results, "/out.go")
+	a.Nil(err)
+	a.Equal("/out.go", single[0].OutputFile)
+	a.Equal("/out.go", single[1].OutputFile)
+	// files without Go code are not changed
+	a.Equal("/schema/a.json", single[2].OutputFile)
+
+	var buf bytes.Buffer
+	a.Nil(printGeneratedCode(&buf, single, gen.FileHeader{}))
+	out := buf.String()
+	a.True(strings.HasPrefix(out, "// file: /out.go\n"))
+	a.Contains(out, "type A struct{}")
+	a.Contains(out, "type B struct{}")
+	a.True(strings.HasSuffix(out, "// file: /schema/a.json\n{}\n"))
+
+	// a single file is printed without a path comment
+	buf.Reset()
+	a.Nil(printGeneratedCode(&buf, single[:2], gen.FileHeader{}))
+	a.True(strings.HasPrefix(buf.String(), "// "+gen.GeneratedFileComment))
+
+	results = append(results, gen.GenResult{Code: code("C"), PackagePath: "example.com/c", PackageName: "c", OutputFile: "/c/c.gen.go"})
+	_, err = toSingleFile(results, "/out.go")
+	a.EqualError(err, "cannot write generated code to single file /out.go, code belongs to multiple packages: example.com/a, example.com/c")
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+)
+
+// Hook post-processes the code produced by the generators before it is merged into files and written.
+// A hook can modify, remove or add results and returns the results to continue with,
+// e.g. to add a license comment to every file, add methods to generated types or generate additional wiring code.
+// If the cache is used (see GeneratorConfig.Cache), hooks only receive the results of the generators that were run.
+type Hook func(module parse.Module, results []gen.GenResult) ([]gen.GenResult, error)
+
+// Run is like Generate, but the given hooks are run after the hooks of the config.
+// It allows programs to use the code generator with custom post-processing, e.g.
+//
+//	err := app.Run(app.GeneratorConfig{InputDir: "."}, func(m parse.Module, results []gen.GenResult) ([]gen.GenResult, error) {
+//		for i := range results {
+//			if results[i].Code != nil {
+//				code := jen.NewFile("").Group
+//				code.Comment("Copyright ...")
+//				code.Add(results[i].Code)
+//				results[i].Code = code
+//			}
+//		}
+//		return results, nil
+//	})
+func Run(config GeneratorConfig, hooks ...Hook) error {
+	config.Hooks = append(append([]Hook{}, config.Hooks...), hooks...)
+	return Generate(config)
+}
+
+// Runs the hooks in order, every hook receives the results of the previous one.
+func runHooks(hooks []Hook, module parse.Module, results []gen.GenResult) ([]gen.GenResult, error) {
+	for i, hook := range hooks {
+		var err error
+		results, err = hook(module, results)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("hook %v failed: %v", i, err))
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dkinzler/kit/codegen/annotations"
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunHooks(t *testing.T) {
+	a := assert.New(t)
+
+	root := t.TempDir()
+	a.Nil(os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/example\n\ngo 1.22\n"), 0644))
+	a.Nil(os.WriteFile(filepath.Join(root, "a.go"), []byte("package example\n\n// @Foo{}\ntype A interface{ A() }\n"), 0644))
+
+	registry := gen.NewRegistry()
+	a.Nil(registry.Register("Foo", func(i parse.Interface, m parse.Module, _ annotations.InterfaceAnnotation) ([]gen.GenResult, error) {
+		f := jen.NewFile("example")
+		f.Type().Id("Foo" + i.Name).Struct()
+		return []gen.GenResult{{Code: f.Group, PackagePath: i.Package, PackageName: "example", OutputFile: m.FileName("", "foo.gen.go")}}, nil
+	}))
+
+	licenseHook := func(m parse.Module, results []gen.GenResult) ([]gen.GenResult, error) {
+		for i := range results {
+			code := jen.NewFile("").Group
+			code.Comment("Copyright example")
+			code.Add(results[i].Code)
+			results[i].Code = code
+		}
+		return results, nil
+	}
+	addHook := func(m parse.Module, results []gen.GenResult) ([]gen.GenResult, error) {
+		a.Len(results, 1)
+		return append(results, gen.GenResult{Content: []byte("extra\n"), OutputFile: m.FileName("", "extra.txt")}), nil
+	}
+
+	config := GeneratorConfig{InputDir: root, Registry: registry, FailOnError: true}
+	a.Nil(Run(config, licenseHook, addHook))
+
+	content, err := os.ReadFile(filepath.Join(root, "foo.gen.go"))
+	a.Nil(err)
+	a.Contains(string(content), "// Copyright example\n")
+	a.Contains(string(content), "type FooA struct{}")
+	content, err = os.ReadFile(filepath.Join(root, "extra.txt"))
+	a.Nil(err)
+	a.Equal("extra\n", string(content))
+
+	// nothing is written if a hook fails
+	a.Nil(os.Remove(filepath.Join(root, "foo.gen.go")))
+	err = Run(config, func(m parse.Module, results []gen.GenResult) ([]gen.GenResult, error) {
+		return nil, errors.New("hook error")
+	})
+	a.EqualError(err, "hook 0 failed: hook error")
+	_, err = os.Stat(filepath.Join(root, "foo.gen.go"))
+	a.True(os.IsNotExist(err))
+}
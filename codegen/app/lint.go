@@ -0,0 +1,43 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dkinzler/kit/codegen/parse"
+)
+
+// Checks the annotations of all interfaces in the input directory without writing any files, e.g. to use as a pre-commit hook.
+// The generators are run for all annotations, so that invalid specifications are found.
+// In addition unknown keys in annotations and annotations without a registered generator are reported.
+// Every problem is printed on a separate line, an error is returned if there is at least one problem.
+// The FailOnError and DryRun fields of the config are ignored.
+func Lint(config GeneratorConfig) error {
+	registry := config.Registry
+	if registry == nil {
+		registry = DefaultRegistry()
+	}
+
+	module, err := getModule(config)
+	if err != nil {
+		return err
+	}
+
+	var problems []error
+	is, err := parse.ParseDir(config.InputDir, module, config.Exclude...)
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		problems = append(problems, joined.Unwrap()...)
+	} else if err != nil {
+		problems = append(problems, err)
+	}
+	_, generatorErrs := runGenerators(is, module, registry, false, generatorOptions{strict: true, options: config.Options})
+	problems = append(problems, generatorErrs...)
+
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return errors.New(fmt.Sprintf("found %v problem(s)", len(problems)))
+	}
+	return nil
+}
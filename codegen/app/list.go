@@ -0,0 +1,141 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dkinzler/kit/codegen/annotations"
+	"github.com/dkinzler/kit/codegen/internal/kit"
+	"github.com/dkinzler/kit/codegen/parse"
+)
+
+// Prints every annotated interface in the input directory, together with its endpoints, http routes and
+// the files that would be written by Generate. No files are written.
+// Errors for single interfaces are printed as part of the list, an error is only returned if the input directory cannot be parsed.
+//
+// Example output:
+//
+//	svc.Service (svc/svc.go:12)
+//	  annotations: Kit, Mock
+//	  endpoints:
+//	    CreateEndpoint  Create  POST /api/v1/items  nats:items.create
+//	  files:
+//	    endpoint/endpoint.gen.go
+//	    http/http.gen.go
+func List(config GeneratorConfig, w io.Writer) error {
+	registry := config.Registry
+	if registry == nil {
+		registry = DefaultRegistry()
+	}
+
+	module, err := getModule(config)
+	if err != nil {
+		return err
+	}
+
+	is, err := parse.ParseDir(config.InputDir, module, config.Exclude...)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range is {
+		a, err := annotations.ParseInterfaceAnnotations(i)
+		if err != nil {
+			fmt.Fprintf(w, "%v (%v)\n  error: %v\n", interfaceName(i), relativePath(module, i.File, i.Line), err)
+			continue
+		}
+		if len(a) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(a))
+		for name := range a {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(w, "%v (%v)\n", interfaceName(i), relativePath(module, i.File, i.Line))
+		fmt.Fprintf(w, "  annotations: %v\n", strings.Join(names, ", "))
+
+		// errors are printed below when the generator is run
+		if annotation, ok := a["Kit"]; ok {
+			if spec, err := kit.SpecFromAnnotations(i, module, annotation); err == nil {
+				listEndpoints(w, spec)
+			}
+		}
+
+		var files []string
+		for _, name := range names {
+			generator, ok := registry.Get(name)
+			if !ok {
+				fmt.Fprintf(w, "  unknown annotation: %v\n", name)
+				continue
+			}
+			results, err := generator(i, module, a[name])
+			if err != nil {
+				fmt.Fprintf(w, "  error: %v\n", err)
+				continue
+			}
+			for _, r := range results {
+				files = append(files, relativePath(module, r.OutputFile, 0))
+			}
+		}
+		if len(files) > 0 {
+			sort.Strings(files)
+			fmt.Fprintln(w, "  files:")
+			for j, f := range files {
+				// multiple results can be merged into the same file
+				if j > 0 && files[j-1] == f {
+					continue
+				}
+				fmt.Fprintf(w, "    %v\n", f)
+			}
+		}
+	}
+	return nil
+}
+
+func listEndpoints(w io.Writer, spec kit.KitGenSpecification) {
+	summaries := spec.EndpointSummaries()
+	if len(summaries) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "  endpoints:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, s := range summaries {
+		var transports []string
+		if s.HttpPath != "" {
+			route := s.HttpMethod + " " + s.HttpPath
+			if s.Websocket {
+				route += " (websocket)"
+			}
+			transports = append(transports, route)
+		}
+		if s.NatsSubject != "" {
+			transports = append(transports, "nats:"+s.NatsSubject)
+		}
+		if s.AmqpQueue != "" {
+			transports = append(transports, "amqp:"+s.AmqpQueue)
+		}
+		fmt.Fprintf(tw, "    %v\t%v\t%v\n", s.Name, s.Method, strings.Join(transports, "\t"))
+	}
+	tw.Flush()
+}
+
+// Name of the interface qualified with the package name, e.g. "svc.Service".
+func interfaceName(i parse.Interface) string {
+	return path.Base(i.Package) + "." + i.Name
+}
+
+// Returns the path of the file relative to the module root, with the line appended if it is not 0.
+func relativePath(module parse.Module, file string, line int) string {
+	file = moduleRelativePath(module, file)
+	if line > 0 {
+		return fmt.Sprintf("%v:%v", file, line)
+	}
+	return file
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/annotations"
+	"github.com/dkinzler/kit/codegen/internal/kit"
+	"github.com/dkinzler/kit/codegen/parse"
+)
+
+// Generates a main package for every interface in the input directory with a Kit annotation, that wires the generated endpoints
+// and http handlers and runs a http server. Only interfaces with the given name are considered if it is not empty.
+//
+// The file is written to "cmd/<interface name in lowercase>/main.go" in the module root, or to output if it is not empty,
+// which is only allowed if there is a single interface.
+// Since the scaffold is meant to be edited, existing files are only overwritten if force is true.
+func Scaffold(config GeneratorConfig, interfaceName, output string, force bool) error {
+	module, err := getModule(config)
+	if err != nil {
+		return err
+	}
+
+	is, err := parse.ParseDir(config.InputDir, module, config.Exclude...)
+	if err != nil {
+		return err
+	}
+
+	var specs []kit.KitGenSpecification
+	for _, i := range is {
+		if interfaceName != "" && i.Name != interfaceName {
+			continue
+		}
+		a, err := annotations.ParseInterfaceAnnotations(i)
+		if err != nil {
+			return i.WrapError(err)
+		}
+		annotation, ok := a["Kit"]
+		if !ok {
+			continue
+		}
+		annotation.Options = config.Options
+		spec, err := kit.SpecFromAnnotations(i, module, annotation)
+		if err != nil {
+			return i.WrapError(err)
+		}
+		specs = append(specs, spec)
+	}
+
+	if len(specs) == 0 {
+		return errors.New("no interface with a Kit annotation found")
+	}
+	if output != "" && len(specs) > 1 {
+		return errors.New(fmt.Sprintf("found %v interfaces with a Kit annotation, use --interface to select one when setting --output", len(specs)))
+	}
+
+	for _, spec := range specs {
+		filename := output
+		if filename == "" {
+			filename = filepath.Join(spec.Module.Path, "cmd", strings.ToLower(spec.Interface.Name), "main.go")
+		}
+		if _, err := os.Stat(filename); err == nil && !force {
+			log.Printf("file %v already exists, use --force to overwrite it\n", filename)
+			continue
+		}
+
+		f, err := kit.NewKitGenerator(spec).GenerateScaffold()
+		if err != nil {
+			return spec.Interface.WrapError(err)
+		}
+		var buf bytes.Buffer
+		if err := f.Render(&buf); err != nil {
+			return fmt.Errorf("could not render file %v: %w", filename, err)
+		}
+		if err := saveFile(buf.Bytes(), filename); err != nil {
+			return err
+		}
+		log.Printf("wrote scaffold for interface %v to %v\n", spec.Interface.Name, filename)
+	}
+	return nil
+}
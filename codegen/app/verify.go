@@ -0,0 +1,132 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/imports"
+)
+
+// Verifies the output files if enabled, see GeneratorConfig.Verify.
+// The hashes of files whose imports are fixed are updated.
+func verifyOutput(config GeneratorConfig, hashes map[string]string) error {
+	if !config.Verify {
+		return nil
+	}
+	var files []string
+	for file := range hashes {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return verifyGeneratedCode(files, hashes)
+}
+
+// Fixes the imports of the given generated files and type checks the packages that contain them, including test files.
+// Files that do not contain Go code are ignored.
+// Returns an error with all compile errors of these packages, errors in other packages, e.g. dependencies, are not reported.
+// Files are checked with the module they belong to, which is not necessarily the module of the interfaces,
+// see parse.Module.OutputModule. Files that do not belong to any module can not be type checked, they are logged.
+//
+// Imports are usually already correct, since they are managed by jennifer when rendering files.
+// Fixed files are written again and their hashes updated in the given map if it is not nil.
+func verifyGeneratedCode(files []string, hashes map[string]string) error {
+	var errs []string
+	// maps the root directory of a module to the directories of the packages to check
+	dirsByModule := make(map[string][]string)
+	for _, file := range files {
+		if filepath.Ext(file) != ".go" {
+			continue
+		}
+		if err := fixImports(file, hashes); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		module, err := parse.NewModuleFromDir(filepath.Dir(file))
+		if err != nil {
+			log.Printf("could not verify file %v, it does not belong to a module: %v\n", file, err)
+			continue
+		}
+		dir := filepath.Dir(file)
+		if !slices.Contains(dirsByModule[module.Path], dir) {
+			dirsByModule[module.Path] = append(dirsByModule[module.Path], dir)
+		}
+	}
+
+	var modules []string
+	for module := range dirsByModule {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		errs = append(errs, typeCheck(module, dirsByModule[module])...)
+	}
+
+	if len(errs) > 0 {
+		return errors.New(fmt.Sprintf("verification of generated code failed:\n%v", strings.Join(errs, "\n")))
+	}
+	return nil
+}
+
+func fixImports(file string, hashes map[string]string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	fixed, err := imports.Process(file, content, &imports.Options{Comments: true, TabIndent: true, TabWidth: 8})
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(content, fixed) {
+		return nil
+	}
+	if err := saveFile(fixed, file); err != nil {
+		return err
+	}
+	if hashes != nil {
+		hashes[file] = hashBytes(fixed)
+	}
+	return nil
+}
+
+// Type checks the packages in the given directories of a module and returns their errors.
+func typeCheck(module string, dirs []string) []string {
+	var patterns []string
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(module, dir)
+		if err != nil {
+			return []string{err.Error()}
+		}
+		patterns = append(patterns, "./"+filepath.ToSlash(rel))
+	}
+	sort.Strings(patterns)
+
+	pkgs, err := packages.Load(&packages.Config{
+		// dependencies are type checked from source as well, export data of the go command is not necessarily readable by go/packages
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes,
+		Dir:   module,
+		Tests: true,
+	}, patterns...)
+	if err != nil {
+		return []string{fmt.Sprintf("could not load packages %v: %v", strings.Join(patterns, " "), err)}
+	}
+	var errs []string
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			// errors of a package are repeated in its test variant
+			if !slices.Contains(errs, e.Error()) {
+				errs = append(errs, e.Error())
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyGeneratedCode(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	write := func(file, content string) string {
+		path := filepath.Join(dir, file)
+		a.Nil(os.MkdirAll(filepath.Dir(path), 0755))
+		a.Nil(os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+	write("example/go.mod", "module example.com/example\n\ngo 1.22\n")
+	ok := write("example/a/a.gen.go", "package a\n\nfunc A() int { return 1 }\n")
+	schema := write("example/schema/a.json", "{")
+
+	a.Nil(verifyGeneratedCode([]string{ok, schema}, nil))
+	a.Nil(verifyOutput(GeneratorConfig{}, map[string]string{"/does/not/exist.go": ""}))
+
+	// missing imports are fixed and the hash is updated
+	imports := write("example/c/c.gen.go", "package c\n\nfunc C() string { return strings.ToUpper(\"c\") }\n")
+	hashes := map[string]string{imports: ""}
+	a.Nil(verifyOutput(GeneratorConfig{Verify: true}, hashes))
+	content, err := os.ReadFile(imports)
+	a.Nil(err)
+	a.Contains(string(content), "import \"strings\"")
+	a.Equal(hashBytes(content), hashes[imports])
+
+	broken := write("example/b/b.gen.go", "package b\n\nfunc B() int { return \"b\" }\n")
+	brokenTest := write("example/b/b_test.go", "package b\n\nvar _ string = B()\n")
+	// files in a different module are checked as well, see parse.Module.OutputModule
+	write("other/go.mod", "module example.com/other\n\ngo 1.22\n")
+	other := write("other/d/d.gen.go", "package d\n\nvar D int = \"d\"\n")
+	err = verifyGeneratedCode([]string{ok, broken, brokenTest, other}, nil)
+	a.NotNil(err)
+	a.Contains(err.Error(), "b.gen.go:3")
+	a.Contains(err.Error(), "b_test.go:3")
+	a.Contains(err.Error(), "d.gen.go:3")
+
+	// errors in packages that are not verified are not reported, even if a verified package imports them
+	write("example/e/e.go", "package e\n\nvar E int = \"e\"\n")
+	importsBroken := write("example/f/f.gen.go", "package f\n\nimport \"example.com/example/e\"\n\nvar F = e.E\n")
+	a.Nil(verifyGeneratedCode([]string{importsBroken}, nil))
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/gen"
+)
+
+// Version of the code generator, written to the header of generated files.
+// It is the version of the module github.com/dkinzler/kit the binary was built with, e.g. "0.2.1" if it was installed with
+// "go install github.com/dkinzler/kit/codegen@v0.2.1", or DevelVersion if the version is not known,
+// e.g. when the code generator is built from a local checkout or the module is replaced.
+var Version = versionFromBuildInfo(debug.ReadBuildInfo())
+
+// Version of a code generator built from a local checkout of the module, see Version.
+const DevelVersion = "devel"
+
+const modulePath = "github.com/dkinzler/kit"
+
+func versionFromBuildInfo(info *debug.BuildInfo, ok bool) string {
+	if !ok {
+		return DevelVersion
+	}
+	module := &info.Main
+	if module.Path != modulePath {
+		module = nil
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				module = dep
+				break
+			}
+		}
+	}
+	if module != nil && module.Replace != nil {
+		module = module.Replace
+	}
+	if module == nil || module.Version == "" || module.Version == "(devel)" {
+		return DevelVersion
+	}
+	// build metadata like "+dirty" is not relevant for comparing versions
+	version, _, _ := strings.Cut(module.Version, "+")
+	return strings.TrimPrefix(version, "v")
+}
+
+// Returns an error if the existing content of a file was generated by a newer version of the code generator than the given one.
+// Files without a version in the header (see gen.HeaderVersion) can always be overwritten.
+// Versions are not compared if either of them is DevelVersion, since it is unknown which one is newer.
+func checkFileVersion(existing []byte, filename, version string) error {
+	fileVersion := gen.HeaderVersion(existing)
+	if fileVersion == "" || fileVersion == DevelVersion || version == DevelVersion || compareVersions(fileVersion, version) <= 0 {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("file %v was generated by version %v of the code generator, refusing to overwrite it with version %v", filename, fileVersion, version))
+}
+
+// Compares two versions consisting of dot separated parts, e.g. "0.1" and "0.10.2".
+// Numeric parts are compared as numbers, other parts as strings and missing parts count as 0.
+// Returns -1 if a < b, 0 if a == b and 1 if a > b.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := "0", "0"
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		if xErr == nil && yErr == nil {
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		} else if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
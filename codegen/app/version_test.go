@@ -0,0 +1,45 @@
+package app
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(0, compareVersions("0.1", "0.1"))
+	a.Equal(0, compareVersions("0.1", "0.1.0"))
+	a.Equal(-1, compareVersions("0.1", "0.2"))
+	a.Equal(-1, compareVersions("0.2", "0.10"))
+	a.Equal(1, compareVersions("1.0", "0.9.9"))
+	a.Equal(1, compareVersions("0.1.1", "0.1"))
+
+	a.Nil(checkFileVersion([]byte("package x"), "x.go", "0.1"))
+	a.Nil(checkFileVersion([]byte("// codegen version: 0.1\npackage x"), "x.go", "0.2"))
+	a.NotNil(checkFileVersion([]byte("// codegen version: 0.3\npackage x"), "x.go", "0.2"))
+	a.Nil(checkFileVersion([]byte("// codegen version: 0.3\npackage x"), "x.go", DevelVersion))
+	a.Nil(checkFileVersion([]byte("// codegen version: devel\npackage x"), "x.go", "0.2"))
+}
+
+func TestVersionFromBuildInfo(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(DevelVersion, versionFromBuildInfo(nil, false))
+	// installed with go install
+	a.Equal("0.2.1", versionFromBuildInfo(&debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "v0.2.1"}}, true))
+	a.Equal("0.2.2-0.20240101120000-abcdef123456", versionFromBuildInfo(&debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "v0.2.2-0.20240101120000-abcdef123456+dirty"}}, true))
+	// built from a local checkout
+	a.Equal(DevelVersion, versionFromBuildInfo(&debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "(devel)"}}, true))
+	// custom code generator binary, see package documentation
+	custom := &debug.BuildInfo{
+		Main: debug.Module{Path: "example.com/x", Version: "v1.0.0"},
+		Deps: []*debug.Module{{Path: "example.com/y", Version: "v2.0.0"}, {Path: modulePath, Version: "v0.3.0"}},
+	}
+	a.Equal("0.3.0", versionFromBuildInfo(custom, true))
+	custom.Deps[1].Replace = &debug.Module{Path: "../kit"}
+	a.Equal(DevelVersion, versionFromBuildInfo(custom, true))
+	a.Equal(DevelVersion, versionFromBuildInfo(&debug.BuildInfo{Main: debug.Module{Path: "example.com/x"}}, true))
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dkinzler/kit/codegen/gen"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Time to wait after the last file change before code is generated again.
+// Saving a file in an editor or switching git branches can cause many events in a short amount of time.
+const watchDebounce = 500 * time.Millisecond
+
+// Generates code and then watches the input directory (recursively) for changes to go files.
+// Whenever a file changes code is generated again.
+// Errors during code generation are logged, but do not stop watching.
+//
+// This function blocks until an error occurs while setting up or using the file watcher.
+func Watch(config GeneratorConfig) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// fsnotify does not watch directories recursively, every subdirectory needs to be added explicitly.
+	err = watchDirRecursive(watcher, config.InputDir)
+	if err != nil {
+		return err
+	}
+
+	runGenerate := func() {
+		err := Generate(config)
+		if err != nil {
+			log.Println("code generation failed:", err)
+		} else {
+			log.Println("code generated, watching for changes...")
+		}
+	}
+
+	runGenerate()
+
+	// nil until a relevant file changes, reading from a nil channel blocks forever
+	var pending <-chan time.Time
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := watchDirRecursive(watcher, event.Name); err != nil {
+						log.Println("could not watch directory", event.Name, err)
+					}
+					continue
+				}
+			}
+			if !isWatchedFile(event.Name) {
+				continue
+			}
+			pending = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-pending:
+			pending = nil
+			runGenerate()
+		}
+	}
+}
+
+func watchDirRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// Returns true if a change to the given file should cause code to be generated again.
+// Test files and files created by the code generator are ignored, otherwise writing the generated code would trigger another run.
+func isWatchedFile(name string) bool {
+	if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+		return false
+	}
+	content, err := os.ReadFile(name)
+	if err != nil {
+		// file was removed or renamed, which can also affect the generated code
+		return errors.Is(err, fs.ErrNotExist)
+	}
+	return !bytes.HasPrefix(content, []byte("// "+gen.GeneratedFileComment))
+}
@@ -4,6 +4,10 @@ package gen
 
 import (
 	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"unicode"
 
@@ -12,6 +16,10 @@ import (
 	"github.com/dave/jennifer/jen"
 )
 
+// Package comment added to every generated file.
+// Files starting with it are not parsed by parse.ParseDir.
+const GeneratedFileComment = parse.GeneratedFileComment
+
 // A piece of code returned by a code generator, that is assigned to a particular output package/file.
 // Multiple instances of GenResult can be merged into a single code file, since different code generators might provide parts of it.
 type GenResult struct {
@@ -26,6 +34,16 @@ type GenResult struct {
 	Imports map[string]string
 	// Path of the file the code should ultimately be written to.
 	OutputFile string
+	// Interface the code was generated for, e.g. "example.com/abc.Service", and the file it is defined in.
+	// Written to the header of the output file, see FileHeader.
+	Source     string
+	SourceFile string
+	// Content of an output file that does not contain Go code, e.g. TypeScript code.
+	// If not nil, Code, PackagePath, PackageName and Imports are ignored and no header is written.
+	// The contents of all results for the same output file are concatenated.
+	Content []byte
+	// Http routes registered by the generated code, used to detect conflicting routes, see CheckRoutes.
+	Routes []Route
 }
 
 // The interface all code generators should implement.
@@ -38,25 +56,79 @@ type Generator interface {
 	Generate() ([]GenResult, error)
 }
 
+// Metadata written to the header of every generated file, below GeneratedFileComment.
+type FileHeader struct {
+	// Version of the code generator, omitted if empty. See HeaderVersion.
+	Version string
+	// Command to generate the files again, e.g. "go run github.com/dkinzler/kit/codegen".
+	// If not empty, a //go:generate directive is added that runs the command with the flag --inputDir
+	// for every directory that contains a source of the files of an output package.
+	// Since "go generate" runs every directive of a package, the directives are written only to one file per package,
+	// the first file in lexical order that is not a test file.
+	Command string
+}
+
+const headerVersionPrefix = "codegen version: "
+
+// Returns the version of the code generator written to the header of a generated file, or an empty string if there is none.
+func HeaderVersion(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "// "+headerVersionPrefix); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
 type GeneratedFile struct {
 	File *jen.File
-	Path string
+	// Content of a file that does not contain Go code, see GenResult.Content. If not nil, File is nil.
+	Content []byte
+	Path    string
+}
+
+// Writes the rendered Go code or the content of the file to w.
+func (gf GeneratedFile) Render(w io.Writer) error {
+	if gf.Content != nil {
+		_, err := w.Write(gf.Content)
+		return err
+	}
+	return gf.File.Render(w)
 }
 
 // Generates a list of GeneratedFile values by merging together all the code pieces for the same output file path into a single code file.
-func MergeResults(results []GenResult) []GeneratedFile {
+// The header is written to every file together with the sources of the code pieces.
+// Import aliases that collide with declarations in existing files of the output package are replaced, see resolveImportAliases.
+func MergeResults(results []GenResult, header FileHeader) []GeneratedFile {
 	resultsByFile := make(map[string][]GenResult)
 	for _, result := range results {
 		resultsByFile[result.OutputFile] = append(resultsByFile[result.OutputFile], result)
 	}
 
+	generateFiles := generateDirectiveFiles(resultsByFile)
+
 	result := make([]GeneratedFile, len(resultsByFile))
 	i := 0
 	for outputFile, r := range resultsByFile {
 		rr := r[0]
+		if rr.Content != nil {
+			var content []byte
+			for _, part := range r {
+				content = append(content, part.Content...)
+			}
+			result[i] = GeneratedFile{Content: content, Path: outputFile}
+			i++
+			continue
+		}
 		f := jen.NewFilePathName(rr.PackagePath, rr.PackageName)
-		f.PackageComment("generated code, do not modify")
-		for path, alias := range mergeImports(r) {
+		f.PackageComment(GeneratedFileComment)
+		addHeader(f, header, outputFile, r, generateFiles[outputFile])
+		// aliases must not collide with identifiers of hand-written files in the same package
+		scope := readPackageScope(outputFile, rr.PackageName)
+		for path, alias := range resolveImportAliases(mergeImports(r), scope) {
 			f.ImportAlias(path, alias)
 		}
 		for _, part := range r {
@@ -68,6 +140,71 @@ func MergeResults(results []GenResult) []GeneratedFile {
 	return result
 }
 
+// Returns the Go output file of every output directory that contains the //go:generate directives, see FileHeader.Command.
+// Maps the file to the results of all files in the directory, whose sources the directives must cover.
+func generateDirectiveFiles(resultsByFile map[string][]GenResult) map[string][]GenResult {
+	filesByDir := make(map[string][]string)
+	for outputFile, r := range resultsByFile {
+		if r[0].Content == nil {
+			dir := filepath.Dir(outputFile)
+			filesByDir[dir] = append(filesByDir[dir], outputFile)
+		}
+	}
+	result := make(map[string][]GenResult)
+	for _, files := range filesByDir {
+		sort.Slice(files, func(i, j int) bool {
+			// test files last, they are not needed to generate the code again
+			ti, tj := strings.HasSuffix(files[i], "_test.go"), strings.HasSuffix(files[j], "_test.go")
+			if ti != tj {
+				return tj
+			}
+			return files[i] < files[j]
+		})
+		var r []GenResult
+		for _, file := range files {
+			r = append(r, resultsByFile[file]...)
+		}
+		result[files[0]] = r
+	}
+	return result
+}
+
+// The //go:generate directives are only written if generateResults is not empty, see generateDirectiveFiles.
+func addHeader(f *jen.File, header FileHeader, outputFile string, r []GenResult, generateResults []GenResult) {
+	var sources, inputDirs []string
+	for _, x := range r {
+		if x.Source != "" && !slices.Contains(sources, x.Source) {
+			sources = append(sources, x.Source)
+		}
+	}
+	for _, x := range generateResults {
+		if x.SourceFile == "" {
+			continue
+		}
+		inputDir, err := filepath.Rel(filepath.Dir(outputFile), filepath.Dir(x.SourceFile))
+		if err == nil && !slices.Contains(inputDirs, inputDir) {
+			inputDirs = append(inputDirs, inputDir)
+		}
+	}
+	sort.Strings(inputDirs)
+
+	if header.Version == "" && len(sources) == 0 && (header.Command == "" || len(inputDirs) == 0) {
+		return
+	}
+	f.PackageComment("")
+	if header.Version != "" {
+		f.PackageComment(headerVersionPrefix + header.Version)
+	}
+	if len(sources) > 0 {
+		f.PackageComment("source: " + strings.Join(sources, ", "))
+	}
+	if header.Command != "" {
+		for _, inputDir := range inputDirs {
+			f.PackageComment(fmt.Sprintf("//go:generate %v --inputDir %v", header.Command, filepath.ToSlash(inputDir)))
+		}
+	}
+}
+
 func mergeImports(r []GenResult) map[string]string {
 	result := make(map[string]string)
 	for _, x := range r {
@@ -94,17 +231,89 @@ func (s *SimpleGenerator) GenParamType(p parse.ParamType) jen.Code {
 		} else {
 			return jen.Qual(t.Package, t.Type)
 		}
+	case parse.GenericType:
+		args := make([]jen.Code, len(t.TypeArgs))
+		for i, arg := range t.TypeArgs {
+			args[i] = s.GenParamType(arg)
+		}
+		return jen.Add(s.GenParamType(t.Type)).Types(args...)
 	case parse.MapType:
 		return jen.Map(s.GenParamType(t.KeyType)).Add(s.GenParamType(t.ValueType))
 	case parse.ArrayType:
 		return jen.Index().Add(s.GenParamType(t.Type))
 	case parse.StarType:
 		return jen.Op("*").Add(s.GenParamType(t.Type))
+	case parse.EllipsisType:
+		return jen.Op("...").Add(s.GenParamType(t.Type))
+	case parse.ChanType:
+		switch t.Dir {
+		case parse.ChanSend:
+			return jen.Chan().Op("<-").Add(s.GenParamType(t.Type))
+		case parse.ChanRecv:
+			return jen.Op("<-").Chan().Add(s.GenParamType(t.Type))
+		default:
+			return jen.Chan().Add(s.GenParamType(t.Type))
+		}
+	case parse.FuncType:
+		params := make([]jen.Code, len(t.Params))
+		for i, param := range t.Params {
+			params[i] = jen.Id(param.Name).Add(s.GenParamType(param.Type))
+		}
+		return jen.Func().Params(params...).Add(s.GenReturnParams(t.Returns))
+	case parse.StructType:
+		fields := make([]jen.Code, len(t.Fields))
+		for i, field := range t.Fields {
+			f := jen.Empty()
+			if field.Name != "" {
+				f = jen.Id(field.Name)
+			}
+			f.Add(s.GenParamType(field.Type))
+			if len(field.Tags) > 0 {
+				f.Tag(field.Tags)
+			}
+			fields[i] = f
+		}
+		return jen.Struct(fields...)
+	case parse.UnionType:
+		terms := make([]jen.Code, len(t.Terms))
+		for i, term := range t.Terms {
+			terms[i] = s.GenParamType(term)
+		}
+		return jen.Union(terms...)
+	case parse.TildeType:
+		return jen.Op("~").Add(s.GenParamType(t.Type))
 	default:
 		panic("unimplemented parse.ParamType in GenParamType()")
 	}
 }
 
+// Generates the type parameter list of a generic type, e.g. [T any, K comparable].
+// Returns an empty statement if there are no type parameters.
+func (s *SimpleGenerator) GenTypeParams(params []parse.TypeParam) jen.Code {
+	if len(params) == 0 {
+		return jen.Empty()
+	}
+	result := make([]jen.Code, len(params))
+	for i, param := range params {
+		result[i] = jen.Id(param.Name).Add(s.GenParamType(param.Constraint))
+	}
+	return jen.Types(result...)
+}
+
+// Generates the type argument list to instantiate a generic type with its own type parameters, e.g. [T, K].
+// Useful for method receivers of generic types.
+// Returns an empty statement if there are no type parameters.
+func (s *SimpleGenerator) GenTypeArgs(params []parse.TypeParam) jen.Code {
+	if len(params) == 0 {
+		return jen.Empty()
+	}
+	result := make([]jen.Code, len(params))
+	for i, param := range params {
+		result[i] = jen.Id(param.Name)
+	}
+	return jen.Types(result...)
+}
+
 // Generates a struct type with the given name and fields.
 func (s *SimpleGenerator) GenStructType(name string, fields []jen.Code) jen.Code {
 	return jen.Type().Id(name).Struct(fields...)
@@ -137,8 +346,29 @@ func (s *SimpleGenerator) GenReturnParams(params []parse.Param) jen.Code {
 	} else if len(returnParams) > 1 {
 		return jen.Params(returnParams...)
 	} else {
-		return jen.Empty()
+		// Null instead of Empty, otherwise e.g. a function type without return values would be rendered as "func() "
+		return jen.Null()
+	}
+}
+
+// Like GenParamType but returns the type of a value of the parameter, i.e. a slice type for variadic parameters.
+// Should be used e.g. for variables and struct fields that hold the value of a parameter.
+func (s *SimpleGenerator) GenValueType(p parse.ParamType) jen.Code {
+	if t, ok := p.(parse.EllipsisType); ok {
+		return jen.Index().Add(s.GenParamType(t.Type))
+	}
+	return s.GenParamType(p)
+}
+
+// Returns the arguments to call a method with the given parameters.
+// If the last parameter is variadic, the last argument is expanded with "...".
+func (s *SimpleGenerator) GenCallArgs(params []parse.Param, args []jen.Code) []jen.Code {
+	if len(params) > 0 && len(args) == len(params) {
+		if _, ok := params[len(params)-1].Type.(parse.EllipsisType); ok {
+			args[len(args)-1] = jen.Add(args[len(args)-1]).Op("...")
+		}
 	}
+	return args
 }
 
 func (s *SimpleGenerator) GenParamTypes(params []parse.Param) []jen.Code {
@@ -198,6 +428,38 @@ func UppercaseFirst(s string) string {
 	return strings.Title(s)
 }
 
+// Converts an identifier to lower camel case, e.g. "UserID" to "userID" and "URLPath" to "urlPath".
+// A leading sequence of uppercase letters is lowercased, except for the last one if it starts a new word.
+func CamelCase(s string) string {
+	runes := []rune(s)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// Converts an identifier to snake case, e.g. "userID" to "user_id" and "URLPath" to "url_path".
+// A new word starts at an uppercase letter that follows a lowercase letter or digit,
+// or that is followed by a lowercase letter and preceded by another uppercase letter.
+func SnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
 // Adds the following package comment to the given code file:
 // "generated code, do not edit"
 func AddDefaultPackageComment(f *jen.File) {
@@ -0,0 +1,162 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenParamTypeChanAndFunc(t *testing.T) {
+	a := assert.New(t)
+
+	g := NewSimpleGenerator()
+	event := parse.SimpleType{Type: "Event", Package: "example.com/example"}
+	render := func(p parse.ParamType) string {
+		return jen.Var().Id("x").Add(g.GenParamType(p)).GoString()
+	}
+
+	a.Equal("var x chan example.Event", render(parse.ChanType{Dir: parse.ChanBoth, Type: event}))
+	a.Equal("var x chan<- example.Event", render(parse.ChanType{Dir: parse.ChanSend, Type: event}))
+	a.Equal("var x <-chan *example.Event", render(parse.ChanType{Dir: parse.ChanRecv, Type: parse.StarType{Type: event}}))
+	a.Equal("var x func(e example.Event) error", render(parse.FuncType{
+		Params:  []parse.Param{{Name: "e", Type: event}},
+		Returns: []parse.Param{{Type: parse.SimpleType{Type: "error"}}},
+	}))
+	a.Equal("var x func(int, ...string) (bool, error)", render(parse.FuncType{
+		Params: []parse.Param{
+			{Type: parse.SimpleType{Type: "int"}},
+			{Type: parse.EllipsisType{Type: parse.SimpleType{Type: "string"}}},
+		},
+		Returns: []parse.Param{{Type: parse.SimpleType{Type: "bool"}}, {Type: parse.SimpleType{Type: "error"}}},
+	}))
+	a.Equal("var x func()", render(parse.FuncType{}))
+}
+
+func TestGenParamTypeStruct(t *testing.T) {
+	a := assert.New(t)
+
+	g := NewSimpleGenerator()
+	st := parse.StructType{Fields: []parse.StructField{
+		{Name: "Name", Type: parse.SimpleType{Type: "string"}, Tags: map[string]string{"json": "name"}},
+		{Type: parse.StarType{Type: parse.SimpleType{Type: "Event", Package: "example.com/example"}}},
+	}}
+	a.Equal("var x struct {\n\tName string `json:\"name\"`\n\t*example.Event\n}", jen.Var().Id("x").Add(g.GenParamType(st)).GoString())
+}
+
+func TestGenParamTypeGeneric(t *testing.T) {
+	a := assert.New(t)
+
+	g := NewSimpleGenerator()
+	gt := parse.MapType{
+		KeyType: parse.SimpleType{Type: "string"},
+		ValueType: parse.StarType{Type: parse.GenericType{
+			Type:     parse.SimpleType{Type: "Result", Package: "example.com/page"},
+			TypeArgs: []parse.ParamType{parse.SimpleType{Type: "Item", Package: "example.com/example"}, parse.SimpleType{Type: "int"}},
+		}},
+	}
+	a.Equal("var x map[string]*page.Result[example.Item, int]", jen.Var().Id("x").Add(g.GenParamType(gt)).GoString())
+}
+
+func TestCamelCaseAndSnakeCase(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("userId", CamelCase("userId"))
+	a.Equal("userID", CamelCase("UserID"))
+	a.Equal("urlPath", CamelCase("URLPath"))
+	a.Equal("id", CamelCase("ID"))
+	a.Equal("", CamelCase(""))
+
+	a.Equal("user_id", SnakeCase("userId"))
+	a.Equal("user_id", SnakeCase("userID"))
+	a.Equal("url_path", SnakeCase("URLPath"))
+	a.Equal("page2_size", SnakeCase("page2Size"))
+	a.Equal("id", SnakeCase("ID"))
+	a.Equal("a", SnakeCase("a"))
+}
+
+func TestMergeResultsHeader(t *testing.T) {
+	a := assert.New(t)
+
+	part := func(source, sourceFile string) GenResult {
+		return GenResult{
+			Code:        jen.NewFile("gen").Group,
+			PackagePath: "example.com/example/gen",
+			PackageName: "gen",
+			OutputFile:  "/src/gen/out.go",
+			Source:      source,
+			SourceFile:  sourceFile,
+		}
+	}
+	files := MergeResults([]GenResult{
+		part("example.com/example/a.A", "/src/a/a.go"),
+		part("example.com/example/a.B", "/src/a/a.go"),
+		part("example.com/example/b.C", "/src/b/b.go"),
+	}, FileHeader{Version: "0.1", Command: "codegen"})
+	a.Len(files, 1)
+
+	content := files[0].File.GoString()
+	a.Contains(content, "// source: example.com/example/a.A, example.com/example/a.B, example.com/example/b.C\n")
+	a.Contains(content, "//go:generate codegen --inputDir ../a\n//go:generate codegen --inputDir ../b\n")
+	a.Equal("0.1", HeaderVersion([]byte(content)))
+	a.Equal("", HeaderVersion([]byte("package gen\n\n// codegen version: 0.1\n")))
+
+	// the directives are written to only one file per output package, covering the sources of all its files
+	inFile := func(p GenResult, outputFile string) GenResult {
+		p.OutputFile = outputFile
+		return p
+	}
+	files = MergeResults([]GenResult{
+		inFile(part("example.com/example/a.A", "/src/a/a.go"), "/src/gen/a_test.go"),
+		inFile(part("example.com/example/b.C", "/src/b/b.go"), "/src/gen/b.go"),
+		inFile(part("example.com/example/a.A", "/src/a/a.go"), "/src/gen/c.go"),
+		inFile(part("example.com/example/d.D", "/src/d/d.go"), "/src/other/d.go"),
+	}, FileHeader{Version: "0.1", Command: "codegen"})
+	a.Len(files, 4)
+	contents := make(map[string]string)
+	for _, f := range files {
+		contents[f.Path] = f.File.GoString()
+	}
+	a.Contains(contents["/src/gen/b.go"], "//go:generate codegen --inputDir ../a\n//go:generate codegen --inputDir ../b\n")
+	a.NotContains(contents["/src/gen/a_test.go"], "//go:generate")
+	a.NotContains(contents["/src/gen/c.go"], "//go:generate")
+	a.Contains(contents["/src/gen/c.go"], "// source: example.com/example/a.A\n")
+	a.Contains(contents["/src/other/d.go"], "//go:generate codegen --inputDir ../d\n")
+}
+
+func TestMergeResultsImportAliases(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		a.Nil(os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("a.go", "package x\n\nimport ep \"example.com/endpoint\"\n\nvar e = ep.X\n\nfunc t() {}\n")
+	write("a_test.go", "package x\n\nvar h int\n")
+	write("other.go", "package y\n\nvar h int\n")
+	write("old.gen.go", "// "+GeneratedFileComment+"\npackage x\n\nvar h int\n")
+
+	code := jen.NewFile("x").Group
+	code.Var().Id("_").Op("=").Qual("example.com/endpoint", "X")
+	code.Var().Id("_").Op("=").Qual("example.com/http", "X")
+	code.Var().Id("_").Op("=").Qual("example.com/other", "X")
+	files := MergeResults([]GenResult{{
+		Code:        code,
+		PackagePath: "example.com/x",
+		PackageName: "x",
+		Imports:     map[string]string{"example.com/endpoint": "e", "example.com/http": "t", "example.com/other": "h"},
+		OutputFile:  filepath.Join(dir, "x.gen.go"),
+	}}, FileHeader{})
+	a.Len(files, 1)
+
+	content := files[0].File.GoString()
+	// the alias of the existing file is reused
+	a.Contains(content, "ep \"example.com/endpoint\"")
+	a.Contains(content, "t2 \"example.com/http\"")
+	// declarations of test files, other packages and generated files are ignored
+	a.Contains(content, "h \"example.com/other\"")
+}
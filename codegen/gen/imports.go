@@ -0,0 +1,114 @@
+package gen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Identifiers of the package a generated file is written to, declared in the existing files of the package.
+type packageScope struct {
+	// names of package-level declarations
+	names map[string]bool
+	// import aliases used by the files, by import path
+	aliases map[string][]string
+}
+
+// Reads the package-level declarations and import aliases of the files in the directory of the output file,
+// that belong to the given package. Files produced by the code generator and the output file itself are ignored,
+// since they are replaced. Test files are only considered if the output file is a test file as well.
+// Files that cannot be read or parsed are ignored.
+func readPackageScope(outputFile, packageName string) packageScope {
+	scope := packageScope{names: make(map[string]bool), aliases: make(map[string][]string)}
+	dir := filepath.Dir(outputFile)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return scope
+	}
+	isTest := strings.HasSuffix(outputFile, "_test.go")
+	for _, entry := range entries {
+		name := entry.Name()
+		filename := filepath.Join(dir, name)
+		if entry.IsDir() || filepath.Ext(name) != ".go" || filename == outputFile || (!isTest && strings.HasSuffix(name, "_test.go")) {
+			continue
+		}
+		content, err := os.ReadFile(filename)
+		if err != nil || strings.HasPrefix(string(content), "// "+GeneratedFileComment) {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), filename, content, parser.SkipObjectResolution)
+		if err != nil || f.Name.Name != packageName {
+			continue
+		}
+		for _, imp := range f.Imports {
+			if imp.Name == nil || imp.Name.Name == "_" || imp.Name.Name == "." {
+				continue
+			}
+			if path, err := strconv.Unquote(imp.Path.Value); err == nil {
+				scope.aliases[path] = append(scope.aliases[path], imp.Name.Name)
+			}
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					scope.names[d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						scope.names[s.Name.Name] = true
+					case *ast.ValueSpec:
+						for _, n := range s.Names {
+							scope.names[n.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return scope
+}
+
+// Returns the import aliases to use in a generated file.
+// An alias must not be the name of a package-level declaration of the package, otherwise the generated file would not compile,
+// e.g. if a package that declares a function "t" contains a generated file that imports a package with alias "t".
+// A conflicting alias is replaced with an alias the existing files of the package use for the same import path,
+// or otherwise with the alias followed by a number, e.g. "t2".
+func resolveImportAliases(imports map[string]string, scope packageScope) map[string]string {
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	result := make(map[string]string, len(imports))
+	used := make(map[string]bool)
+	for _, path := range paths {
+		alias := imports[path]
+		if scope.names[alias] || used[alias] {
+			alias = ""
+			for _, a := range scope.aliases[path] {
+				if !scope.names[a] && !used[a] {
+					alias = a
+					break
+				}
+			}
+			for i := 2; alias == ""; i++ {
+				a := imports[path] + strconv.Itoa(i)
+				if !scope.names[a] && !used[a] {
+					alias = a
+				}
+			}
+		}
+		result[path] = alias
+		used[alias] = true
+	}
+	return result
+}
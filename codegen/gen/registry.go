@@ -0,0 +1,63 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dkinzler/kit/codegen/annotations"
+	"github.com/dkinzler/kit/codegen/parse"
+)
+
+// GeneratorFunc generates code for an interface that has an annotation with the name the function was registered with.
+// The annotation on the interface and its methods is passed as the last argument.
+//
+// Generator functions are called concurrently for different interfaces and must be safe for concurrent use,
+// e.g. state shared between calls must be guarded by a mutex, and must not rely on the order in which interfaces are processed.
+type GeneratorFunc func(i parse.Interface, m parse.Module, a annotations.InterfaceAnnotation) ([]GenResult, error)
+
+// Registry maps annotation names to the generator functions that handle them.
+// E.g. a generator function registered with the name "Foo" will be called for every interface with a @Foo{...} annotation.
+//
+// A Registry is not safe for concurrent use, generators should be registered before code is generated.
+type Registry struct {
+	generators map[string]GeneratorFunc
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		generators: make(map[string]GeneratorFunc),
+	}
+}
+
+// Registers a generator function for the annotation with the given name.
+// Returns an error if the name is empty or a generator function was already registered for it.
+func (r *Registry) Register(name string, f GeneratorFunc) error {
+	if name == "" {
+		return errors.New("annotation name is empty")
+	}
+	if f == nil {
+		return errors.New(fmt.Sprintf("generator function for annotation %v is nil", name))
+	}
+	if _, ok := r.generators[name]; ok {
+		return errors.New(fmt.Sprintf("generator already registered for annotation %v", name))
+	}
+	r.generators[name] = f
+	return nil
+}
+
+// Returns the generator function registered for the given annotation name.
+func (r *Registry) Get(name string) (GeneratorFunc, bool) {
+	f, ok := r.generators[name]
+	return f, ok
+}
+
+// Returns the sorted names of all annotations a generator function is registered for.
+func (r *Registry) Names() []string {
+	result := make([]string, 0, len(r.generators))
+	for name := range r.generators {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
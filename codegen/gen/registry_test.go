@@ -0,0 +1,38 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/dkinzler/kit/codegen/annotations"
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	a := assert.New(t)
+
+	f := func(i parse.Interface, m parse.Module, an annotations.InterfaceAnnotation) ([]GenResult, error) {
+		return []GenResult{{OutputFile: i.Name}}, nil
+	}
+
+	r := NewRegistry()
+	a.Empty(r.Names())
+	_, ok := r.Get("Foo")
+	a.False(ok)
+
+	a.Nil(r.Register("Foo", f))
+	a.Nil(r.Register("Bar", f))
+	// cannot register the same name twice
+	a.NotNil(r.Register("Foo", f))
+	// empty name or nil function are invalid
+	a.NotNil(r.Register("", f))
+	a.NotNil(r.Register("Baz", nil))
+
+	a.Equal([]string{"Bar", "Foo"}, r.Names())
+	g, ok := r.Get("Foo")
+	a.True(ok)
+	result, err := g(parse.Interface{Name: "X"}, parse.Module{}, annotations.InterfaceAnnotation{})
+	a.Nil(err)
+	a.Equal("X", result[0].OutputFile)
+}
@@ -0,0 +1,50 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A http route registered by generated code.
+type Route struct {
+	// http method, e.g. "GET"
+	Method string
+	// Full path of the route including any prefix, e.g. "/api/v1/items/{id}".
+	Path string
+	// Name of the handler, e.g. the name of the endpoint.
+	Name string
+}
+
+// Matches path variables, e.g. "{id}", "{id:[0-9]+}" or "{path...}", the name is the first submatch.
+var routeVariableRegexp = regexp.MustCompile(`\{[A-Za-z_][A-Za-z0-9_]*((?:[:.][^}]*)?)\}`)
+
+// Returns the path with the names of variables removed, since e.g. "/items/{id}" and "/items/{itemId}" match the same requests.
+func NormalizeRoutePath(path string) string {
+	return routeVariableRegexp.ReplaceAllString(path, "{$1}")
+}
+
+// Returns an error for every route of the results that has the same method and path as a route of an earlier result
+// in the same package, since only one of the handlers could be reached or registering the second handler would panic.
+// Routes are compared after removing the names of path variables.
+func CheckRoutes(results []GenResult) []error {
+	type registered struct {
+		route  Route
+		source string
+	}
+	seen := make(map[string]registered)
+	var errs []error
+	for _, r := range results {
+		for _, route := range r.Routes {
+			key := r.PackagePath + " " + strings.ToUpper(route.Method) + " " + NormalizeRoutePath(route.Path)
+			if other, ok := seen[key]; ok {
+				errs = append(errs, errors.New(fmt.Sprintf("duplicate http route %v %v in package %v: handler %v of %v conflicts with handler %v of %v",
+					strings.ToUpper(route.Method), route.Path, r.PackagePath, route.Name, r.Source, other.route.Name, other.source)))
+				continue
+			}
+			seen[key] = registered{route: route, source: r.Source}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,49 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRoutes(t *testing.T) {
+	a := assert.New(t)
+
+	results := []GenResult{
+		{
+			PackagePath: "example.com/x/http",
+			Source:      "example.com/x.A",
+			Routes: []Route{
+				{Method: "GET", Path: "/items/{id}", Name: "GetItem"},
+				{Method: "POST", Path: "/items", Name: "CreateItem"},
+			},
+		},
+		{
+			PackagePath: "example.com/x/http",
+			Source:      "example.com/x.B",
+			Routes: []Route{
+				// different method
+				{Method: "PUT", Path: "/items/{id}", Name: "UpdateItem"},
+				// same route with a different variable name
+				{Method: "get", Path: "/items/{itemId}", Name: "FindItem"},
+				// different regular expression
+				{Method: "GET", Path: "/items/{id:[0-9]+}", Name: "GetItemById"},
+			},
+		},
+		{
+			// different package
+			PackagePath: "example.com/y/http",
+			Source:      "example.com/y.C",
+			Routes: []Route{
+				{Method: "POST", Path: "/items", Name: "CreateItem"},
+			},
+		},
+	}
+
+	errs := CheckRoutes(results)
+	if a.Len(errs, 1) {
+		a.Equal("duplicate http route GET /items/{itemId} in package example.com/x/http: handler FindItem of example.com/x.B conflicts with handler GetItem of example.com/x.A", errs[0].Error())
+	}
+
+	a.Empty(CheckRoutes(results[:1]))
+}
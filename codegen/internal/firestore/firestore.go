@@ -0,0 +1,237 @@
+// Package firestore provides a code generator to generate firestore implementations of repository-style interfaces,
+// built on the helpers of package "github.com/dkinzler/kit/firebase/firestore".
+package firestore
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const firestorePackage = "cloud.google.com/go/firestore"
+const firestoreHelperPackage = "github.com/dkinzler/kit/firebase/firestore"
+
+// The receiver is not named "r" or "s", which are common parameter names.
+const receiverName = "repo"
+
+type FirestoreGenerator struct {
+	Spec GenSpecification
+	g    *gen.SimpleGenerator
+}
+
+func NewFirestoreGenerator(spec GenSpecification) *FirestoreGenerator {
+	return &FirestoreGenerator{
+		Spec: spec,
+		g:    gen.NewSimpleGenerator(),
+	}
+}
+
+func (f *FirestoreGenerator) Generate() (result []gen.GenResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = errors.New(fmt.Sprint(r))
+		}
+	}()
+
+	code := jen.NewFile("").Group
+	f.genStruct(code)
+	for _, ms := range f.Spec.Methods {
+		code.Line()
+		code.Add(f.genMethod(ms))
+	}
+
+	return []gen.GenResult{{
+		Code:        code,
+		PackagePath: f.Spec.Module.FullPackagePath(f.Spec.Package),
+		PackageName: f.Spec.PackageName(),
+		Imports: map[string]string{
+			firestorePackage:       "firestore",
+			firestoreHelperPackage: "kitfirestore",
+		},
+		OutputFile: f.Spec.Module.FileName(f.Spec.Package, f.Spec.Output),
+	}}, nil
+}
+
+func (f *FirestoreGenerator) structName() string {
+	return "Firestore" + f.Spec.I.Name
+}
+
+// Names of the collections used by the methods, sorted.
+func (f *FirestoreGenerator) collections() []string {
+	var result []string
+	for _, ms := range f.Spec.Methods {
+		if !slices.Contains(result, ms.Collection) {
+			result = append(result, ms.Collection)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+var nonAlphanumericRegexp = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// Name of the struct field that holds the reference to a collection, e.g. "userNotesCollection" for the collection "user-notes".
+func collectionFieldName(collection string) string {
+	parts := nonAlphanumericRegexp.Split(collection, -1)
+	for i := range parts {
+		parts[i] = gen.UppercaseFirst(parts[i])
+	}
+	return gen.LowercaseFirst(strings.Join(parts, "")) + "Collection"
+}
+
+// Generates the struct type, an interface assertion and a constructor.
+func (f *FirestoreGenerator) genStruct(g *jen.Group) {
+	name := f.structName()
+	collections := f.collections()
+
+	fields := []jen.Code{jen.Id("client").Op("*").Qual(firestorePackage, "Client")}
+	values := jen.Dict{jen.Id("client"): jen.Id("client")}
+	for _, c := range collections {
+		fields = append(fields, jen.Id(collectionFieldName(c)).Op("*").Qual(firestorePackage, "CollectionRef"))
+		values[jen.Id(collectionFieldName(c))] = jen.Id("client").Dot("Collection").Call(jen.Lit(c))
+	}
+
+	g.Comment(fmt.Sprintf("%v implements %v using firestore.", name, f.Spec.I.Name))
+	g.Type().Id(name).Struct(fields...)
+	g.Line()
+	g.Var().Id("_").Qual(f.Spec.I.Package, f.Spec.I.Name).Op("=").Parens(jen.Op("*").Id(name)).Parens(jen.Nil())
+	g.Line()
+	g.Func().Id("New" + name).Params(jen.Id("client").Op("*").Qual(firestorePackage, "Client")).Op("*").Id(name).Block(
+		jen.Return(jen.Op("&").Id(name).Values(values)),
+	)
+}
+
+func (f *FirestoreGenerator) genMethod(ms MethodSpecification) jen.Code {
+	m := ms.Method
+	names := f.g.GenParamNames(m.Params)
+	param := func(i int) *jen.Statement {
+		return jen.Id(names[i])
+	}
+	// returns a name for a local variable that does not conflict with a parameter
+	local := func(name string) *jen.Statement {
+		for slices.Contains(names, name) {
+			name += "_"
+		}
+		return jen.Id(name)
+	}
+	ctx := param(0)
+	col := jen.Id(receiverName).Dot(collectionFieldName(ms.Collection))
+	id := func() *jen.Statement {
+		if ms.IdParam >= 0 {
+			return param(ms.IdParam)
+		}
+		return param(ms.DocParam).Dot(ms.IdField)
+	}
+
+	var stmts []jen.Code
+	switch ms.Operation {
+	case OperationGet:
+		doc, te := local("doc"), local("te")
+		docType, isPointer := f.docType(ms.DocType)
+		result := doc.Clone()
+		zero := jen.Add(docType).Values()
+		if isPointer {
+			result = jen.Op("&").Add(doc)
+			zero = jen.Nil()
+		}
+		stmts = append(stmts, jen.Var().Add(doc).Add(docType))
+		if ms.ReturnsTE {
+			stmts = append(stmts,
+				jen.List(te, jen.Err()).Op(":=").Qual(firestoreHelperPackage, "GetDocumentByIdWithTE").Call(ctx, col, id(), jen.Op("&").Add(doc)),
+				jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(zero, jen.Nil(), jen.Err())),
+				jen.Return(result, te, jen.Nil()),
+			)
+		} else {
+			stmts = append(stmts,
+				jen.If(
+					jen.Err().Op(":=").Qual(firestoreHelperPackage, "GetDocumentById").Call(ctx, col, id(), jen.Op("&").Add(doc)),
+					jen.Err().Op("!=").Nil(),
+				).Block(jen.Return(zero, jen.Err())),
+				jen.Return(result, jen.Nil()),
+			)
+		}
+	case OperationCreate:
+		stmts = append(stmts, jen.Return(jen.Qual(firestoreHelperPackage, "CreateDocument").Call(ctx, col, id(), param(ms.DocParam))))
+	case OperationUpdate:
+		if ms.TEParam >= 0 {
+			stmts = append(stmts, jen.Return(f.genTransaction(ctx, param(ms.TEParam), local("tx"), func(tx jen.Code) jen.Code {
+				return jen.Add(tx).Dot("Set").Call(col.Clone().Dot("Doc").Call(id()), param(ms.DocParam))
+			})))
+		} else {
+			stmts = append(stmts, jen.Return(jen.Qual(firestoreHelperPackage, "SetDocument").Call(ctx, col, id(), param(ms.DocParam))))
+		}
+	case OperationDelete:
+		if ms.TEParam >= 0 {
+			stmts = append(stmts, jen.Return(f.genTransaction(ctx, param(ms.TEParam), local("tx"), func(tx jen.Code) jen.Code {
+				return jen.Add(tx).Dot("Delete").Call(col.Clone().Dot("Doc").Call(id()))
+			})))
+		} else {
+			stmts = append(stmts, jen.Return(jen.Qual(firestoreHelperPackage, "DeleteDocument").Call(ctx, col, id())))
+		}
+	case OperationList:
+		snaps, docs, i, snap := local("snaps"), local("docs"), local("i"), local("snap")
+		docType, isPointer := f.docType(ms.DocType)
+		query := col.Clone().Dot("Query")
+		if ms.LimitParam >= 0 {
+			query = col.Clone().Dot("Limit").Call(param(ms.LimitParam))
+		}
+		target := jen.Op("&").Add(docs).Index(i)
+		var loop []jen.Code
+		if isPointer {
+			target = docs.Clone().Index(i)
+			loop = append(loop, docs.Clone().Index(i).Op("=").New(docType))
+		}
+		loop = append(loop, jen.If(
+			jen.Err().Op(":=").Qual(firestoreHelperPackage, "UnmarshalDocSnapshot").Call(snap, target),
+			jen.Err().Op("!=").Nil(),
+		).Block(jen.Return(jen.Nil(), jen.Err())))
+		stmts = append(stmts,
+			jen.List(snaps, jen.Err()).Op(":=").Qual(firestoreHelperPackage, "GetDocumentsForQuery").Call(ctx, query),
+			jen.If(jen.Err().Op("!=").Nil()).Block(jen.Return(jen.Nil(), jen.Err())),
+			docs.Clone().Op(":=").Make(f.g.GenParamType(parse.ArrayType{Type: ms.DocType}), jen.Len(snaps)),
+			jen.For(jen.List(i, snap).Op(":=").Range().Add(snaps)).Block(loop...),
+			jen.Return(docs, jen.Nil()),
+		)
+	}
+
+	return f.g.GenFunction(
+		jen.Id(receiverName).Op("*").Id(f.structName()),
+		m.Name,
+		f.g.GenFunctionParams(m.Params),
+		f.g.GenReturnParams(m.Returns),
+		stmts,
+	)
+}
+
+// Generates a call that runs the given write in a transaction, after verifying the transaction expectations.
+// The write is only performed if none of the documents of the transaction expectations were modified since they were read.
+func (f *FirestoreGenerator) genTransaction(ctx, te, tx jen.Code, write func(tx jen.Code) jen.Code) jen.Code {
+	return jen.Id(receiverName).Dot("client").Dot("RunTransaction").Call(
+		ctx,
+		jen.Func().Params(jen.Add(ctx).Qual("context", "Context"), jen.Add(tx).Op("*").Qual(firestorePackage, "Transaction")).Error().Block(
+			jen.If(
+				jen.Err().Op(":=").Qual(firestoreHelperPackage, "VerifyTransactionExpectations").Call(tx, te),
+				jen.Err().Op("!=").Nil(),
+			).Block(jen.Return(jen.Err())),
+			jen.Return(write(tx)),
+		),
+		jen.Qual(firestorePackage, "MaxAttempts").Call(jen.Lit(1)),
+	)
+}
+
+// Returns the named type of a document and whether the document is a pointer to it.
+func (f *FirestoreGenerator) docType(t parse.ParamType) (jen.Code, bool) {
+	if st, ok := t.(parse.StarType); ok {
+		return f.g.GenParamType(st.Type), true
+	}
+	return f.g.GenParamType(t), false
+}
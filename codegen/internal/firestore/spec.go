@@ -0,0 +1,231 @@
+package firestore
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/annotations"
+	"github.com/dkinzler/kit/codegen/parse"
+)
+
+// GenSpecification defines the input data and configuration for FirestoreGenerator.
+type GenSpecification struct {
+	// the interface to generate an implementation for
+	I parse.Interface
+	// module the code is generated in, usually the module the interface belongs to
+	Module parse.Module
+	// Package the file will be created in, relative to the module.
+	// If empty use the same package as the interface.
+	Package string `json:"package"`
+	// Filename of the output, defaults to "firestore.gen.go".
+	Output string `json:"output"`
+	// Name of the collection the documents are stored in, can be overridden for single methods.
+	Collection string `json:"collection"`
+	// Name of the field of the document type that contains the id of the document,
+	// used by create and update methods that do not have an id parameter. Can be overridden for single methods.
+	IdField string `json:"idField"`
+
+	Methods []MethodSpecification
+}
+
+// Operations an interface method can implement.
+// Unless set in the method annotation, the operation is determined by the prefix of the method name, e.g. "GetNote" is a get operation.
+const (
+	OperationGet    = "get"
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+	OperationList   = "list"
+)
+
+var operations = []string{OperationGet, OperationCreate, OperationUpdate, OperationDelete, OperationList}
+
+// MethodSpecification defines how an interface method is implemented, it is read from the @Firestore annotation on the method.
+type MethodSpecification struct {
+	Method parse.Method
+	// One of the operations above, defaults to the operation given by the prefix of the method name.
+	Operation string `json:"operation"`
+	// Collection and id field of the method, default to the ones of the interface.
+	Collection string `json:"collection"`
+	IdField    string `json:"idField"`
+
+	// Type of the document the method reads or writes, a named type or a pointer to one.
+	DocType parse.ParamType
+	// Indices of the parameters of the method, -1 if the method has no such parameter.
+	IdParam    int
+	DocParam   int
+	TEParam    int
+	LimitParam int
+	// If true, the method returns TransactionExpectations for the document it reads.
+	ReturnsTE bool
+}
+
+// The package name to use in a source file, the last element of the full package path.
+func (g GenSpecification) PackageName() string {
+	return path.Base(g.Module.FullPackagePath(g.Package))
+}
+
+var collectionNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+func SpecFromAnnotations(i parse.Interface, m parse.Module, a annotations.InterfaceAnnotation) (GenSpecification, error) {
+	var spec GenSpecification
+
+	err := a.ParseJSON(a.Annotation, &spec)
+	if err != nil {
+		return spec, errors.New(fmt.Sprintf("could not parse annotation for interface %v, error: %v", i.Name, err))
+	}
+	if i.IsGeneric() {
+		return spec, errors.New(fmt.Sprintf("interface %v is generic, cannot generate a firestore implementation for generic interfaces", i.Name))
+	}
+
+	spec.I = i
+	spec.Module = m
+	if spec.Package == "" {
+		spec.Package = m.PackagePathWithoutModule(i.Package)
+	}
+	if spec.Output == "" {
+		spec.Output = "firestore.gen.go"
+	}
+
+	for j, method := range i.Methods {
+		ms := MethodSpecification{Method: method}
+		if j < len(a.MethodAnnotations) && a.MethodAnnotations[j] != "" {
+			if err := a.ParseJSON(a.MethodAnnotations[j], &ms); err != nil {
+				return spec, i.WrapMethodError(method, errors.New(fmt.Sprintf("could not parse annotation for method %v, error: %v", method.Name, err)))
+			}
+		}
+		if ms.Collection == "" {
+			ms.Collection = spec.Collection
+		}
+		if ms.IdField == "" {
+			ms.IdField = spec.IdField
+		}
+		if err := ms.analyze(); err != nil {
+			return spec, i.WrapMethodError(method, errors.New(fmt.Sprintf("cannot generate firestore implementation for method %v: %v", method.Name, err)))
+		}
+		spec.Methods = append(spec.Methods, ms)
+	}
+
+	return spec, nil
+}
+
+// Determines the operation of the method and checks that its parameters and return values are supported by the operation:
+//   - get: (ctx, id string) (Doc, error) or (ctx, id string) (Doc, TransactionExpectations, error)
+//   - create: (ctx, doc Doc) error or (ctx, id string, doc Doc) error
+//   - update: (ctx, doc Doc) error or (ctx, id string, doc Doc) error, optionally with a last parameter of type TransactionExpectations
+//   - delete: (ctx, id string) error, optionally with a last parameter of type TransactionExpectations
+//   - list: (ctx) ([]Doc, error) or (ctx, limit int) ([]Doc, error)
+//
+// Doc must be a named type or a pointer to one, TransactionExpectations is the type from package "github.com/dkinzler/kit/firebase/firestore".
+func (ms *MethodSpecification) analyze() error {
+	m := ms.Method
+	if ms.Operation == "" {
+		for _, op := range operations {
+			if strings.HasPrefix(m.Name, strings.ToUpper(op[:1])+op[1:]) {
+				ms.Operation = op
+				break
+			}
+		}
+		if ms.Operation == "" {
+			return errors.New("operation cannot be determined from the method name, set it in the annotation")
+		}
+	}
+	if !collectionNameRegexp.MatchString(ms.Collection) {
+		return errors.New(fmt.Sprintf("invalid collection name %q", ms.Collection))
+	}
+	if len(m.Params) == 0 || !parse.IsSimpleType(m.Params[0].Type, "Context", "context") {
+		return errors.New("first parameter must be a context.Context")
+	}
+	if len(m.Returns) == 0 || !parse.IsSimpleType(m.Returns[len(m.Returns)-1].Type, "error", "") {
+		return errors.New("last return value must be an error")
+	}
+
+	ms.IdParam, ms.DocParam, ms.TEParam, ms.LimitParam = -1, -1, -1, -1
+	params := m.Params[1:]
+	returns := m.Returns[:len(m.Returns)-1]
+	if (ms.Operation == OperationUpdate || ms.Operation == OperationDelete) && len(params) > 0 && isTEType(params[len(params)-1].Type) {
+		ms.TEParam = len(m.Params) - 1
+		params = params[:len(params)-1]
+	}
+
+	switch ms.Operation {
+	case OperationGet:
+		if len(params) != 1 || !isIdType(params[0].Type) {
+			return errors.New("get methods must have a single id parameter of type string")
+		}
+		ms.IdParam = 1
+		if len(returns) == 2 && isTEType(returns[1].Type) {
+			ms.ReturnsTE = true
+			returns = returns[:1]
+		}
+		if len(returns) != 1 {
+			return errors.New("get methods must return the document and optionally transaction expectations")
+		}
+		ms.DocType = returns[0].Type
+	case OperationCreate, OperationUpdate:
+		switch len(params) {
+		case 1:
+			if ms.IdField == "" {
+				return errors.New("methods without id parameter require an idField")
+			}
+			ms.DocParam = 1
+		case 2:
+			if !isIdType(params[0].Type) {
+				return errors.New("id parameter must be of type string")
+			}
+			ms.IdParam, ms.DocParam = 1, 2
+		default:
+			return errors.New(fmt.Sprintf("%v methods must have a document parameter and optionally an id parameter before it", ms.Operation))
+		}
+		ms.DocType = m.Params[ms.DocParam].Type
+	case OperationDelete:
+		if len(params) != 1 || !isIdType(params[0].Type) {
+			return errors.New("delete methods must have a single id parameter of type string")
+		}
+		ms.IdParam = 1
+	case OperationList:
+		if len(params) > 1 || (len(params) == 1 && !parse.IsSimpleType(params[0].Type, "int", "")) {
+			return errors.New("list methods can only have a limit parameter of type int")
+		}
+		if len(params) == 1 {
+			ms.LimitParam = 1
+		}
+		if len(returns) != 1 {
+			return errors.New("list methods must return a slice of documents")
+		}
+		at, ok := returns[0].Type.(parse.ArrayType)
+		if !ok {
+			return errors.New("list methods must return a slice of documents")
+		}
+		ms.DocType = at.Type
+	default:
+		return errors.New(fmt.Sprintf("unknown operation %v", ms.Operation))
+	}
+
+	if ms.Operation != OperationGet && ms.Operation != OperationList && len(returns) != 0 {
+		return errors.New(fmt.Sprintf("%v methods can only return an error", ms.Operation))
+	}
+	if ms.DocType != nil && !isDocType(ms.DocType) {
+		return errors.New("documents must be of a named type defined in a package or a pointer to one")
+	}
+	return nil
+}
+
+func isIdType(t parse.ParamType) bool {
+	return parse.IsSimpleType(t, "string", "")
+}
+
+func isTEType(t parse.ParamType) bool {
+	return parse.IsSimpleType(t, "TransactionExpectations", firestoreHelperPackage)
+}
+
+func isDocType(t parse.ParamType) bool {
+	if st, ok := t.(parse.StarType); ok {
+		t = st.Type
+	}
+	st, ok := t.(parse.SimpleType)
+	return ok && st.Package != ""
+}
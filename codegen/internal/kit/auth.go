@@ -0,0 +1,54 @@
+package kit
+
+import (
+	"github.com/dave/jennifer/jen"
+)
+
+const firebaseAuthPackage = "github.com/dkinzler/kit/firebase/auth"
+const kitJwtPackage = "github.com/go-kit/kit/auth/jwt"
+
+// Defines whether requests to an endpoint must be authenticated with a Firebase Authentication token,
+// see package "github.com/dkinzler/kit/firebase/auth".
+// The token is read from the "Authorization" header of http requests.
+type AuthSpec struct {
+	// If true, requests must contain a valid token.
+	Required bool `json:"required"`
+	// Claims the authenticated user must have, see auth.User.HasClaims. Implies Required.
+	Claims []string `json:"claims"`
+}
+
+func (a *AuthSpec) enabled() bool {
+	return a != nil && (a.Required || len(a.Claims) > 0)
+}
+
+// Returns true if at least one endpoint requires authentication.
+func (spec KitGenSpecification) usesAuth() bool {
+	for _, es := range spec.Endpoints {
+		for _, e := range es.EndpointSpecs {
+			if e.Auth.enabled() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Parameters of NewEndpoints and NewInstrumentedEndpoints used by the auth middleware.
+func authParams() []jen.Code {
+	return []jen.Code{
+		jen.Id("ac").Qual(firebaseAuthPackage, "AuthChecker"),
+		jen.Id("ctxBuilder").Qual(firebaseAuthPackage, "ContextBuilderFunc"),
+	}
+}
+
+// The auth middleware for the endpoint.
+func (e EndpointSpecification) authMiddleware() jen.Code {
+	if len(e.Auth.Claims) == 0 {
+		return jen.Qual(firebaseAuthPackage, "NewAuthEndpointMiddleware").Call(jen.Id("ac"), jen.Id("ctxBuilder"))
+	}
+	args := []jen.Code{jen.Id("ac"), jen.Id("ctxBuilder")}
+	for _, c := range e.Auth.Claims {
+		args = append(args, jen.Lit(c))
+	}
+	return jen.Qual(firebaseAuthPackage, "NewClaimsEndpointMiddleware").Call(args...)
+}
@@ -0,0 +1,98 @@
+package kit
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// Configures the CORS middleware the generated http handlers are wrapped with,
+// see CORSMiddleware in package "github.com/dkinzler/kit/transport/http".
+type CorsSpec struct {
+	// Allowed origins, "*" allows any origin.
+	Origins []string `json:"origins"`
+	// Allowed methods of preflight requests, defaults to the http methods of the endpoints.
+	Methods []string `json:"methods"`
+	// Allowed request headers of preflight requests, "*" allows any header.
+	Headers []string `json:"headers"`
+	// Response headers that are made available to scripts.
+	ExposedHeaders []string `json:"exposedHeaders"`
+	// Whether credentials like cookies are allowed in cross-origin requests.
+	AllowCredentials bool `json:"allowCredentials"`
+	// Seconds browsers may cache the result of a preflight request, not set if 0.
+	MaxAge int `json:"maxAge"`
+}
+
+func (c *CorsSpec) IsValid() error {
+	if c == nil {
+		return nil
+	}
+	if len(c.Origins) == 0 {
+		return errors.New("cors spec must allow at least one origin")
+	}
+	if c.MaxAge < 0 {
+		return errors.New("cors max age must not be negative")
+	}
+	return nil
+}
+
+// Statement that creates the CORS config in the generated RegisterHttpHandlers function.
+func (g *KitGenerator) generateCorsConfig() jen.Code {
+	methods := g.Spec.Cors.Methods
+	if len(methods) == 0 {
+		methods = g.Spec.httpMethods()
+	}
+	fields := jen.Dict{
+		jen.Id("Origins"): stringSliceLit(g.Spec.Cors.Origins),
+		jen.Id("Methods"): stringSliceLit(methods),
+	}
+	if len(g.Spec.Cors.Headers) > 0 {
+		fields[jen.Id("Headers")] = stringSliceLit(g.Spec.Cors.Headers)
+	}
+	if len(g.Spec.Cors.ExposedHeaders) > 0 {
+		fields[jen.Id("ExposedHeaders")] = stringSliceLit(g.Spec.Cors.ExposedHeaders)
+	}
+	if g.Spec.Cors.AllowCredentials {
+		fields[jen.Id("AllowCredentials")] = jen.True()
+	}
+	if g.Spec.Cors.MaxAge > 0 {
+		fields[jen.Id("MaxAge")] = jen.Lit(g.Spec.Cors.MaxAge).Op("*").Qual("time", "Second")
+	}
+	return jen.Id("cors").Op(":=").Qual(g.Spec.HttpHelperPackage, "CORSConfig").Values(fields)
+}
+
+// The handler registered with the router for the endpoint, wrapped with the CORS middleware if configured.
+func (g *KitGenerator) httpHandler(spec EndpointSpecification) jen.Code {
+	if g.Spec.Cors == nil {
+		return jen.Id(spec.httpHandlerVarName())
+	}
+	return jen.Qual(g.Spec.HttpHelperPackage, "CORSMiddleware").Call(jen.Id(spec.httpHandlerVarName()), jen.Id("cors"))
+}
+
+// Returns the sorted http methods of all http handlers.
+func (spec KitGenSpecification) httpMethods() []string {
+	methods := make(map[string]bool)
+	for _, endpoints := range [][]EndpointSpecifications{spec.Endpoints, spec.WebsocketEndpoints} {
+		for _, es := range endpoints {
+			for _, e := range es.EndpointSpecs {
+				methods[strings.ToUpper(e.HttpSpec.Method)] = true
+			}
+		}
+	}
+	var result []string
+	for m := range methods {
+		result = append(result, m)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func stringSliceLit(values []string) jen.Code {
+	var items []jen.Code
+	for _, v := range values {
+		items = append(items, jen.Lit(v))
+	}
+	return jen.Index().String().Values(items...)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
 
 	"github.com/dave/jennifer/jen"
 )
@@ -17,6 +18,14 @@ func (g *KitGenerator) generateEndpoints() gen.GenResult {
 		if len(es.EndpointSpecs) > 0 {
 			code.Add(g.generateMethodEndpointRequestType(es))
 			code.Line()
+			if g.Spec.TypedEndpoints && len(es.Method.Returns) == 2 {
+				code.Add(g.generateMethodEndpointResponseType(es))
+				code.Line()
+			}
+			if es.hasValidation() {
+				code.Add(g.generateRequestValidateFunc(es))
+				code.Line()
+			}
 			for _, ess := range es.EndpointSpecs {
 				code.Add(g.generateMethodEndpointMakeFunc(es, ess))
 				code.Add()
@@ -28,14 +37,22 @@ func (g *KitGenerator) generateEndpoints() gen.GenResult {
 	code.Line()
 	code.Add(g.generateEndpointMiddlewaresStruct())
 	code.Line()
+	if len(g.Spec.MiddlewareStages) > 0 {
+		code.Add(g.generateMiddlewareStages())
+		code.Line()
+	}
 	code.Add(g.generateNewEndpointsFunc())
+	if g.Spec.Instrumentation {
+		code.Line()
+		code.Add(g.generateNewInstrumentedEndpointsFunc())
+	}
 	return gen.GenResult{
 		Code:        code,
 		PackagePath: g.Spec.EndpointPackageFullPath,
 		PackageName: g.Spec.endpointPackageName(),
 		Imports: map[string]string{
-			kitEndpointPackage:   "endpoint",
-			localEndpointPackage: "e",
+			kitEndpointPackage:           "endpoint",
+			g.Spec.EndpointHelperPackage: "e",
 		},
 		OutputFile: g.Spec.Module.FileName(g.Spec.EndpointPackage, g.Spec.EndpointOutput),
 	}
@@ -50,17 +67,98 @@ func (g *KitGenerator) generateMethodEndpointRequestType(es EndpointSpecificatio
 		return jen.Empty()
 	}
 
+	code := jen.Null()
 	typeName := es.endpointRequestTypeName()
 	var fields []jen.Code
 	//first parameter is context, can be skipped
 	for _, param := range method.Params[1:] {
 		paramName := es.endpointRequestTypeParamName(param.Name)
-		fields = append(fields, jen.Id(paramName).Add(g.g.GenParamType(param.Type)))
+		if st, ok := param.Type.(parse.StructType); ok {
+			code.Type().Id(es.anonymousStructTypeName(param.Name)).Add(g.g.GenParamType(st)).Line().Line()
+		}
+		field := jen.Id(paramName).Add(g.paramValueType(es, param))
+		if jsonName := es.requestFieldJsonName(g.Spec.JsonNaming, param.Name); jsonName != "" {
+			field.Tag(map[string]string{"json": jsonName})
+		}
+		fields = append(fields, field)
 	}
-	return g.g.GenStructType(typeName, fields)
+	return code.Add(g.g.GenStructType(typeName, fields))
+}
+
+// Type of the endpoint request field for the parameter.
+// Anonymous struct parameters are replaced by a named type in the endpoint package, so that requests can be created
+// without repeating the struct definition. The named type is assignable to the anonymous struct type of the parameter.
+func (g *KitGenerator) paramValueType(es EndpointSpecifications, p parse.Param) jen.Code {
+	if _, ok := p.Type.(parse.StructType); ok {
+		return jen.Qual(g.Spec.EndpointPackageFullPath, es.anonymousStructTypeName(p.Name))
+	}
+	return g.g.GenValueType(p.Type)
+}
+
+// Generates an alias for the result type of the method, used as response type of typed endpoints.
+func (g *KitGenerator) generateMethodEndpointResponseType(es EndpointSpecifications) jen.Code {
+	return jen.Type().Id(es.endpointResponseTypeName()).Op("=").Add(g.g.GenValueType(es.Method.Returns[0].Type))
+}
+
+// Request and response types of the typed endpoint for the method, Empty from the endpoint helper package
+// is used for methods without parameters or result.
+func (g *KitGenerator) typedEndpointTypes(es EndpointSpecifications) (jen.Code, jen.Code) {
+	var req, resp jen.Code = jen.Qual(g.Spec.EndpointHelperPackage, "Empty"), jen.Qual(g.Spec.EndpointHelperPackage, "Empty")
+	if len(es.Method.Params) > 1 {
+		req = jen.Id(es.endpointRequestTypeName())
+	}
+	if len(es.Method.Returns) == 2 {
+		resp = jen.Id(es.endpointResponseTypeName())
+	}
+	return req, resp
+}
+
+// Generates a function that returns a typed endpoint, see TypedEndpoint in the endpoint helper package.
+// The endpoint set contains the endpoint.Endpoint obtained with AdaptTypedEndpoint.
+func (g *KitGenerator) generateMethodTypedEndpointMakeFunc(es EndpointSpecifications, spec EndpointSpecification) jen.Code {
+	m := es.Method
+	req, resp := g.typedEndpointTypes(es)
+
+	params := []jen.Code{jen.Id("ctx")}
+	for _, p := range m.Params[1:] {
+		params = append(params, jen.Id("req").Dot(es.endpointRequestTypeParamName(p.Name)))
+	}
+	params = g.g.GenCallArgs(m.Params, params)
+
+	var stmts []jen.Code
+	if len(m.Returns) == 2 {
+		stmts = append(stmts, jen.Return(jen.Id("svc").Dot(m.Name).Call(params...)))
+	} else {
+		stmts = append(stmts,
+			jen.Id("err").Op(":=").Id("svc").Dot(m.Name).Call(params...),
+			jen.Return(jen.Qual(g.Spec.EndpointHelperPackage, "Empty").Values(), jen.Id("err")),
+		)
+	}
+
+	return g.g.GenFunction(
+		nil,
+		spec.makeEndpointFuncName(),
+		jen.Params(jen.Id("svc").Qual(g.Spec.Interface.Package, g.Spec.Interface.Name)),
+		jen.Qual(g.Spec.EndpointHelperPackage, "TypedEndpoint").Types(req, resp),
+		[]jen.Code{
+			jen.Return(g.g.GenFunction(
+				nil,
+				"",
+				jen.Params(
+					jen.Id("ctx").Qual("context", "Context"),
+					jen.Id("req").Add(req),
+				),
+				jen.Params(resp, jen.Error()),
+				stmts,
+			)),
+		},
+	)
 }
 
 func (g *KitGenerator) generateMethodEndpointMakeFunc(es EndpointSpecifications, spec EndpointSpecification) jen.Code {
+	if g.Spec.TypedEndpoints {
+		return g.generateMethodTypedEndpointMakeFunc(es, spec)
+	}
 	svcMethodCall, hasResult := g.generateInterfaceMethodCall(es)
 
 	var resultValue jen.Code = jen.Nil()
@@ -68,7 +166,7 @@ func (g *KitGenerator) generateMethodEndpointMakeFunc(es EndpointSpecifications,
 		resultValue = jen.Id("r")
 	}
 
-	returnStmt := jen.Return(jen.Qual(localEndpointPackage, "Response").Values(
+	returnStmt := jen.Return(jen.Qual(g.Spec.EndpointHelperPackage, "Response").Values(
 		jen.Dict{
 			jen.Id("R"):   resultValue,
 			jen.Id("Err"): jen.Id("err"),
@@ -119,6 +217,7 @@ func (g *KitGenerator) generateInterfaceMethodCall(es EndpointSpecifications) (j
 	for _, p := range m.Params[1:] {
 		params = append(params, jen.Id("req").Dot(es.endpointRequestTypeParamName(p.Name)))
 	}
+	params = g.g.GenCallArgs(m.Params, params)
 
 	if len(m.Returns) == 1 {
 		//this should be error
@@ -172,10 +271,18 @@ func (g *KitGenerator) generateNewEndpointsFunc() jen.Code {
 			stmts = append(
 				stmts,
 				jen.Var().Id(endpointVar).Qual(kitEndpointPackage, "Endpoint"),
-				jen.Block(
-					jen.Id(endpointVar).Op("=").Id(ess.makeEndpointFuncName()).Call(jen.Id("svc")),
-					jen.Id(endpointVar).Op("=").Qual(localEndpointPackage, "ApplyMiddlewares").Call(jen.Id(endpointVar), jen.Id("mws").Dot(ess.endpointSetFieldName()).Op("...")),
-				),
+				jen.BlockFunc(func(b *jen.Group) {
+					b.Id(endpointVar).Op("=").Add(g.makeEndpointCall(ess, jen.Id("svc")))
+					// error logging is always the innermost middleware, so that it sees the response of the service before any other middleware
+					if g.Spec.Logging {
+						b.Id(endpointVar).Op("=").Qual(g.Spec.EndpointHelperPackage, "ErrorLoggingMiddleware").Call(jen.Id("logger")).Call(jen.Id(endpointVar))
+					}
+					// the auth middleware is applied before the middlewares of the caller, so that they also see unauthenticated requests
+					if ess.Auth.enabled() {
+						b.Id(endpointVar).Op("=").Add(ess.authMiddleware()).Call(jen.Id(endpointVar))
+					}
+					b.Id(endpointVar).Op("=").Qual(g.Spec.EndpointHelperPackage, "ApplyMiddlewares").Call(jen.Id(endpointVar), jen.Id("mws").Dot(ess.endpointSetFieldName()).Op("..."))
+				}),
 				jen.Line(),
 			)
 		}
@@ -183,10 +290,75 @@ func (g *KitGenerator) generateNewEndpointsFunc() jen.Code {
 
 	stmts = append(stmts, returnStmt)
 
+	params := []jen.Code{
+		jen.Id("svc").Qual(g.Spec.Interface.Package, g.Spec.Interface.Name),
+		jen.Id("mws").Id("Middlewares"),
+	}
+	if g.Spec.Logging {
+		params = append(params, jen.Id("logger").Qual(kitLogPackage, "Logger"))
+	}
+	if g.Spec.usesAuth() {
+		params = append(params, authParams()...)
+	}
+
 	return jen.Func().Id("NewEndpoints").Params(
+		params...,
+	).Id("EndpointSet").Block(
+		stmts...,
+	)
+}
+
+// Generates a function that creates the endpoints like NewEndpoints, but additionally records the request duration of every endpoint.
+// The instrumentation middleware is added last and is therefore the outermost middleware.
+func (g *KitGenerator) generateNewInstrumentedEndpointsFunc() jen.Code {
+	var stmts []jen.Code
+	for _, es := range g.Spec.Endpoints {
+		for _, ess := range es.EndpointSpecs {
+			field := jen.Id("mws").Dot(ess.endpointSetFieldName())
+			// use a full slice expression so that append allocates a new array and the caller's slice is not modified
+			stmts = append(stmts, field.Clone().Op("=").Append(
+				field.Clone().Index(jen.Op(":").Len(field.Clone()).Op(":").Len(field.Clone())),
+				jen.Qual(g.Spec.EndpointHelperPackage, "InstrumentRequestTimeMiddleware").Call(
+					jen.Id("duration").Dot("With").Call(jen.Lit("endpoint"), jen.Lit(ess.Name)),
+				),
+			))
+		}
+	}
+	args := []jen.Code{jen.Id("svc"), jen.Id("mws")}
+	if g.Spec.Logging {
+		args = append(args, jen.Id("logger"))
+	}
+	if g.Spec.usesAuth() {
+		args = append(args, jen.Id("ac"), jen.Id("ctxBuilder"))
+	}
+	stmts = append(stmts, jen.Return(jen.Id("NewEndpoints").Call(args...)))
+
+	params := []jen.Code{
 		jen.Id("svc").Qual(g.Spec.Interface.Package, g.Spec.Interface.Name),
 		jen.Id("mws").Id("Middlewares"),
+		jen.Id("duration").Qual(kitMetricsPackage, "Histogram"),
+	}
+	if g.Spec.Logging {
+		params = append(params, jen.Id("logger").Qual(kitLogPackage, "Logger"))
+	}
+	if g.Spec.usesAuth() {
+		params = append(params, authParams()...)
+	}
+
+	return jen.Comment("NewInstrumentedEndpoints works like NewEndpoints, but additionally records the time it takes each endpoint to process requests.").Line().
+		Comment(`Observations are labeled with "endpoint" (the name of the endpoint) and "success" (whether or not the service returned an error).`).Line().
+		Func().Id("NewInstrumentedEndpoints").Params(
+		params...,
 	).Id("EndpointSet").Block(
 		stmts...,
 	)
 }
+
+// Call of the function that creates the endpoint.Endpoint for the given service, typed endpoints are adapted.
+func (g *KitGenerator) makeEndpointCall(spec EndpointSpecification, svc jen.Code) jen.Code {
+	call := jen.Id(spec.makeEndpointFuncName()).Call(svc)
+	if g.Spec.TypedEndpoints {
+		return jen.Qual(g.Spec.EndpointHelperPackage, "AdaptTypedEndpoint").Call(call)
+	}
+	return call
+}
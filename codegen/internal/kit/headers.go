@@ -0,0 +1,144 @@
+package kit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// A header value can contain placeholders that refer to the result value returned by the interface method.
+// E.g. for a method returning a struct with a field "Id", the header value "/items/{result.Id}" will be set to "/items/" + fmt.Sprint(result.Id).
+// The placeholder "{result}" refers to the result value itself.
+type headerTemplatePart struct {
+	// literal text, only set if IsPlaceholder is false
+	Literal string
+	// selector path of the placeholder, e.g. ["Id"] for "{result.Id}" or an empty slice for "{result}"
+	Field []string
+	// true if this part is a placeholder
+	IsPlaceholder bool
+}
+
+func parseHeaderTemplate(value string) ([]headerTemplatePart, error) {
+	var result []headerTemplatePart
+	rest := value
+	for rest != "" {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			result = append(result, headerTemplatePart{Literal: rest})
+			break
+		}
+		if start > 0 {
+			result = append(result, headerTemplatePart{Literal: rest[:start]})
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return nil, errors.New(fmt.Sprintf("unclosed placeholder in header value %v", value))
+		}
+		placeholder := rest[start+1 : start+end]
+		parts := strings.Split(placeholder, ".")
+		if parts[0] != "result" {
+			return nil, errors.New(fmt.Sprintf("invalid placeholder {%v} in header value %v, must be {result} or {result.Field}", placeholder, value))
+		}
+		for _, p := range parts[1:] {
+			if p == "" {
+				return nil, errors.New(fmt.Sprintf("invalid placeholder {%v} in header value %v", placeholder, value))
+			}
+		}
+		result = append(result, headerTemplatePart{Field: parts[1:], IsPlaceholder: true})
+		rest = rest[start+end+1:]
+	}
+	return result, nil
+}
+
+func (spec HttpSpec) headersUseResult() bool {
+	for _, value := range spec.Headers {
+		parts, _ := parseHeaderTemplate(value)
+		for _, p := range parts {
+			if p.IsPlaceholder {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e EndpointSpecification) httpResponseHeadersFuncName() string {
+	return "http" + gen.UppercaseFirst(e.Name) + "ResponseHeaders"
+}
+
+// Generates a function that returns the response headers for the given endpoint.
+// The function is passed to MakeGenericJSONEncodeFuncWithHeaders.
+// If the method returns a pointer, headers that refer to the result are not set if the result is nil.
+func (g *KitGenerator) generateHttpResponseHeadersFunc(es EndpointSpecifications, spec EndpointSpecification) jen.Code {
+	var stmts []jen.Code
+	_, resultIsPointer := es.Method.Returns[0].Type.(parse.StarType)
+	if spec.HttpSpec.headersUseResult() {
+		resultType := g.g.GenParamType(es.Method.Returns[0].Type)
+		if resultIsPointer {
+			stmts = append(stmts, jen.List(jen.Id("result"), jen.Id("_")).Op(":=").Id("response").Assert(resultType))
+		} else {
+			stmts = append(stmts, jen.Id("result").Op(":=").Id("response").Assert(resultType))
+		}
+	}
+	stmts = append(stmts, jen.Id("h").Op(":=").Make(jen.Qual("net/http", "Header")))
+
+	// sort header names for deterministic output
+	var names []string
+	for name := range spec.HttpSpec.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var resultStmts []jen.Code
+	for _, name := range names {
+		parts, err := parseHeaderTemplate(spec.HttpSpec.Headers[name])
+		if err != nil {
+			panic(err)
+		}
+		usesResult := false
+		value := jen.Empty()
+		for i, p := range parts {
+			if i > 0 {
+				value.Op("+")
+			}
+			if !p.IsPlaceholder {
+				value.Lit(p.Literal)
+				continue
+			}
+			usesResult = true
+			field := jen.Id("result")
+			for _, f := range p.Field {
+				field = field.Dot(f)
+			}
+			value.Qual("fmt", "Sprint").Call(field)
+		}
+		if len(parts) == 0 {
+			value.Lit("")
+		}
+		stmt := jen.Id("h").Dot("Set").Call(jen.Lit(name), value)
+		if usesResult && resultIsPointer {
+			resultStmts = append(resultStmts, stmt)
+		} else {
+			stmts = append(stmts, stmt)
+		}
+	}
+	if len(resultStmts) > 0 {
+		// a method might return a nil pointer without an error
+		stmts = append(stmts, jen.If(jen.Id("result").Op("!=").Nil()).Block(resultStmts...))
+	}
+	stmts = append(stmts, jen.Return(jen.Id("h")))
+
+	return g.g.GenFunction(
+		nil,
+		spec.httpResponseHeadersFuncName(),
+		jen.Params(jen.Id("response").Interface()),
+		jen.Qual("net/http", "Header"),
+		stmts,
+	)
+}
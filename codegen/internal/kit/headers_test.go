@@ -0,0 +1,43 @@
+package kit
+
+import (
+	"testing"
+
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateHttpResponseHeadersFunc(t *testing.T) {
+	a := assert.New(t)
+
+	item := parse.SimpleType{Type: "Item", Package: "example.com/x"}
+	spec := EndpointSpecification{Name: "CreateItem", HttpSpec: HttpSpec{Headers: map[string]string{
+		"Location":  "/items/{result.Id}",
+		"X-Version": "1",
+	}}}
+	render := func(result parse.ParamType) string {
+		es := EndpointSpecifications{Method: parse.Method{Returns: []parse.Param{{Type: result}, {Type: parse.SimpleType{Type: "error"}}}}}
+		return jen.Add(NewKitGenerator(KitGenSpecification{}).generateHttpResponseHeadersFunc(es, spec)).GoString()
+	}
+
+	a.Equal(`func httpCreateItemResponseHeaders(response interface{}) http.Header {
+	result := response.(x.Item)
+	h := make(http.Header)
+	h.Set("Location", "/items/"+fmt.Sprint(result.Id))
+	h.Set("X-Version", "1")
+	return h
+}`, render(item))
+
+	// a nil pointer result does not cause a panic, headers that do not refer to the result are still set
+	a.Equal(`func httpCreateItemResponseHeaders(response interface{}) http.Header {
+	result, _ := response.(*x.Item)
+	h := make(http.Header)
+	h.Set("X-Version", "1")
+	if result != nil {
+		h.Set("Location", "/items/"+fmt.Sprint(result.Id))
+	}
+	return h
+}`, render(parse.StarType{Type: item}))
+}
@@ -1,7 +1,9 @@
 package kit
 
 import (
+	"errors"
 	"fmt"
+	"go/token"
 	"sort"
 	"strings"
 
@@ -17,24 +19,52 @@ func (g *KitGenerator) generateHttp() gen.GenResult {
 	var code *jen.Group = jen.NewFile("").Group
 
 	for _, es := range g.Spec.Endpoints {
-		if len(es.EndpointSpecs) > 0 {
+		if es.usesGeneratedHttpDecodeFunc() {
 			code.Add(g.generateMethodHttpDecodeFunc(es))
 			code.Line()
 		}
+		for _, spec := range es.EndpointSpecs {
+			if len(spec.HttpSpec.Headers) > 0 {
+				code.Add(g.generateHttpResponseHeadersFunc(es, spec))
+				code.Line()
+			}
+		}
+	}
+
+	for _, es := range g.Spec.WebsocketEndpoints {
+		code.Add(g.generateWebsocketDecodeFunc(es))
+		code.Line()
 	}
 
 	code.Add(g.generateHttpRegisterHandlersFunc())
+
+	var routes []gen.Route
+	for _, endpoints := range [][]EndpointSpecifications{g.Spec.Endpoints, g.Spec.WebsocketEndpoints} {
+		for _, es := range endpoints {
+			for _, spec := range es.EndpointSpecs {
+				routes = append(routes, gen.Route{Method: spec.HttpSpec.Method, Path: g.Spec.PathPrefix + spec.HttpSpec.Path, Name: spec.Name})
+			}
+		}
+	}
+
+	code.Line()
+	code.Add(g.generateHttpRegisterRoutesFunc(routes))
+
 	return gen.GenResult{
 		Code:        code,
 		PackagePath: g.Spec.HttpPackageFullPath,
 		PackageName: g.Spec.httpPackageName(),
 		Imports: map[string]string{
-			kitEndpointPackage: "endpoint",
-			localHttpPackage:   "t",
-			kitHttpPackage:     "kithttp",
-			gorillaMuxPackage:  "mux",
+			kitEndpointPackage:       "endpoint",
+			g.Spec.HttpHelperPackage: "t",
+			kitHttpPackage:           "kithttp",
+			gorillaMuxPackage:        "mux",
+			chiPackage:               "chi",
+			kitJwtPackage:            "kitjwt",
+			localErrorsPackage:       "errors",
 		},
 		OutputFile: g.Spec.Module.FileName(g.Spec.HttpPackage, g.Spec.HttpOutput),
+		Routes:     routes,
 	}
 }
 
@@ -52,26 +82,41 @@ func (g *KitGenerator) generateMethodHttpDecodeFunc(es EndpointSpecifications) j
 
 	var stmts []jen.Code
 	returnFields := make(jen.Dict)
+	// whether the "err" variable has already been declared, scalar query params do not declare it
+	hasError := false
 	for i, p := range m.Params[1:] {
-		hasError := false
-		if i > 0 {
-			hasError = true
-		}
-		httpParamType := es.HttpParams[i]
+		httpParamType := es.HttpParams[i].Type
 		if httpParamType == HttpTypeJson {
-			stmts = append(stmts, g.generateHttpDecodeFuncJsonParam(p, hasError)...)
+			stmts = append(stmts, g.generateHttpDecodeFuncJsonParam(es, p, hasError)...)
 		} else if httpParamType == HttpTypeUrl {
-			stmts = append(stmts, g.generateHttpDecodeFuncUrlParam(p)...)
+			stmts = append(stmts, g.generateHttpDecodeFuncUrlParam(p, es.HttpParams[i].varName(p.Name))...)
+		} else if httpParamType == HttpTypeQuery && isScalarQueryType(p.Type) {
+			stmts = append(stmts, g.generateHttpDecodeFuncScalarQueryParam(p, es.HttpParams[i].varName(p.Name))...)
+			stmts = append(stmts, jen.Line())
+			returnFields[jen.Id(es.endpointRequestTypeParamName(p.Name))] = jen.Id(p.Name)
+			continue
 		} else if httpParamType == HttpTypeQuery {
-			stmts = append(stmts, g.generateHttpDecodeFuncQueryParam(p, hasError)...)
+			stmts = append(stmts, g.generateHttpDecodeFuncQueryParam(es, p, hasError)...)
+		} else if httpParamType == HttpTypeMultipart {
+			stmts = append(stmts, g.generateHttpDecodeFuncMultipartParam(p, es.multipartMaxMemory())...)
 		}
 		stmts = append(stmts, jen.Line())
 		returnFields[jen.Id(es.endpointRequestTypeParamName(p.Name))] = jen.Id(p.Name)
+		hasError = true
+	}
+	request := jen.Qual(g.Spec.EndpointPackageFullPath, es.endpointRequestTypeName()).Values(returnFields)
+	if es.hasValidation() {
+		// validate the request before it is passed to the endpoint
+		stmts = append(stmts,
+			jen.Id("req").Op(":=").Add(request),
+			jen.If(jen.Id("err").Op(":=").Id("req").Dot("Validate").Call(), jen.Id("err").Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Id("err")),
+			),
+			jen.Return(jen.Id("req"), jen.Nil()),
+		)
+	} else {
+		stmts = append(stmts, jen.Return(request, jen.Nil()))
 	}
-	stmts = append(stmts, jen.Return(
-		jen.Qual(g.Spec.EndpointPackageFullPath, es.endpointRequestTypeName()).Values(returnFields),
-		jen.Nil(),
-	))
 
 	return g.g.GenFunction(
 		nil,
@@ -89,14 +134,14 @@ func (g *KitGenerator) generateMethodHttpDecodeFunc(es EndpointSpecifications) j
 }
 
 // hasError indicates whether the "err" var has already been defined, if yes we use operator "=" instead of ":="
-func (g *KitGenerator) generateHttpDecodeFuncJsonParam(p parse.Param, hasError bool) []jen.Code {
+func (g *KitGenerator) generateHttpDecodeFuncJsonParam(es EndpointSpecifications, p parse.Param, hasError bool) []jen.Code {
 	op := ":="
 	if hasError {
 		op = "="
 	}
 	result := []jen.Code{
-		jen.Var().Id(p.Name).Add(g.g.GenParamType(p.Type)),
-		jen.Id("err").Op(op).Qual(localHttpPackage, "DecodeJSONBody").Call(jen.Id("r"), jen.Op("&").Id(p.Name)),
+		jen.Var().Id(p.Name).Add(g.paramValueType(es, p)),
+		jen.Id("err").Op(op).Qual(g.Spec.HttpHelperPackage, "DecodeJSONBody").Call(jen.Id("r"), jen.Op("&").Id(p.Name)),
 		jen.If(jen.Id("err").Op("!=").Nil()).Block(
 			jen.Return(jen.Nil(), jen.Id("err")),
 		),
@@ -104,9 +149,10 @@ func (g *KitGenerator) generateHttpDecodeFuncJsonParam(p parse.Param, hasError b
 	return result
 }
 
-func (g *KitGenerator) generateHttpDecodeFuncUrlParam(p parse.Param) []jen.Code {
+// The value of parameter p is decoded from the url variable with the given name.
+func (g *KitGenerator) generateHttpDecodeFuncUrlParam(p parse.Param, name string) []jen.Code {
 	result := []jen.Code{
-		jen.List(jen.Id(p.Name), jen.Id("err")).Op(":=").Qual(localHttpPackage, "DecodeURLParameter").Call(jen.Id("r"), jen.Lit(p.Name)),
+		jen.List(jen.Id(p.Name), jen.Id("err")).Op(":=").Qual(g.Spec.HttpHelperPackage, g.Spec.Router.urlParamDecodeFunc()).Call(jen.Id("r"), jen.Lit(name)),
 		jen.If(jen.Id("err").Op("!=").Nil()).Block(
 			jen.Return(jen.Nil(), jen.Id("err")),
 		),
@@ -114,14 +160,14 @@ func (g *KitGenerator) generateHttpDecodeFuncUrlParam(p parse.Param) []jen.Code
 	return result
 }
 
-func (g *KitGenerator) generateHttpDecodeFuncQueryParam(p parse.Param, hasError bool) []jen.Code {
+func (g *KitGenerator) generateHttpDecodeFuncQueryParam(es EndpointSpecifications, p parse.Param, hasError bool) []jen.Code {
 	op := ":="
 	if hasError {
 		op = "="
 	}
 	result := []jen.Code{
-		jen.Var().Id(p.Name).Add(g.g.GenParamType(p.Type)),
-		jen.Id("err").Op(op).Qual(localHttpPackage, "DecodeQueryParameters").Call(jen.Id("r"), jen.Op("&").Id(p.Name)),
+		jen.Var().Id(p.Name).Add(g.paramValueType(es, p)),
+		jen.Id("err").Op(op).Qual(g.Spec.HttpHelperPackage, "DecodeQueryParameters").Call(jen.Id("r"), jen.Op("&").Id(p.Name)),
 		jen.If(jen.Id("err").Op("!=").Nil()).Block(
 			jen.Return(jen.Nil(), jen.Id("err")),
 		),
@@ -129,6 +175,35 @@ func (g *KitGenerator) generateHttpDecodeFuncQueryParam(p parse.Param, hasError
 	return result
 }
 
+func (g *KitGenerator) generateHttpDecodeFuncMultipartParam(p parse.Param, maxMemory int64) []jen.Code {
+	decodeFunc := "DecodeMultipartFile"
+	if _, ok := p.Type.(parse.ArrayType); ok {
+		decodeFunc = "DecodeMultipartFileBytes"
+	}
+	var maxMemoryValue jen.Code = jen.Qual(g.Spec.HttpHelperPackage, "DefaultMultipartMaxMemory")
+	if maxMemory > 0 {
+		maxMemoryValue = jen.Lit(int(maxMemory))
+	}
+	result := []jen.Code{
+		jen.List(jen.Id(p.Name), jen.Id("err")).Op(":=").Qual(g.Spec.HttpHelperPackage, decodeFunc).Call(jen.Id("r"), jen.Lit(p.Name), maxMemoryValue),
+		jen.If(jen.Id("err").Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Id("err")),
+		),
+	}
+	return result
+}
+
+// Returns true if the type is *multipart.FileHeader or []byte.
+func isMultipartFileType(t parse.ParamType) bool {
+	if st, ok := t.(parse.StarType); ok {
+		return parse.IsSimpleType(st.Type, "FileHeader", "mime/multipart")
+	}
+	if at, ok := t.(parse.ArrayType); ok {
+		return parse.IsSimpleType(at.Type, "byte", "")
+	}
+	return false
+}
+
 type httpEndpointCodeStmts struct {
 	Path  string
 	Stmts []jen.Code
@@ -137,41 +212,77 @@ type httpEndpointCodeStmts struct {
 func (g *KitGenerator) generateHttpRegisterHandlersFunc() jen.Code {
 	//generate code for each endpoint, we will then sort them by path afterwards
 	stmts := []httpEndpointCodeStmts{}
+	optionsPaths := make(map[string]bool)
 
 	for _, es := range g.Spec.Endpoints {
 		for _, spec := range es.EndpointSpecs {
 			var decodeFuncName jen.Code
-			if len(es.HttpParams) > 0 {
+			if spec.HttpSpec.DecodeFunc != "" {
+				decodeFuncName = funcRefCode(spec.HttpSpec.DecodeFunc)
+			} else if len(es.HttpParams) > 0 {
 				decodeFuncName = jen.Id(es.httpDecodeFuncName())
 			} else {
 				decodeFuncName = jen.Qual(kitHttpPackage, "NopRequestDecoder")
 			}
+			encodeFunc := jen.Qual(g.Spec.HttpHelperPackage, "MakeGenericJSONEncodeFunc").Call(jen.Lit(spec.HttpSpec.SuccessCode))
+			if spec.HttpSpec.EncodeFunc != "" {
+				encodeFunc = funcRefCode(spec.HttpSpec.EncodeFunc)
+			} else if g.Spec.Envelope != "" {
+				headers := jen.Nil()
+				if len(spec.HttpSpec.Headers) > 0 {
+					headers = jen.Id(spec.httpResponseHeadersFuncName())
+				}
+				encodeFunc = jen.Qual(g.Spec.HttpHelperPackage, "MakeEnvelopeJSONEncodeFunc").Call(jen.Lit(spec.HttpSpec.SuccessCode), headers, g.envelopeFunc())
+			} else if len(spec.HttpSpec.Headers) > 0 {
+				encodeFunc = jen.Qual(g.Spec.HttpHelperPackage, "MakeGenericJSONEncodeFuncWithHeaders").Call(jen.Lit(spec.HttpSpec.SuccessCode), jen.Id(spec.httpResponseHeadersFuncName()))
+			}
+			optsVar := "opts"
+			if spec.Auth.enabled() {
+				optsVar = "authOpts"
+			}
+			handlerStmts := []jen.Code{
+				jen.Id(spec.httpHandlerVarName()).Op(":=").Qual(kitHttpPackage, "NewServer").Call(
+					jen.Id("endpoints").Dot(spec.endpointSetFieldName()),
+					decodeFuncName,
+					encodeFunc,
+					jen.Id(optsVar).Op("..."),
+				),
+			}
+			handlerStmts = append(handlerStmts, g.Spec.Router.registerHandler(spec, g.Spec.PathPrefix, g.httpHandler(spec), optionsPaths)...)
 			stmts = append(stmts, httpEndpointCodeStmts{
-				Path: spec.HttpSpec.Path,
-				Stmts: []jen.Code{
-					jen.Id(spec.httpHandlerVarName()).Op(":=").Qual(kitHttpPackage, "NewServer").Call(
-						jen.Id("endpoints").Dot(spec.endpointSetFieldName()),
-						decodeFuncName,
-						jen.Qual(localHttpPackage, "MakeGenericJSONEncodeFunc").Call(jen.Lit(spec.HttpSpec.SuccessCode)),
-						jen.Id("opts").Op("..."),
-					),
-					jen.Id("router").Dot("Handle").Call(
-						jen.Lit(spec.HttpSpec.Path),
-						jen.Id(spec.httpHandlerVarName()),
-					).Dot("Methods").Call(
-						jen.Lit(strings.ToUpper(spec.HttpSpec.Method)),
-						jen.Lit("OPTIONS"),
-					),
-				},
+				Path:  spec.HttpSpec.Path,
+				Stmts: handlerStmts,
 			})
 		}
 	}
 
+	for _, es := range g.Spec.WebsocketEndpoints {
+		stmts = append(stmts, g.generateWebsocketHandlerStmts(es, optionsPaths)...)
+	}
+
 	sort.Slice(stmts, func(i, j int) bool {
 		return sortEndpointsByHttpPath(stmts[i].Path, stmts[j].Path)
 	})
 
-	combinedStmts := []jen.Code{}
+	combinedStmts := g.Spec.Router.mountPathPrefix(g.Spec.PathPrefix)
+	if g.Spec.DefaultServerOptions {
+		combinedStmts = append(combinedStmts, jen.Id("opts").Op(":=").Add(g.defaultServerOptions()), jen.Line())
+	}
+	if g.Spec.Cors != nil {
+		// preflight requests are answered by the CORS middleware, since handlers are also registered for OPTIONS requests
+		combinedStmts = append(combinedStmts, g.generateCorsConfig(), jen.Line())
+	}
+	if g.Spec.usesAuth() {
+		// handlers of endpoints that require authentication store the token from the Authorization header in the context
+		// use a full slice expression so that append allocates a new array and the caller's slice is not modified
+		combinedStmts = append(combinedStmts,
+			jen.Id("authOpts").Op(":=").Append(
+				jen.Id("opts").Index(jen.Op(":").Len(jen.Id("opts")).Op(":").Len(jen.Id("opts"))),
+				jen.Qual(kitHttpPackage, "ServerBefore").Call(jen.Qual(kitJwtPackage, "HTTPToContext").Call()),
+			),
+			jen.Line(),
+		)
+	}
 	for i, s := range stmts {
 		combinedStmts = append(combinedStmts, s.Stmts...)
 		if i < len(stmts)-1 {
@@ -179,19 +290,117 @@ func (g *KitGenerator) generateHttpRegisterHandlersFunc() jen.Code {
 		}
 	}
 
+	params := []jen.Code{
+		jen.Id("endpoints").Qual(g.Spec.EndpointPackageFullPath, "EndpointSet"),
+		g.Spec.Router.routerParam(),
+	}
+	// Websocket handlers call the service directly.
+	// The service parameter is not named "svc" since this is a common package name, e.g. of the package the interface is defined in.
+	if len(g.Spec.WebsocketEndpoints) > 0 {
+		params = append(params,
+			jen.Id("s").Qual(g.Spec.Interface.Package, g.Spec.Interface.Name),
+			jen.Id("upgrader").Op("*").Qual(gorillaWebsocketPackage, "Upgrader"),
+		)
+	}
+	if g.Spec.DefaultServerOptions {
+		params = append(params, jen.Id("logger").Qual(kitLogPackage, "Logger"))
+	} else {
+		params = append(params, jen.Id("opts").Index().Qual(kitHttpPackage, "ServerOption"))
+	}
+
 	return g.g.GenFunction(
 		nil,
 		"RegisterHttpHandlers",
-		jen.Params(
-			jen.Id("endpoints").Qual(g.Spec.EndpointPackageFullPath, "EndpointSet"),
-			jen.Id("router").Op("*").Qual(gorillaMuxPackage, "Router"),
-			jen.Id("opts").Index().Qual(kitHttpPackage, "ServerOption"),
-		),
+		jen.Params(params...),
 		jen.Empty(),
 		combinedStmts,
 	)
 }
 
+// Generates a function that registers the names and full paths of the http handlers, so that urls of the handlers
+// can be built with URLFor of the http helper package instead of hardcoding paths.
+func (g *KitGenerator) generateHttpRegisterRoutesFunc(routes []gen.Route) jen.Code {
+	sorted := make([]gen.Route, len(routes))
+	copy(sorted, routes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var stmts []jen.Code
+	for _, route := range sorted {
+		stmts = append(stmts,
+			jen.If(
+				jen.Id("err").Op(":=").Id("routes").Dot("Register").Call(jen.Lit(route.Name), jen.Lit(route.Path)),
+				jen.Id("err").Op("!=").Nil(),
+			).Block(jen.Return(jen.Id("err"))),
+		)
+	}
+	stmts = append(stmts, jen.Return(jen.Nil()))
+
+	return jen.Comment("RegisterHttpRoutes registers the names and paths of the handlers registered by RegisterHttpHandlers,").Line().
+		Comment("so that their urls can be built with URLFor instead of hardcoding paths.").Line().
+		Add(g.g.GenFunction(
+			nil,
+			"RegisterHttpRoutes",
+			jen.Params(jen.Id("routes").Op("*").Qual(g.Spec.HttpHelperPackage, "Routes")),
+			jen.Error(),
+			stmts,
+		))
+}
+
+// Returns the envelope function the response values of http handlers are wrapped with, see KitGenSpecification.Envelope.
+func (g *KitGenerator) envelopeFunc() jen.Code {
+	if g.Spec.Envelope == EnvelopeData {
+		return jen.Qual(g.Spec.HttpHelperPackage, "DataEnvelope")
+	}
+	return funcRefCode(g.Spec.Envelope)
+}
+
+// Server options that encode errors with EncodeError and log them with the logger variable "logger".
+func (g *KitGenerator) defaultServerOptions() jen.Code {
+	return jen.Index().Qual(kitHttpPackage, "ServerOption").Values(
+		jen.Line().Qual(kitHttpPackage, "ServerErrorEncoder").Call(
+			jen.Func().Params(
+				jen.Id("ctx").Qual("context", "Context"),
+				jen.Id("err").Error(),
+				jen.Id("w").Qual("net/http", "ResponseWriter"),
+			).Block(
+				jen.Qual(g.Spec.HttpHelperPackage, "EncodeError").Call(jen.Id("ctx"), jen.Id("err"), jen.Id("w")),
+			),
+		),
+		jen.Line().Qual(kitHttpPackage, "ServerErrorHandler").Call(
+			jen.Qual(g.Spec.HttpHelperPackage, "NewLogErrorHandler").Call(jen.Id("logger")),
+		).Op(",").Line(),
+	)
+}
+
+// Splits a function reference of the form "some/package/path.FuncName" into package path and function name.
+// The package path is empty if the reference is just a function name.
+func parseFuncRef(ref string) (string, string, error) {
+	pkg, name := "", ref
+	if i := strings.LastIndex(ref, "."); i >= 0 {
+		pkg, name = ref[:i], ref[i+1:]
+		if pkg == "" || strings.ContainsAny(pkg, " \t") {
+			return "", "", errors.New(fmt.Sprintf("invalid function reference %v", ref))
+		}
+	}
+	if !token.IsIdentifier(name) {
+		return "", "", errors.New(fmt.Sprintf("invalid function reference %v, %v is not a valid function name", ref, name))
+	}
+	return pkg, name, nil
+}
+
+func funcRefCode(ref string) *jen.Statement {
+	pkg, name, err := parseFuncRef(ref)
+	if err != nil {
+		panic(err)
+	}
+	if pkg == "" {
+		return jen.Id(name)
+	}
+	return jen.Qual(pkg, name)
+}
+
 func sortEndpointsByHttpPath(a, b string) bool {
 	path1 := strings.TrimSpace(a)
 	path1 = strings.TrimPrefix(path1, "/")
@@ -0,0 +1,76 @@
+package kit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dkinzler/kit/codegen/gen"
+)
+
+// JsonNaming defines the json tags of the fields of generated endpoint request types.
+// Request types are e.g. encoded as json when sent over NATS or AMQP.
+type JsonNaming string
+
+// Default, fields do not have json tags and the field names are used, i.e. the parameter names with an uppercase first letter.
+const JsonNamingNone JsonNaming = ""
+
+// Fields have json tags with the parameter name in camel case, e.g. "userId" for a parameter "userId" or "UserId".
+const JsonNamingCamelCase JsonNaming = "camelCase"
+
+// Fields have json tags with the parameter name in snake case, e.g. "user_id" for a parameter "userId".
+const JsonNamingSnakeCase JsonNaming = "snake_case"
+
+func (n JsonNaming) IsValid() error {
+	switch n {
+	case JsonNamingNone, JsonNamingCamelCase, JsonNamingSnakeCase:
+		return nil
+	}
+	return errors.New(fmt.Sprintf("unknown json naming %v", n))
+}
+
+// Returns the json name of the request field for the parameter, an empty string if the field should not have a json tag.
+// A name set for the parameter in JsonNames takes precedence over the naming strategy.
+func (e EndpointSpecifications) requestFieldJsonName(naming JsonNaming, paramName string) string {
+	if name, ok := e.JsonNames[paramName]; ok {
+		return name
+	}
+	switch naming {
+	case JsonNamingCamelCase:
+		return gen.CamelCase(paramName)
+	case JsonNamingSnakeCase:
+		return gen.SnakeCase(paramName)
+	}
+	return ""
+}
+
+// Checks that json names are set only for parameters of the method and that the request fields have distinct json names.
+func (e EndpointSpecifications) jsonNamesValid(naming JsonNaming) error {
+	m := e.Method
+	for name, jsonName := range e.JsonNames {
+		found := false
+		for _, p := range m.Params[1:] {
+			if p.Name == name {
+				found = true
+			}
+		}
+		if !found {
+			return errors.New(fmt.Sprintf("json name for unknown parameter %v of interface method %v", name, m.Name))
+		}
+		if jsonName == "" || jsonName == "-" {
+			return errors.New(fmt.Sprintf("invalid json name %q for parameter %v of interface method %v", jsonName, name, m.Name))
+		}
+	}
+
+	names := make(map[string]string)
+	for _, p := range m.Params[1:] {
+		jsonName := e.requestFieldJsonName(naming, p.Name)
+		if jsonName == "" {
+			jsonName = e.endpointRequestTypeParamName(p.Name)
+		}
+		if other, ok := names[jsonName]; ok {
+			return errors.New(fmt.Sprintf("parameters %v and %v of interface method %v have the same json name %v", other, p.Name, m.Name, jsonName))
+		}
+		names[jsonName] = p.Name
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package kit
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Generates a JSON Schema file for the request body of every endpoint with json http params, e.g. to validate requests in an api gateway.
+// Endpoints with a custom decode function are skipped.
+//
+// Every json parameter of a method is decoded from the whole request body, if a method has multiple json parameters the body must match all of their schemas.
+// Validation rules of the parameters are included in the schema.
+// Types defined in Go packages are unknown to the generator and accept any value.
+func (g *KitGenerator) generateJsonSchemas() []gen.GenResult {
+	var result []gen.GenResult
+	for _, es := range g.Spec.Endpoints {
+		var schemas []map[string]interface{}
+		for i, p := range es.Method.Params[1:] {
+			if es.HttpParams[i].Type != HttpTypeJson {
+				continue
+			}
+			schema := jsonSchemaType(p.Type)
+			if rules, ok := es.Validation[p.Name]; ok {
+				schema = applyJsonSchemaValidation(schema, p.Type, rules)
+			}
+			schemas = append(schemas, schema)
+		}
+		if len(schemas) == 0 {
+			continue
+		}
+
+		for _, spec := range es.EndpointSpecs {
+			if spec.HttpSpec.DecodeFunc != "" {
+				continue
+			}
+			schema := map[string]interface{}{}
+			if len(schemas) == 1 {
+				for k, v := range schemas[0] {
+					schema[k] = v
+				}
+			} else {
+				schema["allOf"] = schemas
+			}
+			schema["$schema"] = jsonSchemaDialect
+			schema["$comment"] = gen.GeneratedFileComment
+			schema["title"] = spec.Name + " request body"
+			schema["description"] = fmt.Sprintf("%v %v", strings.ToUpper(spec.HttpSpec.Method), g.Spec.PathPrefix+spec.HttpSpec.Path)
+
+			content, err := json.MarshalIndent(schema, "", "  ")
+			if err != nil {
+				panic(fmt.Sprintf("could not encode json schema for endpoint %v: %v", spec.Name, err))
+			}
+			result = append(result, gen.GenResult{
+				Content:    append(content, '\n'),
+				OutputFile: g.Spec.Module.FileName("", filepath.Join(g.Spec.JsonSchemaOutput, spec.Name+".schema.json")),
+			})
+		}
+	}
+	return result
+}
+
+// Returns the schema of the json encoding of a Go type.
+func jsonSchemaType(p parse.ParamType) map[string]interface{} {
+	switch t := p.(type) {
+	case parse.SimpleType:
+		return jsonSchemaSimpleType(t)
+	case parse.ArrayType:
+		return jsonSchemaArrayType(t.Type)
+	case parse.EllipsisType:
+		return jsonSchemaArrayType(t.Type)
+	case parse.MapType:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchemaType(t.ValueType)}
+	case parse.StarType:
+		return map[string]interface{}{"anyOf": []interface{}{jsonSchemaType(t.Type), map[string]interface{}{"type": "null"}}}
+	case parse.StructType:
+		properties := make(map[string]interface{})
+		for _, f := range t.Fields {
+			name, opts, _ := strings.Cut(f.Tags["json"], ",")
+			// embedded and unexported fields are skipped
+			if (name == "-" && opts == "") || f.Name == "" || !token.IsExported(f.Name) {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = jsonSchemaType(f.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func jsonSchemaArrayType(elem parse.ParamType) map[string]interface{} {
+	// byte slices are encoded as base64 strings
+	if parse.IsSimpleType(elem, "byte", "") || parse.IsSimpleType(elem, "uint8", "") {
+		return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+	}
+	return map[string]interface{}{"type": "array", "items": jsonSchemaType(elem)}
+}
+
+func jsonSchemaSimpleType(t parse.SimpleType) map[string]interface{} {
+	if t.Package == "" {
+		switch t.Type {
+		case "string":
+			return map[string]interface{}{"type": "string"}
+		case "bool":
+			return map[string]interface{}{"type": "boolean"}
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune":
+			return map[string]interface{}{"type": "integer"}
+		case "float32", "float64":
+			return map[string]interface{}{"type": "number"}
+		}
+		return map[string]interface{}{}
+	}
+	switch t.Package + "." + t.Type {
+	case "time.Time":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "time.Duration":
+		return map[string]interface{}{"type": "integer"}
+	}
+	return map[string]interface{}{"$comment": fmt.Sprintf("Go type %v.%v, its definition is not known to the code generator", t.Package, t.Type)}
+}
+
+// Adds the validation rules of a parameter to its schema.
+// Rules are only added for the types they are supported for, see ValidationRules.IsValid.
+func applyJsonSchemaValidation(schema map[string]interface{}, t parse.ParamType, rules ValidationRules) map[string]interface{} {
+	// a required pointer must not be nil, the value it points to can be empty
+	if st, ok := t.(parse.StarType); ok {
+		innerRules := rules
+		innerRules.Required = false
+		inner := applyJsonSchemaValidation(jsonSchemaType(st.Type), st.Type, innerRules)
+		if rules.Required {
+			return inner
+		}
+		return map[string]interface{}{"anyOf": []interface{}{inner, map[string]interface{}{"type": "null"}}}
+	}
+
+	minLength, maxLength := rules.MinLength, rules.MaxLength
+	if rules.Required && (minLength == nil || *minLength < 1) {
+		one := 1
+		minLength = &one
+	}
+	var minKey, maxKey string
+	switch schema["type"] {
+	case "string":
+		// the length of a base64 encoded byte slice differs from the number of bytes
+		if schema["contentEncoding"] != nil {
+			break
+		}
+		minKey, maxKey = "minLength", "maxLength"
+		if rules.Pattern != "" {
+			schema["pattern"] = rules.Pattern
+		}
+	case "array":
+		minKey, maxKey = "minItems", "maxItems"
+	case "object":
+		minKey, maxKey = "minProperties", "maxProperties"
+	case "integer", "number":
+		if rules.Min != nil {
+			schema["minimum"] = *rules.Min
+		}
+		if rules.Max != nil {
+			schema["maximum"] = *rules.Max
+		}
+	}
+	if minKey != "" && minLength != nil {
+		schema[minKey] = *minLength
+	}
+	if maxKey != "" && maxLength != nil {
+		schema[maxKey] = *maxLength
+	}
+	return schema
+}
@@ -1,6 +1,8 @@
 // Package kit provides a code generator to generate endpoints and http handlers for an interface.
 // For the code generator to work, the following requirements should be met:
-//   - Interface methods do not contain function types, channel types or anonymous structs as parameter or return values.
+//   - The interface is not generic, i.e. does not have type parameters.
+//   - Interface methods do not contain function types, channel types or anonymous structs as parameter or return values,
+//     except for the last parameter of methods with transport "websocket".
 //   - The source file that contains the interface should not import any types that are used in the interface definition using ".", i.e. (import them without a prefix/qualifier).
 //   - Every interface method has a context.Context as the first parameter.
 //   - Every interface method has 1 or 2 return values, where the last one is always "error".
@@ -15,8 +17,10 @@ import (
 
 const kitEndpointPackage = "github.com/go-kit/kit/endpoint"
 const kitHttpPackage = "github.com/go-kit/kit/transport/http"
-const localEndpointPackage = "github.com/dkinzler/kit/endpoint"
-const localHttpPackage = "github.com/dkinzler/kit/transport/http"
+const kitMetricsPackage = "github.com/go-kit/kit/metrics"
+const kitLogPackage = "github.com/go-kit/log"
+const defaultEndpointHelperPackage = "github.com/dkinzler/kit/endpoint"
+const defaultHttpHelperPackage = "github.com/dkinzler/kit/transport/http"
 const gorillaMuxPackage = "github.com/gorilla/mux"
 
 type KitGenerator struct {
@@ -53,5 +57,49 @@ func (g *KitGenerator) Generate() (result []gen.GenResult, err error) {
 		http := g.generateHttp()
 		result = append(result, http)
 	}
+	if g.Spec.GenerateTests {
+		result = append(result, g.generateEndpointTests())
+		if g.Spec.GenerateHttp {
+			if tests := g.generateHttpTests(); tests.Code != nil {
+				result = append(result, tests)
+			}
+		}
+	}
+	if g.Spec.GenerateNats {
+		result = append(result, g.generateNats())
+	}
+	if g.Spec.GenerateAmqp {
+		result = append(result, g.generateAmqp())
+	}
+	if g.Spec.GenerateTypescript {
+		result = append(result, g.generateTypescript())
+	}
+	if g.Spec.GenerateProto {
+		result = append(result, g.generateProto())
+	}
+	if g.Spec.GenerateJsonSchema {
+		result = append(result, g.generateJsonSchemas()...)
+	}
+	g.applyImportAliases(result)
 	return result, nil
 }
+
+// Replaces the default import aliases of the generated Go code with the ones configured in the annotation.
+func (g *KitGenerator) applyImportAliases(results []gen.GenResult) {
+	if len(g.Spec.Imports) == 0 {
+		return
+	}
+	for i := range results {
+		if results[i].Code == nil {
+			continue
+		}
+		imports := make(map[string]string)
+		for path, alias := range results[i].Imports {
+			imports[path] = alias
+		}
+		for path, alias := range g.Spec.Imports {
+			imports[path] = alias
+		}
+		results[i].Imports = imports
+	}
+}
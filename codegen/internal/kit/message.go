@@ -0,0 +1,335 @@
+package kit
+
+import (
+	"github.com/dkinzler/kit/codegen/gen"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const kitNatsPackage = "github.com/go-kit/kit/transport/nats"
+const kitAmqpPackage = "github.com/go-kit/kit/transport/amqp"
+const localNatsPackage = "github.com/dkinzler/kit/transport/nats"
+const localAmqpPackage = "github.com/dkinzler/kit/transport/amqp"
+const natsPackage = "github.com/nats-io/nats.go"
+const amqpPackage = "github.com/streadway/amqp"
+
+// Defines how an endpoint is exposed over NATS.
+// Endpoints without a subject are not exposed.
+type NatsSpec struct {
+	// subject the subscriber for the endpoint listens on and the publisher sends requests to
+	Subject string `json:"subject"`
+	// Optional queue group of the subscriber, each request is only delivered to one subscriber of the group.
+	Queue string `json:"queue"`
+}
+
+// Defines how an endpoint is exposed over AMQP.
+// Endpoints without a queue are not exposed.
+type AmqpSpec struct {
+	// queue the subscriber for the endpoint consumes and the publisher sends requests to
+	Queue string `json:"queue"`
+}
+
+// Message based transports (NATS and AMQP) have a lot in common, the specifics of a transport are captured by this type.
+// All method parameters (except the first context parameter) are decoded from a JSON message, i.e. the endpoint request type is sent as JSON.
+type messageTransport struct {
+	// e.g. "Nats" or "Amqp", used in function names
+	name string
+	// package of the Go kit transport
+	kitPackage string
+	// helper package in this module
+	localPackage string
+	// message type passed to decode funcs, e.g. nats.Msg
+	msgType jen.Code
+	// returns the endpoint specs that are exposed over the transport
+	exposed func(spec EndpointSpecification) bool
+}
+
+var natsTransport = messageTransport{
+	name:         "Nats",
+	kitPackage:   kitNatsPackage,
+	localPackage: localNatsPackage,
+	msgType:      jen.Qual(natsPackage, "Msg"),
+	exposed: func(spec EndpointSpecification) bool {
+		return spec.NatsSpec.Subject != ""
+	},
+}
+
+var amqpTransport = messageTransport{
+	name:         "Amqp",
+	kitPackage:   kitAmqpPackage,
+	localPackage: localAmqpPackage,
+	msgType:      jen.Qual(amqpPackage, "Delivery"),
+	exposed: func(spec EndpointSpecification) bool {
+		return spec.AmqpSpec.Queue != ""
+	},
+}
+
+func (t messageTransport) decodeFuncName(es EndpointSpecifications) string {
+	return "decode" + t.name + gen.UppercaseFirst(es.Method.Name) + "Request"
+}
+
+// Returns true if at least one endpoint for the method is exposed over the transport.
+func (t messageTransport) methodExposed(es EndpointSpecifications) bool {
+	for _, spec := range es.EndpointSpecs {
+		if t.exposed(spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// Decode function for the method, for methods that only have a context parameter the NopRequestDecoder of the helper package is used.
+func (t messageTransport) decodeFunc(es EndpointSpecifications) jen.Code {
+	if len(es.Method.Params) <= 1 {
+		if t.kitPackage == kitNatsPackage {
+			return jen.Qual(kitNatsPackage, "NopRequestDecoder")
+		}
+		return jen.Qual(t.localPackage, "NopRequestDecoder")
+	}
+	return jen.Id(t.decodeFuncName(es))
+}
+
+// Generates a function that decodes a message into the request type of the endpoint for the method.
+func (g *KitGenerator) generateMessageDecodeFunc(t messageTransport, es EndpointSpecifications) jen.Code {
+	if len(es.Method.Params) <= 1 {
+		return jen.Empty()
+	}
+
+	stmts := []jen.Code{
+		jen.Var().Id("req").Qual(g.Spec.EndpointPackageFullPath, es.endpointRequestTypeName()),
+		jen.Id("err").Op(":=").Qual(t.localPackage, "DecodeJSONRequest").Call(jen.Id("msg"), jen.Op("&").Id("req")),
+		jen.If(jen.Id("err").Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Id("err")),
+		),
+	}
+	if es.hasValidation() {
+		stmts = append(stmts,
+			jen.If(jen.Id("err").Op(":=").Id("req").Dot("Validate").Call(), jen.Id("err").Op("!=").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Id("err")),
+			),
+		)
+	}
+	stmts = append(stmts, jen.Return(jen.Id("req"), jen.Nil()))
+
+	return g.g.GenFunction(
+		nil,
+		t.decodeFuncName(es),
+		jen.Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("msg").Op("*").Add(t.msgType),
+		),
+		jen.Params(
+			jen.Interface(),
+			jen.Error(),
+		),
+		stmts,
+	)
+}
+
+// DecodeJSONResponse function of the helper package, instantiated with the result type of the method.
+func (g *KitGenerator) messageDecodeResponseFunc(t messageTransport, es EndpointSpecifications) jen.Code {
+	var resultType jen.Code = jen.Interface()
+	if len(es.Method.Returns) == 2 {
+		resultType = g.g.GenParamType(es.Method.Returns[0].Type)
+	}
+	return jen.Qual(t.localPackage, "DecodeJSONResponse").Types(resultType)
+}
+
+// Statement that adds the error encoder of the helper package to the subscriber options.
+// It is added first, so that it can be overridden by the options passed to the generated function.
+func (t messageTransport) prependErrorEncoder() jen.Code {
+	return jen.Id("opts").Op("=").Append(
+		jen.Index().Qual(t.kitPackage, "SubscriberOption").Values(
+			jen.Qual(t.kitPackage, "SubscriberErrorEncoder").Call(jen.Qual(t.localPackage, "EncodeError")),
+		),
+		jen.Id("opts").Op("..."),
+	)
+}
+
+func (t messageTransport) newSubscriber(es EndpointSpecifications, spec EndpointSpecification) jen.Code {
+	return jen.Qual(t.kitPackage, "NewSubscriber").Call(
+		jen.Id("endpoints").Dot(spec.endpointSetFieldName()),
+		t.decodeFunc(es),
+		jen.Qual(t.localPackage, "EncodeGenericJSONResponse"),
+		jen.Id("opts").Op("..."),
+	)
+}
+
+func (g *KitGenerator) generateNats() gen.GenResult {
+	g.g = gen.NewSimpleGenerator()
+	t := natsTransport
+
+	var code *jen.Group = jen.NewFile("").Group
+
+	var subscriptions []jen.Code
+	clientEndpoints := make(jen.Dict)
+	for _, es := range g.Spec.Endpoints {
+		if !t.methodExposed(es) {
+			continue
+		}
+		code.Add(g.generateMessageDecodeFunc(t, es))
+		code.Line()
+		for _, spec := range es.EndpointSpecs {
+			if !t.exposed(spec) {
+				continue
+			}
+			subscription := jen.Dict{
+				jen.Id("Subject"):    jen.Lit(spec.NatsSpec.Subject),
+				jen.Id("Subscriber"): t.newSubscriber(es, spec),
+			}
+			if spec.NatsSpec.Queue != "" {
+				subscription[jen.Id("Queue")] = jen.Lit(spec.NatsSpec.Queue)
+			}
+			subscriptions = append(subscriptions, jen.Values(subscription))
+			clientEndpoints[jen.Id(spec.endpointSetFieldName())] = jen.Qual(kitNatsPackage, "NewPublisher").Call(
+				jen.Id("nc"),
+				jen.Lit(spec.NatsSpec.Subject),
+				jen.Qual(kitNatsPackage, "EncodeJSONRequest"),
+				g.messageDecodeResponseFunc(t, es),
+				jen.Id("opts").Op("..."),
+			).Dot("Endpoint").Call()
+		}
+	}
+
+	code.Comment("RegisterNatsSubscribers subscribes to the subjects of all endpoints exposed over NATS.").Line().Add(g.g.GenFunction(
+		nil,
+		"RegisterNatsSubscribers",
+		jen.Params(
+			jen.Id("endpoints").Qual(g.Spec.EndpointPackageFullPath, "EndpointSet"),
+			jen.Id("nc").Op("*").Qual(natsPackage, "Conn"),
+			jen.Id("opts").Index().Qual(kitNatsPackage, "SubscriberOption"),
+		),
+		jen.Params(jen.Index().Op("*").Qual(natsPackage, "Subscription"), jen.Error()),
+		[]jen.Code{
+			t.prependErrorEncoder(),
+			jen.Return(jen.Qual(localNatsPackage, "Subscribe").Call(
+				jen.Id("nc"),
+				jen.Index().Qual(localNatsPackage, "Subscription").ValuesFunc(func(g *jen.Group) {
+					for _, s := range subscriptions {
+						g.Line().Add(s)
+					}
+					if len(subscriptions) > 0 {
+						g.Line()
+					}
+				}),
+			)),
+		},
+	))
+	code.Line()
+	code.Comment("NewNatsClientEndpoints returns endpoints that send requests to the subscribers created by RegisterNatsSubscribers.").Line().
+		Comment("Endpoints that are not exposed over NATS are nil.").Line().Add(g.g.GenFunction(
+		nil,
+		"NewNatsClientEndpoints",
+		jen.Params(
+			jen.Id("nc").Op("*").Qual(natsPackage, "Conn"),
+			jen.Id("opts").Index().Qual(kitNatsPackage, "PublisherOption"),
+		),
+		jen.Qual(g.Spec.EndpointPackageFullPath, "EndpointSet"),
+		[]jen.Code{
+			jen.Return(jen.Qual(g.Spec.EndpointPackageFullPath, "EndpointSet").Values(clientEndpoints)),
+		},
+	))
+
+	return gen.GenResult{
+		Code:        code,
+		PackagePath: g.Spec.NatsPackageFullPath,
+		PackageName: g.Spec.natsPackageName(),
+		Imports: map[string]string{
+			kitNatsPackage:   "kitnats",
+			localNatsPackage: "tnats",
+			natsPackage:      "nats",
+		},
+		OutputFile: g.Spec.Module.FileName(g.Spec.NatsPackage, g.Spec.NatsOutput),
+	}
+}
+
+func (g *KitGenerator) generateAmqp() gen.GenResult {
+	g.g = gen.NewSimpleGenerator()
+	t := amqpTransport
+
+	var code *jen.Group = jen.NewFile("").Group
+
+	var subscriptions []jen.Code
+	clientEndpoints := make(jen.Dict)
+	for _, es := range g.Spec.Endpoints {
+		if !t.methodExposed(es) {
+			continue
+		}
+		code.Add(g.generateMessageDecodeFunc(t, es))
+		code.Line()
+		for _, spec := range es.EndpointSpecs {
+			if !t.exposed(spec) {
+				continue
+			}
+			subscriptions = append(subscriptions, jen.Values(jen.Dict{
+				jen.Id("Queue"):      jen.Lit(spec.AmqpSpec.Queue),
+				jen.Id("Subscriber"): t.newSubscriber(es, spec),
+			}))
+			// requests are published to the default exchange with the queue name as routing key
+			clientEndpoints[jen.Id(spec.endpointSetFieldName())] = jen.Qual(kitAmqpPackage, "NewPublisher").Call(
+				jen.Id("ch"),
+				jen.Id("replyQueue"),
+				jen.Qual(localAmqpPackage, "EncodeJSONRequest"),
+				g.messageDecodeResponseFunc(t, es),
+				jen.Append(
+					jen.Index().Qual(kitAmqpPackage, "PublisherOption").Values(
+						jen.Qual(kitAmqpPackage, "PublisherBefore").Call(jen.Qual(kitAmqpPackage, "SetPublishKey").Call(jen.Lit(spec.AmqpSpec.Queue))),
+					),
+					jen.Id("opts").Op("..."),
+				).Op("..."),
+			).Dot("Endpoint").Call()
+		}
+	}
+
+	code.Comment("RegisterAmqpSubscribers starts consuming the queues of all endpoints exposed over AMQP.").Line().Add(g.g.GenFunction(
+		nil,
+		"RegisterAmqpSubscribers",
+		jen.Params(
+			jen.Id("endpoints").Qual(g.Spec.EndpointPackageFullPath, "EndpointSet"),
+			jen.Id("ch").Op("*").Qual(amqpPackage, "Channel"),
+			jen.Id("opts").Index().Qual(kitAmqpPackage, "SubscriberOption"),
+		),
+		jen.Error(),
+		[]jen.Code{
+			t.prependErrorEncoder(),
+			jen.Return(jen.Qual(localAmqpPackage, "Consume").Call(
+				jen.Id("ch"),
+				jen.Index().Qual(localAmqpPackage, "Subscription").ValuesFunc(func(g *jen.Group) {
+					for _, s := range subscriptions {
+						g.Line().Add(s)
+					}
+					if len(subscriptions) > 0 {
+						g.Line()
+					}
+				}),
+			)),
+		},
+	))
+	code.Line()
+	code.Comment("NewAmqpClientEndpoints returns endpoints that send requests to the subscribers created by RegisterAmqpSubscribers.").Line().
+		Comment("Responses are received on the given reply queue. Endpoints that are not exposed over AMQP are nil.").Line().Add(g.g.GenFunction(
+		nil,
+		"NewAmqpClientEndpoints",
+		jen.Params(
+			jen.Id("ch").Op("*").Qual(amqpPackage, "Channel"),
+			jen.Id("replyQueue").Op("*").Qual(amqpPackage, "Queue"),
+			jen.Id("opts").Index().Qual(kitAmqpPackage, "PublisherOption"),
+		),
+		jen.Qual(g.Spec.EndpointPackageFullPath, "EndpointSet"),
+		[]jen.Code{
+			jen.Return(jen.Qual(g.Spec.EndpointPackageFullPath, "EndpointSet").Values(clientEndpoints)),
+		},
+	))
+
+	return gen.GenResult{
+		Code:        code,
+		PackagePath: g.Spec.AmqpPackageFullPath,
+		PackageName: g.Spec.amqpPackageName(),
+		Imports: map[string]string{
+			kitAmqpPackage:   "kitamqp",
+			localAmqpPackage: "tamqp",
+			amqpPackage:      "amqp",
+		},
+		OutputFile: g.Spec.Module.FileName(g.Spec.AmqpPackage, g.Spec.AmqpOutput),
+	}
+}
@@ -0,0 +1,285 @@
+package kit
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+)
+
+// Generates a .proto file with a service that has a rpc for every interface method with endpoints,
+// together with request and response messages derived from the parameters and results of the methods.
+// The file is meant as a starting point for a gRPC api that stays in sync with the interface.
+//
+// Like for the TypeScript types, types defined in Go packages are unknown to the generator,
+// they are declared as empty messages.
+func (g *KitGenerator) generateProto() gen.GenResult {
+	types := newProtoTypes()
+	for _, es := range g.Spec.Endpoints {
+		types.used[es.endpointRequestTypeName()] = true
+		types.used[es.endpointResponseTypeName()] = true
+	}
+
+	var service, messages strings.Builder
+	fmt.Fprintf(&service, "service %v {\n", g.Spec.Interface.Name)
+	for _, es := range g.Spec.Endpoints {
+		method := es.Method
+		fmt.Fprintf(&service, "  rpc %v(%v) returns (%v);\n", method.Name, es.endpointRequestTypeName(), es.endpointResponseTypeName())
+
+		var fields []protoField
+		for _, p := range method.Params[1:] {
+			fields = append(fields, protoField{Name: gen.SnakeCase(p.Name), Type: p.Type})
+		}
+		types.writeMessage(&messages, "", es.endpointRequestTypeName(), fields)
+		messages.WriteString("\n")
+
+		fields = nil
+		if len(method.Returns) == 2 {
+			fields = append(fields, protoField{Name: "result", Type: method.Returns[0].Type})
+		}
+		types.writeMessage(&messages, "", es.endpointResponseTypeName(), fields)
+		messages.WriteString("\n")
+	}
+	service.WriteString("}\n")
+
+	for _, m := range types.unknown {
+		fmt.Fprintf(&messages, "// Go type %v, its definition is not known to the code generator.\nmessage %v {}\n\n", m.goType, m.name)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %v\n// source: %v.%v\n\n", gen.GeneratedFileComment, g.Spec.Interface.Package, g.Spec.Interface.Name)
+	b.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&b, "package %v;\n\n", g.Spec.ProtoPackage)
+	if len(types.imports) > 0 {
+		var imports []string
+		for i := range types.imports {
+			imports = append(imports, i)
+		}
+		sort.Strings(imports)
+		for _, i := range imports {
+			fmt.Fprintf(&b, "import %q;\n", i)
+		}
+		b.WriteString("\n")
+	}
+	if len(g.Spec.ProtoOptions) > 0 {
+		var keys []string
+		for key := range g.Spec.ProtoOptions {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "option %v = %v;\n", key, strconv.Quote(g.Spec.ProtoOptions[key]))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(service.String())
+	b.WriteString("\n")
+	b.WriteString(messages.String())
+
+	return gen.GenResult{
+		Content:    []byte(strings.TrimSuffix(b.String(), "\n")),
+		OutputFile: g.Spec.Module.FileName("", g.Spec.ProtoOutput),
+	}
+}
+
+// Matches valid proto package names, e.g. "example.v1".
+var protoPackageRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// Default proto package for an interface, the name of the package of the interface.
+func defaultProtoPackage(i parse.Interface) string {
+	return strings.ToLower(strings.NewReplacer("-", "_", ".", "_").Replace(path.Base(i.Package)))
+}
+
+type protoField struct {
+	Name string
+	Type parse.ParamType
+}
+
+type protoTypes struct {
+	// message names of Go types defined in packages
+	names   map[string]string
+	used    map[string]bool
+	unknown []protoUnknownType
+	// files imported for well-known types
+	imports map[string]bool
+}
+
+type protoUnknownType struct {
+	goType string
+	name   string
+}
+
+func newProtoTypes() *protoTypes {
+	return &protoTypes{
+		names:   make(map[string]string),
+		used:    make(map[string]bool),
+		imports: make(map[string]bool),
+	}
+}
+
+// Writes a message with the given fields, anonymous struct types of fields are written as nested messages.
+func (t *protoTypes) writeMessage(b *strings.Builder, indent, name string, fields []protoField) {
+	if len(fields) == 0 {
+		fmt.Fprintf(b, "%vmessage %v {}\n", indent, name)
+		return
+	}
+	fmt.Fprintf(b, "%vmessage %v {\n", indent, name)
+	for i, f := range fields {
+		label, typ := t.fieldType(b, indent+"  ", f.Name, f.Type)
+		if label != "" {
+			label += " "
+		}
+		fmt.Fprintf(b, "%v  %v%v %v = %v;\n", indent, label, typ, f.Name, i+1)
+	}
+	fmt.Fprintf(b, "%v}\n", indent)
+}
+
+// Returns the label ("repeated", "optional" or empty) and type of a field.
+// Nested messages for anonymous struct types are written to b.
+func (t *protoTypes) fieldType(b *strings.Builder, indent, fieldName string, p parse.ParamType) (string, string) {
+	switch pt := p.(type) {
+	case parse.ArrayType:
+		return t.repeatedType(b, indent, fieldName, pt.Type)
+	case parse.EllipsisType:
+		return t.repeatedType(b, indent, fieldName, pt.Type)
+	case parse.StarType:
+		label, typ := t.fieldType(b, indent, fieldName, pt.Type)
+		if label == "" && isProtoScalar(typ) {
+			label = "optional"
+		}
+		return label, typ
+	case parse.MapType:
+		key := t.scalarType(pt.KeyType)
+		if key == "" || key == "bytes" || key == "float" || key == "double" {
+			panic(fmt.Sprintf("field %v: map keys must be of type string, bool or an integer type to be used in a proto file", fieldName))
+		}
+		_, value := t.nestedType(b, indent, fieldName, pt.ValueType)
+		return "", "map<" + key + ", " + value + ">"
+	case parse.StructType:
+		name := gen.UppercaseFirst(gen.CamelCase(fieldName))
+		var fields []protoField
+		for _, f := range pt.Fields {
+			jsonName, opts, _ := strings.Cut(f.Tags["json"], ",")
+			// embedded and unexported fields are skipped
+			if (jsonName == "-" && opts == "") || f.Name == "" || !token.IsExported(f.Name) {
+				continue
+			}
+			fields = append(fields, protoField{Name: gen.SnakeCase(f.Name), Type: f.Type})
+		}
+		t.writeMessage(b, indent, name, fields)
+		return "", name
+	case parse.SimpleType:
+		if typ := t.scalarType(pt); typ != "" {
+			return "", typ
+		}
+		return "", t.messageType(pt)
+	case parse.GenericType:
+		return "", t.messageType(pt.Type)
+	default:
+		panic(fmt.Sprintf("field %v: type %T cannot be used in a proto file", fieldName, p))
+	}
+}
+
+func (t *protoTypes) repeatedType(b *strings.Builder, indent, fieldName string, elem parse.ParamType) (string, string) {
+	// byte slices are encoded as bytes
+	if parse.IsSimpleType(elem, "byte", "") || parse.IsSimpleType(elem, "uint8", "") {
+		return "", "bytes"
+	}
+	if st, ok := elem.(parse.StarType); ok {
+		elem = st.Type
+	}
+	_, typ := t.nestedType(b, indent, fieldName, elem)
+	return "repeated", typ
+}
+
+// Like fieldType but for elements of slices and values of maps, which cannot be repeated or maps in a proto file.
+// Nested slices and maps are encoded using the well-known types ListValue and Struct.
+func (t *protoTypes) nestedType(b *strings.Builder, indent, fieldName string, p parse.ParamType) (string, string) {
+	label, typ := t.fieldType(b, indent, fieldName, p)
+	if label == "repeated" {
+		t.imports["google/protobuf/struct.proto"] = true
+		return "", "google.protobuf.ListValue"
+	}
+	if strings.HasPrefix(typ, "map<") {
+		t.imports["google/protobuf/struct.proto"] = true
+		return "", "google.protobuf.Struct"
+	}
+	return "", typ
+}
+
+// Returns the proto type of a built-in Go type or an empty string if there is none.
+func (t *protoTypes) scalarType(p parse.ParamType) string {
+	st, ok := p.(parse.SimpleType)
+	if !ok || st.Package != "" {
+		return ""
+	}
+	switch st.Type {
+	case "string":
+		return "string"
+	case "bool":
+		return "bool"
+	case "int", "int64":
+		return "int64"
+	case "int8", "int16", "int32", "rune":
+		return "int32"
+	case "uint", "uint64", "uintptr":
+		return "uint64"
+	case "uint8", "uint16", "uint32", "byte":
+		return "uint32"
+	case "float32":
+		return "float"
+	case "float64":
+		return "double"
+	}
+	return ""
+}
+
+func isProtoScalar(typ string) bool {
+	switch typ {
+	case "string", "bool", "int64", "int32", "uint64", "uint32", "float", "double", "bytes":
+		return true
+	}
+	return false
+}
+
+// Returns the message type of a named Go type, well-known types are used for some of the types of the standard library.
+func (t *protoTypes) messageType(st parse.SimpleType) string {
+	switch st.Package + "." + st.Type {
+	case "time.Time":
+		t.imports["google/protobuf/timestamp.proto"] = true
+		return "google.protobuf.Timestamp"
+	case "time.Duration":
+		t.imports["google/protobuf/duration.proto"] = true
+		return "google.protobuf.Duration"
+	case "encoding/json.RawMessage", ".interface{}", ".any", ".error":
+		t.imports["google/protobuf/struct.proto"] = true
+		return "google.protobuf.Value"
+	}
+	if st.Package == "" {
+		// type parameters and other built-in types
+		panic(fmt.Sprintf("type %v cannot be used in a proto file", st.Type))
+	}
+
+	goType := st.Package + "." + st.Type
+	if name, ok := t.names[goType]; ok {
+		return name
+	}
+	// types with the same name from different packages are prefixed with the package name
+	name := st.Type
+	if t.used[name] {
+		name = gen.UppercaseFirst(path.Base(st.Package)) + st.Type
+	}
+	for i := 2; t.used[name]; i++ {
+		name = fmt.Sprintf("%v%v%v", gen.UppercaseFirst(path.Base(st.Package)), st.Type, i)
+	}
+	t.names[goType] = name
+	t.used[name] = true
+	t.unknown = append(t.unknown, protoUnknownType{goType: goType, name: name})
+	return name
+}
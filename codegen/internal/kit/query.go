@@ -0,0 +1,103 @@
+package kit
+
+import (
+	"fmt"
+
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// Returns true if a query http param of the given type is decoded from a single named query parameter,
+// i.e. if it is a string, bool, integer or floating point number or a slice of these.
+// Other types, e.g. structs, are decoded from all query parameters, see DecodeQueryParameters in package "github.com/dkinzler/kit/transport/http".
+func isScalarQueryType(t parse.ParamType) bool {
+	if at, ok := t.(parse.ArrayType); ok {
+		t = at.Type
+	}
+	st, ok := t.(parse.SimpleType)
+	if !ok || st.Package != "" {
+		return false
+	}
+	_, ok = scalarQueryParseFuncs[st.Type]
+	return ok
+}
+
+// For every supported type the strconv function and its arguments after the value, that parse query parameter values.
+// Strings are not parsed.
+var scalarQueryParseFuncs = map[string]struct {
+	name string
+	args []jen.Code
+}{
+	"string":  {},
+	"bool":    {name: "ParseBool"},
+	"int":     {name: "ParseInt", args: []jen.Code{jen.Lit(10), jen.Lit(0)}},
+	"int8":    {name: "ParseInt", args: []jen.Code{jen.Lit(10), jen.Lit(8)}},
+	"int16":   {name: "ParseInt", args: []jen.Code{jen.Lit(10), jen.Lit(16)}},
+	"int32":   {name: "ParseInt", args: []jen.Code{jen.Lit(10), jen.Lit(32)}},
+	"int64":   {name: "ParseInt", args: []jen.Code{jen.Lit(10), jen.Lit(64)}},
+	"uint":    {name: "ParseUint", args: []jen.Code{jen.Lit(10), jen.Lit(0)}},
+	"uint8":   {name: "ParseUint", args: []jen.Code{jen.Lit(10), jen.Lit(8)}},
+	"uint16":  {name: "ParseUint", args: []jen.Code{jen.Lit(10), jen.Lit(16)}},
+	"uint32":  {name: "ParseUint", args: []jen.Code{jen.Lit(10), jen.Lit(32)}},
+	"uint64":  {name: "ParseUint", args: []jen.Code{jen.Lit(10), jen.Lit(64)}},
+	"float32": {name: "ParseFloat", args: []jen.Code{jen.Lit(32)}},
+	"float64": {name: "ParseFloat", args: []jen.Code{jen.Lit(64)}},
+}
+
+// Generates statements that decode the value of parameter p from the query parameter with the given name.
+// If the query parameter is missing, p has its zero value. Slices are decoded from all values of the query parameter, e.g. "?id=1&id=2".
+// The statements do not declare the "err" variable in the scope of the decode function.
+func (g *KitGenerator) generateHttpDecodeFuncScalarQueryParam(p parse.Param, name string) []jen.Code {
+	elemType := p.Type
+	at, isSlice := p.Type.(parse.ArrayType)
+	if isSlice {
+		elemType = at.Type
+	}
+	typeName := elemType.(parse.SimpleType).Type
+	query := jen.Id("r").Dot("URL").Dot("Query").Call()
+	// names of the variables for the raw and parsed value, must differ from the parameter name
+	v, x := "v", "x"
+	if p.Name == v || p.Name == x {
+		v, x = "value", "parsed"
+	}
+
+	if typeName == "string" {
+		if isSlice {
+			return []jen.Code{jen.Id(p.Name).Op(":=").Add(query).Index(jen.Lit(name))}
+		}
+		return []jen.Code{jen.Id(p.Name).Op(":=").Add(query).Dot("Get").Call(jen.Lit(name))}
+	}
+
+	parseFunc := scalarQueryParseFuncs[typeName]
+	var value jen.Code = jen.Id(x)
+	// strconv returns int64, uint64 and float64 values, other types need a conversion
+	if typeName != "bool" && typeName != "int64" && typeName != "uint64" && typeName != "float64" {
+		value = jen.Id(typeName).Call(jen.Id(x))
+	}
+	parseStmts := []jen.Code{
+		jen.List(jen.Id(x), jen.Id("err")).Op(":=").Qual("strconv", parseFunc.name).Call(append([]jen.Code{jen.Id(v)}, parseFunc.args...)...),
+		jen.If(jen.Id("err").Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual(localErrorsPackage, "New").Call(
+				jen.Id("err"),
+				jen.Lit(g.Spec.httpPackageName()),
+				jen.Qual(localErrorsPackage, "InvalidArgument"),
+			).Dot("WithPublicMessage").Call(jen.Lit(fmt.Sprintf("invalid value for query parameter %v", name)))),
+		),
+	}
+
+	if isSlice {
+		return []jen.Code{
+			jen.Var().Id(p.Name).Add(g.g.GenValueType(p.Type)),
+			jen.For(jen.List(jen.Id("_"), jen.Id(v)).Op(":=").Range().Add(query).Index(jen.Lit(name))).Block(
+				append(parseStmts, jen.Id(p.Name).Op("=").Append(jen.Id(p.Name), value))...,
+			),
+		}
+	}
+	return []jen.Code{
+		jen.Var().Id(p.Name).Add(g.g.GenValueType(p.Type)),
+		jen.If(jen.Id(v).Op(":=").Add(query).Dot("Get").Call(jen.Lit(name)), jen.Id(v).Op("!=").Lit("")).Block(
+			append(parseStmts, jen.Id(p.Name).Op("=").Add(value))...,
+		),
+	}
+}
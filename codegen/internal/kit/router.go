@@ -0,0 +1,162 @@
+package kit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/gen"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const chiPackage = "github.com/go-chi/chi/v5"
+
+// Router defines the package the generated http handlers are registered with.
+type Router string
+
+// Default, handlers are registered with a *mux.Router from "github.com/gorilla/mux".
+const RouterGorilla Router = "gorilla"
+
+// Handlers are registered with a chi.Router from "github.com/go-chi/chi/v5".
+const RouterChi Router = "chi"
+
+// Handlers are registered with a *http.ServeMux from the standard library, using the path patterns introduced in Go 1.22.
+const RouterStdlib Router = "stdlib"
+
+func (r Router) IsValid() error {
+	switch r {
+	case RouterGorilla, RouterChi, RouterStdlib:
+		return nil
+	}
+	return errors.New(fmt.Sprintf("unknown router %v", r))
+}
+
+// Checks that the router supports the given path.
+func (r Router) validatePath(path string) error {
+	if r == RouterStdlib && strings.Contains(path, ":") {
+		// e.g. "/abc/{id:[0-9]+}" is valid for gorilla/mux and chi, but http.ServeMux does not support regular expressions
+		return errors.New(fmt.Sprintf("path %v contains a regular expression, which is not supported by router %v", path, r))
+	}
+	return nil
+}
+
+// The router parameter of the generated RegisterHttpHandlers function.
+func (r Router) routerParam() jen.Code {
+	switch r {
+	case RouterChi:
+		return jen.Id("router").Qual(chiPackage, "Router")
+	case RouterStdlib:
+		return jen.Id("router").Op("*").Qual("net/http", "ServeMux")
+	default:
+		return jen.Id("router").Op("*").Qual(gorillaMuxPackage, "Router")
+	}
+}
+
+// Statements at the beginning of the generated RegisterHttpHandlers function, that replace the router with a subrouter for the given path prefix.
+// For http.ServeMux there are no subrouters, the prefix is instead added to every pattern (see fullPath).
+func (r Router) mountPathPrefix(prefix string) []jen.Code {
+	if prefix == "" {
+		return nil
+	}
+	switch r {
+	case RouterChi:
+		return []jen.Code{
+			jen.Id("subrouter").Op(":=").Qual(chiPackage, "NewRouter").Call(),
+			jen.Id("router").Dot("Mount").Call(jen.Lit(prefix), jen.Id("subrouter")),
+			jen.Id("router").Op("=").Id("subrouter"),
+			jen.Line(),
+		}
+	case RouterStdlib:
+		return nil
+	default:
+		return []jen.Code{
+			jen.Id("router").Op("=").Id("router").Dot("PathPrefix").Call(jen.Lit(prefix)).Dot("Subrouter").Call(),
+			jen.Line(),
+		}
+	}
+}
+
+// The path a handler is registered with.
+func (r Router) fullPath(prefix, path string) string {
+	if r == RouterStdlib {
+		return prefix + path
+	}
+	return path
+}
+
+// Statements that register the handler for the given endpoint with the router.
+// Handlers also respond to OPTIONS requests, e.g. for CORS preflight requests.
+//
+// The paths OPTIONS requests have already been registered for are tracked in optionsPaths,
+// since registering the same pattern twice with a http.ServeMux panics.
+// Paths are tracked without the names of variables, e.g. "/items/{id}" and "/items/{userId}" are the same pattern for a http.ServeMux.
+func (r Router) registerHandler(spec EndpointSpecification, prefix string, handler jen.Code, optionsPaths map[string]bool) []jen.Code {
+	method := strings.ToUpper(spec.HttpSpec.Method)
+	switch r {
+	case RouterStdlib:
+		path := r.fullPath(prefix, spec.HttpSpec.Path)
+		result := []jen.Code{
+			jen.Id("router").Dot("Handle").Call(jen.Lit(method+" "+path), handler),
+		}
+		if key := gen.NormalizeRoutePath(path); !optionsPaths[key] {
+			optionsPaths[key] = true
+			result = append(result, jen.Id("router").Dot("Handle").Call(jen.Lit("OPTIONS "+path), handler))
+		}
+		return result
+	case RouterChi:
+		return []jen.Code{
+			jen.Id("router").Dot("Method").Call(jen.Lit(method), jen.Lit(spec.HttpSpec.Path), handler),
+			jen.Id("router").Dot("Method").Call(jen.Lit("OPTIONS"), jen.Lit(spec.HttpSpec.Path), handler),
+		}
+	default:
+		return []jen.Code{
+			jen.Id("router").Dot("Handle").Call(
+				jen.Lit(spec.HttpSpec.Path),
+				handler,
+			).Dot("Methods").Call(
+				jen.Lit(method),
+				jen.Lit("OPTIONS"),
+			),
+		}
+	}
+}
+
+// Name of the function in package "github.com/dkinzler/kit/transport/http" that decodes url parameters for this router.
+func (r Router) urlParamDecodeFunc() string {
+	switch r {
+	case RouterChi:
+		return "DecodeChiURLParameter"
+	case RouterStdlib:
+		return "DecodePathValue"
+	default:
+		return "DecodeURLParameter"
+	}
+}
+
+// Statements that set the url parameters of request r to the values of params, a map[string]string.
+// Used in generated tests to call http decode functions without routing the request.
+func (r Router) setUrlParams(params jen.Code) []jen.Code {
+	switch r {
+	case RouterChi:
+		return []jen.Code{
+			jen.Id("rctx").Op(":=").Qual(chiPackage, "NewRouteContext").Call(),
+			jen.For(jen.List(jen.Id("k"), jen.Id("v")).Op(":=").Range().Add(params)).Block(
+				jen.Id("rctx").Dot("URLParams").Dot("Add").Call(jen.Id("k"), jen.Id("v")),
+			),
+			jen.Id("r").Op("=").Id("r").Dot("WithContext").Call(
+				jen.Qual("context", "WithValue").Call(jen.Id("r").Dot("Context").Call(), jen.Qual(chiPackage, "RouteCtxKey"), jen.Id("rctx")),
+			),
+		}
+	case RouterStdlib:
+		return []jen.Code{
+			jen.For(jen.List(jen.Id("k"), jen.Id("v")).Op(":=").Range().Add(params)).Block(
+				jen.Id("r").Dot("SetPathValue").Call(jen.Id("k"), jen.Id("v")),
+			),
+		}
+	default:
+		return []jen.Code{
+			jen.Id("r").Op("=").Qual(gorillaMuxPackage, "SetURLVars").Call(jen.Id("r"), params),
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package kit
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/stretchr/testify/assert"
+)
+
+var handlePatternRegexp = regexp.MustCompile(`router\.Handle\(("[^"]*")`)
+
+func TestRegisterHandlerStdlib(t *testing.T) {
+	a := assert.New(t)
+
+	specs := []EndpointSpecification{
+		{Name: "CreateItem", HttpSpec: HttpSpec{Method: "post", Path: "/items/{user_id}"}},
+		{Name: "DeleteItem", HttpSpec: HttpSpec{Method: "DELETE", Path: "/items/{id}"}},
+		{Name: "ListItems", HttpSpec: HttpSpec{Method: "GET", Path: "/items"}},
+		{Name: "GetFile", HttpSpec: HttpSpec{Method: "GET", Path: "/files/{path...}"}},
+		{Name: "PutFile", HttpSpec: HttpSpec{Method: "PUT", Path: "/files/{name...}"}},
+	}
+	optionsPaths := make(map[string]bool)
+	var stmts []jen.Code
+	for _, spec := range specs {
+		stmts = append(stmts, RouterStdlib.registerHandler(spec, "/api/v1", jen.Id("handler"), optionsPaths)...)
+	}
+
+	var patterns []string
+	for _, m := range handlePatternRegexp.FindAllStringSubmatch(jen.Block(stmts...).GoString(), -1) {
+		pattern, err := strconv.Unquote(m[1])
+		a.Nil(err)
+		patterns = append(patterns, pattern)
+	}
+	a.Equal([]string{
+		"POST /api/v1/items/{user_id}",
+		"OPTIONS /api/v1/items/{user_id}",
+		"DELETE /api/v1/items/{id}",
+		"GET /api/v1/items",
+		"OPTIONS /api/v1/items",
+		"GET /api/v1/files/{path...}",
+		"OPTIONS /api/v1/files/{path...}",
+		"PUT /api/v1/files/{name...}",
+	}, patterns)
+
+	// the generated patterns can be registered with the same mux without panicking
+	mux := http.NewServeMux()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, pattern := range patterns {
+		a.NotPanics(func() { mux.Handle(pattern, handler) }, pattern)
+	}
+}
@@ -0,0 +1,95 @@
+package kit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const localLogPackage = "github.com/dkinzler/kit/log"
+
+// Comment added to scaffold files, which are only generated once and meant to be edited.
+const ScaffoldFileComment = "scaffold generated by codegen, edit as needed"
+
+// Generates a main package that creates the endpoints and http handlers of the interface and runs a http server
+// with RunDefaultServer from package "github.com/dkinzler/kit/transport/http".
+// Values that cannot be generated, like the implementation of the interface, are marked with TODO comments.
+func (g *KitGenerator) GenerateScaffold() (result *jen.File, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = errors.New(fmt.Sprint(r))
+		}
+	}()
+
+	if !g.Spec.GenerateHttp {
+		return nil, errors.New(fmt.Sprintf("interface %v does not generate http handlers, cannot generate scaffold", g.Spec.Interface.Name))
+	}
+
+	f := jen.NewFile("main")
+	f.PackageComment(ScaffoldFileComment)
+	f.ImportAlias(g.Spec.HttpHelperPackage, "t")
+	f.ImportAlias(kitHttpPackage, "kithttp")
+	for path, alias := range g.Spec.Imports {
+		f.ImportAlias(path, alias)
+	}
+
+	var stmts []jen.Code
+	stmts = append(stmts,
+		jen.Id("logger").Op(":=").Qual(localLogPackage, "DefaultJSONLogger").Call(),
+		jen.Line(),
+		jen.Comment("TODO create the service"),
+		jen.Var().Id("s").Qual(g.Spec.Interface.Package, g.Spec.Interface.Name),
+		jen.Line(),
+	)
+
+	newEndpointsArgs := []jen.Code{jen.Id("s"), jen.Qual(g.Spec.EndpointPackageFullPath, "Middlewares").Values()}
+	if g.Spec.Logging {
+		newEndpointsArgs = append(newEndpointsArgs, jen.Id("logger"))
+	}
+	if g.Spec.usesAuth() {
+		stmts = append(stmts,
+			jen.Comment("TODO create an auth checker, e.g. with auth.NewAuthChecker"),
+			jen.Var().Id("ac").Qual(firebaseAuthPackage, "AuthChecker"),
+			jen.Line(),
+		)
+		newEndpointsArgs = append(newEndpointsArgs, jen.Id("ac"), jen.Nil())
+	}
+	stmts = append(stmts, jen.Id("endpoints").Op(":=").Qual(g.Spec.EndpointPackageFullPath, "NewEndpoints").Call(newEndpointsArgs...), jen.Line())
+
+	var newRouter jen.Code
+	switch g.Spec.Router {
+	case RouterChi:
+		newRouter = jen.Qual(chiPackage, "NewRouter").Call()
+	case RouterStdlib:
+		newRouter = jen.Qual("net/http", "NewServeMux").Call()
+	default:
+		newRouter = jen.Qual(gorillaMuxPackage, "NewRouter").Call()
+	}
+	registerArgs := []jen.Code{jen.Id("endpoints"), jen.Id("router")}
+	if len(g.Spec.WebsocketEndpoints) > 0 {
+		registerArgs = append(registerArgs, jen.Id("s"), jen.Op("&").Qual(gorillaWebsocketPackage, "Upgrader").Values())
+	}
+	if g.Spec.DefaultServerOptions {
+		registerArgs = append(registerArgs, jen.Id("logger"))
+	} else {
+		registerArgs = append(registerArgs, g.defaultServerOptions())
+	}
+	stmts = append(stmts,
+		jen.Id("router").Op(":=").Add(newRouter),
+		jen.Qual(g.Spec.HttpPackageFullPath, "RegisterHttpHandlers").Call(registerArgs...),
+		jen.Line(),
+		jen.Id("config").Op(":=").Qual(g.Spec.HttpHelperPackage, "NewServerConfig").Call().Dot("WithPort").Call(jen.Lit(8080)),
+		jen.If(
+			jen.Id("err").Op(":=").Qual(g.Spec.HttpHelperPackage, "RunDefaultServer").Call(jen.Id("router"), jen.Nil(), jen.Id("config")),
+			jen.Id("err").Op("!=").Nil(),
+		).Block(
+			jen.Id("logger").Dot("Error").Call().Dot("Log").Call(jen.Lit("message"), jen.Lit("server stopped with error"), jen.Lit("error"), jen.Id("err")),
+			jen.Qual("os", "Exit").Call(jen.Lit(1)),
+		),
+	)
+
+	f.Func().Id("main").Params().Block(stmts...)
+	return f, nil
+}
@@ -1,9 +1,13 @@
 package kit
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"go/token"
 	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/dkinzler/kit/codegen/annotations"
 	"github.com/dkinzler/kit/codegen/gen"
@@ -16,13 +20,23 @@ import (
 type KitGenSpecification struct {
 	//the interface for which code should be generated
 	Interface parse.Interface
-	//go module the interface belongs to
+	//go module the code is generated in, usually the module the interface belongs to
 	Module parse.Module
+	// Optional name and root directory of a different module the code is generated in.
+	// The path can be relative to the root directory of the module of the interface.
+	// If only the path is set, the name is read from the go.mod file of the output module.
+	// Package options are then relative to the output module.
+	OutputModule     string `json:"outputModule"`
+	OutputModulePath string `json:"outputModulePath"`
 
 	// if false, nothing will be generated
 	GenerateEndpoints bool
 	// if false, will not generate http handlers for endpoints
 	GenerateHttp bool
+	// if false, will not generate NATS subscribers and publishers for endpoints
+	GenerateNats bool
+	// if false, will not generate AMQP subscribers and publishers for endpoints
+	GenerateAmqp bool
 
 	// package name used for generated endpoints
 	// can be a full package path or relative to the module name
@@ -39,9 +53,91 @@ type KitGenSpecification struct {
 	HttpPackageFullPath string
 	// output file for http code
 	HttpOutput string `json:"httpOutput"`
+	// Router the generated http handlers are registered with, defaults to RouterGorilla.
+	Router Router `json:"router"`
+	// Package name used for generated NATS subscribers and publishers.
+	// If empty will not generate anything.
+	NatsPackage         string `json:"natsPackage"`
+	NatsPackageFullPath string
+	// output file for NATS code
+	NatsOutput string `json:"natsOutput"`
+	// Package name used for generated AMQP subscribers and publishers.
+	// If empty will not generate anything.
+	AmqpPackage         string `json:"amqpPackage"`
+	AmqpPackageFullPath string
+	// output file for AMQP code
+	AmqpOutput string `json:"amqpOutput"`
+	// Optional path of a TypeScript file relative to the module root, e.g. "web/src/api/service.gen.ts".
+	// If set, TypeScript types for the endpoint requests and responses and a client for the http handlers are generated.
+	// Every interface must use a different file.
+	TypescriptOutput   string `json:"typescriptOutput"`
+	GenerateTypescript bool
+	// Optional directory relative to the module root, e.g. "api/schemas".
+	// If set and http handlers are generated, a JSON Schema file "<endpoint name>.schema.json" is generated for the request body of every endpoint with json http params.
+	JsonSchemaOutput   string `json:"jsonSchemaOutput"`
+	GenerateJsonSchema bool
+	// Optional path of a .proto file relative to the module root, e.g. "api/proto/service.proto".
+	// If set, a gRPC service with request and response messages for the endpoints is generated.
+	ProtoOutput   string `json:"protoOutput"`
+	GenerateProto bool
+	// Package of the .proto file, defaults to the name of the package of the interface.
+	ProtoPackage string `json:"protoPackage"`
+	// Options written to the .proto file, e.g. {"go_package": "example.com/xyz/api/proto"}.
+	ProtoOptions map[string]string `json:"protoOptions"`
+	// Common prefix for the paths of all http handlers, e.g. "/api/v1".
+	// Handlers are registered with a subrouter for the prefix if the router supports it.
+	PathPrefix string `json:"pathPrefix"`
+
+	// Optional CORS configuration, if set the http handlers are wrapped with a middleware that answers preflight requests
+	// and sets the CORS headers of responses.
+	Cors *CorsSpec `json:"cors"`
+
+	// Import paths of the helper packages used by the generated endpoint and http code,
+	// default to github.com/dkinzler/kit/endpoint and github.com/dkinzler/kit/transport/http.
+	// A replacement must provide the same functions and types, e.g. a fork of these packages.
+	// Defaults can also be set for all interfaces with the options "endpointHelperPackage" and "httpHelperPackage",
+	// see annotations.InterfaceAnnotation.Options.
+	EndpointHelperPackage string `json:"endpointHelperPackage"`
+	HttpHelperPackage     string `json:"httpHelperPackage"`
+	// Import aliases used in the generated code by import path, e.g. {"github.com/dkinzler/kit/transport/http": "kithttputil"}.
+	// Replace the default aliases, e.g. "e" and "t" for the helper packages, that might collide with identifiers of the package.
+	Imports map[string]string `json:"imports"`
+
+	// Json tags of the fields of generated endpoint request types, see JsonNaming.
+	// Can be overridden for individual parameters with EndpointSpecifications.JsonNames.
+	JsonNaming JsonNaming `json:"jsonNaming"`
+
+	// If true, skeleton table-driven tests are generated in "endpoint_gen_test.go" in the endpoint package and,
+	// if http handlers are generated, in "http_gen_test.go" in the http package.
+	// The endpoint tests use the mock generated for the interface with the @Mock annotation.
+	GenerateTests bool `json:"generateTests"`
+	// Package of the mock generated with the @Mock annotation, can be a full package path or relative to the module name.
+	// Defaults to the package of the interface, like the @Mock annotation.
+	MockPackage         string `json:"mockPackage"`
+	MockPackageFullPath string
+
+	// If true, MakeXEndpoint functions return a strongly typed endpoint func(ctx, XRequest) (XResponse, error),
+	// that is adapted to a go-kit endpoint.Endpoint with AdaptTypedEndpoint from the endpoint helper package.
+	TypedEndpoints bool `json:"typedEndpoints"`
+	// Optional envelope the response values of http handlers are wrapped with, either EnvelopeData for responses of the form {"data": ...}
+	// or a reference to a function with the signature of Envelope from the http helper package, e.g. "example.com/xyz/api.Envelope".
+	// By default response values are encoded as is. Endpoints with a custom encode function are not affected.
+	Envelope string `json:"envelope"`
+	// If true, RegisterHttpHandlers takes a logger instead of a slice of server options and creates the standard options,
+	// that encode errors with EncodeError and log them with LogErrorHandler from the http helper package.
+	DefaultServerOptions bool `json:"defaultServerOptions"`
+	// If true, generate a NewInstrumentedEndpoints function that records request durations for every endpoint.
+	Instrumentation bool `json:"instrumentation"`
+	// If true, NewEndpoints takes an additional logger parameter and errors returned by the service are logged for every endpoint.
+	Logging bool `json:"logging"`
+	// Optional names of middleware stages, if set a MiddlewareStages struct is generated that groups the middlewares of the endpoints
+	// into the stages and combines them in the given order.
+	MiddlewareStages MiddlewareStages `json:"middlewareStages"`
 
 	// each element specifies the endpoints to generate for an interface method
 	Endpoints []EndpointSpecifications
+	// Interface methods with transport "websocket", for which websocket handlers instead of endpoints are generated.
+	WebsocketEndpoints []EndpointSpecifications
 }
 
 func (g KitGenSpecification) endpointPackageName() string {
@@ -52,34 +148,120 @@ func (g KitGenSpecification) httpPackageName() string {
 	return path.Base(g.HttpPackage)
 }
 
+func (g KitGenSpecification) natsPackageName() string {
+	return path.Base(g.NatsPackage)
+}
+
+func (g KitGenSpecification) amqpPackageName() string {
+	return path.Base(g.AmqpPackage)
+}
+
 // Checks if a given specification is valid.
 // A specification is not valid if one of the following conditions is not satisfied:
+//   - the interface cannot be generic, i.e. have type parameters
 //   - there cannot be two endpoints with the same name
 //   - any interface method (for which at least one endpoint is defined) must have a context.Context value as first parameter
 //   - any interface method (for which at least one endpoint is defined) must have at most two return values and last return value must be of type error
 //   - if http code is generated, endpoints should have http method, path and success code set
 func (spec KitGenSpecification) IsValid() error {
+	if spec.Interface.IsGeneric() {
+		return errors.New(fmt.Sprintf("interface %v is generic, cannot generate endpoints for generic interfaces", spec.Interface.Name))
+	}
+
 	err := spec.ContainsDuplicateEndpointName()
 	if err != nil {
 		return err
 	}
 
+	if err := spec.JsonNaming.IsValid(); err != nil {
+		return err
+	}
+	if err := spec.MiddlewareStages.IsValid(); err != nil {
+		return err
+	}
+	for path, alias := range spec.Imports {
+		if path == "" || !token.IsIdentifier(alias) || alias == "_" {
+			return errors.New(fmt.Sprintf("invalid import alias %v for package %v", alias, path))
+		}
+	}
+	if spec.GenerateTypescript && (filepath.IsAbs(spec.TypescriptOutput) || filepath.Ext(spec.TypescriptOutput) != ".ts") {
+		return errors.New(fmt.Sprintf("typescript output %v must be a relative path of a .ts file", spec.TypescriptOutput))
+	}
+	if spec.GenerateProto && (filepath.IsAbs(spec.ProtoOutput) || filepath.Ext(spec.ProtoOutput) != ".proto") {
+		return errors.New(fmt.Sprintf("proto output %v must be a relative path of a .proto file", spec.ProtoOutput))
+	}
+	if spec.GenerateProto && !protoPackageRegexp.MatchString(spec.ProtoPackage) {
+		return errors.New(fmt.Sprintf("invalid proto package %v", spec.ProtoPackage))
+	}
+	if spec.GenerateJsonSchema && filepath.IsAbs(spec.JsonSchemaOutput) {
+		return errors.New(fmt.Sprintf("json schema output %v must be a relative path", spec.JsonSchemaOutput))
+	}
+
 	// check that endpoint specifications are valid
 	for _, e := range spec.Endpoints {
 		err = e.IsValid()
 		if err != nil {
-			return err
+			return spec.Interface.WrapMethodError(e.Method, err)
+		}
+		err = e.jsonNamesValid(spec.JsonNaming)
+		if err != nil {
+			return spec.Interface.WrapMethodError(e.Method, err)
+		}
+	}
+
+	if len(spec.WebsocketEndpoints) > 0 && !spec.GenerateHttp {
+		return errors.New(fmt.Sprintf("interface %v has websocket endpoints, but no http handlers are generated", spec.Interface.Name))
+	}
+	for _, e := range spec.WebsocketEndpoints {
+		err = e.IsValid()
+		if err != nil {
+			return spec.Interface.WrapMethodError(e.Method, err)
+		}
+		err = e.websocketValid(spec.Router)
+		if err != nil {
+			return spec.Interface.WrapMethodError(e.Method, err)
+		}
+	}
+
+	for _, e := range spec.Endpoints {
+		for _, es := range e.EndpointSpecs {
+			if spec.GenerateNats && es.NatsSpec.Subject == "" && es.NatsSpec.Queue != "" {
+				return spec.Interface.WrapMethodError(e.Method, errors.New(fmt.Sprintf("invalid nats spec for endpoint %v: queue set without subject", es.Name)))
+			}
+			// tokens are only read from http requests
+			if es.Auth.enabled() && ((spec.GenerateNats && es.NatsSpec.Subject != "") || (spec.GenerateAmqp && es.AmqpSpec.Queue != "")) {
+				return spec.Interface.WrapMethodError(e.Method, errors.New(fmt.Sprintf("endpoint %v requires authentication, which is only supported for http", es.Name)))
+			}
+		}
+	}
+	for _, e := range spec.WebsocketEndpoints {
+		for _, es := range e.EndpointSpecs {
+			if es.Auth.enabled() {
+				return spec.Interface.WrapMethodError(e.Method, errors.New(fmt.Sprintf("websocket endpoint %v cannot require authentication", es.Name)))
+			}
 		}
 	}
 
 	// If GenerateHttp is true, check that http specs are valid.
 	if spec.GenerateHttp {
+		if err := spec.Router.IsValid(); err != nil {
+			return err
+		}
+		if err := spec.Cors.IsValid(); err != nil {
+			return err
+		}
+		if spec.PathPrefix != "" && (!strings.HasPrefix(spec.PathPrefix, "/") || strings.HasSuffix(spec.PathPrefix, "/")) {
+			return errors.New(fmt.Sprintf("path prefix %v must start with and not end with /", spec.PathPrefix))
+		}
+		if spec.Envelope != "" && spec.Envelope != EnvelopeData {
+			if _, _, err := parseFuncRef(spec.Envelope); err != nil {
+				return errors.New(fmt.Sprintf("invalid envelope: %v", err))
+			}
+		}
+
 		for _, e := range spec.Endpoints {
-			for _, es := range e.EndpointSpecs {
-				err = es.HttpSpec.IsValid()
-				if err != nil {
-					return fmt.Errorf("invalid http spec for endpoint %v: %w", es.Name, err)
-				}
+			if err := spec.httpSpecsValid(e); err != nil {
+				return spec.Interface.WrapMethodError(e.Method, err)
 			}
 		}
 	}
@@ -87,14 +269,41 @@ func (spec KitGenSpecification) IsValid() error {
 	return nil
 }
 
+// Checks the http params and http specs of the endpoints for a method.
+func (spec KitGenSpecification) httpSpecsValid(e EndpointSpecifications) error {
+	err := e.httpParamsValid()
+	if err != nil {
+		return err
+	}
+	for _, es := range e.EndpointSpecs {
+		err = es.HttpSpec.IsValid()
+		if err != nil {
+			return fmt.Errorf("invalid http spec for endpoint %v: %w", es.Name, err)
+		}
+		err = spec.Router.validatePath(es.HttpSpec.Path)
+		if err != nil {
+			return fmt.Errorf("invalid http spec for endpoint %v: %w", es.Name, err)
+		}
+		if es.HttpSpec.MultipartMaxMemory < 0 {
+			return errors.New(fmt.Sprintf("invalid http spec for endpoint %v: multipartMaxMemory cannot be negative", es.Name))
+		}
+		if es.HttpSpec.headersUseResult() && len(e.Method.Returns) < 2 {
+			return errors.New(fmt.Sprintf("invalid http spec for endpoint %v: headers refer to result, but interface method %v has no result value", es.Name, e.Method.Name))
+		}
+	}
+	return nil
+}
+
 func (spec KitGenSpecification) ContainsDuplicateEndpointName() error {
 	names := make(map[string]bool)
-	for _, es := range spec.Endpoints {
-		for _, e := range es.EndpointSpecs {
-			if _, ok := names[e.Name]; ok {
-				return errors.New(fmt.Sprintf("duplicate endpoint name %v", e.Name))
+	for _, endpoints := range [][]EndpointSpecifications{spec.Endpoints, spec.WebsocketEndpoints} {
+		for _, es := range endpoints {
+			for _, e := range es.EndpointSpecs {
+				if _, ok := names[e.Name]; ok {
+					return errors.New(fmt.Sprintf("duplicate endpoint name %v", e.Name))
+				}
+				names[e.Name] = true
 			}
-			names[e.Name] = true
 		}
 	}
 	return nil
@@ -113,7 +322,21 @@ type EndpointSpecifications struct {
 	// Since we expect the first parameter of an interface method to be a "context.Context", the length of this slice
 	// should equal len(Method.Params) - 1.
 	// TODO we could let every endpoint for this method define their own http params, which would result in multiple http decode funcs, but this is not necessary for now.
-	HttpParams []HttpParamType `json:"httpParams"`
+	HttpParams []HttpParam `json:"httpParams"`
+
+	// Json names of the endpoint request fields, maps parameter names to json names.
+	// Takes precedence over KitGenSpecification.JsonNaming.
+	JsonNames map[string]string `json:"jsonNames"`
+
+	// Transport used for the endpoints of this method, either empty (default) or "websocket", see TransportWebsocket.
+	Transport string `json:"transport"`
+
+	// Default auth spec for the endpoints of this method, read from an @Auth annotation on the method.
+	Auth AuthSpec `json:"-"`
+
+	// Validation rules for the method parameters, read from a @Validate annotation on the method.
+	// Maps parameter names to rules.
+	Validation map[string]ValidationRules `json:"-"`
 }
 
 func (e EndpointSpecifications) IsValid() error {
@@ -138,17 +361,93 @@ func (e EndpointSpecifications) IsValid() error {
 		return errors.New(fmt.Sprintf("interface method %v does not have error as last return value", m.Name))
 	}
 
+	// check that validation rules refer to parameters of the method and can be applied to them
+	for name, rules := range e.Validation {
+		var param *parse.Param
+		for i, p := range m.Params[1:] {
+			if p.Name == name {
+				param = &m.Params[i+1]
+			}
+		}
+		if param == nil {
+			return errors.New(fmt.Sprintf("validation rules for unknown parameter %v of interface method %v", name, m.Name))
+		}
+		if err := rules.IsValid(*param); err != nil {
+			return errors.New(fmt.Sprintf("invalid validation rules for interface method %v: %v", m.Name, err))
+		}
+	}
+
 	return nil
 }
 
+// Checks that the http param types can be applied to the parameters of the method.
+func (e EndpointSpecifications) httpParamsValid() error {
+	m := e.Method
+	hasJson, hasMultipart := false, false
+	for i, p := range e.HttpParams {
+		t := p.Type
+		if p.Name != "" && t != HttpTypeUrl && !(t == HttpTypeQuery && i+1 < len(m.Params) && isScalarQueryType(m.Params[i+1].Type)) {
+			return errors.New(fmt.Sprintf("http param %v of interface method %v has a name, which is only supported for url params and scalar query params", i, m.Name))
+		}
+		if i+1 < len(m.Params) && t != HttpTypeJson {
+			if _, ok := m.Params[i+1].Type.(parse.EllipsisType); ok {
+				return errors.New(fmt.Sprintf("variadic parameter %v of interface method %v must be a json http param", m.Params[i+1].Name, m.Name))
+			}
+		}
+		switch t {
+		case HttpTypeJson:
+			hasJson = true
+		case HttpTypeMultipart:
+			hasMultipart = true
+			if i+1 < len(m.Params) && !isMultipartFileType(m.Params[i+1].Type) {
+				return errors.New(fmt.Sprintf("multipart parameter %v of interface method %v must be of type *multipart.FileHeader or []byte", m.Params[i+1].Name, m.Name))
+			}
+		}
+	}
+	if hasJson && hasMultipart {
+		return errors.New(fmt.Sprintf("interface method %v cannot have both json and multipart http params", m.Name))
+	}
+	return nil
+}
+
+// Returns the largest multipart max memory value set by an endpoint that uses the generated http decode function, 0 if none is set.
+func (e EndpointSpecifications) multipartMaxMemory() int64 {
+	var result int64
+	for _, es := range e.EndpointSpecs {
+		if es.HttpSpec.DecodeFunc == "" && es.HttpSpec.MultipartMaxMemory > result {
+			result = es.HttpSpec.MultipartMaxMemory
+		}
+	}
+	return result
+}
+
+// Returns true if at least one endpoint uses the generated http decode function, i.e. does not have a custom one.
+func (e EndpointSpecifications) usesGeneratedHttpDecodeFunc() bool {
+	for _, es := range e.EndpointSpecs {
+		if es.HttpSpec.DecodeFunc == "" {
+			return true
+		}
+	}
+	return false
+}
+
 func (e EndpointSpecifications) endpointRequestTypeName() string {
 	return gen.UppercaseFirst(e.Method.Name) + "Request"
 }
 
+func (e EndpointSpecifications) endpointResponseTypeName() string {
+	return gen.UppercaseFirst(e.Method.Name) + "Response"
+}
+
 func (e EndpointSpecifications) endpointRequestTypeParamName(paramName string) string {
 	return gen.UppercaseFirst(paramName)
 }
 
+// Name of the type generated for an anonymous struct parameter, e.g. "CreateRequestOptions".
+func (e EndpointSpecifications) anonymousStructTypeName(paramName string) string {
+	return e.endpointRequestTypeName() + gen.UppercaseFirst(paramName)
+}
+
 func (e EndpointSpecifications) httpDecodeFuncName() string {
 	return "decodeHttp" + gen.UppercaseFirst(e.Method.Name) + "Request"
 }
@@ -160,6 +459,12 @@ type EndpointSpecification struct {
 
 	// specifies how the http handler for this endpoint is generated
 	HttpSpec HttpSpec `json:"http"`
+	// Specifies the NATS subject of this endpoint, only used if NATS code is generated.
+	NatsSpec NatsSpec `json:"nats"`
+	// Specifies the AMQP queue of this endpoint, only used if AMQP code is generated.
+	AmqpSpec AmqpSpec `json:"amqp"`
+	// Specifies whether requests must be authenticated, defaults to the @Auth annotation of the method.
+	Auth *AuthSpec `json:"auth"`
 }
 
 // the name used for the function that creates the endpoint.Endpoint
@@ -187,7 +492,19 @@ type HttpSpec struct {
 	Method string `json:"method"`
 	// http code for the response on success
 	SuccessCode int `json:"successCode"`
-	// how the values from the interface method are obtained from the http request
+	// Headers to set on the response on success, maps header names to values.
+	// Values can contain placeholders that refer to the result of the interface method, e.g. "/items/{result.Id}".
+	Headers map[string]string `json:"headers"`
+	// Optional reference to an existing function that is used instead of the generated decode function, e.g. "example.com/x/transport.DecodeUpload".
+	// The function must be of type go-kit/kit/transport/http.DecodeRequestFunc and return the request type of the endpoint.
+	// If no package path is given, the function is expected to be in the generated http package.
+	DecodeFunc string `json:"decodeFunc"`
+	// Optional reference to an existing function that is used instead of the generic json encode function.
+	// The function must be of type go-kit/kit/transport/http.EncodeResponseFunc, see DecodeFunc for the format.
+	EncodeFunc string `json:"encodeFunc"`
+	// Maximum number of bytes of a multipart request body stored in memory, defaults to transport/http.DefaultMultipartMaxMemory.
+	// Only used for methods with "multipart" http params. If multiple endpoints for the same method set a value, the largest one is used.
+	MultipartMaxMemory int64 `json:"multipartMaxMemory"`
 }
 
 func (spec HttpSpec) IsValid() error {
@@ -200,9 +517,73 @@ func (spec HttpSpec) IsValid() error {
 	if spec.SuccessCode == 0 {
 		return errors.New("success code not set for endpoint")
 	}
+	if spec.DecodeFunc != "" {
+		if _, _, err := parseFuncRef(spec.DecodeFunc); err != nil {
+			return err
+		}
+	}
+	if spec.EncodeFunc != "" {
+		if _, _, err := parseFuncRef(spec.EncodeFunc); err != nil {
+			return err
+		}
+		if len(spec.Headers) > 0 {
+			return errors.New("headers cannot be used together with a custom encode function")
+		}
+	}
+	for name, value := range spec.Headers {
+		if name == "" {
+			return errors.New("empty header name")
+		}
+		if _, err := parseHeaderTemplate(value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// For methods with this transport, websocket handlers are generated that stream values to the client.
+// The last parameter of the method must be a channel or a callback function the method uses to produce values,
+// see NewWebsocketStreamHandler in package "github.com/dkinzler/kit/transport/http".
+const TransportWebsocket = "websocket"
+
+// Envelope that wraps the response values of http handlers in an object {"data": ...},
+// see DataEnvelope in package "github.com/dkinzler/kit/transport/http".
+const EnvelopeData = "data"
+
+// How a single parameter of an interface method is obtained from a http request.
+// In annotations it can be given either as a string, e.g. "url", or as an object, e.g. {"type": "url", "name": "user_id"}.
+type HttpParam struct {
+	Type HttpParamType `json:"type"`
+	// Name of the url variable or query parameter, defaults to the name of the parameter.
+	// Only supported for url params and query params decoded from a single query parameter (see isScalarQueryType),
+	// e.g. to use idiomatic url naming while parameters keep Go naming.
+	Name string `json:"name"`
+}
+
+func (p *HttpParam) UnmarshalJSON(data []byte) error {
+	var t HttpParamType
+	if err := json.Unmarshal(data, &t); err == nil {
+		*p = HttpParam{Type: t}
+		return nil
+	}
+	// alias type without the UnmarshalJSON method
+	type httpParam HttpParam
+	var v httpParam
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*p = HttpParam(v)
+	return nil
+}
+
+// Name of the url variable or query parameter the parameter is decoded from.
+func (p HttpParam) varName(paramName string) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return paramName
+}
+
 // HttpParamType represents how the parameters of an interface method should be obtained from a http request.
 // E.g. by parsing the request body as json or extracting the parameter from the url path or query parameters.
 type HttpParamType string
@@ -211,27 +592,59 @@ const HttpTypeJson HttpParamType = "json"
 const HttpTypeUrl HttpParamType = "url"
 const HttpTypeQuery HttpParamType = "query"
 
+// Parameter is a file of a multipart/form-data request, the form field name equals the parameter name.
+// Supported for parameters of type *multipart.FileHeader and []byte.
+const HttpTypeMultipart HttpParamType = "multipart"
+
 func SpecFromAnnotations(i parse.Interface, m parse.Module, a annotations.InterfaceAnnotation) (KitGenSpecification, error) {
 	var spec KitGenSpecification
 
-	err := annotations.ParseJSONAnnotation(a.Annotation, &spec)
+	err := a.ParseJSON(a.Annotation, &spec)
 	if err != nil {
 		return spec, errors.New(fmt.Sprintf("could not parse interface annotation for interface %v, error: %v", i.Name, err))
 	}
 
 	spec.Interface = i
+	m, err = m.OutputModule(spec.OutputModule, spec.OutputModulePath)
+	if err != nil {
+		return spec, err
+	}
 	spec.Module = m
+	if spec.EndpointHelperPackage == "" {
+		spec.EndpointHelperPackage = a.Option("endpointHelperPackage", defaultEndpointHelperPackage)
+	}
+	if spec.HttpHelperPackage == "" {
+		spec.HttpHelperPackage = a.Option("httpHelperPackage", defaultHttpHelperPackage)
+	}
 
 	var endpointsForMethods []EndpointSpecifications
 
 	for j, m := range i.Methods {
 		if a.MethodAnnotations[j] != "" {
 			var es EndpointSpecifications
-			err := annotations.ParseJSONAnnotation(a.MethodAnnotations[j], &es)
+			err := a.ParseJSON(a.MethodAnnotations[j], &es)
 			if err != nil {
-				return spec, errors.New(fmt.Sprintf("could not parse method annotation for method %v in interface %v, error: %v", m.Name, i.Name, err))
+				return spec, i.WrapMethodError(m, errors.New(fmt.Sprintf("could not parse method annotation for method %v, error: %v", m.Name, err)))
 			}
 			es.Method = m
+
+			ma, err := annotations.ParseMethodAnnotations(m)
+			if err != nil {
+				return spec, i.WrapMethodError(m, errors.New(fmt.Sprintf("could not parse annotations for method %v, error: %v", m.Name, err)))
+			}
+			if v, ok := ma["Validate"]; ok {
+				err := a.ParseJSON(v, &es.Validation)
+				if err != nil {
+					return spec, i.WrapMethodError(m, errors.New(fmt.Sprintf("could not parse validate annotation for method %v, error: %v", m.Name, err)))
+				}
+			}
+			if v, ok := ma["Auth"]; ok {
+				err := a.ParseJSON(v, &es.Auth)
+				if err != nil {
+					return spec, i.WrapMethodError(m, errors.New(fmt.Sprintf("could not parse auth annotation for method %v, error: %v", m.Name, err)))
+				}
+			}
+
 			for k, endpoint := range es.EndpointSpecs {
 				//set default endpoint name if empty
 				if endpoint.Name == "" {
@@ -241,9 +654,26 @@ func SpecFromAnnotations(i parse.Interface, m parse.Module, a annotations.Interf
 				if endpoint.HttpSpec.SuccessCode == 0 {
 					endpoint.HttpSpec.SuccessCode = 200
 				}
+				//endpoints without an auth spec use the one of the method
+				if endpoint.Auth == nil {
+					auth := es.Auth
+					endpoint.Auth = &auth
+				}
+				//websocket connections are always established with a GET request
+				if es.Transport == TransportWebsocket && endpoint.HttpSpec.Method == "" {
+					endpoint.HttpSpec.Method = "GET"
+				}
 				es.EndpointSpecs[k] = endpoint
 			}
-			endpointsForMethods = append(endpointsForMethods, es)
+
+			switch es.Transport {
+			case "":
+				endpointsForMethods = append(endpointsForMethods, es)
+			case TransportWebsocket:
+				spec.WebsocketEndpoints = append(spec.WebsocketEndpoints, es)
+			default:
+				return spec, i.WrapMethodError(m, errors.New(fmt.Sprintf("unknown transport %v for method %v", es.Transport, m.Name)))
+			}
 		}
 	}
 
@@ -267,9 +697,49 @@ func SpecFromAnnotations(i parse.Interface, m parse.Module, a annotations.Interf
 		spec.HttpOutput = "http.gen.go"
 	}
 
+	if spec.NatsPackage != "" {
+		spec.NatsPackageFullPath = m.FullPackagePath(spec.NatsPackage)
+		if spec.GenerateEndpoints {
+			spec.GenerateNats = true
+		}
+	}
+	if spec.NatsOutput == "" {
+		spec.NatsOutput = "nats.gen.go"
+	}
+
+	if spec.AmqpPackage != "" {
+		spec.AmqpPackageFullPath = m.FullPackagePath(spec.AmqpPackage)
+		if spec.GenerateEndpoints {
+			spec.GenerateAmqp = true
+		}
+	}
+	if spec.AmqpOutput == "" {
+		spec.AmqpOutput = "amqp.gen.go"
+	}
+	if spec.TypescriptOutput != "" && spec.GenerateEndpoints {
+		spec.GenerateTypescript = true
+	}
+	if spec.ProtoOutput != "" && spec.GenerateEndpoints {
+		spec.GenerateProto = true
+	}
+	if spec.ProtoPackage == "" {
+		spec.ProtoPackage = defaultProtoPackage(i)
+	}
+	if spec.JsonSchemaOutput != "" && spec.GenerateHttp {
+		spec.GenerateJsonSchema = true
+	}
+	if spec.MockPackage != "" {
+		spec.MockPackageFullPath = m.FullPackagePath(spec.MockPackage)
+	} else {
+		spec.MockPackageFullPath = i.Package
+	}
+	if spec.Router == "" {
+		spec.Router = RouterGorilla
+	}
+
 	err = spec.IsValid()
 	if err != nil {
-		return spec, err
+		return spec, i.WrapError(err)
 	}
 
 	return spec, nil
@@ -0,0 +1,80 @@
+package kit
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/gen"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// Names of middleware stages, e.g. ["auth", "logging", "metrics", "custom"].
+// The stages are applied in order, the middlewares of the first stage are innermost.
+type MiddlewareStages []string
+
+func (s MiddlewareStages) IsValid() error {
+	names := make(map[string]bool)
+	for _, stage := range s {
+		name := s.fieldName(stage)
+		if !token.IsIdentifier(name) {
+			return errors.New(fmt.Sprintf("invalid middleware stage name %v, must be a valid Go identifier", stage))
+		}
+		if names[name] {
+			return errors.New(fmt.Sprintf("duplicate middleware stage %v", stage))
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// Name of the field of a stage in the generated MiddlewareStages struct.
+func (s MiddlewareStages) fieldName(stage string) string {
+	return gen.UppercaseFirst(stage)
+}
+
+// Generates constants with the names of the stages, a MiddlewareStages struct with a Middlewares field for every stage
+// and a method that combines the middlewares of all stages in order.
+func (g *KitGenerator) generateMiddlewareStages() jen.Code {
+	stages := g.Spec.MiddlewareStages
+	order := make([]string, len(stages))
+	for i, stage := range stages {
+		order[i] = fmt.Sprintf("%q", stage)
+	}
+
+	code := jen.Comment("Names of the middleware stages, in the order they are applied.").Line()
+	code.Const().DefsFunc(func(d *jen.Group) {
+		for _, stage := range stages {
+			d.Id("MiddlewareStage" + stages.fieldName(stage)).Op("=").Lit(stage)
+		}
+	}).Line().Line()
+
+	var fields []jen.Code
+	for _, stage := range stages {
+		fields = append(fields, jen.Id(stages.fieldName(stage)).Id("Middlewares"))
+	}
+	code.Comment("MiddlewareStages groups the middlewares of the endpoints into named stages.").Line()
+	code.Comment(fmt.Sprintf("Stages are applied in the order %v, the middlewares of the first stage are innermost.", strings.Join(order, ", "))).Line()
+	code.Type().Id("MiddlewareStages").Struct(fields...).Line().Line()
+
+	var stmts []jen.Code
+	stmts = append(stmts, jen.Var().Id("mws").Id("Middlewares"))
+	for _, stage := range stages {
+		for _, es := range g.Spec.Endpoints {
+			for _, ess := range es.EndpointSpecs {
+				field := ess.endpointSetFieldName()
+				stmts = append(stmts, jen.Id("mws").Dot(field).Op("=").Append(
+					jen.Id("mws").Dot(field),
+					jen.Id("s").Dot(stages.fieldName(stage)).Dot(field).Op("..."),
+				))
+			}
+		}
+	}
+	stmts = append(stmts, jen.Return(jen.Id("mws")))
+
+	code.Comment("Middlewares combines the middlewares of all stages in order, the result can be passed to NewEndpoints.").Line()
+	code.Func().Params(jen.Id("s").Id("MiddlewareStages")).Id("Middlewares").Params().Id("Middlewares").Block(stmts...)
+	return code
+}
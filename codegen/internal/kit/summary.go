@@ -0,0 +1,48 @@
+package kit
+
+import "strings"
+
+// Summary of a generated endpoint, e.g. to list what would be generated for an interface.
+type EndpointSummary struct {
+	// Name of the endpoint
+	Name string
+	// Name of the interface method the endpoint calls
+	Method string
+	// http method and path (including the path prefix) of the handler, empty if no http handler is generated
+	HttpMethod string
+	HttpPath   string
+	// True if the http handler streams values over a websocket connection
+	Websocket bool
+	// Subject and queue the endpoint is exposed on, empty if no NATS or AMQP transport is generated
+	NatsSubject string
+	AmqpQueue   string
+}
+
+// Returns summaries of all endpoints, websocket endpoints are listed last.
+func (spec KitGenSpecification) EndpointSummaries() []EndpointSummary {
+	var result []EndpointSummary
+	for _, endpoints := range [][]EndpointSpecifications{spec.Endpoints, spec.WebsocketEndpoints} {
+		websocket := len(endpoints) > 0 && endpoints[0].Transport == TransportWebsocket
+		for _, es := range endpoints {
+			for _, e := range es.EndpointSpecs {
+				s := EndpointSummary{
+					Name:      e.Name,
+					Method:    es.Method.Name,
+					Websocket: websocket,
+				}
+				if spec.GenerateHttp {
+					s.HttpMethod = strings.ToUpper(e.HttpSpec.Method)
+					s.HttpPath = spec.PathPrefix + e.HttpSpec.Path
+				}
+				if spec.GenerateNats {
+					s.NatsSubject = e.NatsSpec.Subject
+				}
+				if spec.GenerateAmqp {
+					s.AmqpQueue = e.AmqpSpec.Queue
+				}
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,199 @@
+package kit
+
+import (
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const testifyAssertPackage = "github.com/stretchr/testify/assert"
+
+// Name of the mock type generated for the interface by the @Mock annotation.
+func (g KitGenSpecification) mockTypeName() string {
+	return "Mock" + gen.UppercaseFirst(g.Interface.Name)
+}
+
+// Generates skeleton table-driven tests for the endpoints, that call the endpoint with a mock of the interface.
+// The tests are in the endpoint package, so that they can be extended with cases for unexported helpers.
+func (g *KitGenerator) generateEndpointTests() gen.GenResult {
+	g.g = gen.NewSimpleGenerator()
+
+	var code *jen.Group = jen.NewFile("").Group
+	for _, es := range g.Spec.Endpoints {
+		for _, spec := range es.EndpointSpecs {
+			code.Add(g.generateEndpointTest(es, spec))
+			code.Line()
+		}
+	}
+
+	return gen.GenResult{
+		Code:        code,
+		PackagePath: g.Spec.EndpointPackageFullPath,
+		PackageName: g.Spec.endpointPackageName(),
+		Imports: map[string]string{
+			g.Spec.EndpointHelperPackage: "e",
+		},
+		OutputFile: g.Spec.Module.FileName(g.Spec.EndpointPackage, "endpoint_gen_test.go"),
+	}
+}
+
+func (g *KitGenerator) generateEndpointTest(es EndpointSpecifications, spec EndpointSpecification) jen.Code {
+	m := es.Method
+	hasRequest := len(m.Params) > 1
+	hasResult := len(m.Returns) == 2
+
+	fields := []jen.Code{jen.Id("name").String()}
+	if hasRequest {
+		fields = append(fields, jen.Id("request").Id(es.endpointRequestTypeName()))
+	}
+	if hasResult {
+		fields = append(fields, jen.Id("result").Add(g.g.GenValueType(m.Returns[0].Type)))
+	}
+	fields = append(fields, jen.Id("err").Error())
+
+	// arguments the mock expects, the context is not passed to the mock
+	onArgs := []jen.Code{jen.Lit(m.Name)}
+	callArgs := []jen.Code{jen.Qual("context", "Background").Call()}
+	if hasRequest {
+		for _, p := range m.Params[1:] {
+			arg := jen.Id("tt").Dot("request").Dot(es.endpointRequestTypeParamName(p.Name))
+			// the endpoint request uses a named type for anonymous struct parameters, the mock receives the anonymous struct
+			if _, ok := p.Type.(parse.StructType); ok {
+				arg = jen.Parens(g.g.GenValueType(p.Type)).Call(arg)
+			}
+			onArgs = append(onArgs, arg)
+		}
+		callArgs = append(callArgs, jen.Id("tt").Dot("request"))
+	} else {
+		callArgs = append(callArgs, jen.Nil())
+	}
+	var returnArgs []jen.Code
+	if hasResult {
+		returnArgs = append(returnArgs, jen.Id("tt").Dot("result"))
+	}
+	returnArgs = append(returnArgs, jen.Id("tt").Dot("err"))
+
+	var responseAsserts []jen.Code
+	if hasResult {
+		responseAsserts = append(responseAsserts, jen.Id("a").Dot("Equal").Call(jen.Id("tt").Dot("result"), jen.Id("r").Dot("R")))
+	}
+	responseAsserts = append(responseAsserts, jen.Id("a").Dot("Equal").Call(jen.Id("tt").Dot("err"), jen.Id("r").Dot("Err")))
+
+	return jen.Func().Id("Test"+spec.makeEndpointFuncName()).Params(jen.Id("t").Op("*").Qual("testing", "T")).Block(
+		jen.Id("tests").Op(":=").Index().Struct(fields...).Values(testCases(
+			jen.Values(jen.Dict{jen.Id("name"): jen.Lit("success")}),
+			jen.Values(jen.Dict{
+				jen.Id("name"): jen.Lit("service returns error"),
+				jen.Id("err"):  jen.Qual("errors", "New").Call(jen.Lit("test error")),
+			}),
+		)...),
+		jen.Line(),
+		jen.For(jen.List(jen.Id("_"), jen.Id("tt")).Op(":=").Range().Id("tests")).Block(
+			jen.Id("t").Dot("Run").Call(jen.Id("tt").Dot("name"), jen.Func().Params(jen.Id("t").Op("*").Qual("testing", "T")).BlockFunc(func(b *jen.Group) {
+				b.Id("a").Op(":=").Qual(testifyAssertPackage, "New").Call(jen.Id("t"))
+				b.Line()
+				b.Id("s").Op(":=").Qual(g.Spec.MockPackageFullPath, "New"+g.Spec.mockTypeName()).Call(jen.Id("t"))
+				b.Id("s").Dot("On").Call(onArgs...).Dot("Return").Call(returnArgs...)
+				b.Line()
+				b.List(jen.Id("response"), jen.Id("err")).Op(":=").Add(g.makeEndpointCall(spec, jen.Id("s"))).Call(callArgs...)
+				b.Id("a").Dot("Nil").Call(jen.Id("err"))
+				b.List(jen.Id("r"), jen.Id("ok")).Op(":=").Id("response").Assert(jen.Qual(g.Spec.EndpointHelperPackage, "Response"))
+				b.If(jen.Id("a").Dot("True").Call(jen.Id("ok"))).Block(responseAsserts...)
+			})),
+		),
+	)
+}
+
+// Generates skeleton table-driven tests for the generated http decode functions.
+// The tests are in the http package, since the decode functions are not exported.
+// Code of the result is nil if there are no generated decode functions.
+func (g *KitGenerator) generateHttpTests() gen.GenResult {
+	g.g = gen.NewSimpleGenerator()
+
+	var code *jen.Group = jen.NewFile("").Group
+	empty := true
+	for _, es := range g.Spec.Endpoints {
+		if len(es.Method.Params) > 1 && es.usesGeneratedHttpDecodeFunc() {
+			code.Add(g.generateHttpDecodeFuncTest(es))
+			code.Line()
+			empty = false
+		}
+	}
+	if empty {
+		return gen.GenResult{}
+	}
+
+	return gen.GenResult{
+		Code:        code,
+		PackagePath: g.Spec.HttpPackageFullPath,
+		PackageName: g.Spec.httpPackageName(),
+		Imports: map[string]string{
+			gorillaMuxPackage: "mux",
+			chiPackage:        "chi",
+		},
+		OutputFile: g.Spec.Module.FileName(g.Spec.HttpPackage, "http_gen_test.go"),
+	}
+}
+
+func (g *KitGenerator) generateHttpDecodeFuncTest(es EndpointSpecifications) jen.Code {
+	hasUrlParams := es.hasHttpParam(HttpTypeUrl)
+
+	fields := []jen.Code{
+		jen.Id("name").String(),
+		jen.Id("request").Op("*").Qual("net/http", "Request"),
+	}
+	if hasUrlParams {
+		fields = append(fields, jen.Id("urlParams").Map(jen.String()).String())
+	}
+	fields = append(fields,
+		jen.Id("want").Qual(g.Spec.EndpointPackageFullPath, es.endpointRequestTypeName()),
+		jen.Id("wantErr").Bool(),
+	)
+
+	var cases []jen.Code
+	if es.hasHttpParam(HttpTypeJson) {
+		cases = append(cases, jen.Values(jen.Dict{
+			jen.Id("name"):    jen.Lit("invalid json body"),
+			jen.Id("request"): jen.Qual("net/http/httptest", "NewRequest").Call(jen.Lit("POST"), jen.Lit("/"), jen.Qual("strings", "NewReader").Call(jen.Lit("{"))),
+			jen.Id("wantErr"): jen.True(),
+		}))
+	}
+
+	return jen.Func().Id("Test"+gen.UppercaseFirst(es.httpDecodeFuncName())).Params(jen.Id("t").Op("*").Qual("testing", "T")).Block(
+		jen.Id("tests").Op(":=").Index().Struct(fields...).Values(testCases(cases...)...),
+		jen.Line(),
+		jen.For(jen.List(jen.Id("_"), jen.Id("tt")).Op(":=").Range().Id("tests")).Block(
+			jen.Id("t").Dot("Run").Call(jen.Id("tt").Dot("name"), jen.Func().Params(jen.Id("t").Op("*").Qual("testing", "T")).BlockFunc(func(b *jen.Group) {
+				b.Id("a").Op(":=").Qual(testifyAssertPackage, "New").Call(jen.Id("t"))
+				b.Line()
+				b.Id("r").Op(":=").Id("tt").Dot("request")
+				if hasUrlParams {
+					for _, s := range g.Spec.Router.setUrlParams(jen.Id("tt").Dot("urlParams")) {
+						b.Add(s)
+					}
+				}
+				b.List(jen.Id("request"), jen.Id("err")).Op(":=").Id(es.httpDecodeFuncName()).Call(jen.Qual("context", "Background").Call(), jen.Id("r"))
+				b.If(jen.Id("tt").Dot("wantErr")).Block(
+					jen.Id("a").Dot("NotNil").Call(jen.Id("err")),
+					jen.Return(),
+				)
+				b.Id("a").Dot("Nil").Call(jen.Id("err"))
+				b.Id("a").Dot("Equal").Call(jen.Id("tt").Dot("want"), jen.Id("request"))
+			})),
+		),
+	)
+}
+
+// Returns the elements of a slice literal of test cases, one per line and preceded by a TODO comment.
+func testCases(cases ...jen.Code) []jen.Code {
+	result := []jen.Code{jen.Line().Comment("TODO add test cases").Line()}
+	if len(cases) > 0 {
+		result[0] = result[0].(*jen.Statement).Add(cases[0])
+		for _, c := range cases[1:] {
+			result = append(result, jen.Line().Add(c))
+		}
+		result[len(result)-1] = result[len(result)-1].(*jen.Statement).Op(",").Line()
+	}
+	return result
+}
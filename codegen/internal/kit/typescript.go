@@ -0,0 +1,331 @@
+package kit
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+)
+
+// Generates TypeScript types for the endpoint requests and responses.
+// If http handlers are generated, a client class with a method for every endpoint is generated as well, it uses fetch to make requests.
+//
+// Types defined in Go packages, e.g. a parameter of type "example.com/abc.Item", are unknown to the generator
+// and are declared as aliases of "unknown".
+func (g *KitGenerator) generateTypescript() gen.GenResult {
+	types := newTsTypes()
+	// aliases for Go types must not clash with the other generated declarations
+	types.used["ApiError"] = true
+	types.used[g.Spec.Interface.Name+"Client"] = true
+	for _, es := range g.Spec.Endpoints {
+		types.used[es.endpointRequestTypeName()] = true
+		types.used[es.endpointResponseTypeName()] = true
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %v\n// source: %v.%v\n\n", gen.GeneratedFileComment, g.Spec.Interface.Package, g.Spec.Interface.Name)
+
+	for _, es := range g.Spec.Endpoints {
+		method := es.Method
+		if len(method.Params) > 1 {
+			fmt.Fprintf(&b, "export interface %v {\n", es.endpointRequestTypeName())
+			for _, p := range method.Params[1:] {
+				fmt.Fprintf(&b, "  %v: %v;\n", tsPropertyName(g.requestFieldName(es, p.Name)), types.tsType(p.Type))
+			}
+			b.WriteString("}\n\n")
+		}
+		if len(method.Returns) == 2 {
+			fmt.Fprintf(&b, "export type %v = %v;\n\n", es.endpointResponseTypeName(), types.tsType(method.Returns[0].Type))
+		}
+	}
+
+	if g.Spec.GenerateHttp {
+		g.generateTypescriptClient(&b)
+	}
+
+	for _, alias := range types.aliases {
+		fmt.Fprintf(&b, "/** Go type %v, its definition is not known to the code generator. */\nexport type %v = unknown;\n\n", alias.goType, alias.name)
+	}
+
+	return gen.GenResult{
+		Content:    []byte(strings.TrimSuffix(b.String(), "\n")),
+		OutputFile: g.Spec.Module.FileName("", g.Spec.TypescriptOutput),
+	}
+}
+
+const tsApiError = `export class ApiError extends Error {
+  constructor(
+    public readonly status: number,
+    public readonly code?: number,
+    message?: string,
+  ) {
+    super(message ?? ` + "`request failed with status ${status}`" + `);
+  }
+}
+
+`
+
+const tsClientRequestFunc = `  private async request(method: string, path: string, query: unknown[], body?: unknown): Promise<any> {
+    const params = new URLSearchParams();
+    for (const q of query) {
+      for (const [key, value] of Object.entries((q ?? {}) as object)) {
+        for (const v of Array.isArray(value) ? value : [value]) {
+          if (v !== undefined && v !== null) {
+            params.append(key, String(v));
+          }
+        }
+      }
+    }
+    const search = params.toString();
+    const init: RequestInit = { method };
+    if (body !== undefined) {
+      init.headers = { "Content-Type": "application/json" };
+      init.body = JSON.stringify(body);
+    }
+    const res = await this.fetchFn(this.baseUrl + path + (search === "" ? "" : "?" + search), init);
+    const text = await res.text();
+    const data = text === "" ? undefined : JSON.parse(text);
+    if (!res.ok) {
+      throw new ApiError(res.status, data?.error?.code, data?.error?.message);
+    }
+    return data;
+  }
+`
+
+// Generates a client class with a method for every endpoint that has a http handler.
+// Endpoints with a custom decode or encode function or multipart parameters are skipped, since their wire format is not known.
+// For the same reason the client has no methods if responses are wrapped with a custom envelope.
+func (g *KitGenerator) generateTypescriptClient(b *strings.Builder) {
+	b.WriteString(tsApiError)
+	fmt.Fprintf(b, "export class %vClient {\n", g.Spec.Interface.Name)
+	b.WriteString("  constructor(\n    private readonly baseUrl: string,\n    private readonly fetchFn: typeof fetch = (input, init) => fetch(input, init),\n  ) {}\n\n")
+	b.WriteString(tsClientRequestFunc)
+
+	for _, es := range g.Spec.Endpoints {
+		for _, spec := range es.EndpointSpecs {
+			if spec.HttpSpec.DecodeFunc != "" || spec.HttpSpec.EncodeFunc != "" || es.hasHttpParam(HttpTypeMultipart) {
+				continue
+			}
+			if g.Spec.Envelope != "" && g.Spec.Envelope != EnvelopeData {
+				continue
+			}
+			g.generateTypescriptClientMethod(b, es, spec)
+		}
+	}
+	b.WriteString("}\n\n")
+}
+
+func (g *KitGenerator) generateTypescriptClientMethod(b *strings.Builder, es EndpointSpecifications, spec EndpointSpecification) {
+	method := es.Method
+	httpMethod := strings.ToUpper(spec.HttpSpec.Method)
+	fullPath := g.Spec.PathPrefix + spec.HttpSpec.Path
+
+	var query []string
+	body := ""
+	urlParams := make(map[string]string)
+	for i, p := range method.Params[1:] {
+		value := "req" + tsPropertyAccess(g.requestFieldName(es, p.Name))
+		switch es.HttpParams[i].Type {
+		case HttpTypeUrl:
+			urlParams[es.HttpParams[i].varName(p.Name)] = value
+		case HttpTypeQuery:
+			if isScalarQueryType(p.Type) {
+				value = "{ " + tsPropertyName(es.HttpParams[i].varName(p.Name)) + ": " + value + " }"
+			}
+			query = append(query, value)
+		case HttpTypeJson:
+			// every json parameter is decoded from the whole request body
+			if body == "" {
+				body = value
+			}
+		}
+	}
+
+	reqParam := ""
+	if len(method.Params) > 1 {
+		reqParam = "req: " + es.endpointRequestTypeName()
+	}
+	resultType := "void"
+	if len(method.Returns) == 2 {
+		resultType = es.endpointResponseTypeName()
+	}
+	args := []string{strconv.Quote(httpMethod), tsPathTemplate(fullPath, urlParams), "[" + strings.Join(query, ", ") + "]"}
+	if body != "" {
+		args = append(args, body)
+	}
+
+	fmt.Fprintf(b, "\n  /** %v %v */\n", httpMethod, fullPath)
+	fmt.Fprintf(b, "  async %v(%v): Promise<%v> {\n", gen.LowercaseFirst(spec.Name), reqParam, resultType)
+	if resultType == "void" {
+		fmt.Fprintf(b, "    await this.request(%v);\n", strings.Join(args, ", "))
+	} else if g.Spec.Envelope == EnvelopeData {
+		fmt.Fprintf(b, "    return (await this.request(%v)).data;\n", strings.Join(args, ", "))
+	} else {
+		fmt.Fprintf(b, "    return this.request(%v);\n", strings.Join(args, ", "))
+	}
+	b.WriteString("  }\n")
+}
+
+// Name of the field of the endpoint request type for the parameter when encoded as json.
+func (g *KitGenerator) requestFieldName(es EndpointSpecifications, paramName string) string {
+	if name := es.requestFieldJsonName(g.Spec.JsonNaming, paramName); name != "" {
+		return name
+	}
+	return es.endpointRequestTypeParamName(paramName)
+}
+
+func (e EndpointSpecifications) hasHttpParam(t HttpParamType) bool {
+	for _, p := range e.HttpParams {
+		if p.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches path variables of all supported routers, e.g. "{id}", "{id:[0-9]+}" or "{path...}".
+var pathVariableRegexp = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(?:[:.][^}]*)?\}`)
+
+// Returns a TypeScript template literal for the path, where variables are replaced with the url encoded values of the given expressions.
+func tsPathTemplate(p string, values map[string]string) string {
+	p = strings.ReplaceAll(p, "{$}", "")
+	p = strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${").Replace(p)
+	p = pathVariableRegexp.ReplaceAllStringFunc(p, func(v string) string {
+		name := pathVariableRegexp.FindStringSubmatch(v)[1]
+		if value, ok := values[name]; ok {
+			return "${encodeURIComponent(String(" + value + "))}"
+		}
+		return v
+	})
+	return "`" + p + "`"
+}
+
+var tsIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func tsPropertyName(name string) string {
+	if tsIdentifierRegexp.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func tsPropertyAccess(name string) string {
+	if tsIdentifierRegexp.MatchString(name) {
+		return "." + name
+	}
+	return "[" + strconv.Quote(name) + "]"
+}
+
+// Maps Go types to TypeScript types based on their json encoding.
+type tsTypes struct {
+	// maps qualified Go type names to TypeScript type names
+	names   map[string]string
+	used    map[string]bool
+	aliases []tsAlias
+}
+
+type tsAlias struct {
+	goType string
+	name   string
+}
+
+func newTsTypes() *tsTypes {
+	return &tsTypes{
+		names: make(map[string]string),
+		used:  make(map[string]bool),
+	}
+}
+
+func (t *tsTypes) tsType(p parse.ParamType) string {
+	switch pt := p.(type) {
+	case parse.SimpleType:
+		return t.simpleType(pt)
+	case parse.ArrayType:
+		return t.arrayType(pt.Type)
+	case parse.EllipsisType:
+		return t.arrayType(pt.Type)
+	case parse.MapType:
+		return "Record<string, " + t.tsType(pt.ValueType) + ">"
+	case parse.StarType:
+		return t.tsType(pt.Type) + " | null"
+	case parse.StructType:
+		var fields []string
+		for _, f := range pt.Fields {
+			name, opts, _ := strings.Cut(f.Tags["json"], ",")
+			// embedded and unexported fields are skipped
+			if (name == "-" && opts == "") || f.Name == "" || !token.IsExported(f.Name) {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			optional := ""
+			if strings.Contains(","+opts+",", ",omitempty,") {
+				optional = "?"
+			}
+			fields = append(fields, tsPropertyName(name)+optional+": "+t.tsType(f.Type))
+		}
+		if len(fields) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(fields, "; ") + " }"
+	default:
+		return "unknown"
+	}
+}
+
+func (t *tsTypes) arrayType(elem parse.ParamType) string {
+	// byte slices are encoded as base64 strings
+	if parse.IsSimpleType(elem, "byte", "") || parse.IsSimpleType(elem, "uint8", "") {
+		return "string"
+	}
+	e := t.tsType(elem)
+	if strings.Contains(e, " ") && !strings.HasPrefix(e, "{") {
+		e = "(" + e + ")"
+	}
+	return e + "[]"
+}
+
+func (t *tsTypes) simpleType(st parse.SimpleType) string {
+	if st.Package == "" {
+		switch st.Type {
+		case "string":
+			return "string"
+		case "bool":
+			return "boolean"
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune", "float32", "float64":
+			return "number"
+		}
+		return "unknown"
+	}
+	switch st.Package + "." + st.Type {
+	case "time.Time":
+		return "string"
+	case "time.Duration":
+		return "number"
+	case "encoding/json.RawMessage":
+		return "unknown"
+	}
+
+	goType := st.Package + "." + st.Type
+	if name, ok := t.names[goType]; ok {
+		return name
+	}
+	// types with the same name from different packages are prefixed with the package name
+	name := st.Type
+	if t.used[name] {
+		name = gen.UppercaseFirst(path.Base(st.Package)) + st.Type
+	}
+	for i := 2; t.used[name]; i++ {
+		name = fmt.Sprintf("%v%v%v", gen.UppercaseFirst(path.Base(st.Package)), st.Type, i)
+	}
+	t.names[goType] = name
+	t.used[name] = true
+	t.aliases = append(t.aliases, tsAlias{goType: goType, name: name})
+	return name
+}
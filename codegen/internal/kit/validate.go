@@ -0,0 +1,201 @@
+package kit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const localErrorsPackage = "github.com/dkinzler/kit/errors"
+
+// ValidationRules define how a single parameter of an interface method is validated.
+// Rules are read from a @Validate annotation on the interface method, that maps parameter names to rules.
+//
+// Example:
+//
+//	@Validate{
+//	  "name": {"required": true, "maxLength": 64, "pattern": "^[a-z]+$"},
+//	  "count": {"min": 1, "max": 100}
+//	}
+//
+// For every endpoint request type with validation rules a Validate() method is generated, which is called by the generated http decode function.
+// If validation fails an error with code InvalidArgument and a public error message is returned.
+type ValidationRules struct {
+	// Parameter must not be empty, supported for strings, pointers, slices and maps.
+	Required bool `json:"required"`
+	// Bounds for numeric parameters.
+	Min *float64 `json:"min"`
+	Max *float64 `json:"max"`
+	// Bounds for the length of strings, slices and maps.
+	MinLength *int `json:"minLength"`
+	MaxLength *int `json:"maxLength"`
+	// Regular expression a string parameter must match.
+	Pattern string `json:"pattern"`
+}
+
+// Checks that the rules can be applied to the given parameter.
+func (v ValidationRules) IsValid(p parse.Param) error {
+	t := p.Type
+	if v.Required && !(isStringType(t) || isNilableType(t)) {
+		return errors.New(fmt.Sprintf("required rule not supported for parameter %v", p.Name))
+	}
+	if v.Min != nil || v.Max != nil {
+		if !isNumericType(t) {
+			return errors.New(fmt.Sprintf("min/max rules not supported for non-numeric parameter %v", p.Name))
+		}
+		for _, x := range []*float64{v.Min, v.Max} {
+			if x == nil {
+				continue
+			}
+			if isIntegerType(t) && *x != math.Trunc(*x) {
+				return errors.New(fmt.Sprintf("min/max rules for integer parameter %v must be integers", p.Name))
+			}
+			if isUnsignedType(t) && *x < 0 {
+				return errors.New(fmt.Sprintf("min/max rules for unsigned parameter %v cannot be negative", p.Name))
+			}
+		}
+	}
+	if (v.MinLength != nil || v.MaxLength != nil) && !hasLength(t) {
+		return errors.New(fmt.Sprintf("minLength/maxLength rules not supported for parameter %v", p.Name))
+	}
+	if v.Pattern != "" {
+		if !isStringType(t) {
+			return errors.New(fmt.Sprintf("pattern rule not supported for non-string parameter %v", p.Name))
+		}
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return errors.New(fmt.Sprintf("invalid pattern for parameter %v: %v", p.Name, err))
+		}
+	}
+	return nil
+}
+
+var integerTypes = []string{"int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune"}
+var unsignedTypes = []string{"uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte"}
+
+func isBuiltinType(t parse.ParamType, names ...string) bool {
+	for _, name := range names {
+		if parse.IsSimpleType(t, name, "") {
+			return true
+		}
+	}
+	return false
+}
+
+func isStringType(t parse.ParamType) bool {
+	return isBuiltinType(t, "string")
+}
+
+func isIntegerType(t parse.ParamType) bool {
+	return isBuiltinType(t, integerTypes...)
+}
+
+func isUnsignedType(t parse.ParamType) bool {
+	return isBuiltinType(t, unsignedTypes...)
+}
+
+func isNumericType(t parse.ParamType) bool {
+	return isIntegerType(t) || isBuiltinType(t, "float32", "float64")
+}
+
+func isNilableType(t parse.ParamType) bool {
+	switch t.(type) {
+	case parse.StarType, parse.ArrayType, parse.MapType, parse.EllipsisType:
+		return true
+	}
+	return false
+}
+
+func hasLength(t parse.ParamType) bool {
+	switch t.(type) {
+	case parse.ArrayType, parse.MapType, parse.EllipsisType:
+		return true
+	}
+	return isStringType(t)
+}
+
+func (e EndpointSpecifications) hasValidation() bool {
+	return len(e.Validation) > 0
+}
+
+func (e EndpointSpecifications) validationPatternVarName(paramName string) string {
+	return gen.LowercaseFirst(e.endpointRequestTypeName()) + gen.UppercaseFirst(paramName) + "Pattern"
+}
+
+// Generates the Validate() method for the request type of the given method, together with any regular expressions used.
+func (g *KitGenerator) generateRequestValidateFunc(es EndpointSpecifications) jen.Code {
+	if !es.hasValidation() || len(es.Method.Params) <= 1 {
+		return jen.Empty()
+	}
+
+	code := jen.Empty()
+	var stmts []jen.Code
+
+	fail := func(message string) jen.Code {
+		return jen.Return(
+			jen.Qual(localErrorsPackage, "New").Call(
+				jen.Nil(),
+				jen.Lit(g.Spec.endpointPackageName()),
+				jen.Qual(localErrorsPackage, "InvalidArgument"),
+			).Dot("WithPublicMessage").Call(jen.Lit(message)),
+		)
+	}
+
+	// iterate over method params instead of the map to get a deterministic order
+	for _, p := range es.Method.Params[1:] {
+		rules, ok := es.Validation[p.Name]
+		if !ok {
+			continue
+		}
+		field := jen.Id("r").Dot(es.endpointRequestTypeParamName(p.Name))
+
+		if rules.Required {
+			if isStringType(p.Type) {
+				stmts = append(stmts, jen.If(field.Clone().Op("==").Lit("")).Block(fail(fmt.Sprintf("%v is required", p.Name))))
+			} else if _, ok := p.Type.(parse.StarType); ok {
+				stmts = append(stmts, jen.If(field.Clone().Op("==").Nil()).Block(fail(fmt.Sprintf("%v is required", p.Name))))
+			} else {
+				stmts = append(stmts, jen.If(jen.Len(field.Clone()).Op("==").Lit(0)).Block(fail(fmt.Sprintf("%v is required", p.Name))))
+			}
+		}
+		if rules.Min != nil {
+			stmts = append(stmts, jen.If(field.Clone().Op("<").Add(numberLit(p.Type, *rules.Min))).Block(fail(fmt.Sprintf("%v must be at least %v", p.Name, *rules.Min))))
+		}
+		if rules.Max != nil {
+			stmts = append(stmts, jen.If(field.Clone().Op(">").Add(numberLit(p.Type, *rules.Max))).Block(fail(fmt.Sprintf("%v must be at most %v", p.Name, *rules.Max))))
+		}
+		if rules.MinLength != nil {
+			stmts = append(stmts, jen.If(jen.Len(field.Clone()).Op("<").Lit(*rules.MinLength)).Block(fail(fmt.Sprintf("length of %v must be at least %v", p.Name, *rules.MinLength))))
+		}
+		if rules.MaxLength != nil {
+			stmts = append(stmts, jen.If(jen.Len(field.Clone()).Op(">").Lit(*rules.MaxLength)).Block(fail(fmt.Sprintf("length of %v must be at most %v", p.Name, *rules.MaxLength))))
+		}
+		if rules.Pattern != "" {
+			varName := es.validationPatternVarName(p.Name)
+			code.Var().Id(varName).Op("=").Qual("regexp", "MustCompile").Call(jen.Lit(rules.Pattern)).Line().Line()
+			stmts = append(stmts, jen.If(jen.Op("!").Id(varName).Dot("MatchString").Call(field.Clone())).Block(fail(fmt.Sprintf("%v does not match pattern %v", p.Name, rules.Pattern))))
+		}
+	}
+	stmts = append(stmts, jen.Return(jen.Nil()))
+
+	return code.Add(g.g.GenFunction(
+		jen.Id("r").Id(es.endpointRequestTypeName()),
+		"Validate",
+		jen.Params(),
+		jen.Error(),
+		stmts,
+	))
+}
+
+// Integer parameters need an integer literal, otherwise a comparison like "r.N < 1.0" would not compile.
+func numberLit(t parse.ParamType, v float64) jen.Code {
+	if isIntegerType(t) {
+		return jen.Lit(int(v))
+	}
+	return jen.Lit(v)
+}
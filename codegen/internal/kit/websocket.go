@@ -0,0 +1,204 @@
+package kit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const gorillaWebsocketPackage = "github.com/gorilla/websocket"
+
+// Checks that websocket handlers can be generated for the method.
+// The method must only return an error and its last parameter must be either
+//   - a channel the method sends values to, e.g. "events chan<- Event", or
+//   - a callback function the method calls for every value, e.g. "onEvent func(e Event) error".
+//
+// All other parameters (except the first context parameter) must be obtained from the url path or query parameters.
+func (e EndpointSpecifications) websocketValid(router Router) error {
+	m := e.Method
+	if len(m.Returns) != 1 {
+		return errors.New(fmt.Sprintf("websocket interface method %v must only return an error", m.Name))
+	}
+	if len(m.Params) < 2 || !isStreamType(m.Params[len(m.Params)-1].Type) {
+		return errors.New(fmt.Sprintf("last parameter of websocket interface method %v must be a channel or a function with a single parameter", m.Name))
+	}
+	if len(e.HttpParams) != len(m.Params)-2 {
+		return errors.New(fmt.Sprintf("missing or too many http parameter annotations for websocket interface method %v", m.Name))
+	}
+	for _, p := range e.HttpParams {
+		if p.Type != HttpTypeUrl && p.Type != HttpTypeQuery {
+			return errors.New(fmt.Sprintf("websocket interface method %v can only have url and query http params", m.Name))
+		}
+	}
+	if e.hasValidation() {
+		return errors.New(fmt.Sprintf("validation not supported for websocket interface method %v", m.Name))
+	}
+	for _, es := range e.EndpointSpecs {
+		spec := es.HttpSpec
+		if strings.ToUpper(spec.Method) != "GET" {
+			return errors.New(fmt.Sprintf("websocket endpoint %v must use http method GET", es.Name))
+		}
+		if spec.Path == "" {
+			return errors.New(fmt.Sprintf("http path is empty for websocket endpoint %v", es.Name))
+		}
+		if err := router.validatePath(spec.Path); err != nil {
+			return fmt.Errorf("invalid http spec for websocket endpoint %v: %w", es.Name, err)
+		}
+		if len(spec.Headers) > 0 || spec.DecodeFunc != "" || spec.EncodeFunc != "" {
+			return errors.New(fmt.Sprintf("headers, decodeFunc and encodeFunc are not supported for websocket endpoint %v", es.Name))
+		}
+	}
+	return nil
+}
+
+// Returns true for channels the method can send to and functions that take a single value and return nothing or an error.
+func isStreamType(t parse.ParamType) bool {
+	switch st := t.(type) {
+	case parse.ChanType:
+		return st.Dir != parse.ChanRecv
+	case parse.FuncType:
+		if len(st.Params) != 1 {
+			return false
+		}
+		return len(st.Returns) == 0 || (len(st.Returns) == 1 && parse.IsSimpleType(st.Returns[0].Type, "error", ""))
+	}
+	return false
+}
+
+func (e EndpointSpecifications) websocketDecodeFuncName() string {
+	return "makeWebsocket" + gen.UppercaseFirst(e.Method.Name) + "DecodeFunc"
+}
+
+// Generates a function that returns a WebsocketDecodeFunc for the method.
+// The decode func obtains the method parameters from the http request and returns a stream function, that calls the method
+// and sends every value the method produces to the client.
+func (g *KitGenerator) generateWebsocketDecodeFunc(es EndpointSpecifications) jen.Code {
+	m := es.Method
+	streamParam := m.Params[len(m.Params)-1]
+
+	var stmts []jen.Code
+	args := []jen.Code{jen.Id("ctx")}
+	// whether the "err" variable has already been declared, scalar query params do not declare it
+	hasError := false
+	for i, p := range m.Params[1 : len(m.Params)-1] {
+		if es.HttpParams[i].Type == HttpTypeUrl {
+			stmts = append(stmts, g.generateHttpDecodeFuncUrlParam(p, es.HttpParams[i].varName(p.Name))...)
+			hasError = true
+		} else if isScalarQueryType(p.Type) {
+			stmts = append(stmts, g.generateHttpDecodeFuncScalarQueryParam(p, es.HttpParams[i].varName(p.Name))...)
+		} else {
+			stmts = append(stmts, g.generateHttpDecodeFuncQueryParam(es, p, hasError)...)
+			hasError = true
+		}
+		stmts = append(stmts, jen.Line())
+		args = append(args, jen.Id(p.Name))
+	}
+
+	var streamStmts []jen.Code
+	switch t := streamParam.Type.(type) {
+	case parse.ChanType:
+		streamStmts = g.generateWebsocketChanStream(es, t, args)
+	case parse.FuncType:
+		streamStmts = g.generateWebsocketCallbackStream(es, t, args)
+	}
+
+	stmts = append(stmts, jen.Return(
+		jen.Func().Params(
+			jen.Id("ctx").Qual("context", "Context"),
+			jen.Id("send").Func().Params(jen.Id("v").Interface()).Error(),
+		).Error().Block(streamStmts...),
+		jen.Nil(),
+	))
+
+	return g.g.GenFunction(
+		nil,
+		es.websocketDecodeFuncName(),
+		jen.Params(jen.Id("s").Qual(g.Spec.Interface.Package, g.Spec.Interface.Name)),
+		jen.Qual(g.Spec.HttpHelperPackage, "WebsocketDecodeFunc"),
+		[]jen.Code{
+			jen.Return(g.g.GenFunction(
+				nil,
+				"",
+				jen.Params(
+					jen.Id("ctx").Qual("context", "Context"),
+					jen.Id("r").Op("*").Qual("net/http", "Request"),
+				),
+				jen.Params(
+					jen.Qual(g.Spec.HttpHelperPackage, "WebsocketStreamFunc"),
+					jen.Error(),
+				),
+				stmts,
+			)),
+		},
+	)
+}
+
+// The method is run in a separate goroutine and sends values to a channel, which are forwarded to the client until the method returns.
+// The method may close the channel before returning.
+func (g *KitGenerator) generateWebsocketChanStream(es EndpointSpecifications, t parse.ChanType, args []jen.Code) []jen.Code {
+	streamParam := es.Method.Params[len(es.Method.Params)-1]
+	args = append(args, jen.Id(streamParam.Name))
+	return []jen.Code{
+		jen.Id(streamParam.Name).Op(":=").Make(jen.Chan().Add(g.g.GenParamType(t.Type))),
+		jen.Id("done").Op(":=").Make(jen.Chan().Error(), jen.Lit(1)),
+		jen.Go().Func().Params().Block(
+			jen.Id("done").Op("<-").Id("s").Dot(es.Method.Name).Call(args...),
+		).Call(),
+		jen.For().Block(
+			jen.Select().Block(
+				jen.Case(jen.List(jen.Id("v"), jen.Id("ok")).Op(":=").Op("<-").Id(streamParam.Name)).Block(
+					jen.If(jen.Op("!").Id("ok")).Block(
+						jen.Return(jen.Op("<-").Id("done")),
+					),
+					jen.If(jen.Id("err").Op(":=").Id("send").Call(jen.Id("v")), jen.Id("err").Op("!=").Nil()).Block(
+						jen.Return(jen.Id("err")),
+					),
+				),
+				jen.Case(jen.Id("err").Op(":=").Op("<-").Id("done")).Block(
+					jen.Return(jen.Id("err")),
+				),
+			),
+		),
+	}
+}
+
+// The method is called with a callback that sends every value to the client.
+// If the callback returns an error, a failed send is reported to the method.
+func (g *KitGenerator) generateWebsocketCallbackStream(es EndpointSpecifications, t parse.FuncType, args []jen.Code) []jen.Code {
+	valueParam := jen.Id("v").Add(g.g.GenParamType(t.Params[0].Type))
+	var callback jen.Code
+	if len(t.Returns) == 0 {
+		// errors are not reported, the context is cancelled if the client closes the connection
+		callback = jen.Func().Params(valueParam).Block(jen.Id("send").Call(jen.Id("v")))
+	} else {
+		callback = jen.Func().Params(valueParam).Error().Block(jen.Return(jen.Id("send").Call(jen.Id("v"))))
+	}
+	args = append(args, callback)
+	return []jen.Code{
+		jen.Return(jen.Id("s").Dot(es.Method.Name).Call(args...)),
+	}
+}
+
+// Statements that create and register the websocket handlers for the endpoints of the method.
+func (g *KitGenerator) generateWebsocketHandlerStmts(es EndpointSpecifications, optionsPaths map[string]bool) []httpEndpointCodeStmts {
+	var result []httpEndpointCodeStmts
+	for _, spec := range es.EndpointSpecs {
+		stmts := []jen.Code{
+			jen.Id(spec.httpHandlerVarName()).Op(":=").Qual(g.Spec.HttpHelperPackage, "NewWebsocketStreamHandler").Call(
+				jen.Id(es.websocketDecodeFuncName()).Call(jen.Id("s")),
+				jen.Id("upgrader"),
+			),
+		}
+		stmts = append(stmts, g.Spec.Router.registerHandler(spec, g.Spec.PathPrefix, g.httpHandler(spec), optionsPaths)...)
+		result = append(result, httpEndpointCodeStmts{
+			Path:  spec.HttpSpec.Path,
+			Stmts: stmts,
+		})
+	}
+	return result
+}
@@ -33,7 +33,7 @@ func (m *MockGenerator) Generate() (result []gen.GenResult, err error) {
 	}()
 
 	code := m.genInterfaceMock(m.Spec.I)
-	packagePath := m.Spec.Module.FullPackagePath(m.Spec.Package)
+	packagePath := m.Spec.PackagePath()
 	packageName := m.Spec.PackageName()
 	outputFile := m.Spec.Module.FileName(m.Spec.Package, m.Spec.Output)
 	return []gen.GenResult{{
@@ -47,35 +47,92 @@ func (m *MockGenerator) Generate() (result []gen.GenResult, err error) {
 func (m *MockGenerator) genInterfaceMock(i parse.Interface) *jen.Group {
 	m.g = gen.NewSimpleGenerator()
 
-	structType := m.g.GenStructType(mockStructName(i.Name), []jen.Code{jen.Qual(testifyMockPackage, "Mock")})
+	structType := m.genStructType(mockStructName(i.Name), []jen.Code{jen.Qual(testifyMockPackage, "Mock")})
 
 	var g *jen.Group = jen.NewFile("").Group
 
 	g.Add(structType)
 	g.Line()
+	g.Add(m.genInterfaceAssertion(mockStructName(i.Name)))
+	g.Add(m.genConstructor(i))
+	g.Line()
 	for _, method := range i.Methods {
 		m.genMockFunc(g, method, i)
 		g.Line()
 	}
 
+	if m.Spec.Expecter {
+		m.genExpecter(g, i)
+	}
+
+	if m.Spec.Stub {
+		m.genStub(g, i)
+	}
+
 	return g
 }
 
+// Generates a struct type with the given name and fields.
+// If the interface is generic, the struct has the same type parameters as the interface.
+func (m *MockGenerator) genStructType(name string, fields []jen.Code) jen.Code {
+	return jen.Type().Id(name).Add(m.g.GenTypeParams(m.Spec.I.TypeParams)).Struct(fields...)
+}
+
+// Type arguments used to refer to the generated (possibly generic) types, e.g. in method receivers.
+func (m *MockGenerator) typeArgs() jen.Code {
+	return m.g.GenTypeArgs(m.Spec.I.TypeParams)
+}
+
+// Generates a compile-time assertion that the type with the given name implements the interface, e.g.
+//
+//	var _ example.Service = (*MockService)(nil)
+//
+// Nothing is generated for generic interfaces, since the assertion would require type arguments.
+func (m *MockGenerator) genInterfaceAssertion(name string) jen.Code {
+	if m.Spec.I.IsGeneric() {
+		return jen.Null()
+	}
+	return jen.Var().Id("_").Qual(m.Spec.I.Package, m.Spec.I.Name).Op("=").Parens(jen.Op("*").Id(name)).Call(jen.Nil()).Line().Line()
+}
+
+// Generates a function that creates a mock and registers a cleanup function with t that asserts the expectations of the mock,
+// i.e. AssertExpectations does not need to be called at the end of a test.
+func (m *MockGenerator) genConstructor(i parse.Interface) jen.Code {
+	name := mockStructName(i.Name)
+	return jen.Comment(fmt.Sprintf("New%v creates a new mock, whose expectations are asserted when the test and all its subtests complete.", name)).Line().
+		Func().Id("New"+name).Add(m.g.GenTypeParams(i.TypeParams)).Params(
+		jen.Id("t").Interface(
+			jen.Qual(testifyMockPackage, "TestingT"),
+			jen.Id("Cleanup").Params(jen.Func().Params()),
+		),
+	).Op("*").Id(name).Add(m.typeArgs()).Block(
+		jen.Id("m").Op(":=").Op("&").Id(name).Add(m.typeArgs()).Values(),
+		jen.Id("m").Dot("Mock").Dot("Test").Call(jen.Id("t")),
+		jen.Id("t").Dot("Cleanup").Call(jen.Func().Params().Block(
+			jen.Id("m").Dot("AssertExpectations").Call(jen.Id("t")),
+		)),
+		jen.Return(jen.Id("m")),
+	).Line()
+}
+
 func mockStructName(name string) string {
 	return "Mock" + gen.UppercaseFirst(name)
 }
 
+// Returns the parameters of the method that are passed to m.Called(...).
+// The call to m.Called(arg1, arg2) should not contain the first argument if it is context.Context.
+func calledParams(method parse.Method) []parse.Param {
+	if len(method.Params) > 0 && parse.IsSimpleType(method.Params[0].Type, "Context", "context") {
+		return method.Params[1:]
+	}
+	return method.Params
+}
+
 func (m *MockGenerator) genMockFunc(g *jen.Group, method parse.Method, i parse.Interface) {
 	funcParams := m.g.GenFunctionParams(method.Params)
 	returnParams := m.g.GenReturnParams(method.Returns)
 
-	paramNames := m.g.GenParamNames(method.Params)
-	//the call to m.Called(arg1, arg2), should not contain the first argument if it is context.Context
-	if len(method.Params) > 0 {
-		if st, ok := method.Params[0].Type.(parse.SimpleType); ok && st.Type == "Context" && st.Package == "context" {
-			paramNames = paramNames[1:]
-		}
-	}
+	paramNames := m.g.GenParamNames(calledParams(method))
 	paramIds := make([]jen.Code, len(paramNames))
 	for i, paramName := range paramNames {
 		paramIds[i] = jen.Id(paramName)
@@ -97,7 +154,7 @@ func (m *MockGenerator) genMockFunc(g *jen.Group, method parse.Method, i parse.I
 	}
 
 	g.Add(m.g.GenFunction(
-		jen.Id("m").Op("*").Id(mockStructName(i.Name)),
+		jen.Id("m").Op("*").Id(mockStructName(i.Name)).Add(m.typeArgs()),
 		method.Name,
 		funcParams,
 		returnParams,
@@ -105,6 +162,126 @@ func (m *MockGenerator) genMockFunc(g *jen.Group, method parse.Method, i parse.I
 	))
 }
 
+func expecterStructName(name string) string {
+	return mockStructName(name) + "Expecter"
+}
+
+func expecterCallStructName(name, method string) string {
+	return mockStructName(name) + gen.UppercaseFirst(method) + "Call"
+}
+
+// Generates a typed builder similar to the "expecter" mode of mockery (github.com/vektra/mockery).
+// Instead of
+//
+//	m.On("Method1", "a", x).Return(y, nil)
+//
+// calls can be set up with
+//
+//	m.EXPECT().Method1("a", x).Return(y, nil)
+//
+// where the arguments to Run(...) and Return(...) are checked by the compiler.
+func (m *MockGenerator) genExpecter(g *jen.Group, i parse.Interface) {
+	expecterName := expecterStructName(i.Name)
+
+	g.Add(m.genStructType(expecterName, []jen.Code{jen.Id("mock").Op("*").Qual(testifyMockPackage, "Mock")}))
+	g.Line()
+	g.Add(m.g.GenFunction(
+		jen.Id("m").Op("*").Id(mockStructName(i.Name)).Add(m.typeArgs()),
+		"EXPECT",
+		jen.Params(),
+		jen.Op("*").Id(expecterName).Add(m.typeArgs()),
+		[]jen.Code{
+			jen.Return(jen.Op("&").Id(expecterName).Add(m.typeArgs()).Values(jen.Dict{
+				jen.Id("mock"): jen.Op("&").Id("m").Dot("Mock"),
+			})),
+		},
+	))
+	g.Line()
+
+	for _, method := range i.Methods {
+		m.genExpecterMethod(g, method, i)
+	}
+}
+
+func (m *MockGenerator) genExpecterMethod(g *jen.Group, method parse.Method, i parse.Interface) {
+	callName := expecterCallStructName(i.Name, method.Name)
+	params := calledParams(method)
+	paramNames := m.g.GenParamNames(params)
+
+	g.Add(m.genStructType(callName, []jen.Code{jen.Op("*").Qual(testifyMockPackage, "Call")}))
+	g.Line()
+
+	// Expecter method, arguments are of type interface{} so that e.g. mock.Anything can be used.
+	expecterParams := make([]jen.Code, len(paramNames))
+	onArgs := []jen.Code{jen.Lit(method.Name)}
+	for j, paramName := range paramNames {
+		expecterParams[j] = jen.Id(paramName).Interface()
+		onArgs = append(onArgs, jen.Id(paramName))
+	}
+	g.Add(m.g.GenFunction(
+		jen.Id("e").Op("*").Id(expecterStructName(i.Name)).Add(m.typeArgs()),
+		method.Name,
+		jen.Params(expecterParams...),
+		jen.Op("*").Id(callName).Add(m.typeArgs()),
+		[]jen.Code{
+			jen.Return(jen.Op("&").Id(callName).Add(m.typeArgs()).Values(jen.Dict{
+				jen.Id("Call"): jen.Id("e").Dot("mock").Dot("On").Call(onArgs...),
+			})),
+		},
+	))
+	g.Line()
+
+	// Run, arguments passed to the mock are converted to their actual types.
+	// Nil values are not type asserted, since this would panic e.g. for pointer or interface types.
+	var runStmts []jen.Code
+	runArgs := make([]jen.Code, len(params))
+	for j, param := range params {
+		v := fmt.Sprintf("v%v", j)
+		paramType := m.g.GenValueType(param.Type)
+		runStmts = append(runStmts,
+			jen.Var().Id(v).Add(paramType),
+			jen.If(jen.Id("args").Index(jen.Lit(j)).Op("!=").Nil()).Block(
+				jen.Id(v).Op("=").Id("args").Index(jen.Lit(j)).Assert(paramType),
+			),
+		)
+		runArgs[j] = jen.Id(v)
+	}
+	runStmts = append(runStmts, jen.Id("run").Call(m.g.GenCallArgs(params, runArgs)...))
+	g.Add(m.g.GenFunction(
+		jen.Id("c").Op("*").Id(callName).Add(m.typeArgs()),
+		"Run",
+		jen.Params(jen.Id("run").Func().Add(m.g.GenFunctionParams(params))),
+		jen.Op("*").Id(callName).Add(m.typeArgs()),
+		[]jen.Code{
+			jen.Id("c").Dot("Call").Dot("Run").Call(
+				jen.Func().Params(jen.Id("args").Qual(testifyMockPackage, "Arguments")).Block(runStmts...),
+			),
+			jen.Return(jen.Id("c")),
+		},
+	))
+	g.Line()
+
+	// Return, named r0, r1, ...
+	returnParams := make([]jen.Code, len(method.Returns))
+	returnArgs := make([]jen.Code, len(method.Returns))
+	for j, r := range method.Returns {
+		name := fmt.Sprintf("r%v", j)
+		returnParams[j] = jen.Id(name).Add(m.g.GenParamType(r.Type))
+		returnArgs[j] = jen.Id(name)
+	}
+	g.Add(m.g.GenFunction(
+		jen.Id("c").Op("*").Id(callName).Add(m.typeArgs()),
+		"Return",
+		jen.Params(returnParams...),
+		jen.Op("*").Id(callName).Add(m.typeArgs()),
+		[]jen.Code{
+			jen.Id("c").Dot("Call").Dot("Return").Call(returnArgs...),
+			jen.Return(jen.Id("c")),
+		},
+	))
+	g.Line()
+}
+
 func isErrorParam(param parse.Param) bool {
 	st, ok := (param.Type).(parse.SimpleType)
 	if ok {
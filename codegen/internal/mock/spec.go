@@ -3,7 +3,9 @@ package mock
 import (
 	"errors"
 	"fmt"
+	"go/token"
 	"path"
+	"strings"
 
 	"github.com/dkinzler/kit/codegen/annotations"
 	"github.com/dkinzler/kit/codegen/parse"
@@ -13,38 +15,88 @@ import (
 type GenSpecification struct {
 	// the interface to generate a mock for
 	I parse.Interface
-	// module the interface belongs to, can be used to determine package names and file paths
+	// module the mock is generated in, usually the module the interface belongs to, can be used to determine package names and file paths
 	Module parse.Module
 	// Package the file will be created in.
 	// Path must be relative to the module, e.g. if the module is "example.com/abc" and the package is "example.com/abc/xyz/def" use "xyz/def".
 	// If empty use the same package as the interface.
 	Package string `json:"package"`
-	// Filename of the output, defaults to "mock.go".
+	// Filename of the output, defaults to "mock.go", or "mock_test.go" if PackageSuffix is "_test".
 	Output string `json:"output"`
+	// Optional suffix to generate the mock in an external package that imports the package of the interface,
+	// instead of importable production code.
+	// With "_test" the mock is generated in the external test package in the directory of Package, e.g. "foo_test",
+	// other suffixes are appended to Package, e.g. "_mock" generates the mock in package "foo_mock" next to package "foo".
+	PackageSuffix string `json:"packageSuffix"`
+	// Optional name and root directory of a different module the mock is generated in, e.g. a separate test-support module.
+	// The path can be relative to the root directory of the module of the interface.
+	// If only the path is set, the name is read from the go.mod file of the output module.
+	// Package is then relative to the output module.
+	OutputModule     string `json:"outputModule"`
+	OutputModulePath string `json:"outputModulePath"`
+	// If true, additionally generate a typed EXPECT() builder for the mock.
+	// Calls on the builder are type checked at compile time, unlike calls to mock.On(...).
+	Expecter bool `json:"expecter"`
+	// If true, additionally generate a stub implementation of the interface, a struct with a function field for every method.
+	// This is useful for lightweight fakes that do not need a mocking framework.
+	Stub bool `json:"stub"`
 }
 
+const testPackageSuffix = "_test"
+
 // The package name to use in a source file, the last element of the full package path.
-// E.g. for the package "example.com/abc/xyz" the package name would be "xyz".
+// E.g. for the package "example.com/abc/xyz" the package name would be "xyz", or "xyz_test" for an external test package.
 func (g GenSpecification) PackageName() string {
-	return path.Base(g.Module.FullPackagePath(g.Package))
+	return path.Base(g.PackagePath())
+}
+
+// The full import path of the package, for an external test package the path of the package with suffix "_test".
+// The path differs from the one of the package of the interface, so that the interface package is imported.
+func (g GenSpecification) PackagePath() string {
+	p := g.Module.FullPackagePath(g.Package)
+	if g.PackageSuffix == testPackageSuffix {
+		p += testPackageSuffix
+	}
+	return p
 }
 
 func SpecFromAnnotations(i parse.Interface, m parse.Module, a annotations.InterfaceAnnotation) (GenSpecification, error) {
 	var spec GenSpecification
 
-	err := annotations.ParseJSONAnnotation(a.Annotation, &spec)
+	err := a.ParseJSON(a.Annotation, &spec)
 	if err != nil {
 		return spec, errors.New(fmt.Sprintf("could not parse annotation for interface %v, error: %v", i.Name, err))
 	}
 
 	spec.I = i
-	spec.Module = m
+	spec.Module, err = m.OutputModule(spec.OutputModule, spec.OutputModulePath)
+	if err != nil {
+		return spec, err
+	}
 
+	// defaults to the same package as the interface, or a package with the same relative path in the output module
 	if spec.Package == "" {
 		spec.Package = m.PackagePathWithoutModule(i.Package)
 	}
+	if spec.PackageSuffix != "" {
+		if !token.IsIdentifier("x" + spec.PackageSuffix) {
+			return spec, errors.New(fmt.Sprintf("invalid package suffix %v for interface %v", spec.PackageSuffix, i.Name))
+		}
+		if !token.IsExported(i.Name) {
+			return spec, errors.New(fmt.Sprintf("interface %v must be exported to generate a mock in an external package", i.Name))
+		}
+		if spec.PackageSuffix != testPackageSuffix {
+			spec.Package += spec.PackageSuffix
+		}
+	}
 	if spec.Output == "" {
 		spec.Output = "mock.go"
+		if spec.PackageSuffix == testPackageSuffix {
+			spec.Output = "mock_test.go"
+		}
+	}
+	if spec.PackageSuffix == testPackageSuffix && !strings.HasSuffix(spec.Output, "_test.go") {
+		return spec, errors.New(fmt.Sprintf("output %v of mock for interface %v must end with \"_test.go\" to be part of an external test package", spec.Output, i.Name))
 	}
 
 	return spec, nil
@@ -0,0 +1,78 @@
+package mock
+
+import (
+	"fmt"
+
+	"github.com/dkinzler/kit/codegen/gen"
+	"github.com/dkinzler/kit/codegen/parse"
+
+	"github.com/dave/jennifer/jen"
+)
+
+func stubStructName(name string) string {
+	return "Stub" + gen.UppercaseFirst(name)
+}
+
+func stubFuncFieldName(method string) string {
+	return method + "Func"
+}
+
+// Generates a stub implementation of the interface, i.e. a struct with a function field for every method, e.g.
+//
+//	s := &StubService{
+//		GetFunc: func(ctx context.Context, id string) (Item, error) {
+//			return Item{Id: id}, nil
+//		},
+//	}
+//
+// Methods call the function field, if it is nil they return zero values.
+func (m *MockGenerator) genStub(g *jen.Group, i parse.Interface) {
+	stubName := stubStructName(i.Name)
+
+	fields := make([]jen.Code, len(i.Methods))
+	for j, method := range i.Methods {
+		fields[j] = jen.Id(stubFuncFieldName(method.Name)).Func().Add(m.g.GenFunctionParams(method.Params)).Add(m.g.GenReturnParams(method.Returns))
+	}
+	g.Comment(fmt.Sprintf("%v implements %v by calling the function field of a method, methods with a nil field return zero values.", stubName, i.Name))
+	g.Add(m.genStructType(stubName, fields))
+	g.Line()
+	g.Add(m.genInterfaceAssertion(stubName))
+
+	for _, method := range i.Methods {
+		m.genStubFunc(g, method, i)
+		g.Line()
+	}
+}
+
+func (m *MockGenerator) genStubFunc(g *jen.Group, method parse.Method, i parse.Interface) {
+	paramNames := m.g.GenParamNames(method.Params)
+	args := make([]jen.Code, len(paramNames))
+	for j, paramName := range paramNames {
+		args[j] = jen.Id(paramName)
+	}
+	field := jen.Id("stub").Dot(stubFuncFieldName(method.Name))
+	call := field.Clone().Call(m.g.GenCallArgs(method.Params, args)...)
+
+	var stmts []jen.Code
+	var zeroValues []jen.Code
+	for j, r := range method.Returns {
+		name := fmt.Sprintf("r%v", j)
+		stmts = append(stmts, jen.Var().Id(name).Add(m.g.GenValueType(r.Type)))
+		zeroValues = append(zeroValues, jen.Id(name))
+	}
+	if len(method.Returns) > 0 {
+		stmts = append([]jen.Code{jen.If(field.Clone().Op("!=").Nil()).Block(jen.Return(call))}, stmts...)
+		stmts = append(stmts, jen.Return(zeroValues...))
+	} else {
+		stmts = append(stmts, jen.If(field.Clone().Op("!=").Nil()).Block(call))
+	}
+
+	// the receiver is not named "s" or "m", which are common parameter names
+	g.Add(m.g.GenFunction(
+		jen.Id("stub").Op("*").Id(stubStructName(i.Name)).Add(m.typeArgs()),
+		method.Name,
+		m.g.GenFunctionParams(method.Params),
+		m.g.GenReturnParams(method.Returns),
+		stmts,
+	))
+}
@@ -8,18 +8,64 @@ The code generator is configured by providing annotations in the comments of an
 An annotation has the format @Name{"abc":"xyz"} where:
   - Name denotes the type of code to generate, either Mock or Kit
   - Name is followed by a JSON object which can be split across multiple comment lines
+  - Multiple annotations with the same name, e.g. several @Kit{...} blocks, are merged into one, so that large configurations stay readable.
+    Nested objects are merged and arrays are concatenated, any other key can only be set in one of the blocks.
 
 Run the generator with:
 
 	go run github.com/dkinzler/kit/codegen@latest --inputDir xyz
 
 This will generate code for any annotated interfaces found within directory xyz or (recursively) any subdirectories.
+Like for the go tool, directories named vendor or testdata and directories starting with "." or "_" are skipped,
+as well as test files, files with a "//go:build ignore" constraint and files produced by the generator.
+Use the --exclude flag (can be repeated) to skip further files or directories matching a glob pattern,
+e.g. --exclude internal/legacy --exclude "*_old.go". Patterns are matched against the path relative to the input directory and the base name.
+Use the --dry-run flag to print a diff between the generated code and the existing files instead of writing them,
+the generator exits with a non-zero code if there are any differences.
+Use the --verify flag to fix the imports of the written files and type check their packages afterwards,
+so that a generation run that produces code that does not compile fails instead of leaving it in the tree unnoticed.
+Use the --watch flag to keep the generator running and regenerate code whenever a go file in the input directory changes.
+Use the --cache flag to store hashes of source and output files in .codegen-cache.json in the module root,
+code is then only generated again for interfaces in changed files (the cache file should usually not be committed).
+Output files whose content did not change are never rewritten, so that their modification times stay the same.
+The header of every generated file contains the version of the generator and the interfaces the code was generated for,
+one file per output package also contains //go:generate directives to generate the package again,
+the command can be changed with the --generateCommand flag.
+Use the --check-version flag to not overwrite files that were generated by a newer version of the generator,
+e.g. to avoid that team members with an outdated version silently change generated code.
+The version is the module version the generator was installed with, e.g. with "go install github.com/dkinzler/kit/codegen@v0.2.1",
+it is "devel" for builds from a local checkout, which are never compared with other versions.
+Use the lint command to only validate the annotations without generating any code, e.g. as a pre-commit hook:
+
+	go run github.com/dkinzler/kit/codegen@latest lint --inputDir xyz
+
+In addition to the checks done when generating code, lint reports unknown annotations and unknown keys in annotations, which are otherwise ignored.
+Use the list command to print the annotated interfaces with their endpoints, http routes and the files that would be written,
+e.g. to check what the generator would touch before running it.
+Use the scaffold command to generate a main package for an interface with a Kit annotation,
+that creates the endpoints and http handlers with a JSON logger and runs a http server:
+
+	go run github.com/dkinzler/kit/codegen@latest scaffold --inputDir xyz --interface Service
+
+The file is written to cmd/<interface name in lowercase>/main.go in the module root, use --output to choose a different file.
+Unlike the other generated files it is meant to be edited, values like the implementation of the interface are marked with TODO comments.
+Existing files are not overwritten unless --force is set.
 For the code generator to work, directory xyz must be part of a go module, i.e. xyz or one of its ancestor directories must contain a go.mod file.
-Note also that code can be generated only for the same module as the annotated interfaces.
+By default code is generated in the same module as the annotated interfaces.
+Use the "outputModule" and "outputModulePath" options of the @Mock and @Kit annotations to generate code in a different module instead,
+e.g. mocks for interfaces of an API module in a separate test-support module:
+
+	@Mock{"package": "mocks", "outputModule": "example.com/testsupport", "outputModulePath": "../testsupport"}
+
+"outputModulePath" is the root directory of the output module, relative paths are relative to the root directory of the module of the interface.
+If "outputModule" is empty, the module name is read from the go.mod file in that directory.
+Packages are then relative to the output module, which must require the module of the interface.
 
 # Generating Mocks
 
 To generate a mock implementation of an interface, add a @Mock{...} annotation to the interface comments.
+The generated file also contains a compile-time assertion that the mock implements the interface (omitted for generic interfaces)
+and a constructor, e.g. NewMockExampleInterface(t), that asserts the expectations of the mock when the test completes.
 See the [example project] for a complete example that also contains the generated code.
 
 Example:
@@ -31,11 +77,71 @@ Example:
 	// "output" defines the name of the output file that will contain the generated code.
 	// If empty, defaults to "mock.go".
 	//
-	// @Mock{"package":"xyz", "output":"mock.go"}
+	// "outputModule" and "outputModulePath" optionally define a different module the mock is generated in, see above.
+	//
+	// "packageSuffix" generates the mock in an external package that imports the package of the interface, instead of importable production code.
+	// With "_test" the mock belongs to the external test package in the same directory, e.g. "xyz_test", and "output" defaults to "mock_test.go".
+	// Other suffixes are appended to the package, e.g. with "_mock" the mock is generated in a package "xyz_mock" next to package "xyz".
+	//
+	// If "expecter" is true, a typed EXPECT() builder is generated in addition to the mock.
+	// E.g. m.EXPECT().Method1("a", 42).Return(nil) instead of m.On("Method1", "a", 42).Return(nil),
+	// the arguments to Run(...) and Return(...) are then checked by the compiler.
+	//
+	// If "stub" is true, a struct StubExampleInterface with a function field for every method, e.g. Method1Func, is generated as well.
+	// Its methods call the function fields or return zero values if they are nil, for lightweight fakes without a mocking framework.
+	//
+	// @Mock{"package":"xyz", "output":"mock.go", "expecter": true, "stub": true}
 	type ExampleInterface interface {
 		Method1(ctx context.Context, a string, b int) error
 	}
 
+Mocks can also be generated for generic interfaces, the generated mock type has the same type parameters as the interface,
+e.g. MockRepository[T any, K comparable] for an interface Repository[T any, K comparable].
+
+Arguments of variadic parameters are passed to the mock as a single slice, e.g. a call Delete(ctx, 1, 2) of a method
+"Delete(ctx context.Context, ids ...int) error" matches m.On("Delete", []int{1, 2}).
+
+Methods can have function and channel parameters, e.g. for observer or notifier interfaces.
+Since functions are not comparable, use mock.Anything or mock.AnythingOfType(...) to match function arguments
+and call the function in Run(...), e.g. m.EXPECT().Watch(mock.Anything).Run(func(onEvent func(e Event)) { onEvent(e) }).
+
+# Generating Firestore repositories
+
+To generate an implementation of a repository-style interface that stores documents in Firestore,
+add a @Firestore{...} annotation to the interface comments. The generated struct, e.g. FirestoreNoteRepository for an interface NoteRepository,
+is created with NewFirestoreNoteRepository(client *firestore.Client) and uses the helpers of package "github.com/dkinzler/kit/firebase/firestore".
+
+Example:
+
+	// "package" and "output" define the package and the name of the output file like for @Mock, "output" defaults to "firestore.gen.go".
+	// "collection" is the name of the collection the documents are stored in.
+	// "idField" is the name of the field of the document type that contains the id of a document,
+	// it is used by create and update methods that do not have an id parameter.
+	//
+	// @Firestore{"collection": "notes", "idField": "NoteId"}
+	type NoteRepository interface {
+		GetNote(ctx context.Context, id string) (Note, error)
+		CreateNote(ctx context.Context, note Note) error
+		// @Firestore{"operation": "get", "collection": "archived-notes"}
+		ArchivedNote(ctx context.Context, id string) (*Note, firestore.TransactionExpectations, error)
+		UpdateNote(ctx context.Context, id string, note Note, te firestore.TransactionExpectations) error
+		DeleteNote(ctx context.Context, id string) error
+		ListNotes(ctx context.Context, limit int) ([]Note, error)
+	}
+
+The operation of a method is determined by the prefix of its name (Get, Create, Update, Delete or List) or set with "operation"
+in an annotation on the method, which can also override "collection" and "idField". Supported signatures are:
+
+	get:    (ctx, id string) (Doc, error) or (ctx, id string) (Doc, firestore.TransactionExpectations, error)
+	create: (ctx, doc Doc) error or (ctx, id string, doc Doc) error
+	update: like create, optionally with a last parameter of type firestore.TransactionExpectations
+	delete: (ctx, id string) error, optionally with a last parameter of type firestore.TransactionExpectations
+	list:   (ctx) ([]Doc, error) or (ctx, limit int) ([]Doc, error)
+
+Doc must be a named type or a pointer to one and firestore.TransactionExpectations is the type from "github.com/dkinzler/kit/firebase/firestore".
+Updates and deletes with transaction expectations run in a transaction and fail if any of the documents the expectations
+were created for was modified since it was read, see TransactionExpectations for optimistic concurrency.
+
 # Generating Go kit endpoints and http handlers
 
 To generate Go kit endpoints and http handlers for an interface, add a @Kit{...} annotation to the comments of an interface.
@@ -55,7 +161,91 @@ Example annotation on an interface, for better readability only the JSON annotat
 	  // If empty or not provided nothing will be generated.
 	  "httpPackage": "http",
 	  // Name of output file for http code, defaults to "http.gen.go".
-	  "httpOutput": "http.go"
+	  "httpOutput": "http.go",
+	  // Router the http handlers are registered with, one of:
+	  //   - "gorilla": *mux.Router from github.com/gorilla/mux (default)
+	  //   - "chi": chi.Router from github.com/go-chi/chi/v5
+	  //   - "stdlib": *http.ServeMux from the standard library, using the path patterns introduced in Go 1.22
+	  "router": "gorilla",
+	  // Optional prefix for the paths of all http handlers, e.g. "/api/v1".
+	  // Handlers are registered with a subrouter for the prefix (gorilla/mux and chi) or the prefix is added to every path (stdlib).
+	  "pathPrefix": "/api/v1",
+	  // Optional CORS configuration. If set, the http handlers are wrapped with CORSMiddleware from package "github.com/dkinzler/kit/transport/http",
+	  // which answers preflight requests and sets the "Access-Control-Allow-Origin" header of responses for allowed origins.
+	  // Origins and headers can contain "*" to allow any value, methods default to the http methods of the endpoints.
+	  // Optionally exposed response headers, whether credentials are allowed and how many seconds browsers may cache preflight results can be set.
+	  "cors": {"origins": ["https://example.com"], "methods": ["GET", "POST"], "headers": ["Authorization", "Content-Type"],
+	           "exposedHeaders": ["X-Request-Id"], "allowCredentials": true, "maxAge": 600},
+	  // Packages the generated NATS and AMQP transports will belong to, relative to the full module path.
+	  // If empty or not provided nothing will be generated.
+	  "natsPackage": "nats",
+	  "amqpPackage": "amqp",
+	  // Names of the output files, default to "nats.gen.go" and "amqp.gen.go".
+	  "natsOutput": "nats.go",
+	  "amqpOutput": "amqp.go",
+	  // Optional envelope the response values of the http handlers are wrapped with. Either "data" for responses of the form {"data": ...}
+	  // or a reference to a function func(ctx context.Context, response interface{}) interface{} that returns the value to encode,
+	  // e.g. "example.com/xyz/api.Envelope" to add a "meta" field. Errors are not wrapped and endpoints with a custom encode function are not affected.
+	  // The TypeScript client unwraps "data" envelopes, with a custom envelope it has no methods.
+	  "envelope": "data",
+	  // If true, RegisterHttpHandlers takes a logger (github.com/go-kit/log) instead of a slice of go-kit server options
+	  // and uses the standard options: errors are encoded with EncodeError and logged with LogErrorHandler from the http helper package.
+	  // Handlers of endpoints that require authentication additionally store the token of the Authorization header in the context.
+	  "defaultServerOptions": true,
+	  // If true, generate a function NewInstrumentedEndpoints(svc, mws, duration metrics.Histogram) that
+	  // records the request duration of every endpoint, labeled with "endpoint" and "success".
+	  "instrumentation": true,
+	  // Json tags of the fields of the generated endpoint request types, which are e.g. encoded as JSON when sent over NATS or AMQP.
+	  // One of "camelCase" ("userId") or "snake_case" ("user_id"), by default fields have no json tags.
+	  "jsonNaming": "snake_case",
+	  // Optional path of a TypeScript file relative to the module root. If set, TypeScript types for the endpoint requests
+	  // and responses are generated, together with a client class that calls the http handlers using fetch.
+	  // Types defined in Go packages are declared as aliases of "unknown", since their definition is not known to the generator.
+	  // Endpoints with a custom decode or encode function or multipart parameters are not part of the client.
+	  // Every interface must use a different file.
+	  "typescriptOutput": "web/src/api/example.gen.ts",
+	  // Optional path of a .proto file relative to the module root. If set, a gRPC service with a rpc for every interface method with endpoints
+	  // and request and response messages derived from the parameters and results of the methods are generated, e.g. as a starting point for a gRPC api.
+	  // Like for TypeScript, types defined in Go packages are declared as empty messages.
+	  "protoOutput": "api/proto/example.proto",
+	  // Package of the .proto file, defaults to the name of the package of the interface, and options written to the file.
+	  "protoPackage": "example.v1",
+	  "protoOptions": {"go_package": "example.com/xyz/api/proto"},
+	  // Optional directory relative to the module root. If set, a JSON Schema file "<endpoint name>.schema.json" is generated
+	  // for the request body of every endpoint with json http params, e.g. to validate requests in an api gateway.
+	  // Validation rules of the parameters (see @Validate below) are part of the schema, types defined in Go packages accept any value.
+	  "jsonSchemaOutput": "api/schemas",
+	  // If true, MakeXEndpoint functions return a strongly typed endpoint func(ctx context.Context, req XRequest) (XResponse, error)
+	  // instead of a go-kit endpoint.Endpoint, where XResponse is an alias of the result type of the method.
+	  // Methods without parameters or result use the type Empty from package "github.com/dkinzler/kit/endpoint".
+	  // NewEndpoints adapts them with AdaptTypedEndpoint, which returns an error instead of panicking if an endpoint is called with a wrong request type.
+	  "typedEndpoints": true,
+	  // If true, skeleton table-driven tests are generated in "endpoint_gen_test.go" in the endpoint package, that call every endpoint
+	  // with the mock generated by the @Mock annotation, and in "http_gen_test.go" in the http package for the generated decode functions.
+	  // The files are regenerated like all other output, add further test cases in separate files.
+	  "generateTests": true,
+	  // Package of the mock generated with @Mock relative to the full module path, defaults to the package of the interface.
+	  "mockPackage": "mock",
+	  // Optional name and root directory of a different module the code is generated in, see above.
+	  "outputModule": "example.com/generated",
+	  "outputModulePath": "../generated",
+	  // Optional import paths of the helper packages the generated endpoint and http code uses,
+	  // default to "github.com/dkinzler/kit/endpoint" and "github.com/dkinzler/kit/transport/http".
+	  // A replacement must provide the same functions and types, e.g. a copy of these packages in your own module.
+	  // Defaults for all interfaces can be set with the command line flags --endpointHelperPackage and --httpHelperPackage.
+	  "endpointHelperPackage": "example.com/xyz/internal/endpoint",
+	  "httpHelperPackage": "example.com/xyz/internal/transport/http",
+	  // Optional import aliases by import path, replace the default aliases of the generated code, e.g. "e" and "t" for the helper packages.
+	  // Aliases that collide with declarations of existing files in the output package are replaced automatically,
+	  // with the alias these files use for the same package or otherwise by adding a number, e.g. "t2".
+	  "imports": {"github.com/dkinzler/kit/transport/http": "kithttputil"},
+	  // If true, NewEndpoints takes an additional parameter of type log.Logger (github.com/go-kit/log)
+	  // and errors returned by the interface methods are logged. The logging middleware is always the innermost middleware.
+	  "logging": true,
+	  // Optional names of middleware stages. If set, a struct MiddlewareStages with a field of type Middlewares for every stage
+	  // (e.g. "Auth") and constants with the stage names (e.g. MiddlewareStageAuth) are generated.
+	  // Its method Middlewares() combines the middlewares of all stages in the given order, the first stage is innermost.
+	  "middlewareStages": ["auth", "logging", "metrics", "custom"]
 	}
 
 Example annotation on an interface method "Method(ctx context.Context, a string, b SomeType) error"
@@ -74,97 +264,147 @@ Example annotation on an interface method "Method(ctx context.Context, a string,
 	        // http method
 	        "method": "POST",
 	        // Path the endpoint will be reachable at.
-	        // Can contain variables, the gorilla/mux package is used to decode them.
+	        // Can contain variables, which are decoded using the configured router.
+	        // Generation fails if two endpoints written to the same http package have the same method and path (ignoring the names of variables).
 	        "path": "/some/path/{a}",
 	        // http response code on success, defaults to 200
-	        "successCode": 201
-	      }
+	        "successCode": 201,
+	        // Optional headers to set on the response on success.
+	        // Values can contain placeholders that refer to the first return value of the interface method,
+	        // "{result}" for the value itself and e.g. "{result.Id}" for a field.
+	        "headers": {"Location": "/some/path/{result.Id}"},
+	        // Optional references to existing functions that are used instead of the generated decode function
+	        // and the default json encode function, e.g. for an endpoint that handles file uploads.
+	        // Functions are referenced by package path and name, a function in the generated http package can be referenced by name only.
+	        "decodeFunc": "example.com/some/package.DecodeUpload",
+	        "encodeFunc": "EncodeUploadResponse",
+	        // Maximum number of bytes of a multipart request body stored in memory, only used with "multipart" http params.
+	        // Optional, defaults to 32 MB.
+	        "multipartMaxMemory": 10485760
+	      },
+	      // Optional, exposes the endpoint over NATS. The endpoint request is sent as JSON message to the subject,
+	      // subscribers with the same queue group share the requests.
+	      "nats": {"subject": "example.endpoint", "queue": "example"},
+	      // Optional, exposes the endpoint over AMQP. The endpoint request is sent as JSON message to the queue.
+	      "amqp": {"queue": "example.endpoint"}
 	    }
 	  ],
 	  // Configures how each method parameter (except first) is obtained from an incoming http request.
-	  // Possible values are "url", "query", "json".
+	  // Possible values are "url", "query", "json" and "multipart".
+	  // A variadic parameter, e.g. "tags ...string", must be "json" and is decoded from a JSON array.
+	  // A "multipart" parameter is a file of a multipart/form-data request and must be of type *multipart.FileHeader or []byte.
+	  // A "query" parameter of type string, bool, an integer or floating point type or a slice of these is decoded from the query parameter
+	  // with the same name, e.g. "limit int" from "?limit=10" and "ids []int" from "?ids=1&ids=2". If the query parameter is missing the value is zero.
+	  // Other types, e.g. structs, are decoded from all query parameters using github.com/gorilla/schema.
 	  // In the example "a" will be obtained from the request url path, and "b" from the JSON request body.
-	  "httpParams": ["url", "json"]
+	  // Url params are decoded from the path variable with the same name as the parameter. To use a different variable or query parameter name,
+	  // write the param as an object, e.g. {"type": "url", "name": "user_id"} for a path "/users/{user_id}".
+	  "httpParams": ["url", "json"],
+	  // Optional json names of the endpoint request fields for individual parameters, take precedence over "jsonNaming".
+	  "jsonNames": {"b": "someType"}
 	}
 
+Websocket handlers can be generated for interface methods that stream values to the client, e.g. "Subscribe(ctx context.Context, topic string, events chan<- Event) error".
+The last parameter of such a method must either be a channel the method sends values to, or a callback function with a single parameter, e.g. "onEvent func(e Event) error".
+Every value is sent to the client as a JSON message, the connection is closed when the method returns.
+
+	@Kit{
+	  "transport": "websocket",
+	  // the http method defaults to GET, which is the only method supported for websocket endpoints
+	  "endpoints": [{"http": {"path": "/events/{topic}"}}],
+	  // only "url" and "query" are supported, the stream parameter is not included
+	  "httpParams": ["url"]
+	}
+
+Websocket handlers call the interface methods directly instead of using endpoints.
+If there are any websocket endpoints, the generated RegisterHttpHandlers function takes the service and a *websocket.Upgrader (github.com/gorilla/websocket) as additional parameters.
+
+The generated RegisterHttpRoutes function registers the full path of every http handler under the name of its endpoint,
+so that urls can be built with URLFor of the http helper package, e.g. RegisterHttpRoutes(t.DefaultRoutes) and then t.URLFor("GetItem", "id", id).
+
+Parameters of an interface method can be validated by adding a @Validate annotation to the method, that maps parameter names to validation rules:
+
+	@Validate{
+	  // "required": strings must not be empty, pointers must not be nil, slices and maps must not be empty
+	  // "minLength", "maxLength": bounds for the length of strings, slices and maps
+	  // "pattern": regular expression a string must match
+	  "a": {"required": true, "maxLength": 64, "pattern": "^[a-z]+$"},
+	  // "min", "max": bounds for numeric parameters
+	  "n": {"min": 1, "max": 100}
+	}
+
+A Validate() method is then generated for the endpoint request type, which is called by the generated http decode function.
+If validation fails, an error with code InvalidArgument and a public error message is returned.
+
+Endpoints can require requests to be authenticated with a Firebase Authentication token by adding an @Auth annotation to the method,
+or by setting "auth" for individual endpoints, which takes precedence over the annotation:
+
+	@Auth{
+	  // If true, requests must contain a valid token in the "Authorization: Bearer <token>" header.
+	  "required": true,
+	  // Optional claims the user must have, implies "required". See User.HasClaims in package "github.com/dkinzler/kit/firebase/auth".
+	  "claims": ["admin"]
+	}
+
+NewEndpoints then takes an additional auth.AuthChecker and auth.ContextBuilderFunc and wraps these endpoints with the auth middleware
+of package "github.com/dkinzler/kit/firebase/auth", the generated http handlers store the token from the request in the context.
+Authentication is only supported for http, endpoints that require it cannot have a NATS subject or AMQP queue.
+
 Note that http handlers can be generated only if endpoints are generated.
 Furthermore, it is possible to put generated endpoints and http handlers in the same output package.
 
 For Go kit code generation to work, the following requirements should be met by the source interface:
-  - Interface methods do not contain function types, channel types or anonymous structs as parameter or return values.
+  - The interface is not generic, i.e. does not have type parameters.
+  - Interface methods do not contain function types or channel types as parameter or return values,
+    except for the last parameter of methods with transport "websocket".
+    For an anonymous struct parameter a named type is generated in the endpoint package, e.g. "CreateRequestOptions"
+    for the parameter "options" of method "Create", which is used as the type of the endpoint request field.
   - Interface method parameters should be named, avoid using names like "r" and "w" that are e.g. commonly used in http code.
   - The source file that contains the interface should not import any types that are used in the interface definition using ".", i.e. imported without a prefix/qualifier.
   - Every interface method has a context.Context as the first parameter.
   - Every interface method has 1 or 2 return values, where the last one is always "error".
 
+# Custom annotations
+
+Generators for custom annotations can be added by building a custom code generator binary with package [app].
+A generator function registered for the name "Foo" is called for every interface with a @Foo{...} annotation.
+Generator functions are called concurrently for different interfaces, they must be safe for concurrent use.
+
+	func main() {
+		r := app.DefaultRegistry()
+		r.Register("Foo", func(i parse.Interface, m parse.Module, a annotations.InterfaceAnnotation) ([]gen.GenResult, error) {
+			// generate code
+		})
+		if err := app.New(r).Run(os.Args); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+The code generator can also be run from Go code with app.Run, e.g. in a custom binary or a test.
+Hooks passed to app.Run (or set in GeneratorConfig.Hooks) can modify, remove or add the results of the generators before they are written,
+e.g. to add license headers, extra methods or custom wiring code around the standard output:
+
+	err := app.Run(app.GeneratorConfig{InputDir: "."}, func(m parse.Module, results []gen.GenResult) ([]gen.GenResult, error) {
+		// post-process results
+		return results, nil
+	})
+
 [Go kit]: https://github.com/go-kit/kit
 [Testify Mock]: https://github.com/stretchr/testify
 [example project]: https://github.com/dkinzler/kit/tree/main/codegen/example
+[app]: https://pkg.go.dev/github.com/dkinzler/kit/codegen/app
 */
 package main
 
 import (
 	"log"
 	"os"
-	"path/filepath"
 
-	cli "github.com/urfave/cli/v2"
+	"github.com/dkinzler/kit/codegen/app"
 )
 
-const version string = "0.1"
-
 func main() {
-	app := &cli.App{
-		Name:    "Codegen",
-		Usage:   "generates code, how wonderful",
-		Version: version,
-		Flags: []cli.Flag{
-			&cli.BoolFlag{
-				Name:    "fail-on-error",
-				Value:   true,
-				Aliases: []string{"e"},
-				Usage:   "If true code generation is aborted on first error.",
-			},
-			&cli.StringFlag{
-				Name:  "moduleName",
-				Usage: "Name of the module the input directory belongs to, e.g. github.com/user/example .",
-			},
-			&cli.StringFlag{
-				Name:  "modulePath",
-				Usage: "Path to the root directory of the module the input directory belongs to. If empty will attempt to find the module by looking for a go.mod file in the input directory and its ancestors.",
-			},
-			&cli.StringFlag{
-				Name:        "inputDir",
-				Value:       ".",
-				Usage:       "Directory to search for code generator annotations.",
-				DefaultText: "default: current working directory",
-			},
-		},
-		Action: func(ctx *cli.Context) error {
-			inputDir, err := filepath.Abs(ctx.String("inputDir"))
-			if err != nil {
-				return err
-			}
-
-			modulePath := ctx.String("modulePath")
-			if modulePath != "" {
-				modulePath, err = filepath.Abs(modulePath)
-				if err != nil {
-					return err
-				}
-			}
-
-			config := GeneratorConfig{
-				InputDir:    inputDir,
-				ModuleName:  ctx.String("moduleName"),
-				ModulePath:  modulePath,
-				FailOnError: ctx.Bool("fail-on-error"),
-			}
-			return generate(config)
-		},
-	}
-
-	if err := app.Run(os.Args); err != nil {
+	if err := app.New(app.DefaultRegistry()).Run(os.Args); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,54 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is an error for an interface or one of its methods, annotated with the position in the source code.
+// It is printed as e.g. "example.go:42: interface Service: ...".
+type Error struct {
+	// File the interface is defined in.
+	File string
+	// Line of the interface or method the error refers to.
+	Line int
+	// Name of the interface.
+	Interface string
+	Err       error
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%v:%v: interface %v: %v", e.File, e.Line, e.Interface, e.Err)
+}
+
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Annotates the error with the position of the interface.
+// Returns the error unchanged if it is nil or already annotated.
+func (i Interface) WrapError(err error) error {
+	return i.wrapError(i.Line, err)
+}
+
+// Annotates the error with the position of the given method of the interface.
+// Returns the error unchanged if it is nil or already annotated.
+func (i Interface) WrapMethodError(m Method, err error) error {
+	return i.wrapError(m.Line, err)
+}
+
+func (i Interface) wrapError(line int, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pe Error
+	if errors.As(err, &pe) {
+		return err
+	}
+	return Error{
+		File:      i.File,
+		Line:      line,
+		Interface: i.Name,
+		Err:       err,
+	}
+}
@@ -6,20 +6,27 @@ import (
 	"go/ast"
 	"go/token"
 	"path"
+	"strconv"
 	"strings"
 )
 
 // Returns all the interfaces in the file.
-func findInterfacesInFile(file *ast.File, packagePath string) (result []Interface, err error) {
+// The file set is used to determine the positions of interfaces and methods, e.g. for error messages.
+func findInterfacesInFile(fset *token.FileSet, file *ast.File, packagePath string) (result []Interface, err error) {
+	visitor := &visitor{
+		PackagePath: packagePath,
+		Imports:     importsFromFile(file),
+		fset:        fset,
+	}
 	defer func() {
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprint(r))
+			if visitor.currentInterface != "" {
+				pos := fset.Position(visitor.currentPos)
+				err = Error{File: pos.Filename, Line: pos.Line, Interface: visitor.currentInterface, Err: err}
+			}
 		}
 	}()
-	visitor := &visitor{
-		PackagePath: packagePath,
-		Imports:     importsFromFile(file),
-	}
 	ast.Walk(visitor, file)
 	return visitor.Interfaces, nil
 }
@@ -40,6 +47,14 @@ type visitor struct {
 	//
 	// the map would contain p -> "some/random/package" and pkg -> "another/pkg"
 	Imports map[string]string
+	// Names of the type parameters of the generic interface currently being parsed.
+	// Identifiers with these names are not qualified with the package path.
+	typeParams map[string]bool
+
+	fset *token.FileSet
+	// Name and position of the interface or method currently being parsed, used to annotate errors.
+	currentInterface string
+	currentPos       token.Pos
 }
 
 // TODO a package might be imported with the short ".", i.e the file uses the exported identifiers from that package without a qualifier.
@@ -96,17 +111,51 @@ func (v *visitor) Visit(node ast.Node) ast.Visitor {
 	}
 
 	//found an interface type
+	v.currentInterface = ts.Name.Name
+	v.currentPos = ts.Pos()
+	pos := v.fset.Position(ts.Pos())
+	typeParams := v.parseTypeParams(ts.TypeParams)
 	result := Interface{
-		Name:     ts.Name.Name,
-		Package:  v.PackagePath,
-		Comments: v.parseComments(gd.Doc),
-		Methods:  v.parseMethods(it),
+		Name:       ts.Name.Name,
+		Package:    v.PackagePath,
+		TypeParams: typeParams,
+		Comments:   v.parseComments(gd.Doc),
+		Methods:    v.parseMethods(it),
+		File:       pos.Filename,
+		Line:       pos.Line,
 	}
+	v.typeParams = nil
+	v.currentInterface = ""
 	v.Interfaces = append(v.Interfaces, result)
 
 	return v
 }
 
+// Parses the type parameters of a generic type definition, e.g. "[T any, K comparable]".
+// The names of the type parameters are stored on the visitor, so that they can be recognized when parsing the methods.
+func (v *visitor) parseTypeParams(fl *ast.FieldList) []TypeParam {
+	if fl == nil || len(fl.List) == 0 {
+		return nil
+	}
+
+	// Names need to be known before parsing the constraints, since a constraint can refer to another type parameter, e.g. [T any, S ~[]T].
+	v.typeParams = make(map[string]bool)
+	for _, field := range fl.List {
+		for _, name := range field.Names {
+			v.typeParams[name.Name] = true
+		}
+	}
+
+	var result []TypeParam
+	for _, field := range fl.List {
+		constraint := v.parseParamType(field.Type)
+		for _, name := range field.Names {
+			result = append(result, TypeParam{Name: name.Name, Constraint: constraint})
+		}
+	}
+	return result
+}
+
 func (v *visitor) parseComments(cg *ast.CommentGroup) []string {
 	if cg == nil || len(cg.List) == 0 {
 		return nil
@@ -148,6 +197,7 @@ func (v *visitor) parseMethods(it *ast.InterfaceType) []Method {
 
 	var methods []Method
 	for _, m := range it.Methods.List {
+		v.currentPos = m.Pos()
 		method, ok := v.parseMethod(m)
 		if ok {
 			methods = append(methods, method)
@@ -170,6 +220,7 @@ func (v visitor) parseMethod(method *ast.Field) (Method, bool) {
 
 	result.Name = method.Names[0].Name
 	result.Comments = v.parseComments(method.Doc)
+	result.Line = v.fset.Position(method.Pos()).Line
 
 	var params []Param
 	nextParamId := 0
@@ -231,8 +282,6 @@ func (v visitor) parseParams(param *ast.Field) ([]Param, bool) {
 	return result, true
 }
 
-// TODO there are some other possible types that we don't handle like
-// functions, channels, anonymous structs, ...
 func (v visitor) parseParamType(t ast.Expr) ParamType {
 	switch pt := t.(type) {
 	case *ast.SelectorExpr:
@@ -247,12 +296,16 @@ func (v visitor) parseParamType(t ast.Expr) ParamType {
 		}
 		return SimpleType{Type: typeName, Package: typePackageFull}
 	case *ast.Ident:
-		if isBasicType(pt.Name) {
+		if isBasicType(pt.Name) || v.typeParams[pt.Name] {
 			return SimpleType{Type: pt.Name}
 		} else {
 			//a type that is not a built-in type but has no package qualifier is defined in the current package
 			return SimpleType{Type: pt.Name, Package: v.PackagePath}
 		}
+	case *ast.IndexExpr:
+		return v.parseGenericType(pt.X, []ast.Expr{pt.Index})
+	case *ast.IndexListExpr:
+		return v.parseGenericType(pt.X, pt.Indices)
 	case *ast.ArrayType:
 		inner := v.parseParamType(pt.Elt)
 		return ArrayType{Type: inner}
@@ -263,9 +316,126 @@ func (v visitor) parseParamType(t ast.Expr) ParamType {
 	case *ast.StarExpr:
 		inner := v.parseParamType(pt.X)
 		return StarType{Type: inner}
+	case *ast.Ellipsis:
+		inner := v.parseParamType(pt.Elt)
+		return EllipsisType{Type: inner}
+	case *ast.ChanType:
+		dir := ChanBoth
+		if pt.Dir == ast.SEND {
+			dir = ChanSend
+		} else if pt.Dir == ast.RECV {
+			dir = ChanRecv
+		}
+		return ChanType{Dir: dir, Type: v.parseParamType(pt.Value)}
+	case *ast.FuncType:
+		var result FuncType
+		if pt.Params != nil {
+			for _, field := range pt.Params.List {
+				r, _ := v.parseParams(field)
+				result.Params = append(result.Params, r...)
+			}
+		}
+		if pt.Results != nil {
+			for _, field := range pt.Results.List {
+				r, _ := v.parseParams(field)
+				result.Returns = append(result.Returns, r...)
+			}
+		}
+		return result
+	case *ast.StructType:
+		var result StructType
+		for _, field := range pt.Fields.List {
+			fieldType := v.parseParamType(field.Type)
+			var tags map[string]string
+			if field.Tag != nil {
+				tag, err := strconv.Unquote(field.Tag.Value)
+				if err != nil {
+					panic(fmt.Sprintf("could not parse struct tag %v: %v", field.Tag.Value, err))
+				}
+				tags = parseStructTag(tag)
+			}
+			if len(field.Names) == 0 {
+				result.Fields = append(result.Fields, StructField{Type: fieldType, Tags: tags})
+			}
+			for _, name := range field.Names {
+				result.Fields = append(result.Fields, StructField{Name: name.Name, Type: fieldType, Tags: tags})
+			}
+		}
+		return result
 	case *ast.InterfaceType:
 		return SimpleType{Type: "interface{}"}
+	case *ast.BinaryExpr:
+		// can only appear in type constraints, e.g. "~int | ~string"
+		if pt.Op != token.OR {
+			panic(fmt.Sprintf("tried to parse unsupported binary expression in type: %v", pt.Op))
+		}
+		var terms []ParamType
+		for _, x := range []ast.Expr{pt.X, pt.Y} {
+			t := v.parseParamType(x)
+			// flatten nested unions, "a | b | c" is parsed as "(a | b) | c"
+			if ut, ok := t.(UnionType); ok {
+				terms = append(terms, ut.Terms...)
+			} else {
+				terms = append(terms, t)
+			}
+		}
+		return UnionType{Terms: terms}
+	case *ast.UnaryExpr:
+		if pt.Op != token.TILDE {
+			panic(fmt.Sprintf("tried to parse unsupported unary expression in type: %v", pt.Op))
+		}
+		return TildeType{Type: v.parseParamType(pt.X)}
 	default:
-		panic("tried to parase unimplemented parameter type")
+		panic(fmt.Sprintf("tried to parse unimplemented parameter type %T", t))
+	}
+}
+
+// Parses the instantiation of a generic type, e.g. "Page[Item]" or "pkg.Result[string, *Item]".
+func (v visitor) parseGenericType(t ast.Expr, args []ast.Expr) ParamType {
+	st, ok := v.parseParamType(t).(SimpleType)
+	if !ok {
+		panic(fmt.Sprintf("tried to parse unsupported generic type %T", t))
+	}
+	result := GenericType{Type: st}
+	for _, arg := range args {
+		result.TypeArgs = append(result.TypeArgs, v.parseParamType(arg))
 	}
+	return result
+}
+
+// Parses a struct tag in the conventional format, e.g. `json:"a,omitempty" schema:"a"`, into a map from keys to values.
+// Follows the implementation of reflect.StructTag.Lookup, parsing stops at the first malformed key or value.
+func parseStructTag(tag string) map[string]string {
+	result := make(map[string]string)
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		// scan quoted value
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			break
+		}
+		result[key] = value
+		tag = tag[i+1:]
+	}
+	return result
 }
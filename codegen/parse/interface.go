@@ -7,6 +7,9 @@ type Interface struct {
 	Name string
 	// Package the interface is defined in
 	Package string
+	// Type parameters of a generic interface, e.g. "T" and "K" for "type Repository[T any, K comparable] interface {...}".
+	// Empty for non-generic interfaces.
+	TypeParams []TypeParam
 	// Methods of the interface
 	Methods []Method
 	// Comments belonging to this interface, i.e. the comments directly above the type definition in the source code.
@@ -14,6 +17,21 @@ type Interface struct {
 
 	// File (path) this interface is defined in
 	File string
+	// Line of the interface definition in the file
+	Line int
+}
+
+// Returns true if the interface has type parameters.
+func (i Interface) IsGeneric() bool {
+	return len(i.TypeParams) > 0
+}
+
+// TypeParam represents a type parameter of a generic interface.
+type TypeParam struct {
+	// Name of the type parameter, e.g. "T"
+	Name string
+	// Constraint of the type parameter, e.g. "any", "comparable", "fmt.Stringer" or "~int | ~string".
+	Constraint ParamType
 }
 
 // Method represents a method of an interface.
@@ -26,6 +44,8 @@ type Method struct {
 	Returns []Param
 	// Comments belonging to this method, i.e. the comments directly above the method definition in the source code.
 	Comments []string
+	// Line of the method definition in the file
+	Line int
 }
 
 // Param represents a method parameter or return value
@@ -38,7 +58,6 @@ type Param struct {
 }
 
 // Represents the type of a parameter.
-// Note: this does not support some types like function types, channels and anonymous structs.
 type ParamType interface {
 	// Returns a list of all the packages required by this type.
 	// E.g. a type map[context.Context]*http.Request requires the pacakges "context" and "http".
@@ -89,6 +108,115 @@ func (st StarType) Packages() []string {
 	return st.Type.Packages()
 }
 
+// Type of a variadic parameter, e.g. "...string".
+// Can only be the type of the last parameter of a method or function type.
+type EllipsisType struct {
+	Type ParamType
+}
+
+func (et EllipsisType) Packages() []string {
+	return et.Type.Packages()
+}
+
+// Anonymous struct type, e.g. "struct{ A string `json:"a"` }".
+type StructType struct {
+	Fields []StructField
+}
+
+func (st StructType) Packages() []string {
+	var result []string
+	for _, f := range st.Fields {
+		result = append(result, f.Type.Packages()...)
+	}
+	return result
+}
+
+// Field of an anonymous struct type.
+type StructField struct {
+	// Name of the field, empty for embedded fields.
+	Name string
+	Type ParamType
+	// Tags of the field, e.g. {"json": "a,omitempty"} for the tag `json:"a,omitempty"`.
+	Tags map[string]string
+}
+
+// Direction of a channel type.
+type ChanDir int
+
+const (
+	// chan T
+	ChanBoth ChanDir = iota
+	// chan<- T
+	ChanSend
+	// <-chan T
+	ChanRecv
+)
+
+type ChanType struct {
+	Dir  ChanDir
+	Type ParamType
+}
+
+func (ct ChanType) Packages() []string {
+	return ct.Type.Packages()
+}
+
+// Function type, e.g. "func(e Event) error".
+// Parameter names are empty if the function type does not name them.
+type FuncType struct {
+	Params  []Param
+	Returns []Param
+}
+
+func (ft FuncType) Packages() []string {
+	var result []string
+	for _, p := range ft.Params {
+		result = append(result, p.Type.Packages()...)
+	}
+	for _, p := range ft.Returns {
+		result = append(result, p.Type.Packages()...)
+	}
+	return result
+}
+
+// Instantiation of a generic type, e.g. "Page[Item]", "pkg.Result[string, *Item]" or an instantiated generic type alias "pkg.Alias[T]".
+type GenericType struct {
+	// The generic type, e.g. "Page" for "Page[Item]"
+	Type SimpleType
+	// Type arguments the generic type is instantiated with, e.g. "Item" for "Page[Item]"
+	TypeArgs []ParamType
+}
+
+func (gt GenericType) Packages() []string {
+	result := gt.Type.Packages()
+	for _, arg := range gt.TypeArgs {
+		result = append(result, arg.Packages()...)
+	}
+	return result
+}
+
+// Represents a union of types in a type constraint, e.g. "~int | ~string".
+type UnionType struct {
+	Terms []ParamType
+}
+
+func (ut UnionType) Packages() []string {
+	var result []string
+	for _, t := range ut.Terms {
+		result = append(result, t.Packages()...)
+	}
+	return result
+}
+
+// Represents a type with a tilde in a type constraint, e.g. "~int".
+type TildeType struct {
+	Type ParamType
+}
+
+func (tt TildeType) Packages() []string {
+	return tt.Type.Packages()
+}
+
 var basicTypes = []string{
 	"bool",
 	"string",
@@ -110,6 +238,8 @@ var basicTypes = []string{
 	"float64",
 	"complex64",
 	"complex128",
+	"any",
+	"comparable",
 }
 
 func isBasicType(t string) bool {
@@ -121,6 +251,15 @@ func isBasicType(t string) bool {
 	return false
 }
 
+// Returns true if the last parameter of the method is variadic.
+func (m Method) IsVariadic() bool {
+	if len(m.Params) == 0 {
+		return false
+	}
+	_, ok := m.Params[len(m.Params)-1].Type.(EllipsisType)
+	return ok
+}
+
 func IsSimpleType(p ParamType, typeName, packageName string) bool {
 	if st, ok := p.(SimpleType); ok {
 		if st.Type == typeName && st.Package == packageName {
@@ -3,8 +3,10 @@
 package parse
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"go/build/constraint"
 	"go/parser"
 	"go/token"
 	"io/fs"
@@ -12,30 +14,69 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 
 	"golang.org/x/mod/modfile"
 )
 
+// Comment at the start of every file produced by the code generator.
+const GeneratedFileComment = "generated code, do not modify"
+
 // Recursively searches the directory given by path and parses
 // any interfaces.
-func ParseDir(path string, module Module) ([]Interface, error) {
+// Interfaces are sorted by file and line. If any files cannot be parsed, the errors for all of them are returned
+// (joined with errors.Join) together with the interfaces found in the other files.
+// Packages are parsed in parallel by at most GOMAXPROCS goroutines.
+//
+// Like the go tool, directories named "vendor" or "testdata" and directories starting with "." or "_" are skipped.
+// Test files, files that are only built with the "ignore" build tag and files produced by the code generator are not parsed.
+// Files and directories can be excluded with glob patterns (see path.Match), that are matched against
+// the slash-separated path relative to the given directory and against the base name, e.g. "internal/legacy" or "*_old.go".
+func ParseDir(path string, module Module, exclude ...string) ([]Interface, error) {
 	path, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
 	}
+	for _, pattern := range exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid exclude pattern %v: %v", pattern, err))
+		}
+	}
 
-	var result []Interface
+	packages := findPackages(path, module, exclude)
+	interfaces := make([][]Interface, len(packages))
+	errs := make([]error, len(packages))
 
-	packages := findPackages(path, module)
-	for _, pkg := range packages {
-		i, err := findInterfacesInPackage(pkg)
-		if err != nil {
-			return nil, err
-		}
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for j, pkg := range packages {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(j int, pkg pkgPath) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			interfaces[j], errs[j] = findInterfacesInPackage(pkg, path, exclude)
+		}(j, pkg)
+	}
+	wg.Wait()
+
+	var result []Interface
+	for _, i := range interfaces {
 		result = append(result, i...)
 	}
-	return result, nil
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].File != result[j].File {
+			return result[i].File < result[j].File
+		}
+		return result[i].Line < result[j].Line
+	})
+	return result, errors.Join(errs...)
 }
 
 type pkgPath struct {
@@ -46,13 +87,16 @@ type pkgPath struct {
 }
 
 // Returns a list of packages contained in directory root.
-func findPackages(root string, module Module) []pkgPath {
+func findPackages(root string, module Module, exclude []string) []pkgPath {
 	var result []pkgPath
 	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 		if d.IsDir() {
+			if path != root && (isIgnoredDir(d.Name()) || isExcluded(root, path, exclude)) {
+				return filepath.SkipDir
+			}
 			packagePath, err := module.PackagePathFromFilePath(path)
 			if err != nil {
 				log.Println("could not build package path for file path:", path)
@@ -68,19 +112,82 @@ func findPackages(root string, module Module) []pkgPath {
 	return result
 }
 
-func findInterfacesInPackage(pkg pkgPath) ([]Interface, error) {
+// Directories ignored by the go tool.
+func isIgnoredDir(name string) bool {
+	return name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// Returns true if the file or directory matches one of the exclude patterns.
+func isExcluded(root, path string, exclude []string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range exclude {
+		pattern = filepath.ToSlash(pattern)
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the file was produced by the code generator or is only built with the "ignore" build tag,
+// determined from the comments before the package clause.
+func isIgnoredFile(filename string) bool {
+	f, err := os.Open(filename)
+	if err != nil {
+		// let the parser report the error
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if first && line == "// "+GeneratedFileComment {
+			return true
+		}
+		first = false
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if !constraint.IsGoBuild(line) {
+			continue
+		}
+		expr, err := constraint.Parse(line)
+		if err != nil {
+			return false
+		}
+		// the file is ignored if it is built with the "ignore" tag, but not without it
+		withIgnore := expr.Eval(func(tag string) bool { return true })
+		withoutIgnore := expr.Eval(func(tag string) bool { return tag != "ignore" })
+		return withIgnore && !withoutIgnore
+	}
+	return false
+}
+
+func findInterfacesInPackage(pkg pkgPath, root string, exclude []string) ([]Interface, error) {
 	var result []Interface
+	var errs []error
 
+	fset := token.NewFileSet()
 	// Parser.ParseDir does not work recursively, i.e. it will only consider files in the given directory and not any subdirectories.
 	packageMap, err := parser.ParseDir(
-		token.NewFileSet(),
+		fset,
 		pkg.FilePath,
 		func(fileInfo fs.FileInfo) bool {
 			//exclude test files
 			if strings.HasSuffix(fileInfo.Name(), "_test.go") {
 				return false
 			}
-			return true
+			filename := filepath.Join(pkg.FilePath, fileInfo.Name())
+			return !isExcluded(root, filename, exclude) && !isIgnoredFile(filename)
 		},
 		parser.AllErrors|parser.ParseComments,
 	)
@@ -91,7 +198,7 @@ func findInterfacesInPackage(pkg pkgPath) ([]Interface, error) {
 	// There should at most be one package here,
 	// since a single directory cannot contain files for more than one package (if the go code compiles).
 	for _, p := range packageMap {
-		for filename, f := range p.Files {
+		for _, f := range p.Files {
 			// Name of package directory should match package path, i.e. files for a package "example.com/xyz/abc" should be in a directory "abc"
 			// and each file should contain the line "package abc".
 			base := path.Base(pkg.PackagePath)
@@ -99,20 +206,17 @@ func findInterfacesInPackage(pkg pkgPath) ([]Interface, error) {
 			if base != pname {
 				// log.Println("package directory name", base, "doesn't match package name declared in files", pname)
 			} else {
-				i, err := findInterfacesInFile(f, pkg.PackagePath)
+				i, err := findInterfacesInFile(fset, f, pkg.PackagePath)
 				if err != nil {
-					return nil, err
-				}
-				// set filename
-				for j := 0; j < len(i); j++ {
-					i[j].File = filename
+					errs = append(errs, err)
+					continue
 				}
 				result = append(result, i...)
 			}
 		}
 	}
 
-	return result, nil
+	return result, errors.Join(errs...)
 }
 
 // Module provides functions to convert relative package names and file paths
@@ -156,6 +260,33 @@ func NewModuleFromDir(dir string) (Module, error) {
 	return Module{}, errors.New("no module found")
 }
 
+// Returns the module generated code should be written to, given the "outputModule" and "outputModulePath" options of an annotation.
+// If both are empty, m itself is returned. The path is the root directory of the output module, a relative path is relative to the root directory of m.
+// If the name is empty, it is read from the go.mod file in the root directory of the output module.
+func (m Module) OutputModule(name, modulePath string) (Module, error) {
+	if name == "" && modulePath == "" {
+		return m, nil
+	}
+	if modulePath == "" {
+		return Module{}, errors.New(fmt.Sprintf("path of output module %v is empty", name))
+	}
+	if !filepath.IsAbs(modulePath) {
+		modulePath = filepath.Join(m.Path, modulePath)
+	}
+	modulePath = filepath.Clean(modulePath)
+	if name == "" {
+		content, err := os.ReadFile(filepath.Join(modulePath, "go.mod"))
+		if err != nil {
+			return Module{}, errors.New(fmt.Sprintf("could not read go.mod file of output module: %v", err))
+		}
+		name = modfile.ModulePath(content)
+		if name == "" {
+			return Module{}, errors.New(fmt.Sprintf("could not parse go.mod file of output module in %v", modulePath))
+		}
+	}
+	return Module{Path: modulePath, Name: name}, nil
+}
+
 // Returns a package path without the module prefix.
 // E.g. when called with "example.com/xyz/abc" on a module with name "example.com/xyz"
 // will return "abc".
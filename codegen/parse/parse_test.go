@@ -1,6 +1,10 @@
 package parse
 
 import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -79,6 +83,7 @@ func TestParseDir(t *testing.T) {
 	a.Equal("ExampleInterface", exampleInterface.Name)
 	a.Equal("exampleproject/internal/example", exampleInterface.Package)
 	a.Equal(fp1, exampleInterface.File)
+	a.Equal(17, exampleInterface.Line)
 	a.ElementsMatch(exampleInterface.Methods, []Method{
 		{
 			Name: "Method1",
@@ -91,6 +96,7 @@ func TestParseDir(t *testing.T) {
 				{Name: "", Type: SimpleType{Type: "Y", Package: "exampleproject/internal/example"}},
 				{Name: "", Type: SimpleType{Type: "error", Package: ""}},
 			},
+			Line: 18,
 		},
 		{
 			Name: "Method2",
@@ -108,15 +114,18 @@ func TestParseDir(t *testing.T) {
 			Returns: []Param{
 				{Name: "", Type: SimpleType{Type: "error", Package: ""}},
 			},
+			Line: 19,
 		},
 		{
 			Name: "Method3",
+			Line: 20,
 		},
 	})
 
 	a.Equal("OtherInterface", otherInterface.Name)
 	a.Equal("exampleproject/internal/other", otherInterface.Package)
 	a.Equal(fp2, otherInterface.File)
+	a.Equal(3, otherInterface.Line)
 	a.ElementsMatch(otherInterface.Methods, []Method{
 		{
 			Name: "OtherMethod1",
@@ -130,6 +139,310 @@ func TestParseDir(t *testing.T) {
 				{Name: "", Type: SimpleType{Type: "int"}},
 				{Name: "", Type: SimpleType{Type: "int"}},
 			},
+			Line: 4,
 		},
 	})
 }
+
+func TestParseDirSkipsIgnoredFiles(t *testing.T) {
+	a := assert.New(t)
+
+	root := t.TempDir()
+	write := func(file, content string) {
+		path := filepath.Join(root, file)
+		a.Nil(os.MkdirAll(filepath.Dir(path), 0755))
+		a.Nil(os.WriteFile(path, []byte(content), 0644))
+	}
+	write("go.mod", "module example.com/example\n\ngo 1.22\n")
+	write("a/a.go", "package a\n\ntype A interface{ A() }\n")
+	write("a/linux.go", "//go:build linux\n\npackage a\n\ntype Linux interface{ A() }\n")
+	write("a/ignored.go", "//go:build ignore\n\npackage a\n\ntype Ignored interface{ A() }\n")
+	write("a/a.gen.go", "// "+GeneratedFileComment+"\npackage a\n\ntype Generated interface{ A() }\n")
+	write("a/a_old.go", "package a\n\ntype Old interface{ A() }\n")
+	write("vendor/v/v.go", "package v\n\ntype V interface{ A() }\n")
+	write("a/testdata/t.go", "package testdata\n\ntype T interface{ A() }\n")
+	write("legacy/l.go", "package legacy\n\ntype L interface{ A() }\n")
+
+	m, err := NewModuleFromDir(root)
+	a.Nil(err)
+
+	is, err := ParseDir(root, m, "legacy", "*_old.go")
+	a.Nil(err)
+	var names []string
+	for _, i := range is {
+		names = append(names, i.Name)
+	}
+	a.ElementsMatch([]string{"A", "Linux"}, names)
+
+	is, err = ParseDir(root, m)
+	a.Nil(err)
+	a.Len(is, 4)
+
+	_, err = ParseDir(root, m, "[")
+	a.NotNil(err)
+}
+
+func TestParseGenericInterface(t *testing.T) {
+	a := assert.New(t)
+
+	src := `package example
+
+import (
+	"context"
+	"fmt"
+)
+
+type Repository[T any, K comparable, N ~int | ~int64, S fmt.Stringer] interface {
+	Get(ctx context.Context, id K) (T, error)
+	Put(ctx context.Context, items map[K]*T, n N) error
+}`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "example.go", src, parser.ParseComments)
+	a.Nil(err)
+	is, err := findInterfacesInFile(fset, f, "example.com/example")
+	a.Nil(err)
+	a.Len(is, 1)
+
+	i := is[0]
+	a.True(i.IsGeneric())
+	a.Equal([]TypeParam{
+		{Name: "T", Constraint: SimpleType{Type: "any"}},
+		{Name: "K", Constraint: SimpleType{Type: "comparable"}},
+		{Name: "N", Constraint: UnionType{Terms: []ParamType{
+			TildeType{Type: SimpleType{Type: "int"}},
+			TildeType{Type: SimpleType{Type: "int64"}},
+		}}},
+		{Name: "S", Constraint: SimpleType{Type: "Stringer", Package: "fmt"}},
+	}, i.TypeParams)
+	a.Equal([]Method{
+		{
+			Name: "Get",
+			Params: []Param{
+				{Name: "ctx", Type: SimpleType{Type: "Context", Package: "context"}},
+				{Name: "id", Type: SimpleType{Type: "K"}},
+			},
+			Returns: []Param{
+				{Type: SimpleType{Type: "T"}},
+				{Type: SimpleType{Type: "error"}},
+			},
+			Line: 9,
+		},
+		{
+			Name: "Put",
+			Params: []Param{
+				{Name: "ctx", Type: SimpleType{Type: "Context", Package: "context"}},
+				{Name: "items", Type: MapType{KeyType: SimpleType{Type: "K"}, ValueType: StarType{Type: SimpleType{Type: "T"}}}},
+				{Name: "n", Type: SimpleType{Type: "N"}},
+			},
+			Returns: []Param{
+				{Type: SimpleType{Type: "error"}},
+			},
+			Line: 10,
+		},
+	}, i.Methods)
+}
+
+func TestParseChanAndFuncTypes(t *testing.T) {
+	a := assert.New(t)
+
+	src := `package example
+
+import "context"
+
+type Event struct{}
+
+type Notifier interface {
+	Subscribe(ctx context.Context, events chan<- Event, done <-chan bool) error
+	Watch(ctx context.Context, onEvent func(e *Event) error, c chan int)
+}`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "example.go", src, parser.ParseComments)
+	a.Nil(err)
+	is, err := findInterfacesInFile(fset, f, "example.com/example")
+	a.Nil(err)
+	a.Len(is, 1)
+
+	methods := is[0].Methods
+	a.Len(methods, 2)
+	a.Equal(ChanType{Dir: ChanSend, Type: SimpleType{Type: "Event", Package: "example.com/example"}}, methods[0].Params[1].Type)
+	a.Equal(ChanRecv, methods[0].Params[2].Type.(ChanType).Dir)
+	a.Equal(FuncType{
+		Params:  []Param{{Name: "e", Type: StarType{Type: SimpleType{Type: "Event", Package: "example.com/example"}}}},
+		Returns: []Param{{Type: SimpleType{Type: "error"}}},
+	}, methods[1].Params[1].Type)
+	a.Equal(ChanType{Dir: ChanBoth, Type: SimpleType{Type: "int"}}, methods[1].Params[2].Type)
+}
+
+func TestParseVariadicParams(t *testing.T) {
+	a := assert.New(t)
+
+	src := `package example
+
+import "context"
+
+type Tagger interface {
+	Tag(ctx context.Context, id string, tags ...string) error
+	Untag(ctx context.Context)
+}`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "example.go", src, parser.ParseComments)
+	a.Nil(err)
+	is, err := findInterfacesInFile(fset, f, "example.com/example")
+	a.Nil(err)
+	a.Len(is, 1)
+
+	methods := is[0].Methods
+	a.Len(methods, 2)
+	a.Equal(Param{Name: "tags", Type: EllipsisType{Type: SimpleType{Type: "string"}}}, methods[0].Params[2])
+	a.True(methods[0].IsVariadic())
+	a.False(methods[1].IsVariadic())
+}
+
+func TestParseGenericTypes(t *testing.T) {
+	a := assert.New(t)
+
+	src := `package example
+
+import (
+	"context"
+
+	"example.com/page"
+)
+
+type Item struct{}
+
+type Lister interface {
+	List(ctx context.Context, cursor page.Cursor[string]) (map[string][]*page.Result[Item, int], error)
+}`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "example.go", src, parser.ParseComments)
+	a.Nil(err)
+	is, err := findInterfacesInFile(fset, f, "example.com/example")
+	a.Nil(err)
+	a.Len(is, 1)
+
+	m := is[0].Methods[0]
+	a.Equal(GenericType{
+		Type:     SimpleType{Type: "Cursor", Package: "example.com/page"},
+		TypeArgs: []ParamType{SimpleType{Type: "string"}},
+	}, m.Params[1].Type)
+	a.Equal(MapType{
+		KeyType: SimpleType{Type: "string"},
+		ValueType: ArrayType{Type: StarType{Type: GenericType{
+			Type:     SimpleType{Type: "Result", Package: "example.com/page"},
+			TypeArgs: []ParamType{SimpleType{Type: "Item", Package: "example.com/example"}, SimpleType{Type: "int"}},
+		}}},
+	}, m.Returns[0].Type)
+	a.Equal([]string{"example.com/page", "example.com/example"}, m.Returns[0].Type.Packages())
+}
+
+func TestParseAnonymousStructTypes(t *testing.T) {
+	a := assert.New(t)
+
+	src := `package example
+
+import (
+	"context"
+	"net/http"
+)
+
+type Configurer interface {
+	Configure(ctx context.Context, opts struct {
+		Name string ` + "`json:\"name,omitempty\" schema:\"n\"`" + `
+		A, B int
+		*http.Request
+	}) (struct{ Count int }, error)
+}`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "example.go", src, parser.ParseComments)
+	a.Nil(err)
+	is, err := findInterfacesInFile(fset, f, "example.com/example")
+	a.Nil(err)
+	a.Len(is, 1)
+
+	m := is[0].Methods[0]
+	a.Equal(StructType{Fields: []StructField{
+		{Name: "Name", Type: SimpleType{Type: "string"}, Tags: map[string]string{"json": "name,omitempty", "schema": "n"}},
+		{Name: "A", Type: SimpleType{Type: "int"}},
+		{Name: "B", Type: SimpleType{Type: "int"}},
+		{Type: StarType{Type: SimpleType{Type: "Request", Package: "net/http"}}},
+	}}, m.Params[1].Type)
+	a.Equal([]string{"net/http"}, m.Params[1].Type.Packages())
+	a.Equal(StructType{Fields: []StructField{{Name: "Count", Type: SimpleType{Type: "int"}}}}, m.Returns[0].Type)
+}
+
+func TestParseStructTag(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(map[string]string{}, parseStructTag(""))
+	a.Equal(map[string]string{"json": "a,omitempty"}, parseStructTag(`json:"a,omitempty"`))
+	a.Equal(map[string]string{"json": "a", "schema": `x"y`}, parseStructTag(`json:"a"  schema:"x\"y"`))
+	// parsing stops at malformed values
+	a.Equal(map[string]string{"json": "a"}, parseStructTag(`json:"a" schema:x`))
+}
+
+func TestParseErrorsArePositionAnnotated(t *testing.T) {
+	a := assert.New(t)
+
+	src := `package example
+
+import "context"
+
+type Service interface {
+	Ok(ctx context.Context) error
+	NotOk(ctx context.Context, x list.List[int]) error
+}`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "example.go", src, parser.ParseComments)
+	a.Nil(err)
+	_, err = findInterfacesInFile(fset, f, "example.com/example")
+	a.NotNil(err)
+	var pe Error
+	a.ErrorAs(err, &pe)
+	a.Equal("example.go", pe.File)
+	a.Equal(7, pe.Line)
+	a.Equal("Service", pe.Interface)
+	a.True(strings.HasPrefix(err.Error(), "example.go:7: interface Service: "))
+
+	i := Interface{Name: "Service", File: "example.go", Line: 5}
+	err = i.WrapMethodError(Method{Name: "Ok", Line: 6}, errors.New("invalid"))
+	a.Equal("example.go:6: interface Service: invalid", err.Error())
+	// errors are only annotated once
+	a.Equal(err, i.WrapError(err))
+	a.Nil(i.WrapError(nil))
+}
+
+func TestOutputModule(t *testing.T) {
+	a := assert.New(t)
+
+	m, err := NewModuleFromDir("testdata/exampleproject/internal")
+	a.Nil(err)
+
+	// no options, same module
+	om, err := m.OutputModule("", "")
+	a.Nil(err)
+	a.Equal(m, om)
+
+	om, err = m.OutputModule("example.com/other", "../other")
+	a.Nil(err)
+	a.Equal("example.com/other", om.Name)
+	a.Equal(filepath.Join(filepath.Dir(m.Path), "other"), om.Path)
+
+	// name is read from go.mod
+	dir, err := filepath.Abs("testdata/exampleproject")
+	a.Nil(err)
+	om, err = Module{Path: "/somewhere/else", Name: "else"}.OutputModule("", dir)
+	a.Nil(err)
+	a.Equal(m, om)
+
+	_, err = m.OutputModule("example.com/other", "")
+	a.NotNil(err)
+	_, err = m.OutputModule("", "does/not/exist")
+	a.NotNil(err)
+}
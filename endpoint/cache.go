@@ -0,0 +1,164 @@
+package endpoint
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+)
+
+// Stores the results cached by CacheMiddleware.
+// The in-memory store returned by NewLRUCacheStore caches results per process, an implementation backed by e.g. Redis
+// can be used to share a cache between multiple instances of a service, it has to serialize the values.
+type CacheStore interface {
+	// Returns the value stored for the key, false if there is none or it expired.
+	Get(ctx context.Context, key string) (interface{}, bool, error)
+	// Stores a value for the key that expires after the given time.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+// Returns the key a request is cached with, e.g. the endpoint name and the id of the requested resource.
+// Requests for which false is returned are not cached, e.g. requests that depend on the user making them.
+type CacheKeyFunc func(ctx context.Context, request interface{}) (string, bool)
+
+type lruCacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+type lruCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	// most recently used entries are at the front
+	order *list.List
+	now   func() time.Time
+}
+
+// Returns a CacheStore that keeps at most maxEntries values in memory, the least recently used values are removed first.
+// Defaults to 1000 entries if maxEntries is not positive.
+func NewLRUCacheStore(maxEntries int) CacheStore {
+	return newLRUCacheStore(maxEntries, time.Now)
+}
+
+func newLRUCacheStore(maxEntries int, now func() time.Time) *lruCacheStore {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &lruCacheStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        now,
+	}
+}
+
+func (s *lruCacheStore) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*lruCacheEntry)
+	if !s.now().Before(entry.expires) {
+		s.order.Remove(el)
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+	s.order.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+func (s *lruCacheStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expires := s.now().Add(ttl)
+	if el, ok := s.entries[key]; ok {
+		entry := el.Value.(*lruCacheEntry)
+		entry.value, entry.expires = value, expires
+		s.order.MoveToFront(el)
+		return nil
+	}
+	s.entries[key] = s.order.PushFront(&lruCacheEntry{key: key, value: value, expires: expires})
+	for s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruCacheEntry).key)
+	}
+	return nil
+}
+
+// Functions called by CacheMiddleware, e.g. to record metrics, see CacheMetricsHooks. Functions that are nil are not called.
+type CacheHooks struct {
+	// Called when the result for a request was found in the cache.
+	OnHit func(ctx context.Context, key string)
+	// Called when the result for a request was not found in the cache and the endpoint is called.
+	OnMiss func(ctx context.Context, key string)
+	// Called with errors returned by the store, the endpoint is called if the store fails.
+	OnError func(ctx context.Context, err error)
+}
+
+// Returns hooks that count cache hits and misses with the given counters, counters that are nil are not used.
+func CacheMetricsHooks(hits, misses metrics.Counter) CacheHooks {
+	var hooks CacheHooks
+	if hits != nil {
+		hooks.OnHit = func(ctx context.Context, key string) { hits.Add(1) }
+	}
+	if misses != nil {
+		hooks.OnMiss = func(ctx context.Context, key string) { misses.Add(1) }
+	}
+	return hooks
+}
+
+// Endpoint middleware that caches the results of an endpoint for the given time, should only be used for read-only endpoints.
+// Only successful results of responses that implement Responder are cached, i.e. errors are not cached.
+// For a cached result the next endpoint is not called, instead a Response with the cached result is returned.
+// Note that cached values are shared between requests and must not be modified.
+func CacheMiddleware(keyFunc CacheKeyFunc, store CacheStore, ttl time.Duration) endpoint.Middleware {
+	return CacheMiddlewareWithHooks(keyFunc, store, ttl, CacheHooks{})
+}
+
+// Works like CacheMiddleware, but calls the given hooks, e.g. to record cache hits and misses.
+func CacheMiddlewareWithHooks(keyFunc CacheKeyFunc, store CacheStore, ttl time.Duration, hooks CacheHooks) endpoint.Middleware {
+	onError := func(ctx context.Context, err error) {
+		if hooks.OnError != nil {
+			hooks.OnError(ctx, err)
+		}
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			key, ok := keyFunc(ctx, request)
+			if !ok {
+				return next(ctx, request)
+			}
+
+			value, found, err := store.Get(ctx, key)
+			if err != nil {
+				onError(ctx, err)
+			} else if found {
+				if hooks.OnHit != nil {
+					hooks.OnHit(ctx, key)
+				}
+				return Response{R: value}, nil
+			} else if hooks.OnMiss != nil {
+				hooks.OnMiss(ctx, key)
+			}
+
+			response, err := next(ctx, request)
+			if err != nil {
+				return response, err
+			}
+			if r, ok := response.(Responder); ok && r.Error() == nil {
+				if err := store.Set(ctx, key, r.Response(), ttl); err != nil {
+					onError(ctx, err)
+				}
+			}
+			return response, nil
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package endpoint
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingCacheStore struct{}
+
+func (failingCacheStore) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	return nil, false, stderrors.New("store failed")
+}
+
+func (failingCacheStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return stderrors.New("store failed")
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	a := assert.New(t)
+
+	calls := 0
+	next := func(ctx context.Context, request interface{}) (interface{}, error) {
+		calls++
+		switch request {
+		case "fail":
+			return Response{Err: stderrors.New("someerror")}, nil
+		case "raw":
+			return "raw", nil
+		}
+		return Response{R: calls}, nil
+	}
+	keyFunc := func(ctx context.Context, request interface{}) (string, bool) {
+		key, _ := request.(string)
+		return key, key != "uncached"
+	}
+
+	hits, misses := generic.NewCounter("hits"), generic.NewCounter("misses")
+	e := CacheMiddlewareWithHooks(keyFunc, NewLRUCacheStore(10), time.Minute, CacheMetricsHooks(hits, misses))(next)
+
+	response, err := e(context.Background(), "a")
+	a.Nil(err)
+	a.Equal(Response{R: 1}, response)
+	response, _ = e(context.Background(), "a")
+	a.Equal(Response{R: 1}, response)
+	a.Equal(1, calls)
+	response, _ = e(context.Background(), "b")
+	a.Equal(Response{R: 2}, response)
+	a.Equal(1.0, hits.Value())
+	a.Equal(2.0, misses.Value())
+
+	// requests without key, errors and responses that do not implement Responder are not cached
+	e(context.Background(), "uncached")
+	e(context.Background(), "uncached")
+	a.Equal(4, calls)
+	e(context.Background(), "fail")
+	response, _ = e(context.Background(), "fail")
+	a.Equal(6, calls)
+	a.Equal("someerror", response.(Response).Err.Error())
+	e(context.Background(), "raw")
+	response, _ = e(context.Background(), "raw")
+	a.Equal(8, calls)
+	a.Equal("raw", response)
+
+	// the endpoint is called if the store fails
+	var storeErrs int
+	e = CacheMiddlewareWithHooks(keyFunc, failingCacheStore{}, time.Minute, CacheHooks{
+		OnError: func(ctx context.Context, err error) { storeErrs++ },
+	})(next)
+	response, err = e(context.Background(), "a")
+	a.Nil(err)
+	a.Equal(Response{R: 9}, response)
+	a.Equal(2, storeErrs)
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Now()
+	s := newLRUCacheStore(2, func() time.Time { return now })
+	ctx := context.Background()
+
+	a.Nil(s.Set(ctx, "a", 1, time.Minute))
+	a.Nil(s.Set(ctx, "b", 2, time.Minute))
+	v, ok, err := s.Get(ctx, "a")
+	a.Nil(err)
+	a.True(ok)
+	a.Equal(1, v)
+
+	// "b" is the least recently used entry
+	a.Nil(s.Set(ctx, "c", 3, time.Minute))
+	_, ok, _ = s.Get(ctx, "b")
+	a.False(ok)
+	_, ok, _ = s.Get(ctx, "a")
+	a.True(ok)
+
+	// updating an entry
+	a.Nil(s.Set(ctx, "a", 4, time.Hour))
+	v, _, _ = s.Get(ctx, "a")
+	a.Equal(4, v)
+	a.Len(s.entries, 2)
+
+	// expired entries are removed
+	now = now.Add(2 * time.Minute)
+	_, ok, _ = s.Get(ctx, "c")
+	a.False(ok)
+	_, ok, _ = s.Get(ctx, "a")
+	a.True(ok)
+	a.Len(s.entries, 1)
+	a.Equal(1, s.order.Len())
+}
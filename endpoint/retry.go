@@ -0,0 +1,94 @@
+package endpoint
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Configures RetryMiddleware.
+type RetryPolicy struct {
+	// Maximum number of times the endpoint is called, including the first call. Defaults to 3.
+	MaxAttempts int
+	// Time to wait before the first retry, doubled for every further retry. Defaults to 100ms.
+	InitialBackoff time.Duration
+	// Maximum time to wait between two attempts. Defaults to 5s.
+	MaxBackoff time.Duration
+	// Returns true if a call that failed with the given error should be retried. Defaults to errors.IsRetryable,
+	// i.e. errors with codes Unavailable, Aborted and DeadlineExceeded and errors marked as retryable are retried.
+	Retryable func(err error) bool
+	// If not nil, called before every retry with the number of the next attempt, starting at 2, and the error of the previous attempt,
+	// e.g. to log or count retries.
+	OnRetry func(ctx context.Context, attempt int, err error)
+}
+
+// Endpoint middleware that calls the next endpoint again if it fails with a retryable error, see RetryPolicy.
+// Both errors returned by the endpoint and errors contained in a response that implements Responder are retried.
+// The time between attempts grows exponentially, with a random jitter of up to 20% so that clients that failed at the same time
+// do not all retry at the same time.
+//
+// If the context is done or its deadline would expire before the next attempt, the result of the last attempt is returned.
+// Should only be used for endpoints that are safe to call multiple times, e.g. read-only or idempotent ones.
+func RetryMiddleware(policy RetryPolicy) endpoint.Middleware {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 3
+	}
+	initialBackoff := policy.InitialBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = 100 * time.Millisecond
+	}
+	maxBackoff := policy.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Second
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = errors.IsRetryable
+	}
+
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			for attempt := 1; ; attempt++ {
+				response, err := next(ctx, request)
+				failure := err
+				if failure == nil {
+					if r, ok := response.(Responder); ok {
+						failure = r.Error()
+					}
+				}
+				if failure == nil || attempt >= maxAttempts || !retryable(failure) {
+					return response, err
+				}
+
+				wait := retryBackoff(initialBackoff, maxBackoff, attempt-1)
+				if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+					return response, err
+				}
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return response, err
+				case <-timer.C:
+				}
+				if policy.OnRetry != nil {
+					policy.OnRetry(ctx, attempt+1, failure)
+				}
+			}
+		}
+	}
+}
+
+// Returns the time to wait before the retry with the given number, starting at 0.
+func retryBackoff(initial, max time.Duration, retry int) time.Duration {
+	backoff := initial << retry
+	if backoff <= 0 || backoff > max {
+		backoff = max
+	}
+	return backoff - time.Duration(rand.Int63n(int64(backoff)/5+1))
+}
@@ -0,0 +1,122 @@
+package endpoint
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryMiddleware(t *testing.T) {
+	a := assert.New(t)
+
+	unavailable := errors.New(nil, "test", errors.Unavailable)
+	notFound := errors.New(nil, "test", errors.NotFound)
+
+	calls := 0
+	// fails with the given errors, one per call, and then succeeds
+	failing := func(errs ...error) func(ctx context.Context, request interface{}) (interface{}, error) {
+		calls = 0
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			calls++
+			if calls <= len(errs) {
+				return Response{Err: errs[calls-1]}, nil
+			}
+			return Response{R: "ok"}, nil
+		}
+	}
+
+	var retries []int
+	mw := RetryMiddleware(RetryPolicy{
+		InitialBackoff: time.Millisecond,
+		OnRetry: func(ctx context.Context, attempt int, err error) {
+			retries = append(retries, attempt)
+		},
+	})
+
+	response, err := mw(failing(unavailable, unavailable))(context.Background(), nil)
+	a.Nil(err)
+	a.Equal(Response{R: "ok"}, response)
+	a.Equal(3, calls)
+	a.Equal([]int{2, 3}, retries)
+
+	// gives up after max attempts
+	response, _ = mw(failing(unavailable, unavailable, unavailable))(context.Background(), nil)
+	a.Equal(Response{Err: unavailable}, response)
+	a.Equal(3, calls)
+
+	// errors that are not retryable
+	response, _ = mw(failing(notFound))(context.Background(), nil)
+	a.Equal(Response{Err: notFound}, response)
+	a.Equal(1, calls)
+	mw(failing(stderrors.New("someerror")))(context.Background(), nil)
+	a.Equal(1, calls)
+	mw(failing(unavailable.WithRetryable(false)))(context.Background(), nil)
+	a.Equal(1, calls)
+
+	// errors returned by the endpoint are retried as well
+	calls = 0
+	e := mw(func(ctx context.Context, request interface{}) (interface{}, error) {
+		calls++
+		return nil, unavailable
+	})
+	_, err = e(context.Background(), nil)
+	a.Equal(unavailable, err)
+	a.Equal(3, calls)
+
+	// custom retryable func and max attempts
+	mw = RetryMiddleware(RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+		Retryable:      func(err error) bool { return errors.IsNotFoundError(err) },
+	})
+	response, _ = mw(failing(notFound, notFound, notFound, notFound))(context.Background(), nil)
+	a.Equal(Response{R: "ok"}, response)
+	a.Equal(5, calls)
+}
+
+func TestRetryMiddlewareContext(t *testing.T) {
+	a := assert.New(t)
+
+	calls := 0
+	e := RetryMiddleware(RetryPolicy{InitialBackoff: time.Minute})(func(ctx context.Context, request interface{}) (interface{}, error) {
+		calls++
+		return nil, errors.New(nil, "test", errors.Unavailable)
+	})
+
+	// the deadline expires before the next attempt
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	begin := time.Now()
+	_, err := e(ctx, nil)
+	a.True(errors.IsUnavailableError(err))
+	a.Equal(1, calls)
+	a.Less(time.Since(begin), time.Second)
+
+	// the context is cancelled while waiting
+	calls = 0
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err = e(ctx, nil)
+	a.True(errors.IsUnavailableError(err))
+	a.Equal(1, calls)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	a := assert.New(t)
+
+	for retry, expected := range []time.Duration{100, 200, 400, 500, 500} {
+		b := retryBackoff(100*time.Millisecond, 500*time.Millisecond, retry)
+		a.LessOrEqual(b, expected*time.Millisecond)
+		a.GreaterOrEqual(b, expected*time.Millisecond*4/5)
+	}
+	// no overflow
+	a.LessOrEqual(retryBackoff(time.Second, time.Minute, 100), time.Minute)
+}
@@ -0,0 +1,86 @@
+package endpoint
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+const timeoutErrOrigin = "endpoint/timeout"
+
+type endpointResult struct {
+	response interface{}
+	err      error
+}
+
+// Endpoint middleware that cancels the context passed to the next endpoint after the given timeout.
+// If the next endpoint does not return before the timeout, the call is abandoned, i.e. the middleware returns immediately
+// while the endpoint keeps running in the background until it notices that its context is done.
+// The response is then a Response with an error of code DeadlineExceeded (or Cancelled if the parent context was cancelled)
+// and key-value pair "abandoned": true.
+//
+// If the endpoint returns an error that is not of type Error but wraps context.DeadlineExceeded, either directly or in a Responder,
+// it is converted to an error of code DeadlineExceeded with key-value pair "abandoned": false that is returned in a Response.
+// Panics of the next endpoint are propagated to the caller.
+func TimeoutMiddleware(timeout time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			// buffered, so that abandoned calls do not block forever
+			done := make(chan endpointResult, 1)
+			panicChan := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if e := recover(); e != nil {
+						panicChan <- e
+					}
+				}()
+				response, err := next(ctx, request)
+				done <- endpointResult{response: response, err: err}
+			}()
+
+			select {
+			case e := <-panicChan:
+				panic(e)
+			case result := <-done:
+				if isContextDeadlineError(result.err) {
+					return Response{Err: timeoutError(result.err, timeout, false)}, nil
+				}
+				if r, ok := result.response.(Responder); ok && isContextDeadlineError(r.Error()) {
+					return Response{R: r.Response(), Err: timeoutError(r.Error(), timeout, false)}, nil
+				}
+				return result.response, result.err
+			case <-ctx.Done():
+				return Response{Err: timeoutError(ctx.Err(), timeout, true)}, nil
+			}
+		}
+	}
+}
+
+// Returns true if err is not of type Error and wraps context.DeadlineExceeded.
+func isContextDeadlineError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(errors.Error); ok {
+		return false
+	}
+	return stderrors.Is(err, context.DeadlineExceeded)
+}
+
+func timeoutError(inner error, timeout time.Duration, abandoned bool) errors.Error {
+	code, message := errors.DeadlineExceeded, "request timed out"
+	if stderrors.Is(inner, context.Canceled) {
+		code, message = errors.Cancelled, "request cancelled"
+	}
+	return errors.New(inner, timeoutErrOrigin, code).
+		WithPublicMessage(message).
+		With("timeout", timeout.String()).
+		With("abandoned", abandoned)
+}
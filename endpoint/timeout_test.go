@@ -0,0 +1,86 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	a := assert.New(t)
+
+	mw := TimeoutMiddleware(20 * time.Millisecond)
+
+	// call that finishes in time
+	response, err := mw(func(ctx context.Context, request interface{}) (interface{}, error) {
+		_, ok := ctx.Deadline()
+		a.True(ok)
+		return Response{R: request}, nil
+	})(context.Background(), "abc")
+	a.Nil(err)
+	a.Equal(Response{R: "abc"}, response)
+
+	// call that is abandoned
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	response, err = mw(func(ctx context.Context, request interface{}) (interface{}, error) {
+		defer close(finished)
+		<-release
+		return Response{R: "late"}, nil
+	})(context.Background(), nil)
+	a.Nil(err)
+	e, ok := response.(Response).Err.(errors.Error)
+	a.True(ok)
+	a.Equal(errors.DeadlineExceeded, e.Code)
+	a.Equal(true, e.KeyVals["abandoned"])
+	close(release)
+	<-finished
+
+	// context errors returned by the endpoint are converted, e.g. if a call to another service with a shorter deadline failed
+	response, err = mw(func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+		return Response{Err: fmt.Errorf("query failed: %w", ctx.Err())}, nil
+	})(context.Background(), nil)
+	a.Nil(err)
+	e, ok = response.(Response).Err.(errors.Error)
+	a.True(ok)
+	a.Equal(errors.DeadlineExceeded, e.Code)
+	a.Equal(false, e.KeyVals["abandoned"])
+
+	response, err = mw(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return nil, context.DeadlineExceeded
+	})(context.Background(), nil)
+	a.Nil(err)
+	a.True(errors.IsDeadlineExceededError(response.(Response).Err))
+
+	// errors of type Error are not changed
+	unavailable := errors.New(context.DeadlineExceeded, "test", errors.Unavailable)
+	response, err = mw(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return nil, unavailable
+	})(context.Background(), nil)
+	a.Nil(response)
+	a.Equal(unavailable, err)
+
+	// cancelled parent context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	response, _ = TimeoutMiddleware(time.Minute)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, nil
+	})(ctx, nil)
+	a.True(errors.IsCancelledError(response.(Response).Err))
+
+	// panics are propagated
+	a.Panics(func() {
+		mw(func(ctx context.Context, request interface{}) (interface{}, error) {
+			panic("xyz")
+		})(context.Background(), nil)
+	})
+}
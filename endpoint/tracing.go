@@ -0,0 +1,58 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/go-kit/kit/endpoint"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/dkinzler/kit/endpoint"
+
+// Returns attributes of a request that are added to the span created by TracingMiddleware, e.g. the id of the requested resource.
+// Should not return sensitive values like passwords or tokens.
+type SpanAttributesFunc func(ctx context.Context, request interface{}) []attribute.KeyValue
+
+// Endpoint middleware that starts an OpenTelemetry span with the given name for every call of the next endpoint,
+// which is the parent of spans created by the endpoint, e.g. for database queries.
+// The span has attribute "endpoint" with the name and the attributes returned by the optional attributes function.
+// The error returned by the endpoint or contained in a response that implements Responder is recorded with errors.RecordSpan,
+// so that failed service calls are marked as errors even though the transport handles them successfully.
+// If tracer is nil, a tracer of the global tracer provider is used, see otel.SetTracerProvider.
+func TracingMiddleware(tracer trace.Tracer, name string, attributes SpanAttributesFunc) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			t := tracer
+			if t == nil {
+				// looked up on every call, so that a tracer provider set after the middleware was created is used
+				t = otel.Tracer(tracerName)
+			}
+			attrs := []attribute.KeyValue{attribute.String("endpoint", name)}
+			if attributes != nil {
+				attrs = append(attrs, attributes(ctx, request)...)
+			}
+			ctx, span := t.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attrs...))
+			defer span.End()
+			defer func() {
+				if e := recover(); e != nil {
+					span.SetStatus(codes.Error, fmt.Sprintf("panic: %v", e))
+					panic(e)
+				}
+			}()
+
+			response, err = next(ctx, request)
+			if err != nil {
+				errors.RecordSpan(span, err)
+			} else if r, ok := response.(Responder); ok {
+				errors.RecordSpan(span, r.Error())
+			}
+			return response, err
+		}
+	}
+}
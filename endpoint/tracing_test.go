@@ -0,0 +1,109 @@
+package endpoint
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type testSpan struct {
+	noop.Span
+	name        string
+	attrs       []attribute.KeyValue
+	status      codes.Code
+	description string
+	err         error
+	ended       bool
+}
+
+func (s *testSpan) SetStatus(code codes.Code, description string) {
+	s.status = code
+	s.description = description
+}
+
+func (s *testSpan) RecordError(err error, options ...trace.EventOption) {
+	s.err = err
+}
+
+func (s *testSpan) End(options ...trace.SpanEndOption) {
+	s.ended = true
+}
+
+// Records the spans it starts.
+type testTracer struct {
+	noop.Tracer
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(options...)
+	span := &testSpan{name: name, attrs: config.Attributes()}
+	t.spans = append(t.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	a := assert.New(t)
+
+	tracer := &testTracer{}
+	attributes := func(ctx context.Context, request interface{}) []attribute.KeyValue {
+		return []attribute.KeyValue{attribute.String("id", request.(string))}
+	}
+	notFound := errors.New(nil, "test", errors.NotFound)
+	e := TracingMiddleware(tracer, "GetItem", attributes)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		// the span is the parent of spans created by the endpoint
+		a.Equal(tracer.spans[len(tracer.spans)-1], trace.SpanFromContext(ctx))
+		switch request {
+		case "missing":
+			return Response{Err: notFound}, nil
+		case "fail":
+			return nil, stderrors.New("someerror")
+		case "panic":
+			panic("xyz")
+		}
+		return Response{R: "ok"}, nil
+	})
+
+	response, err := e(context.Background(), "a")
+	a.Nil(err)
+	a.Equal(Response{R: "ok"}, response)
+	span := tracer.spans[0]
+	a.Equal("GetItem", span.name)
+	a.Equal([]attribute.KeyValue{attribute.String("endpoint", "GetItem"), attribute.String("id", "a")}, span.attrs)
+	a.Equal(codes.Unset, span.status)
+	a.True(span.ended)
+
+	// errors in the response are recorded
+	e(context.Background(), "missing")
+	span = tracer.spans[1]
+	a.Equal(codes.Error, span.status)
+	a.Equal("NotFound", span.description)
+	a.Equal(notFound, span.err)
+
+	e(context.Background(), "fail")
+	span = tracer.spans[2]
+	a.Equal(codes.Error, span.status)
+	a.Equal("someerror", span.err.Error())
+
+	a.Panics(func() {
+		e(context.Background(), "panic")
+	})
+	span = tracer.spans[3]
+	a.Equal(codes.Error, span.status)
+	a.True(span.ended)
+
+	// the global tracer provider is used if no tracer is given
+	response, err = TracingMiddleware(nil, "GetItem", nil)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return Response{R: "ok"}, nil
+	})(context.Background(), nil)
+	a.Nil(err)
+	a.Equal(Response{R: "ok"}, response)
+}
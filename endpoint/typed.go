@@ -0,0 +1,89 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+const typedEndpointErrOrigin = "typedEndpoint"
+
+// TypedEndpoint is a strongly typed version of a go-kit endpoint.Endpoint.
+// Request and response values are checked by the compiler instead of type assertions at runtime.
+// Use AdaptTypedEndpoint to obtain an endpoint.Endpoint, e.g. to apply middlewares or create transports.
+type TypedEndpoint[Req, Resp any] func(ctx context.Context, request Req) (Resp, error)
+
+// Empty can be used as the request type of typed endpoints without request values
+// and as the response type of typed endpoints without result.
+type Empty struct{}
+
+// Returns an endpoint.Endpoint that calls the typed endpoint.
+// The result and error of the typed endpoint are wrapped in a Response, the error return value of the endpoint is only
+// non-nil if the request is not of type Req. A nil request is replaced by the zero value of Req,
+// since e.g. go-kit's NopRequestDecoder returns nil for endpoints without request values.
+// A result of type Empty is not included in the Response, i.e. Response.R is nil.
+func AdaptTypedEndpoint[Req, Resp any](te TypedEndpoint[Req, Resp]) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(Req)
+		if !ok && request != nil {
+			return nil, errors.New(nil, typedEndpointErrOrigin, errors.Internal).
+				WithInternalMessage(fmt.Sprintf("invalid request type %T, expected %T", request, req))
+		}
+		r, err := te(ctx, req)
+		var result interface{} = r
+		if _, ok := result.(Empty); ok {
+			result = nil
+		}
+		return Response{R: result, Err: err}, nil
+	}
+}
+
+// Returns a typed endpoint that calls the given endpoint.Endpoint, i.e. the inverse of AdaptTypedEndpoint.
+// If the endpoint returns a Responder, its result and error are returned, otherwise the response value is used as result.
+// A nil result is replaced by the zero value of Resp.
+// Returns an error with code Internal if the result is not of type Resp.
+func AdaptEndpoint[Req, Resp any](e endpoint.Endpoint) TypedEndpoint[Req, Resp] {
+	return func(ctx context.Context, request Req) (Resp, error) {
+		var resp Resp
+		response, err := e(ctx, request)
+		if err != nil {
+			return resp, err
+		}
+		if r, ok := response.(Responder); ok {
+			response, err = r.Response(), r.Error()
+		}
+		if response == nil {
+			return resp, err
+		}
+		resp, ok := response.(Resp)
+		if !ok {
+			return resp, errors.New(nil, typedEndpointErrOrigin, errors.Internal).
+				WithInternalMessage(fmt.Sprintf("invalid response type %T, expected %T", response, resp))
+		}
+		return resp, err
+	}
+}
+
+// TypedMiddleware is a strongly typed version of a go-kit endpoint.Middleware.
+type TypedMiddleware[Req, Resp any] func(next TypedEndpoint[Req, Resp]) TypedEndpoint[Req, Resp]
+
+// Returns a typed middleware that applies the given endpoint.Middleware, e.g. ErrorLoggingMiddleware, to typed endpoints.
+// The typed endpoint is adapted with AdaptTypedEndpoint, so that the middleware sees its result and error wrapped in a Response.
+func AdaptMiddleware[Req, Resp any](mw endpoint.Middleware) TypedMiddleware[Req, Resp] {
+	return func(next TypedEndpoint[Req, Resp]) TypedEndpoint[Req, Resp] {
+		return AdaptEndpoint[Req, Resp](mw(AdaptTypedEndpoint(next)))
+	}
+}
+
+// Applies zero or more typed middlewares to a typed endpoint, in the same order as ApplyMiddlewares,
+// i.e. the first middleware is innermost and the last middleware outermost.
+func ApplyTypedMiddlewares[Req, Resp any](e TypedEndpoint[Req, Resp], mws ...TypedMiddleware[Req, Resp]) TypedEndpoint[Req, Resp] {
+	result := e
+	for _, mw := range mws {
+		result = mw(result)
+	}
+	return result
+}
@@ -0,0 +1,123 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	kiterrors "github.com/dkinzler/kit/errors"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptTypedEndpoint(t *testing.T) {
+	a := assert.New(t)
+
+	e := AdaptTypedEndpoint(func(ctx context.Context, request string) (int, error) {
+		if request == "fail" {
+			return 0, errors.New("someerror")
+		}
+		return len(request), nil
+	})
+
+	response, err := e(context.Background(), "abc")
+	a.Nil(err)
+	a.Equal(Response{R: 3}, response)
+
+	response, err = e(context.Background(), "fail")
+	a.Nil(err)
+	a.Equal(Response{R: 0, Err: errors.New("someerror")}, response)
+
+	// nil request is replaced by the zero value
+	response, err = e(context.Background(), nil)
+	a.Nil(err)
+	a.Equal(Response{R: 0}, response)
+
+	response, err = e(context.Background(), 42)
+	a.Nil(response)
+	a.True(kiterrors.IsInternalError(err))
+}
+
+func TestAdaptTypedEndpointEmptyResponse(t *testing.T) {
+	a := assert.New(t)
+
+	e := AdaptTypedEndpoint(func(ctx context.Context, request Empty) (Empty, error) {
+		return Empty{}, nil
+	})
+	response, err := e(context.Background(), nil)
+	a.Nil(err)
+	a.Equal(Response{}, response)
+}
+
+func TestAdaptEndpoint(t *testing.T) {
+	a := assert.New(t)
+
+	te := AdaptEndpoint[string, int](AdaptTypedEndpoint(func(ctx context.Context, request string) (int, error) {
+		if request == "fail" {
+			return 0, errors.New("someerror")
+		}
+		return len(request), nil
+	}))
+	result, err := te(context.Background(), "abc")
+	a.Nil(err)
+	a.Equal(3, result)
+	_, err = te(context.Background(), "fail")
+	a.Equal(errors.New("someerror"), err)
+
+	// responses that do not implement Responder are used directly
+	te = AdaptEndpoint[string, int](func(ctx context.Context, request interface{}) (interface{}, error) {
+		switch request {
+		case "nil":
+			return nil, nil
+		case "string":
+			return "x", nil
+		case "fail":
+			return nil, errors.New("endpointerror")
+		}
+		return 42, nil
+	})
+	result, err = te(context.Background(), "")
+	a.Nil(err)
+	a.Equal(42, result)
+	result, err = te(context.Background(), "nil")
+	a.Nil(err)
+	a.Equal(0, result)
+	_, err = te(context.Background(), "string")
+	a.True(kiterrors.IsInternalError(err))
+	_, err = te(context.Background(), "fail")
+	a.Equal(errors.New("endpointerror"), err)
+}
+
+func TestTypedMiddlewares(t *testing.T) {
+	a := assert.New(t)
+
+	var calls []string
+	mw := func(name string) TypedMiddleware[int, int] {
+		return func(next TypedEndpoint[int, int]) TypedEndpoint[int, int] {
+			return func(ctx context.Context, request int) (int, error) {
+				calls = append(calls, name)
+				return next(ctx, request+1)
+			}
+		}
+	}
+	untyped := func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			calls = append(calls, "untyped")
+			response, err := next(ctx, request)
+			// untyped middlewares see the result wrapped in a Response
+			r := response.(Response)
+			r.R = r.R.(int) * 10
+			return r, err
+		}
+	}
+
+	e := ApplyTypedMiddlewares(func(ctx context.Context, request int) (int, error) {
+		return request, nil
+	}, mw("inner"), AdaptMiddleware[int, int](untyped), mw("outer"))
+
+	result, err := e(context.Background(), 0)
+	a.Nil(err)
+	a.Equal(20, result)
+	a.Equal([]string{"outer", "untyped", "inner"}, calls)
+}
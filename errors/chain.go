@@ -0,0 +1,46 @@
+package errors
+
+// Returns the errors of type Error in the chain of the given error, see UnstackErrors.
+func chainErrors(err error) []Error {
+	var result []Error
+	for _, x := range UnstackErrors(err) {
+		if e, ok := x.(Error); ok {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Returns the code of the innermost error of type Error in the chain of the given error, i.e. the code of the root cause.
+// Returns Unknown if the chain does not contain an error of type Error.
+func RootCode(err error) ErrorCode {
+	chain := chainErrors(err)
+	if len(chain) == 0 {
+		return Unknown
+	}
+	return chain[len(chain)-1].Code
+}
+
+// Returns the origins of the errors of type Error in the chain of the given error, starting with the outermost one.
+// Empty origins are skipped.
+func OriginChain(err error) []string {
+	var result []string
+	for _, e := range chainErrors(err) {
+		if e.Origin != "" {
+			result = append(result, e.Origin)
+		}
+	}
+	return result
+}
+
+// Returns the outermost error of type Error in the chain of the given error that has the given code.
+// E.g. FirstWithCode(err, NotFound) can be used to find out if a NotFound error occurred anywhere in the chain,
+// even if it was wrapped by an error with a different code.
+func FirstWithCode(err error, code ErrorCode) (Error, bool) {
+	for _, e := range chainErrors(err) {
+		if e.Code == code {
+			return e, true
+		}
+	}
+	return Error{}, false
+}
@@ -0,0 +1,71 @@
+package errors
+
+// Returns true if the operation that failed with the given error can be retried, e.g. by a retry middleware.
+// The first error of type Error in the chain of inner errors with retryability set (see WithRetryable) decides.
+// Otherwise errors of type Error with the codes Unavailable, DeadlineExceeded, Aborted or ResourceExhausted are retryable,
+// errors of other types are not.
+func IsRetryable(err error) bool {
+	e, ok := asError(err)
+	if !ok {
+		return false
+	}
+	for _, x := range UnstackErrors(e) {
+		if xe, ok := x.(Error); ok && xe.Retryable != nil {
+			return *xe.Retryable
+		}
+	}
+	switch e.Code {
+	case Unavailable, DeadlineExceeded, Aborted, ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// Severity of an error, e.g. to choose the level an error is logged with.
+type Severity int
+
+const (
+	// No severity set, SeverityOf then uses a default based on the error code.
+	SeverityUnset Severity = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	names := [...]string{
+		"Unset",
+		"Debug",
+		"Info",
+		"Warning",
+		"Error",
+		"Critical",
+	}
+	if int(s) >= 0 && int(s) < len(names) {
+		return names[s]
+	}
+	return "UndefinedSeverity"
+}
+
+// Returns the severity of the given error.
+// The first error of type Error in the chain of inner errors with a severity set (see WithSeverity) decides.
+// Otherwise errors of type Error with codes that usually indicate a problem with the request of a client,
+// e.g. InvalidArgument or NotFound, have severity SeverityInfo and all other errors have severity SeverityError.
+func SeverityOf(err error) Severity {
+	e, ok := asError(err)
+	if !ok {
+		return SeverityError
+	}
+	for _, x := range UnstackErrors(e) {
+		if xe, ok := x.(Error); ok && xe.Severity != SeverityUnset {
+			return xe.Severity
+		}
+	}
+	switch e.Code {
+	case Cancelled, InvalidArgument, NotFound, AlreadyExists, PermissionDenied, Unauthenticated, FailedPrecondition, OutOfRange:
+		return SeverityInfo
+	}
+	return SeverityError
+}
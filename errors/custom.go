@@ -0,0 +1,82 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Custom error codes must be greater than or equal to CustomCodeBase, see RegisterErrorCode.
+// Codes below are reserved for the codes defined by this package, so that new codes can be added to the package
+// without conflicting with custom codes.
+const CustomCodeBase ErrorCode = 1000
+
+type customCode struct {
+	name       string
+	httpStatus int
+}
+
+var customCodes = struct {
+	sync.RWMutex
+	codes  map[ErrorCode]customCode
+	byName map[string]ErrorCode
+}{codes: make(map[ErrorCode]customCode), byName: make(map[string]ErrorCode)}
+
+// Registers an additional error code for domain-specific errors that are general enough to deserve their own code, e.g.
+//
+//	const PaymentRequired = errors.CustomCodeBase + 1
+//
+//	func init() {
+//		errors.RegisterErrorCode(PaymentRequired, "PaymentRequired", http.StatusPaymentRequired)
+//	}
+//
+// The name is returned by String and recognized by ParseErrorCode, so that custom codes are preserved by ToMap,
+// JSON encoding and the transport packages. The http status is returned by HTTPStatus, if 0 http.StatusInternalServerError is used.
+// Should be called when a program is initialized, before any errors with the code are created.
+// Returns an error if the code is less than CustomCodeBase or the code or name is already in use.
+func RegisterErrorCode(code ErrorCode, name string, httpStatus int) error {
+	if code < CustomCodeBase {
+		return stderrors.New(fmt.Sprintf("custom error code %d must be greater than or equal to %d", code, CustomCodeBase))
+	}
+	if name == "" {
+		return stderrors.New("name of custom error code must not be empty")
+	}
+	if httpStatus == 0 {
+		httpStatus = http.StatusInternalServerError
+	}
+	customCodes.Lock()
+	defer customCodes.Unlock()
+	if _, ok := customCodes.codes[code]; ok {
+		return stderrors.New(fmt.Sprintf("error code %d is already registered", code))
+	}
+	if _, ok := customCodes.byName[name]; ok || isBuiltinCodeName(name) {
+		return stderrors.New(fmt.Sprintf("error code name %v is already in use", name))
+	}
+	customCodes.codes[code] = customCode{name: name, httpStatus: httpStatus}
+	customCodes.byName[name] = code
+	return nil
+}
+
+func lookupCustomCode(code ErrorCode) (customCode, bool) {
+	customCodes.RLock()
+	defer customCodes.RUnlock()
+	c, ok := customCodes.codes[code]
+	return c, ok
+}
+
+func lookupCustomCodeName(name string) (ErrorCode, bool) {
+	customCodes.RLock()
+	defer customCodes.RUnlock()
+	c, ok := customCodes.byName[name]
+	return c, ok
+}
+
+func isBuiltinCodeName(name string) bool {
+	for c := Unknown; c <= ResourceExhausted; c++ {
+		if c.String() == name {
+			return true
+		}
+	}
+	return false
+}
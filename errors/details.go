@@ -0,0 +1,128 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+)
+
+// Detail is structured information about an error that can be attached with WithDetails, similar to the error details of gRPC.
+// Transport layers can e.g. include the details in error responses, so that clients can show which fields of a request are invalid.
+// This package provides the detail types BadRequest, QuotaFailure and ResourceInfo.
+// Other types can be attached as well, but errors with such details cannot be decoded with UnmarshalJSON.
+type Detail interface {
+	// Name of the detail type, used to encode details.
+	DetailType() string
+}
+
+// Describes violations of a request, e.g. invalid fields.
+type BadRequest struct {
+	FieldViolations []FieldViolation `json:"fieldViolations"`
+}
+
+type FieldViolation struct {
+	// Path of the field, e.g. "address.street".
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// Describes quota checks that failed, e.g. because a rate limit was exceeded.
+type QuotaFailure struct {
+	Violations []QuotaViolation `json:"violations"`
+}
+
+type QuotaViolation struct {
+	// Subject the quota applies to, e.g. "user:123" or "project:abc".
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+// Describes the resource an error refers to, e.g. a resource that was not found or already exists.
+type ResourceInfo struct {
+	ResourceType string `json:"resourceType"`
+	ResourceName string `json:"resourceName"`
+	Owner        string `json:"owner,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+func (BadRequest) DetailType() string   { return "BadRequest" }
+func (QuotaFailure) DetailType() string { return "QuotaFailure" }
+func (ResourceInfo) DetailType() string { return "ResourceInfo" }
+
+// Returns the BadRequest detail of the first error of type Error in the chain of the given error that has one.
+func BadRequestOf(err error) (BadRequest, bool) {
+	return detailOf[BadRequest](err)
+}
+
+// Returns the QuotaFailure detail of the first error of type Error in the chain of the given error that has one.
+func QuotaFailureOf(err error) (QuotaFailure, bool) {
+	return detailOf[QuotaFailure](err)
+}
+
+// Returns the ResourceInfo detail of the first error of type Error in the chain of the given error that has one.
+func ResourceInfoOf(err error) (ResourceInfo, bool) {
+	return detailOf[ResourceInfo](err)
+}
+
+func detailOf[T Detail](err error) (T, bool) {
+	for _, x := range UnstackErrors(err) {
+		e, ok := x.(Error)
+		if !ok {
+			continue
+		}
+		for _, d := range e.Details {
+			if t, ok := d.(T); ok {
+				return t, true
+			}
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// Encoded detail, e.g. {"type": "BadRequest", "detail": {"fieldViolations": [...]}}.
+type jsonDetail struct {
+	Type   string          `json:"type"`
+	Detail json.RawMessage `json:"detail"`
+}
+
+func encodeDetails(details []Detail) ([]jsonDetail, error) {
+	var result []jsonDetail
+	for _, d := range details {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, jsonDetail{Type: d.DetailType(), Detail: data})
+	}
+	return result, nil
+}
+
+func decodeDetails(details []jsonDetail) ([]Detail, error) {
+	var result []Detail
+	for _, jd := range details {
+		var d Detail
+		var err error
+		switch jd.Type {
+		case BadRequest{}.DetailType():
+			var x BadRequest
+			err = json.Unmarshal(jd.Detail, &x)
+			d = x
+		case QuotaFailure{}.DetailType():
+			var x QuotaFailure
+			err = json.Unmarshal(jd.Detail, &x)
+			d = x
+		case ResourceInfo{}.DetailType():
+			var x ResourceInfo
+			err = json.Unmarshal(jd.Detail, &x)
+			d = x
+		default:
+			return nil, stderrors.New(fmt.Sprintf("unknown detail type %v", jd.Type))
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
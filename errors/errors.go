@@ -15,9 +15,9 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
-	"runtime/debug"
-	"strings"
+	"time"
 )
 
 // Structured error that can contain additional context about an error, e.g. the component
@@ -27,7 +27,13 @@ type Error struct {
 	Origin string
 	// Wrap another error with additional context.
 	Inner      error
-	StackTrace []byte
+	StackTrace StackTrace
+
+	// Operation that failed and time the error was created.
+	// Set automatically by New and the other functions that create errors, Op is the name of the calling function, e.g. "store.(*Store).Get".
+	// Together with UnstackErrors this shows the path an error took through the layers of a program.
+	Op   string
+	Time time.Time
 
 	// General error code, see comments on type ErrorCode.
 	Code ErrorCode
@@ -44,27 +50,54 @@ type Error struct {
 	InternalCode    int
 	InternalMessage string
 
+	// Whether the operation that failed can be retried, nil if not set. See IsRetryable.
+	Retryable *bool
+	// Severity of the error, e.g. to choose a log level. See SeverityOf.
+	Severity Severity
+
+	// Structured information about the error, e.g. invalid fields of a request. See Detail.
+	Details []Detail
+
 	// Any additional key-value pairs can be added here.
+	// Sensitive values are redacted in the string and map representations of the error, see WithSensitive.
 	KeyVals map[string]interface{}
 }
 
-// A stack trace is added automatically if the inner error is nil or not of type Error.
+// A stack trace is added automatically if the inner error is nil or not of type Error, see MaxStackDepth.
+// Registered hooks are called with the origin and code of the error, see Hook.
 func New(inner error, origin string, code ErrorCode) Error {
-	var stack []byte
-	if _, ok := inner.(Error); !ok {
-		stack = debug.Stack()
-	}
-	return Error{
-		Origin:     origin,
-		Inner:      inner,
-		StackTrace: stack,
-		Code:       code,
+	return newError(inner, origin, code)
+}
+
+// Like New, but the stack trace starts at the caller of the function that called newError.
+func newError(inner error, origin string, code ErrorCode) Error {
+	return initError(Error{
+		Origin: origin,
+		Inner:  inner,
+		Code:   code,
+	})
+}
+
+// Adds a stack trace to a new error and calls the registered hooks.
+// Must be called by an unexported function that is called by an exported one, the stack trace starts at the caller of the exported function.
+func initError(e Error) Error {
+	if _, ok := e.Inner.(Error); !ok {
+		// skip runtime.Callers, callers, initError, the unexported function that called it and the exported function
+		e.StackTrace = callers(5)
 	}
+	// skip initError, the unexported function that called it and the exported function
+	e.Op = callerOp(3)
+	e.Time = time.Now()
+	runHooks(e.Origin, e.Code)
+	return e
 }
 
 // Implement the error interface.
 func (e Error) Error() string {
 	r := fmt.Sprintf("origin: %v, code: %v", e.Origin, e.Code.String())
+	if e.Op != "" {
+		r += fmt.Sprintf(", op: %v", e.Op)
+	}
 	if e.PublicCode != 0 {
 		r += fmt.Sprintf(", publicCode: %v", e.PublicCode)
 	}
@@ -77,11 +110,20 @@ func (e Error) Error() string {
 	if e.InternalMessage != "" {
 		r += fmt.Sprintf(", internalMessage: %v", e.InternalMessage)
 	}
+	if e.Retryable != nil {
+		r += fmt.Sprintf(", retryable: %v", *e.Retryable)
+	}
+	if e.Severity != SeverityUnset {
+		r += fmt.Sprintf(", severity: %v", e.Severity.String())
+	}
+	for _, d := range e.Details {
+		r += fmt.Sprintf(", %v: %+v", d.DetailType(), d)
+	}
 	if e.StackTrace != nil {
-		r += fmt.Sprintf(", stackTrace: %v", string(e.StackTrace))
+		r += fmt.Sprintf(", stackTrace: %v", e.StackTrace.String())
 	}
 	for key, value := range e.KeyVals {
-		r += fmt.Sprintf(", %v: %v", key, value)
+		r += fmt.Sprintf(", %v: %v", key, redactedValue(key, value))
 	}
 
 	if e.Inner != nil {
@@ -90,11 +132,46 @@ func (e Error) Error() string {
 	return r
 }
 
+// Returns the inner error, so that the functions Is and As of the standard library errors package can traverse the inner errors.
+func (e Error) Unwrap() error {
+	return e.Inner
+}
+
+// Used by the function Is of the standard library errors package.
+// Returns true if target is of type Error and has the same error code.
+// Public and internal codes of the target are only compared if they are set,
+// i.e. an Error with only a code set can be used as a sentinel value that matches all errors with that code:
+//
+//	var ErrNotFound = errors.Error{Code: errors.NotFound}
+//
+//	if stderrors.Is(err, ErrNotFound) {...}
+func (e Error) Is(target error) bool {
+	t, ok := target.(Error)
+	if !ok {
+		return false
+	}
+	if e.Code != t.Code {
+		return false
+	}
+	if t.PublicCode != 0 && e.PublicCode != t.PublicCode {
+		return false
+	}
+	if t.InternalCode != 0 && e.InternalCode != t.InternalCode {
+		return false
+	}
+	return true
+}
+
 func (e Error) WithOrigin(origin string) Error {
 	e.Origin = origin
 	return e
 }
 
+func (e Error) WithOp(op string) Error {
+	e.Op = op
+	return e
+}
+
 func (e Error) WithInner(inner error) Error {
 	e.Inner = inner
 	return e
@@ -102,6 +179,7 @@ func (e Error) WithInner(inner error) Error {
 
 func (e Error) WithCode(code ErrorCode) Error {
 	e.Code = code
+	runHooks(e.Origin, code)
 	return e
 }
 
@@ -125,6 +203,22 @@ func (e Error) WithInternalMessage(message string) Error {
 	return e
 }
 
+func (e Error) WithRetryable(retryable bool) Error {
+	e.Retryable = &retryable
+	return e
+}
+
+func (e Error) WithSeverity(severity Severity) Error {
+	e.Severity = severity
+	return e
+}
+
+// Adds details to the error, see Detail.
+func (e Error) WithDetails(details ...Detail) Error {
+	e.Details = append(append([]Detail{}, e.Details...), details...)
+	return e
+}
+
 func (e Error) With(key string, value interface{}) Error {
 	if e.KeyVals == nil {
 		e.KeyVals = make(map[string]interface{})
@@ -147,15 +241,18 @@ func (e Error) ToMap() map[string]interface{} {
 			m["inner"] = e.Inner.Error()
 		}
 	}
+	if e.Op != "" {
+		m["op"] = e.Op
+	}
+	if !e.Time.IsZero() {
+		m["time"] = e.Time
+	}
 	if e.StackTrace != nil {
-		// Since this usually ends up as a json log message, we split the stack trace apart into individual lines.
-		parts := strings.Split(strings.TrimSpace(string(e.StackTrace)), "\n")
-		for i, part := range parts {
-			parts[i] = strings.TrimSpace(part)
-		}
-		m["stackTrace"] = parts
+		// Since this usually ends up as a json log message, the frames are added as structured values.
+		m["stackTrace"] = e.StackTrace.Frames()
 	}
 	m["code"] = e.Code.String()
+	m["fingerprint"] = e.Fingerprint()
 	if e.PublicCode != 0 {
 		m["publicCode"] = e.PublicCode
 	}
@@ -168,31 +265,49 @@ func (e Error) ToMap() map[string]interface{} {
 	if e.InternalMessage != "" {
 		m["internalMessage"] = e.InternalMessage
 	}
+	if e.Retryable != nil {
+		m["retryable"] = *e.Retryable
+	}
+	if e.Severity != SeverityUnset {
+		m["severity"] = e.Severity.String()
+	}
+	if len(e.Details) > 0 {
+		details := make([]map[string]interface{}, len(e.Details))
+		for i, d := range e.Details {
+			details[i] = map[string]interface{}{"type": d.DetailType(), "detail": d}
+		}
+		m["details"] = details
+	}
 	for key, value := range e.KeyVals {
-		m[key] = value
+		m[key] = redactedValue(key, value)
 	}
 	return m
 }
 
 // Return a slice of all the errors found by traversing inner errors.
+// Errors of other types are unwrapped as well, if they implement an Unwrap() error method.
 func UnstackErrors(e error) []error {
 	var result []error
-	var err error = e
-	v, ok := err.(Error)
-	for ok {
-		result = append(result, v)
-		err = v.Inner
-		v, ok = err.(Error)
-	}
-	if err != nil {
+	for err := e; err != nil; err = stderrors.Unwrap(err) {
 		result = append(result, err)
 	}
 	return result
 }
 
+// Returns the first error of type Error in the chain of the given error, using the function As of the standard library errors package.
+// I.e. if the given error is of type Error, it is returned, otherwise errors that wrap an Error, e.g. with fmt.Errorf("...: %w", err), are unwrapped.
+func asError(err error) (Error, bool) {
+	var e Error
+	if stderrors.As(err, &e) {
+		return e, true
+	}
+	return e, false
+}
+
 // Retunrs true if the given error is of type Error and has the given ErrorCode set.
+// If the error is not of type Error but wraps one, the code of the wrapped error is checked.
 func Is(err error, code ErrorCode) bool {
-	e, ok := err.(Error)
+	e, ok := asError(err)
 	if !ok {
 		return false
 	}
@@ -255,9 +370,14 @@ func IsUnavailableError(err error) bool {
 	return Is(err, Unavailable)
 }
 
+func IsResourceExhaustedError(err error) bool {
+	return Is(err, ResourceExhausted)
+}
+
 // Returns true if the given error is of type Error and has the given code set as the internal error code.
+// Like for Is, errors that wrap an Error are unwrapped.
 func HasInternalCode(err error, code int) bool {
-	e, ok := err.(Error)
+	e, ok := asError(err)
 	if !ok {
 		return false
 	}
@@ -265,8 +385,9 @@ func HasInternalCode(err error, code int) bool {
 }
 
 // Returns true if the given error is of type Error and has the given code set as the public error code.
+// Like for Is, errors that wrap an Error are unwrapped.
 func HasPublicCode(err error, code int) bool {
-	e, ok := err.(Error)
+	e, ok := asError(err)
 	if !ok {
 		return false
 	}
@@ -302,6 +423,8 @@ const (
 	Unimplemented
 	Internal
 	Unavailable
+	// A resource or quota has been exhausted, e.g. a client exceeded a rate limit.
+	ResourceExhausted
 )
 
 func (e ErrorCode) String() string {
@@ -320,9 +443,13 @@ func (e ErrorCode) String() string {
 		"Unimplemented",
 		"Internal",
 		"Unavailable",
+		"ResourceExhausted",
 	}
 	if int(e) >= 0 && int(e) < len(s) {
 		return s[e]
 	}
+	if c, ok := lookupCustomCode(e); ok {
+		return c.name
+	}
 	return "UndefinedErrorCode"
 }
@@ -1,10 +1,19 @@
 package errors
 
 import (
+	"encoding/json"
 	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestErrorCode(t *testing.T) {
@@ -37,6 +46,8 @@ func TestNew(t *testing.T) {
 	a.Equal(err.WithPublicMessage("pmessage").PublicMessage, "pmessage")
 	a.Equal(err.WithInternalCode(43).InternalCode, 43)
 	a.Equal(err.WithInternalMessage("imessage").InternalMessage, "imessage")
+	a.True(*err.WithRetryable(true).Retryable)
+	a.Equal(err.WithSeverity(SeverityWarning).Severity, SeverityWarning)
 
 	err = err.With("testkey", 1001)
 	err = err.With("anotherkey", "value")
@@ -48,6 +59,30 @@ func TestNew(t *testing.T) {
 	a.Equal("value", v)
 }
 
+func TestStackTrace(t *testing.T) {
+	a := assert.New(t)
+
+	err := New(nil, "test", Internal)
+	frames := err.StackTrace.Frames()
+	a.NotEmpty(frames)
+	// the first frame is the caller of New
+	a.True(strings.HasSuffix(frames[0].Function, "errors.TestStackTrace"), frames[0].Function)
+	a.True(strings.HasSuffix(frames[0].File, "errors_test.go"))
+	a.Greater(frames[0].Line, 0)
+	a.True(strings.HasPrefix(err.StackTrace.String(), frames[0].Function+"\n\t"+frames[0].File))
+
+	// an inner error of type Error already has a stack trace
+	a.Nil(New(err, "test", Internal).StackTrace)
+
+	defer func(depth int) { MaxStackDepth = depth }(MaxStackDepth)
+	MaxStackDepth = 1
+	a.Len(New(nil, "test", Internal).StackTrace, 1)
+	MaxStackDepth = 0
+	a.Nil(New(nil, "test", Internal).StackTrace)
+	a.Nil(StackTrace(nil).Frames())
+	a.Equal("", StackTrace(nil).String())
+}
+
 func TestErrorString(t *testing.T) {
 	a := assert.New(t)
 
@@ -57,6 +92,8 @@ func TestErrorString(t *testing.T) {
 		WithInternalMessage("internal message").
 		WithPublicCode(43).
 		WithPublicMessage("public message").
+		WithRetryable(false).
+		WithSeverity(SeverityCritical).
 		With("key", "value")
 
 	s := err.Error()
@@ -66,6 +103,8 @@ func TestErrorString(t *testing.T) {
 	a.Contains(s, "internalMessage: internal message")
 	a.Contains(s, "publicCode: 43")
 	a.Contains(s, "publicMessage: public message")
+	a.Contains(s, "retryable: false")
+	a.Contains(s, "severity: Critical")
 	a.Contains(s, "key: value")
 	a.Contains(s, "inner: ["+inner.Error()+"]")
 	a.Contains(s, "stackTrace:")
@@ -80,6 +119,8 @@ func TestErrorToMap(t *testing.T) {
 		WithInternalMessage("internal message").
 		WithPublicCode(43).
 		WithPublicMessage("public message").
+		WithRetryable(true).
+		WithSeverity(SeverityWarning).
 		With("key", "value")
 
 	m := err.ToMap()
@@ -89,9 +130,12 @@ func TestErrorToMap(t *testing.T) {
 	a.Equal("internal message", m["internalMessage"])
 	a.Equal(43, m["publicCode"])
 	a.Equal("public message", m["publicMessage"])
+	a.Equal(true, m["retryable"])
+	a.Equal("Warning", m["severity"])
 	a.Equal("value", m["key"])
 	a.Equal(inner.Error(), m["inner"])
-	a.Contains(m, "stackTrace")
+	a.IsType([]Frame{}, m["stackTrace"])
+	a.Equal(err.Fingerprint(), m["fingerprint"])
 
 	// inner error of type Error should also be encoded as map
 
@@ -136,6 +180,18 @@ func TestIs(t *testing.T) {
 			Code:     Aborted,
 			Expected: true,
 		},
+		{
+			// errors wrapping an Error are unwrapped
+			Err:      fmt.Errorf("wrapped: %w", New(nil, "test", NotFound)),
+			Code:     NotFound,
+			Expected: true,
+		},
+		{
+			// only the code of the outermost Error is checked
+			Err:      New(New(nil, "test", NotFound), "test", Internal),
+			Code:     NotFound,
+			Expected: false,
+		},
 	}
 	for i, c := range cases {
 		actual := Is(c.Err, c.Code)
@@ -155,10 +211,506 @@ func TestIs(t *testing.T) {
 	a.True(IsPermissionDeniedError(New(nil, "test", PermissionDenied)))
 	a.True(IsUnauthenticatedError(New(nil, "test", Unauthenticated)))
 	a.True(IsUnavailableError(New(nil, "test", Unavailable)))
+	a.True(IsResourceExhaustedError(New(nil, "test", ResourceExhausted)))
 	a.True(IsUnimplementedError(New(nil, "test", Unimplemented)))
 	a.True(IsUnknownError(New(nil, "test", Unknown)))
 }
 
+func TestClassification(t *testing.T) {
+	a := assert.New(t)
+
+	// defaults based on the error code
+	a.True(IsRetryable(New(nil, "test", Unavailable)))
+	a.True(IsRetryable(fmt.Errorf("wrapped: %w", New(nil, "test", DeadlineExceeded))))
+	a.True(IsRetryable(New(nil, "test", ResourceExhausted)))
+	a.False(IsRetryable(New(nil, "test", InvalidArgument)))
+	a.False(IsRetryable(stderrors.New("test")))
+	a.False(IsRetryable(nil))
+	a.Equal(SeverityInfo, SeverityOf(New(nil, "test", NotFound)))
+	a.Equal(SeverityError, SeverityOf(New(nil, "test", Internal)))
+	a.Equal(SeverityError, SeverityOf(stderrors.New("test")))
+
+	// explicitly set values take precedence, also if set on an inner error
+	a.False(IsRetryable(New(nil, "test", Unavailable).WithRetryable(false)))
+	a.True(IsRetryable(New(nil, "test", InvalidArgument).WithRetryable(true)))
+	a.True(IsRetryable(New(New(nil, "inner", Internal).WithRetryable(true), "test", Internal)))
+	a.False(IsRetryable(New(New(nil, "inner", Internal).WithRetryable(true), "test", Internal).WithRetryable(false)))
+	a.Equal(SeverityCritical, SeverityOf(New(nil, "test", NotFound).WithSeverity(SeverityCritical)))
+	a.Equal(SeverityDebug, SeverityOf(New(New(nil, "inner", Internal).WithSeverity(SeverityDebug), "test", Internal)))
+
+	a.Equal("Warning", SeverityWarning.String())
+	a.Equal("UndefinedSeverity", Severity(42).String())
+}
+
+func TestJSON(t *testing.T) {
+	a := assert.New(t)
+
+	err := New(New(stderrors.New("xyz"), "inner", NotFound).WithPublicCode(5), "outer", Internal).
+		WithPublicMessage("public message").
+		WithInternalCode(42).
+		WithInternalMessage("internal message").
+		WithRetryable(true).
+		WithSeverity(SeverityWarning).
+		With("key", "value").
+		With("count", 3)
+
+	data, jerr := json.Marshal(err)
+	a.Nil(jerr)
+	a.NotContains(string(data), "stackTrace")
+
+	var decoded Error
+	a.Nil(json.Unmarshal(data, &decoded))
+	a.Equal("outer", decoded.Origin)
+	a.Equal(Internal, decoded.Code)
+	a.Equal("public message", decoded.PublicMessage)
+	a.Equal(42, decoded.InternalCode)
+	a.Equal("internal message", decoded.InternalMessage)
+	a.True(*decoded.Retryable)
+	a.Equal(SeverityWarning, decoded.Severity)
+	a.Equal(map[string]interface{}{"key": "value", "count": float64(3)}, decoded.KeyVals)
+	a.Nil(decoded.StackTrace)
+
+	inner, ok := decoded.Inner.(Error)
+	a.True(ok)
+	a.Equal("inner", inner.Origin)
+	a.Equal(NotFound, inner.Code)
+	a.Equal(5, inner.PublicCode)
+	a.Equal(SeverityUnset, inner.Severity)
+	a.Nil(inner.Retryable)
+	a.EqualError(inner.Inner, "xyz")
+
+	// an error can be decoded as part of another value
+	var v struct {
+		Err *Error `json:"err"`
+	}
+	a.Nil(json.Unmarshal([]byte(`{"err": {"code": "AlreadyExists", "publicCode": 7}}`), &v))
+	a.Equal(AlreadyExists, v.Err.Code)
+	a.Equal(7, v.Err.PublicCode)
+	a.Nil(v.Err.Inner)
+
+	a.NotNil(json.Unmarshal([]byte(`{"code": "NoSuchCode"}`), &decoded))
+	a.NotNil(json.Unmarshal([]byte(`{"code": "Internal", "severity": "Extreme"}`), &decoded))
+
+	code, ok := ParseErrorCode("Unavailable")
+	a.True(ok)
+	a.Equal(Unavailable, code)
+	code, ok = ParseErrorCode("ResourceExhausted")
+	a.True(ok)
+	a.Equal(ResourceExhausted, code)
+	_, ok = ParseErrorCode("UndefinedErrorCode")
+	a.False(ok)
+}
+
+func TestDetails(t *testing.T) {
+	a := assert.New(t)
+
+	badRequest := BadRequest{FieldViolations: []FieldViolation{{Field: "name", Description: "must not be empty"}}}
+	resource := ResourceInfo{ResourceType: "user", ResourceName: "123"}
+	inner := New(nil, "inner", NotFound).WithDetails(resource)
+	err := New(inner, "outer", InvalidArgument).WithDetails(badRequest)
+
+	br, ok := BadRequestOf(err)
+	a.True(ok)
+	a.Equal(badRequest, br)
+	// details of inner errors are found as well
+	ri, ok := ResourceInfoOf(fmt.Errorf("wrapped: %w", err))
+	a.True(ok)
+	a.Equal(resource, ri)
+	_, ok = QuotaFailureOf(err)
+	a.False(ok)
+	_, ok = BadRequestOf(stderrors.New("test"))
+	a.False(ok)
+
+	// WithDetails does not modify the details of the original error
+	quota := QuotaFailure{Violations: []QuotaViolation{{Subject: "user:123", Description: "too many requests"}}}
+	withQuota := err.WithDetails(quota)
+	a.Len(err.Details, 1)
+	a.Len(withQuota.Details, 2)
+
+	a.Contains(err.Error(), "BadRequest: {FieldViolations:[{Field:name Description:must not be empty}]}")
+	a.Equal([]map[string]interface{}{{"type": "BadRequest", "detail": badRequest}}, err.ToMap()["details"])
+
+	data, jerr := json.Marshal(withQuota)
+	a.Nil(jerr)
+	var decoded Error
+	a.Nil(json.Unmarshal(data, &decoded))
+	a.Equal([]Detail{badRequest, quota}, decoded.Details)
+	a.Equal([]Detail{resource}, decoded.Inner.(Error).Details)
+
+	a.NotNil(json.Unmarshal([]byte(`{"code": "Internal", "details": [{"type": "Unknown", "detail": {}}]}`), &decoded))
+}
+
+func TestRedaction(t *testing.T) {
+	a := assert.New(t)
+
+	sensitiveKeys.RLock()
+	previous := make(map[string]bool, len(sensitiveKeys.keys))
+	for k, v := range sensitiveKeys.keys {
+		previous[k] = v
+	}
+	sensitiveKeys.RUnlock()
+	t.Cleanup(func() {
+		sensitiveKeys.Lock()
+		defer sensitiveKeys.Unlock()
+		sensitiveKeys.keys = previous
+	})
+	RegisterSensitiveKeys("SSN")
+	inner := New(nil, "inner", Internal).With("ssn", "123-45-6789")
+	err := New(inner, "outer", Internal).
+		WithInternalMessage("internal").
+		WithPublicMessage("public").
+		WithSensitive("email", "user@example.com").
+		With("Password", "hunter2").
+		With("user", "123")
+
+	// the original values can still be accessed
+	a.Equal(Sensitive{Value: "user@example.com"}, err.KeyVals["email"])
+
+	s := err.Error()
+	a.NotContains(s, "user@example.com")
+	a.NotContains(s, "hunter2")
+	a.NotContains(s, "123-45-6789")
+	a.Contains(s, "email: "+RedactedValue)
+	a.Contains(s, "user: 123")
+
+	m := err.ToMap()
+	a.Equal(RedactedValue, m["email"])
+	a.Equal(RedactedValue, m["Password"])
+	a.Equal("123", m["user"])
+	a.Equal(RedactedValue, m["inner"].(map[string]interface{})["ssn"])
+
+	data, jerr := json.Marshal(err)
+	a.Nil(jerr)
+	a.NotContains(string(data), "user@example.com")
+	a.NotContains(string(data), "hunter2")
+	a.NotContains(string(data), "123-45-6789")
+
+	redacted := err.Redact()
+	a.Equal(RedactedValue, redacted.KeyVals["email"])
+	a.Equal(RedactedValue, redacted.KeyVals["Password"])
+	a.Equal("123", redacted.KeyVals["user"])
+	a.Equal(RedactedValue, redacted.Inner.(Error).KeyVals["ssn"])
+	// the original error is not modified
+	a.Equal("hunter2", err.KeyVals["Password"])
+
+	sanitized := err.WithPublicCode(5).WithDetails(BadRequest{}).Sanitize()
+	a.Equal(Error{Code: Internal, PublicCode: 5, PublicMessage: "public", Details: []Detail{BadRequest{}}}, sanitized)
+}
+
+func TestHTTPStatus(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(http.StatusBadRequest, InvalidArgument.HTTPStatus())
+	a.Equal(http.StatusBadRequest, FailedPrecondition.HTTPStatus())
+	a.Equal(http.StatusNotFound, NotFound.HTTPStatus())
+	a.Equal(http.StatusConflict, AlreadyExists.HTTPStatus())
+	a.Equal(http.StatusServiceUnavailable, Unavailable.HTTPStatus())
+	a.Equal(http.StatusTooManyRequests, ResourceExhausted.HTTPStatus())
+	a.Equal(http.StatusInternalServerError, Internal.HTTPStatus())
+	a.Equal(http.StatusInternalServerError, Unknown.HTTPStatus())
+	a.Equal(http.StatusInternalServerError, ErrorCode(42).HTTPStatus())
+
+	a.Equal(Unauthenticated, FromHTTPStatus(http.StatusUnauthorized))
+	a.Equal(ResourceExhausted, FromHTTPStatus(http.StatusTooManyRequests))
+	a.Equal(Unavailable, FromHTTPStatus(http.StatusBadGateway))
+	a.Equal(InvalidArgument, FromHTTPStatus(http.StatusTeapot))
+	a.Equal(Internal, FromHTTPStatus(http.StatusHTTPVersionNotSupported))
+	a.Equal(Unknown, FromHTTPStatus(http.StatusOK))
+
+	// codes with a specific status are converted back to the same code
+	for c := Cancelled; c <= ResourceExhausted; c++ {
+		switch c {
+		case FailedPrecondition, OutOfRange, Aborted, Internal:
+			// share a status with other codes
+			continue
+		}
+		a.Equal(c, FromHTTPStatus(c.HTTPStatus()), "code %v", c)
+	}
+}
+
+func TestWrapAndErrorf(t *testing.T) {
+	a := assert.New(t)
+
+	inner := stderrors.New("connection refused")
+	err := Wrap(inner, "store", Unavailable, "could not load user %v", 42)
+	a.Equal("store", err.Origin)
+	a.Equal(Unavailable, err.Code)
+	a.Equal(inner, err.Inner)
+	a.Equal("could not load user 42", err.InternalMessage)
+	a.True(strings.HasSuffix(err.StackTrace.Frames()[0].Function, "errors.TestWrapAndErrorf"))
+
+	err = Errorf("store", NotFound, "user %v: %w", 42, inner)
+	a.Equal(NotFound, err.Code)
+	a.Equal(inner, err.Inner)
+	a.Equal("user 42: connection refused", err.InternalMessage)
+	a.True(stderrors.Is(err, inner))
+	a.True(strings.HasSuffix(err.StackTrace.Frames()[0].Function, "errors.TestWrapAndErrorf"))
+
+	err = Errorf("store", Internal, "invalid state %v", "x")
+	a.Nil(err.Inner)
+	a.Equal("invalid state x", err.InternalMessage)
+
+	other := stderrors.New("timeout")
+	err = Errorf("store", Internal, "%w and %w", inner, other)
+	a.True(stderrors.Is(err, inner))
+	a.True(stderrors.Is(err, other))
+	a.Equal("connection refused and timeout", err.InternalMessage)
+
+	// an inner error of type Error already has a stack trace
+	a.Nil(Errorf("store", Internal, "wrapped: %w", New(nil, "x", NotFound)).StackTrace)
+}
+
+func TestChain(t *testing.T) {
+	a := assert.New(t)
+
+	root := New(stderrors.New("no rows"), "store", NotFound)
+	middle := fmt.Errorf("loading user: %w", root)
+	outer := New(middle, "service", Internal)
+	outermost := New(outer, "", Unavailable)
+
+	a.Equal(NotFound, RootCode(outermost))
+	a.Equal(NotFound, RootCode(middle))
+	a.Equal(Unknown, RootCode(stderrors.New("x")))
+	a.Equal(Unknown, RootCode(nil))
+
+	a.Equal([]string{"service", "store"}, OriginChain(outermost))
+	a.Nil(OriginChain(stderrors.New("x")))
+
+	e, ok := FirstWithCode(outermost, NotFound)
+	a.True(ok)
+	a.Equal("store", e.Origin)
+	e, ok = FirstWithCode(outermost, Internal)
+	a.True(ok)
+	a.Equal("service", e.Origin)
+	_, ok = FirstWithCode(outermost, PermissionDenied)
+	a.False(ok)
+}
+
+type testSpan struct {
+	noop.Span
+	status      codes.Code
+	description string
+	err         error
+	attrs       []attribute.KeyValue
+}
+
+func (s *testSpan) SetStatus(code codes.Code, description string) {
+	s.status = code
+	s.description = description
+}
+
+func (s *testSpan) RecordError(err error, options ...trace.EventOption) {
+	s.err = err
+	config := trace.NewEventConfig(options...)
+	s.attrs = config.Attributes()
+}
+
+func TestRecordSpan(t *testing.T) {
+	a := assert.New(t)
+
+	span := &testSpan{}
+	RecordSpan(span, nil)
+	a.Equal(codes.Unset, span.status)
+	a.Nil(span.err)
+
+	err := New(nil, "store", NotFound).WithInternalCode(7).With("id", 42).With("password", "abc")
+	RecordSpan(span, err)
+	a.Equal(codes.Error, span.status)
+	a.Equal("NotFound", span.description)
+	a.Equal(err, span.err)
+	a.Equal([]attribute.KeyValue{
+		attribute.String("error.code", "NotFound"),
+		attribute.String("error.origin", "store"),
+		attribute.Int("error.internal_code", 7),
+		attribute.Int("error.id", 42),
+		attribute.String("error.password", RedactedValue),
+	}, span.attrs)
+
+	span = &testSpan{}
+	RecordSpan(span, stderrors.New("broken"))
+	a.Equal(codes.Error, span.status)
+	a.Equal("broken", span.description)
+	a.Empty(span.attrs)
+}
+
+func newFingerprintTestError(origin string, code ErrorCode, message string) Error {
+	return New(nil, origin, code).WithInternalMessage(message)
+}
+
+func TestFingerprint(t *testing.T) {
+	a := assert.New(t)
+
+	// errors created at the same place with different messages and key-values have the same fingerprint
+	e1 := newFingerprintTestError("store", NotFound, "a").With("id", 1)
+	e2 := newFingerprintTestError("store", NotFound, "b").With("id", 2)
+	a.Len(e1.Fingerprint(), 16)
+	a.Equal(e1.Fingerprint(), e2.Fingerprint())
+
+	a.NotEqual(e1.Fingerprint(), newFingerprintTestError("service", NotFound, "a").Fingerprint())
+	a.NotEqual(e1.Fingerprint(), newFingerprintTestError("store", Internal, "a").Fingerprint())
+	a.NotEqual(e1.Fingerprint(), e1.WithInternalCode(1).Fingerprint())
+	// created by a different function
+	a.NotEqual(e1.Fingerprint(), New(nil, "store", NotFound).Fingerprint())
+
+	// the stack trace of an inner error is used if the error has none
+	outer := New(e1, "service", Internal)
+	a.Nil(outer.StackTrace)
+	a.Equal(outer.Fingerprint(), New(e2, "service", Internal).Fingerprint())
+}
+
+func TestHooks(t *testing.T) {
+	a := assert.New(t)
+	defer func() {
+		hooks.global = nil
+		hooks.byOrigin = make(map[string][]Hook)
+	}()
+
+	type call struct {
+		origin string
+		code   ErrorCode
+	}
+	var global, store []call
+	RegisterHook(func(origin string, code ErrorCode) {
+		global = append(global, call{origin, code})
+	})
+	RegisterOriginHook("store", func(origin string, code ErrorCode) {
+		store = append(store, call{origin, code})
+	})
+
+	err := New(nil, "store", NotFound)
+	_ = Errorf("service", Internal, "failed: %w", err)
+	_ = err.WithCode(Unavailable)
+	_ = err.WithInternalMessage("x")
+
+	a.Equal([]call{{"store", NotFound}, {"service", Internal}, {"store", Unavailable}}, global)
+	a.Equal([]call{{"store", NotFound}, {"store", Unavailable}}, store)
+}
+
+func TestCustomErrorCodes(t *testing.T) {
+	a := assert.New(t)
+	t.Cleanup(func() {
+		customCodes.Lock()
+		defer customCodes.Unlock()
+		customCodes.codes = make(map[ErrorCode]customCode)
+		customCodes.byName = make(map[string]ErrorCode)
+	})
+
+	paymentRequired := CustomCodeBase + 1
+	conflict := CustomCodeBase + 2
+	a.Nil(RegisterErrorCode(paymentRequired, "PaymentRequired", http.StatusPaymentRequired))
+	a.Nil(RegisterErrorCode(conflict, "Conflict", 0))
+
+	a.NotNil(RegisterErrorCode(CustomCodeBase-1, "Reserved", 0))
+	a.NotNil(RegisterErrorCode(CustomCodeBase+3, "", 0))
+	a.NotNil(RegisterErrorCode(paymentRequired, "Other", 0))
+	a.NotNil(RegisterErrorCode(CustomCodeBase+3, "PaymentRequired", 0))
+	a.NotNil(RegisterErrorCode(CustomCodeBase+3, "NotFound", 0))
+
+	a.Equal("PaymentRequired", paymentRequired.String())
+	a.Equal("UndefinedErrorCode", (CustomCodeBase + 3).String())
+	a.Equal(http.StatusPaymentRequired, paymentRequired.HTTPStatus())
+	a.Equal(http.StatusInternalServerError, conflict.HTTPStatus())
+	c, ok := ParseErrorCode("Conflict")
+	a.True(ok)
+	a.Equal(conflict, c)
+
+	err := New(nil, "test", paymentRequired)
+	a.Equal("PaymentRequired", err.ToMap()["code"])
+	data, jsonErr := json.Marshal(err)
+	a.Nil(jsonErr)
+	var decoded Error
+	a.Nil(json.Unmarshal(data, &decoded))
+	a.Equal(paymentRequired, decoded.Code)
+}
+
+func TestOptions(t *testing.T) {
+	a := assert.New(t)
+
+	inner := stderrors.New("xyz")
+	err := NewE(NotFound,
+		Origin("store"),
+		Inner(inner),
+		Public(42, "public message"),
+		InternalInfo(43, "internal message"),
+		KV("id", 1, "name", "x", 7),
+		Retryable(true),
+	)
+	a.Equal("store", err.Origin)
+	a.Equal(NotFound, err.Code)
+	a.Equal(inner, err.Inner)
+	a.Equal(42, err.PublicCode)
+	a.Equal("public message", err.PublicMessage)
+	a.Equal(43, err.InternalCode)
+	a.Equal("internal message", err.InternalMessage)
+	a.Equal(map[string]interface{}{"id": 1, "name": "x", "7": nil}, err.KeyVals)
+	a.True(IsRetryable(err))
+	a.True(strings.HasSuffix(err.StackTrace.Frames()[0].Function, "errors.TestOptions"))
+
+	a.Nil(NewE(Internal, KV()).KeyVals)
+
+	f := NewFactory("service", KV("component", "users"), Public(1, "default"))
+	err = f.New(Unavailable, KV("id", 2), Public(0, "message"))
+	a.Equal("service", err.Origin)
+	a.Equal(Unavailable, err.Code)
+	a.Equal(1, err.PublicCode)
+	a.Equal("message", err.PublicMessage)
+	a.Equal(map[string]interface{}{"component": "users", "id": 2}, err.KeyVals)
+	a.True(strings.HasSuffix(err.StackTrace.Frames()[0].Function, "errors.TestOptions"))
+	// errors created by a factory do not share key-values
+	a.Equal(map[string]interface{}{"component": "users"}, f.New(Internal).KeyVals)
+
+	a.Equal("other", f.New(Internal, Origin("other")).Origin)
+}
+
+type opTestStore struct{}
+
+func (opTestStore) Get() error {
+	return New(stderrors.New("no rows"), "store", NotFound)
+}
+
+func opTestService() error {
+	return Errorf("service", Internal, "could not get user: %w", opTestStore{}.Get())
+}
+
+func TestOpAndTime(t *testing.T) {
+	a := assert.New(t)
+
+	before := time.Now()
+	err := opTestService()
+	chain := chainErrors(err)
+	a.Len(chain, 2)
+	a.Equal("errors.opTestService", chain[0].Op)
+	a.Equal("errors.opTestStore.Get", chain[1].Op)
+	for _, e := range chain {
+		a.False(e.Time.Before(before))
+		a.False(e.Time.After(time.Now()))
+	}
+
+	a.Equal("errors.TestOpAndTime", NewE(Internal).Op)
+	a.Equal("errors.TestOpAndTime", NewFactory("x").New(Internal).Op)
+	a.Equal("errors.TestOpAndTime", Wrap(nil, "x", Internal, "y").Op)
+	a.Equal("custom", New(nil, "x", Internal).WithOp("custom").Op)
+
+	e := chain[0]
+	m := e.ToMap()
+	a.Equal(e.Op, m["op"])
+	a.Equal(e.Time, m["time"])
+	a.Contains(e.Error(), "op: errors.opTestService")
+
+	data, jerr := json.Marshal(e)
+	a.Nil(jerr)
+	var decoded Error
+	a.Nil(json.Unmarshal(data, &decoded))
+	a.Equal(e.Op, decoded.Op)
+	a.True(e.Time.Equal(decoded.Time))
+	a.Equal("errors.opTestStore.Get", decoded.Inner.(Error).Op)
+
+	data, jerr = json.Marshal(Error{Code: Internal})
+	a.Nil(jerr)
+	a.NotContains(string(data), "time")
+	a.NotContains(Error{}.ToMap(), "time")
+}
+
 func TestHasCode(t *testing.T) {
 	a := assert.New(t)
 
@@ -214,6 +766,32 @@ func TestHasCode(t *testing.T) {
 	for i, c := range cases {
 		a.Equal(c.Expected, HasInternalCode(c.Err, c.Code), "test case %v", i)
 	}
+
+	wrapped := fmt.Errorf("wrapped: %w", New(nil, "test", InvalidArgument).WithPublicCode(42).WithInternalCode(43))
+	a.True(HasPublicCode(wrapped, 42))
+	a.True(HasInternalCode(wrapped, 43))
+}
+
+func TestStdlibCompatibility(t *testing.T) {
+	a := assert.New(t)
+
+	sentinel := stderrors.New("sentinel")
+	err := New(New(sentinel, "inner", NotFound).WithPublicCode(5), "outer", Internal)
+
+	a.Equal(sentinel, stderrors.Unwrap(stderrors.Unwrap(err)))
+	a.True(stderrors.Is(err, sentinel))
+	a.True(stderrors.Is(fmt.Errorf("wrapped: %w", err), sentinel))
+
+	// codes are compared if the target is an Error
+	a.True(stderrors.Is(err, Error{Code: Internal}))
+	a.True(stderrors.Is(err, Error{Code: NotFound}))
+	a.True(stderrors.Is(err, Error{Code: NotFound, PublicCode: 5}))
+	a.False(stderrors.Is(err, Error{Code: NotFound, PublicCode: 6}))
+	a.False(stderrors.Is(err, Error{Code: AlreadyExists}))
+
+	var e Error
+	a.True(stderrors.As(fmt.Errorf("wrapped: %w", err), &e))
+	a.Equal("outer", e.Origin)
 }
 
 func TestUnstackErrors(t *testing.T) {
@@ -252,6 +830,13 @@ func TestUnstackErrors(t *testing.T) {
 				eeee,
 			},
 		},
+		{
+			Err: fmt.Errorf("wrapped: %w", case1),
+			Expected: []error{
+				fmt.Errorf("wrapped: %w", case1),
+				case1,
+			},
+		},
 	}
 	for i, c := range cases {
 		actual := UnstackErrors(c.Err)
@@ -0,0 +1,29 @@
+package errors
+
+import (
+	"fmt"
+	"hash/fnv"
+	"runtime"
+)
+
+// Returns a hash that identifies the kind of the error, e.g. so that log pipelines and alerting can group identical errors.
+// The hash is computed from the origin, code, internal code and the function of the top frame of the stack trace.
+// Messages, key-values and line numbers are ignored, since they can differ for errors with the same cause or change between versions of a program.
+// If the error has no stack trace, the stack trace of the first inner error of type Error that has one is used.
+func (e Error) Fingerprint() string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%v\x00%v\x00%v\x00", e.Origin, e.Code, e.InternalCode)
+	for _, x := range chainErrors(e) {
+		if len(x.StackTrace) > 0 {
+			h.Write([]byte(x.StackTrace.topFunction()))
+			break
+		}
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// Returns the function of the innermost function call of the stack trace.
+func (s StackTrace) topFunction() string {
+	frame, _ := runtime.CallersFrames(s[:1]).Next()
+	return frame.Function
+}
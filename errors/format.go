@@ -0,0 +1,30 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+)
+
+// Creates an error with the given inner error and an internal message formatted with fmt.Errorf, i.e. a shorthand for
+//
+//	New(err, origin, code).WithInternalMessage(fmt.Sprintf(format, args...))
+func Wrap(err error, origin string, code ErrorCode, format string, args ...interface{}) Error {
+	return newError(err, origin, code).WithInternalMessage(fmt.Errorf(format, args...).Error())
+}
+
+// Creates an error with an internal message formatted with fmt.Errorf.
+// Like for fmt.Errorf, an error argument for a %w verb is wrapped, i.e. it becomes the inner error:
+//
+//	Errorf("store", NotFound, "could not find user %v: %w", id, err)
+//
+// If the format contains multiple %w verbs, the inner error wraps all of them.
+func Errorf(origin string, code ErrorCode, format string, args ...interface{}) Error {
+	formatted := fmt.Errorf(format, args...)
+	var inner error
+	if wrapped := stderrors.Unwrap(formatted); wrapped != nil {
+		inner = wrapped
+	} else if _, ok := formatted.(interface{ Unwrap() []error }); ok {
+		inner = formatted
+	}
+	return newError(inner, origin, code).WithInternalMessage(formatted.Error())
+}
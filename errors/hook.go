@@ -0,0 +1,40 @@
+package errors
+
+import "sync"
+
+// Hook is called whenever an error is created with New, Wrap or Errorf or the code of an error is changed with WithCode.
+// Hooks can be used to collect metrics without changes to the code that creates errors,
+// e.g. to increment a Prometheus counter labeled by origin and code.
+// Hooks are called synchronously and must therefore be fast and safe for concurrent use.
+type Hook func(origin string, code ErrorCode)
+
+var hooks = struct {
+	sync.RWMutex
+	global   []Hook
+	byOrigin map[string][]Hook
+}{byOrigin: make(map[string][]Hook)}
+
+// Registers a hook that is called for errors of any origin.
+func RegisterHook(hook Hook) {
+	hooks.Lock()
+	defer hooks.Unlock()
+	hooks.global = append(hooks.global, hook)
+}
+
+// Registers a hook that is only called for errors with the given origin.
+func RegisterOriginHook(origin string, hook Hook) {
+	hooks.Lock()
+	defer hooks.Unlock()
+	hooks.byOrigin[origin] = append(hooks.byOrigin[origin], hook)
+}
+
+func runHooks(origin string, code ErrorCode) {
+	hooks.RLock()
+	defer hooks.RUnlock()
+	for _, hook := range hooks.global {
+		hook(origin, code)
+	}
+	for _, hook := range hooks.byOrigin[origin] {
+		hook(origin, code)
+	}
+}
@@ -0,0 +1,77 @@
+package errors
+
+import "net/http"
+
+// Returns an appropriate http response status code for errors with this code.
+// The mapping is the same as the one of the gRPC HTTP gateway, e.g. NotFound is mapped to http.StatusNotFound and
+// Unavailable to http.StatusServiceUnavailable. Codes without a more specific status are mapped to http.StatusInternalServerError.
+// Custom codes are mapped to the status they were registered with, see RegisterErrorCode.
+func (e ErrorCode) HTTPStatus() int {
+	switch e {
+	case Cancelled:
+		// non-standard status "Client Closed Request"
+		return 499
+	case InvalidArgument, FailedPrecondition, OutOfRange:
+		return http.StatusBadRequest
+	case DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case NotFound:
+		return http.StatusNotFound
+	case AlreadyExists, Aborted:
+		return http.StatusConflict
+	case PermissionDenied:
+		return http.StatusForbidden
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case Unimplemented:
+		return http.StatusNotImplemented
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case ResourceExhausted:
+		return http.StatusTooManyRequests
+	default:
+		if c, ok := lookupCustomCode(e); ok {
+			return c.httpStatus
+		}
+		return http.StatusInternalServerError
+	}
+}
+
+// Returns the error code for a http response status code, e.g. to convert the response of an upstream service into an Error.
+// Unknown is returned for status codes that do not indicate an error.
+// Client errors without a more specific code are mapped to InvalidArgument and server errors to Internal.
+func FromHTTPStatus(status int) ErrorCode {
+	switch status {
+	case 499:
+		return Cancelled
+	case http.StatusBadRequest:
+		return InvalidArgument
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusForbidden:
+		return PermissionDenied
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return DeadlineExceeded
+	case http.StatusConflict:
+		return AlreadyExists
+	case http.StatusPreconditionFailed:
+		return FailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return OutOfRange
+	case http.StatusTooManyRequests:
+		return ResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return Unavailable
+	case http.StatusNotImplemented:
+		return Unimplemented
+	}
+	switch {
+	case status >= 400 && status < 500:
+		return InvalidArgument
+	case status >= 500 && status < 600:
+		return Internal
+	}
+	return Unknown
+}
@@ -0,0 +1,157 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+)
+
+// JSON representation of an Error, codes and severities are encoded by name.
+type jsonError struct {
+	Origin          string                 `json:"origin,omitempty"`
+	Op              string                 `json:"op,omitempty"`
+	Time            *time.Time             `json:"time,omitempty"`
+	Code            string                 `json:"code"`
+	PublicCode      int                    `json:"publicCode,omitempty"`
+	PublicMessage   string                 `json:"publicMessage,omitempty"`
+	InternalCode    int                    `json:"internalCode,omitempty"`
+	InternalMessage string                 `json:"internalMessage,omitempty"`
+	Retryable       *bool                  `json:"retryable,omitempty"`
+	Severity        string                 `json:"severity,omitempty"`
+	KeyVals         map[string]interface{} `json:"keyVals,omitempty"`
+	Details         []jsonDetail           `json:"details,omitempty"`
+	// Inner error of type Error as object, other errors as string with their error message.
+	Inner json.RawMessage `json:"inner,omitempty"`
+}
+
+// Encodes the error as JSON, so that it can be transmitted to another service and decoded again with UnmarshalJSON.
+// Inner errors of type Error are encoded recursively, inner errors of other types only with their error message.
+// The stack trace is not encoded, since it is only meaningful in the process that created the error,
+// sensitive values of key-value pairs are redacted (see Redact).
+// Unlike the map returned by ToMap, the result can be decoded again without losing information,
+// except that values of KeyVals are decoded as the default types of package encoding/json, e.g. float64 for numbers.
+func (e Error) MarshalJSON() ([]byte, error) {
+	je := jsonError{
+		Origin:          e.Origin,
+		Op:              e.Op,
+		Code:            e.Code.String(),
+		PublicCode:      e.PublicCode,
+		PublicMessage:   e.PublicMessage,
+		InternalCode:    e.InternalCode,
+		InternalMessage: e.InternalMessage,
+		Retryable:       e.Retryable,
+		KeyVals:         e.Redact().KeyVals,
+	}
+	if !e.Time.IsZero() {
+		je.Time = &e.Time
+	}
+	if e.Severity != SeverityUnset {
+		je.Severity = e.Severity.String()
+	}
+	var err error
+	je.Details, err = encodeDetails(e.Details)
+	if err != nil {
+		return nil, err
+	}
+	if e.Inner != nil {
+		if inner, ok := e.Inner.(Error); ok {
+			je.Inner, err = json.Marshal(inner)
+		} else {
+			je.Inner, err = json.Marshal(e.Inner.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(je)
+}
+
+// Decodes an error encoded with MarshalJSON.
+// Inner errors that are not of type Error are decoded as errors with the original error message.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var je jsonError
+	if err := json.Unmarshal(data, &je); err != nil {
+		return err
+	}
+	code, ok := ParseErrorCode(je.Code)
+	if !ok {
+		return stderrors.New(fmt.Sprintf("unknown error code %v", je.Code))
+	}
+	severity := SeverityUnset
+	if je.Severity != "" {
+		severity, ok = ParseSeverity(je.Severity)
+		if !ok {
+			return stderrors.New(fmt.Sprintf("unknown severity %v", je.Severity))
+		}
+	}
+
+	details, err := decodeDetails(je.Details)
+	if err != nil {
+		return err
+	}
+
+	var inner error
+	if len(je.Inner) > 0 && !bytes.Equal(je.Inner, []byte("null")) {
+		if je.Inner[0] == '"' {
+			var message string
+			if err := json.Unmarshal(je.Inner, &message); err != nil {
+				return err
+			}
+			inner = stderrors.New(message)
+		} else {
+			var innerError Error
+			if err := json.Unmarshal(je.Inner, &innerError); err != nil {
+				return err
+			}
+			inner = innerError
+		}
+	}
+
+	var t time.Time
+	if je.Time != nil {
+		t = *je.Time
+	}
+
+	*e = Error{
+		Origin:          je.Origin,
+		Op:              je.Op,
+		Time:            t,
+		Inner:           inner,
+		Code:            code,
+		PublicCode:      je.PublicCode,
+		PublicMessage:   je.PublicMessage,
+		InternalCode:    je.InternalCode,
+		InternalMessage: je.InternalMessage,
+		Retryable:       je.Retryable,
+		Severity:        severity,
+		KeyVals:         je.KeyVals,
+		Details:         details,
+	}
+	return nil
+}
+
+// Returns the error code with the given name, e.g. NotFound for "NotFound", see ErrorCode.String.
+// Custom codes registered with RegisterErrorCode are recognized as well.
+func ParseErrorCode(s string) (ErrorCode, bool) {
+	for c := Unknown; c <= ResourceExhausted; c++ {
+		if c.String() == s {
+			return c, true
+		}
+	}
+	if c, ok := lookupCustomCodeName(s); ok {
+		return c, true
+	}
+	return Unknown, false
+}
+
+// Returns the severity with the given name, e.g. SeverityWarning for "Warning", see Severity.String.
+func ParseSeverity(s string) (Severity, bool) {
+	for x := SeverityUnset; x <= SeverityCritical; x++ {
+		if x.String() == s {
+			return x, true
+		}
+	}
+	return SeverityUnset, false
+}
@@ -0,0 +1,115 @@
+package errors
+
+import "fmt"
+
+// Option sets fields of an error created with NewE or a Factory.
+type Option func(*Error)
+
+// Creates an error with the given code and options, an alternative to long chains of With* methods, e.g.
+//
+//	NewE(NotFound, Origin("store"), Public(42, "user not found"), KV("id", id))
+//
+// Like for New, a stack trace is added if the inner error is not of type Error and registered hooks are called.
+func NewE(code ErrorCode, opts ...Option) Error {
+	return newErrorWithOptions(code, opts)
+}
+
+func newErrorWithOptions(code ErrorCode, opts []Option) Error {
+	e := Error{Code: code}
+	for _, opt := range opts {
+		opt(&e)
+	}
+	return initError(e)
+}
+
+func Origin(origin string) Option {
+	return func(e *Error) {
+		e.Origin = origin
+	}
+}
+
+func Inner(inner error) Option {
+	return func(e *Error) {
+		e.Inner = inner
+	}
+}
+
+// Sets the public code and message, zero values are ignored.
+func Public(code int, message string) Option {
+	return func(e *Error) {
+		if code != 0 {
+			e.PublicCode = code
+		}
+		if message != "" {
+			e.PublicMessage = message
+		}
+	}
+}
+
+// Sets the internal code and message, zero values are ignored.
+// Not named Internal, since that is the name of an ErrorCode.
+func InternalInfo(code int, message string) Option {
+	return func(e *Error) {
+		if code != 0 {
+			e.InternalCode = code
+		}
+		if message != "" {
+			e.InternalMessage = message
+		}
+	}
+}
+
+// Adds key-value pairs, keyvals must contain alternating keys and values like for a go-kit logger.
+// Keys that are not strings are formatted with fmt.Sprint, a missing value for the last key is set to nil.
+func KV(keyvals ...interface{}) Option {
+	return func(e *Error) {
+		if len(keyvals) > 0 && e.KeyVals == nil {
+			e.KeyVals = make(map[string]interface{}, len(keyvals)/2)
+		}
+		for i := 0; i < len(keyvals); i += 2 {
+			var value interface{}
+			if i+1 < len(keyvals) {
+				value = keyvals[i+1]
+			}
+			key, ok := keyvals[i].(string)
+			if !ok {
+				key = fmt.Sprint(keyvals[i])
+			}
+			e.KeyVals[key] = value
+		}
+	}
+}
+
+func Retryable(retryable bool) Option {
+	return func(e *Error) {
+		e.Retryable = &retryable
+	}
+}
+
+// A Factory creates errors with the same origin and default options,
+// e.g. a package can create a factory once and use it for all the errors it returns.
+//
+//	var errs = errors.NewFactory("store", errors.KV("db", "users"))
+//
+//	func (s *Store) Get(id string) (User, error) {
+//		...
+//		return User{}, errs.New(errors.NotFound, errors.KV("id", id))
+//	}
+type Factory struct {
+	origin string
+	opts   []Option
+}
+
+func NewFactory(origin string, defaultOpts ...Option) Factory {
+	return Factory{origin: origin, opts: defaultOpts}
+}
+
+// Creates an error with the origin of the factory and the given code.
+// The default options of the factory are applied before the given options.
+func (f Factory) New(code ErrorCode, opts ...Option) Error {
+	all := make([]Option, 0, len(f.opts)+len(opts)+1)
+	all = append(all, Origin(f.origin))
+	all = append(all, f.opts...)
+	all = append(all, opts...)
+	return newErrorWithOptions(code, all)
+}
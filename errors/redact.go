@@ -0,0 +1,104 @@
+package errors
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// Placeholder for sensitive values in the string and map representations of an error.
+const RedactedValue = "[REDACTED]"
+
+// Sensitive marks a value of a key-value pair of an error as sensitive, see WithSensitive.
+// The value is never part of the string, map or JSON representation of an error, but can still be accessed by code.
+type Sensitive struct {
+	Value interface{}
+}
+
+func (s Sensitive) String() string {
+	return RedactedValue
+}
+
+func (s Sensitive) GoString() string {
+	return RedactedValue
+}
+
+func (s Sensitive) MarshalJSON() ([]byte, error) {
+	return json.Marshal(RedactedValue)
+}
+
+var sensitiveKeys = struct {
+	sync.RWMutex
+	keys map[string]bool
+}{keys: map[string]bool{
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"authorization": true,
+	"apikey":        true,
+	"cookie":        true,
+}}
+
+// Registers names of keys whose values are always treated as sensitive, in addition to values added with WithSensitive.
+// Keys are compared case-insensitively. By default "password", "secret", "token", "authorization", "apikey" and "cookie" are registered.
+func RegisterSensitiveKeys(keys ...string) {
+	sensitiveKeys.Lock()
+	defer sensitiveKeys.Unlock()
+	for _, key := range keys {
+		sensitiveKeys.keys[strings.ToLower(key)] = true
+	}
+}
+
+func isSensitive(key string, value interface{}) bool {
+	if _, ok := value.(Sensitive); ok {
+		return true
+	}
+	sensitiveKeys.RLock()
+	defer sensitiveKeys.RUnlock()
+	return sensitiveKeys.keys[strings.ToLower(key)]
+}
+
+// Returns the value of a key-value pair as it is included in the string and map representations of an error.
+func redactedValue(key string, value interface{}) interface{} {
+	if isSensitive(key, value) {
+		return RedactedValue
+	}
+	return value
+}
+
+// Adds a key-value pair with a sensitive value, e.g. a token or personal data.
+// The value is replaced with RedactedValue in the string, map and JSON representations of the error.
+func (e Error) WithSensitive(key string, value interface{}) Error {
+	return e.With(key, Sensitive{Value: value})
+}
+
+// Returns a copy of the error, in which sensitive values of key-value pairs are replaced with RedactedValue,
+// e.g. before passing the error to code that does not use ToMap or Error to log it.
+// Values are sensitive if they were added with WithSensitive or their key is registered with RegisterSensitiveKeys.
+// Inner errors of type Error are redacted as well.
+func (e Error) Redact() Error {
+	if e.KeyVals != nil {
+		keyVals := make(map[string]interface{}, len(e.KeyVals))
+		for key, value := range e.KeyVals {
+			keyVals[key] = redactedValue(key, value)
+		}
+		e.KeyVals = keyVals
+	}
+	if inner, ok := e.Inner.(Error); ok {
+		e.Inner = inner.Redact()
+	}
+	return e
+}
+
+// Returns an error that only contains the fields that are safe to be returned to clients,
+// i.e. the code, public code and message, retryability and details.
+// Origin, inner errors, stack trace, internal code and message, severity and key-value pairs are removed.
+func (e Error) Sanitize() Error {
+	return Error{
+		Code:          e.Code,
+		PublicCode:    e.PublicCode,
+		PublicMessage: e.PublicMessage,
+		Retryable:     e.Retryable,
+		Details:       e.Details,
+	}
+}
@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Maximum number of frames of the stack traces captured by New, if 0 no stack traces are captured.
+// Should be set when a program is initialized, before any errors are created.
+var MaxStackDepth = 32
+
+// StackTrace contains the program counters of the function calls on the stack when an error was created.
+// Capturing program counters is cheap, they are only resolved to functions, files and lines when
+// the stack trace is formatted or logged, see Frames.
+type StackTrace []uintptr
+
+// A function call of a stack trace.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Captures the stack trace of the calling goroutine, skip is the number of frames to skip like for runtime.Callers.
+func callers(skip int) StackTrace {
+	if MaxStackDepth <= 0 {
+		return nil
+	}
+	pcs := make([]uintptr, MaxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	return StackTrace(pcs[:n])
+}
+
+// Returns the name of a calling function without the package path, e.g. "store.(*Store).Get",
+// skip is the number of stack frames to skip like for runtime.Caller.
+func callerOp(skip int) string {
+	// unlike runtime.Caller, runtime.Callers accounts for inlined functions
+	pc := make([]uintptr, 1)
+	// skip runtime.Callers and callerOp
+	if runtime.Callers(skip+2, pc) == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames(pc).Next()
+	name := frame.Function
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// Returns the frames of the stack trace, the innermost function call first.
+func (s StackTrace) Frames() []Frame {
+	if len(s) == 0 {
+		return nil
+	}
+	var result []Frame
+	frames := runtime.CallersFrames(s)
+	for {
+		frame, more := frames.Next()
+		result = append(result, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		if !more {
+			break
+		}
+	}
+	return result
+}
+
+// Formats the stack trace like runtime/debug.Stack, i.e. every frame as a line with the function followed by
+// a line with the file and line number.
+func (s StackTrace) String() string {
+	var b strings.Builder
+	for i, frame := range s.Frames() {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%v\n\t%v:%v", frame.Function, frame.File, frame.Line)
+	}
+	return b.String()
+}
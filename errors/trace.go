@@ -0,0 +1,73 @@
+package errors
+
+import (
+	"fmt"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Records the given error on an OpenTelemetry span, so that services using this package have consistent tracing semantics.
+// The error is added as an exception event and the span status is set to codes.Error, with the name of the ErrorCode as description.
+// For errors of type Error, the code, origin, public/internal codes and key-value pairs are added as attributes of the event,
+// with keys prefixed by "error.". Sensitive key-values are redacted, see WithSensitive.
+// Does nothing if err is nil.
+func RecordSpan(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	e, ok := asError(err)
+	if !ok {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.RecordError(err, trace.WithAttributes(spanAttributes(e)...))
+	span.SetStatus(codes.Error, e.Code.String())
+}
+
+func spanAttributes(e Error) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("error.code", e.Code.String()),
+	}
+	if e.Origin != "" {
+		attrs = append(attrs, attribute.String("error.origin", e.Origin))
+	}
+	if e.PublicCode != 0 {
+		attrs = append(attrs, attribute.Int("error.public_code", e.PublicCode))
+	}
+	if e.InternalCode != 0 {
+		attrs = append(attrs, attribute.Int("error.internal_code", e.InternalCode))
+	}
+	// sort keys so that attributes have a deterministic order
+	keys := make([]string, 0, len(e.KeyVals))
+	for key := range e.KeyVals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attrs = append(attrs, spanAttribute("error."+key, redactedValue(key, e.KeyVals[key])))
+	}
+	return attrs
+}
+
+func spanAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", v))
+	}
+}
@@ -24,6 +24,39 @@ type User struct {
 
 type ClaimsFunc func(map[string]interface{}) (interface{}, error)
 
+// A ClaimsFunc that accepts all tokens and returns the claims of the token unchanged.
+// Use it with NewAuthChecker to check claims with User.HasClaims.
+func AllClaims(claims map[string]interface{}) (interface{}, error) {
+	return claims, nil
+}
+
+// Custom claims (see ClaimsFunc) can implement this interface to be checked by User.HasClaims.
+type ClaimsChecker interface {
+	HasClaim(name string) bool
+}
+
+// Returns true if the user has all of the given claims.
+// If the custom claims of the user implement ClaimsChecker, its HasClaim method is used.
+// If they are a map, e.g. when using AllClaims, a claim must have the value true, like custom claims {"admin": true} set with the Firebase Admin SDK.
+// Always returns true if no claims are given.
+func (u User) HasClaims(claims ...string) bool {
+	for _, claim := range claims {
+		switch c := u.CustomClaims.(type) {
+		case ClaimsChecker:
+			if !c.HasClaim(claim) {
+				return false
+			}
+		case map[string]interface{}:
+			if v, ok := c[claim].(bool); !ok || !v {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // AuthChecker checks if a given JWT token is a valid Firebase Authentication token.
 // If the token is valid, a User that contains the user id and custom claims is returned.
 type AuthChecker interface {
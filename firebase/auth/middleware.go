@@ -22,6 +22,12 @@ type ContextBuilderFunc func(context.Context, User) context.Context
 //
 // If the request is not authenticated, the middleware will return an error immediately and not call the next endpoint handler.
 func NewAuthEndpointMiddleware(ac AuthChecker, ctxBuilder ContextBuilderFunc) endpoint.Middleware {
+	return NewClaimsEndpointMiddleware(ac, ctxBuilder)
+}
+
+// Works like NewAuthEndpointMiddleware, but additionally requires the authenticated user to have all of the given claims, see User.HasClaims.
+// If a claim is missing, the middleware returns an error with code PermissionDenied and does not call the next endpoint handler.
+func NewClaimsEndpointMiddleware(ac AuthChecker, ctxBuilder ContextBuilderFunc, claims ...string) endpoint.Middleware {
 	if ac == nil {
 		panic("AuthChecker is nil")
 	}
@@ -37,6 +43,10 @@ func NewAuthEndpointMiddleware(ac AuthChecker, ctxBuilder ContextBuilderFunc) en
 			if err != nil {
 				return nil, err
 			}
+			if !user.HasClaims(claims...) {
+				return nil, errors.New(nil, authMwErrOrigin, errors.PermissionDenied).
+					WithPublicMessage("missing claims")
+			}
 			var newCtx context.Context = ctx
 			if ctxBuilder != nil {
 				newCtx = ctxBuilder(ctx, user)
@@ -112,3 +112,48 @@ func TestAuthEndpointMiddlewarePanicsIfAuthCheckerIsNil(t *testing.T) {
 	}()
 	a.True(paniced)
 }
+
+type testClaims []string
+
+func (c testClaims) HasClaim(name string) bool {
+	for _, x := range c {
+		if x == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClaimsEndpointMiddleware(t *testing.T) {
+	a := assert.New(t)
+
+	ep := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	token := "justatoken"
+	ctx := context.WithValue(context.Background(), kitjwt.JWTContextKey, token)
+
+	for _, customClaims := range []interface{}{
+		map[string]interface{}{"admin": true, "editor": false},
+		testClaims{"admin"},
+	} {
+		ac := &MockAuthChecker{}
+		ac.On("IsAuthenticated", token).Return(User{Uid: "u-1234-5678", CustomClaims: customClaims}, nil)
+
+		resp, err := NewClaimsEndpointMiddleware(ac, nil, "admin")(ep)(ctx, nil)
+		a.Nil(err)
+		a.Equal("ok", resp)
+
+		resp, err = NewClaimsEndpointMiddleware(ac, nil, "admin", "editor")(ep)(ctx, nil)
+		a.Nil(resp)
+		a.True(errors.Is(err, errors.PermissionDenied))
+	}
+
+	// users without custom claims only pass if no claims are required
+	ac := &MockAuthChecker{}
+	ac.On("IsAuthenticated", token).Return(User{Uid: "u-1234-5678"}, nil)
+	_, err := NewClaimsEndpointMiddleware(ac, nil)(ep)(ctx, nil)
+	a.Nil(err)
+	_, err = NewClaimsEndpointMiddleware(ac, nil, "admin")(ep)(ctx, nil)
+	a.True(errors.Is(err, errors.PermissionDenied))
+}
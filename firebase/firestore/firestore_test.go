@@ -10,6 +10,7 @@ import (
 	"github.com/dkinzler/kit/errors"
 	"github.com/dkinzler/kit/firebase"
 	"github.com/dkinzler/kit/firebase/emulator"
+	transport "github.com/dkinzler/kit/transport/http"
 
 	"cloud.google.com/go/firestore"
 	"github.com/stretchr/testify/assert"
@@ -262,3 +263,53 @@ func TestTransactionExpectations(t *testing.T) {
 	a.Nil(err)
 	a.NotNil(tes.Verify(snaps))
 }
+
+func TestIdempotencyStore(t *testing.T) {
+	a := assert.New(t)
+
+	fs, err := initTest(t)
+	a.Nil(err)
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	var s transport.IdempotencyStore = NewIdempotencyStore(fs, "idempotency")
+
+	_, ok, err := s.Begin(ctx, "POST /pay k1", time.Minute)
+	a.Nil(err)
+	a.True(ok)
+	response, ok, err := s.Begin(ctx, "POST /pay k1", time.Minute)
+	a.Nil(err)
+	a.False(ok)
+	a.Nil(response)
+
+	err = s.Complete(ctx, "POST /pay k1", transport.IdempotentResponse{
+		StatusCode: 201,
+		Header:     map[string][]string{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"id": 1}`),
+	}, time.Hour)
+	a.Nil(err)
+	response, ok, err = s.Begin(ctx, "POST /pay k1", time.Minute)
+	a.Nil(err)
+	a.False(ok)
+	a.Equal(201, response.StatusCode)
+	a.Equal([]byte(`{"id": 1}`), response.Body)
+	a.Equal("application/json", response.Header.Get("Content-Type"))
+
+	// released keys can be used again
+	_, ok, err = s.Begin(ctx, "POST /pay k2", time.Minute)
+	a.Nil(err)
+	a.True(ok)
+	a.Nil(s.Release(ctx, "POST /pay k2"))
+	_, ok, err = s.Begin(ctx, "POST /pay k2", time.Minute)
+	a.Nil(err)
+	a.True(ok)
+
+	// expired keys can be used again
+	_, ok, err = s.Begin(ctx, "POST /pay k3", -time.Second)
+	a.Nil(err)
+	a.True(ok)
+	_, ok, err = s.Begin(ctx, "POST /pay k3", time.Minute)
+	a.Nil(err)
+	a.True(ok)
+}
@@ -0,0 +1,103 @@
+package firestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	transport "github.com/dkinzler/kit/transport/http"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IdempotencyStore implements the IdempotencyStore interface of package "github.com/dkinzler/kit/transport/http"
+// by storing responses as documents of a Firestore collection, so that it can be shared by multiple instances of a service.
+//
+// Documents have an "expiresAt" field, a Firestore TTL policy on this field can be used to delete expired documents.
+// Note that Firestore documents are limited to 1 MiB, responses with larger bodies cannot be stored.
+type IdempotencyStore struct {
+	client *firestore.Client
+	col    *firestore.CollectionRef
+}
+
+func NewIdempotencyStore(client *firestore.Client, collection string) *IdempotencyStore {
+	return &IdempotencyStore{
+		client: client,
+		col:    client.Collection(collection),
+	}
+}
+
+type idempotencyDoc struct {
+	Completed  bool                `firestore:"completed"`
+	StatusCode int                 `firestore:"statusCode"`
+	Header     map[string][]string `firestore:"header"`
+	Body       []byte              `firestore:"body"`
+	ExpiresAt  time.Time           `firestore:"expiresAt"`
+}
+
+// Keys can contain characters that are not allowed in document ids, e.g. "/".
+func (s *IdempotencyStore) doc(key string) *firestore.DocumentRef {
+	h := sha256.Sum256([]byte(key))
+	return s.col.Doc(hex.EncodeToString(h[:]))
+}
+
+func (s *IdempotencyStore) Begin(ctx context.Context, key string, ttl time.Duration) (*transport.IdempotentResponse, bool, error) {
+	ref := s.doc(key)
+	var response *transport.IdempotentResponse
+	var started bool
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		response, started = nil, false
+		snap, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		now := time.Now()
+		if snap != nil && snap.Exists() {
+			var doc idempotencyDoc
+			if err := snap.DataTo(&doc); err != nil {
+				return err
+			}
+			if now.Before(doc.ExpiresAt) {
+				if doc.Completed {
+					response = &transport.IdempotentResponse{
+						StatusCode: doc.StatusCode,
+						Header:     doc.Header,
+						Body:       doc.Body,
+					}
+				}
+				return nil
+			}
+		}
+		started = true
+		return tx.Set(ref, idempotencyDoc{ExpiresAt: now.Add(ttl)})
+	})
+	if err != nil {
+		return nil, false, ParseFirestoreError(err).WithInternalMessage("could not begin idempotent request")
+	}
+	return response, started, nil
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, response transport.IdempotentResponse, ttl time.Duration) error {
+	_, err := s.doc(key).Set(ctx, idempotencyDoc{
+		Completed:  true,
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       response.Body,
+		ExpiresAt:  time.Now().Add(ttl),
+	})
+	if err != nil {
+		return ParseFirestoreError(err).WithInternalMessage("could not store idempotent response")
+	}
+	return nil
+}
+
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	_, err := s.doc(key).Delete(ctx)
+	if err != nil {
+		return ParseFirestoreError(err).WithInternalMessage("could not release idempotency key")
+	}
+	return nil
+}
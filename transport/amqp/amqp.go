@@ -0,0 +1,98 @@
+// Package amqp provides helpers to build Go kit (github.com/go-kit/kit) AMQP subscribers and publishers
+// for endpoints that return values implementing endpoint.Responder (see package "github.com/dkinzler/kit/endpoint").
+//
+// Requests are encoded as JSON. Response messages contain either the result or the error returned by an endpoint, e.g.
+//
+//	{"result": {...}}
+//	{"error": {"code": "InvalidArgument", "publicCode": 42, "message": "this is an example error message"}}
+package amqp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dkinzler/kit/errors"
+	"github.com/dkinzler/kit/transport/internal/message"
+
+	kitamqp "github.com/go-kit/kit/transport/amqp"
+	"github.com/streadway/amqp"
+)
+
+const errorOrigin = "transport/amqp"
+
+// Tries to decode the body of the given delivery into target.
+func DecodeJSONRequest(deliv *amqp.Delivery, target interface{}) error {
+	err := json.Unmarshal(deliv.Body, target)
+	if err != nil {
+		return errors.New(err, errorOrigin, errors.InvalidArgument).WithPublicMessage("could not decode json request")
+	}
+	return nil
+}
+
+// A DecodeRequestFunc for endpoints that do not need a request value.
+func NopRequestDecoder(_ context.Context, _ *amqp.Delivery) (interface{}, error) {
+	return nil, nil
+}
+
+// Encodes the request as JSON, can be used as the EncodeRequestFunc of a Go kit publisher.
+func EncodeJSONRequest(_ context.Context, pub *amqp.Publishing, request interface{}) error {
+	b, err := json.Marshal(request)
+	if err != nil {
+		return errors.New(err, errorOrigin, errors.Internal).WithInternalMessage("could not encode json request")
+	}
+	pub.Body = b
+	return nil
+}
+
+// A generic response encoder function for Go kit subscribers.
+// Use this function only if the response value returned by the endpoint implements the Responder interface from package "github.com/dkinzler/kit/endpoint".
+func EncodeGenericJSONResponse(_ context.Context, pub *amqp.Publishing, response interface{}) error {
+	b, err := message.EncodeResponse(response)
+	if err != nil {
+		return err
+	}
+	pub.Body = b
+	return nil
+}
+
+// Replies to the delivery with an error message in the same format as errors returned by the service.
+// Can be used with kitamqp.SubscriberErrorEncoder.
+func EncodeError(ctx context.Context, err error, deliv *amqp.Delivery, ch kitamqp.Channel, pub *amqp.Publishing) {
+	b, err := message.EncodeError(err)
+	if err != nil {
+		return
+	}
+	pub.Body = b
+	kitamqp.DefaultResponsePublisher(ctx, deliv, ch, pub)
+}
+
+// Decodes a response message encoded with EncodeGenericJSONResponse or EncodeError into a value of type endpoint.Response,
+// where the result is of type T. Errors are of type Error from package "github.com/dkinzler/kit/errors".
+// For endpoints without a result use interface{} as type parameter.
+func DecodeJSONResponse[T any](_ context.Context, deliv *amqp.Delivery) (interface{}, error) {
+	return message.DecodeResponse[T](deliv.Body, errorOrigin)
+}
+
+// Consumption of the messages of a queue by a Go kit subscriber.
+type Subscription struct {
+	Queue      string
+	Subscriber *kitamqp.Subscriber
+}
+
+// Starts consuming the queues of the given subscriptions, every subscription is served in its own goroutine until the channel is closed.
+// Messages are acknowledged automatically when they are delivered.
+func Consume(ch *amqp.Channel, subscriptions []Subscription) error {
+	for _, s := range subscriptions {
+		deliveries, err := ch.Consume(s.Queue, "", true, false, false, false, nil)
+		if err != nil {
+			return errors.New(err, errorOrigin, errors.Unavailable).WithInternalMessage("could not consume queue " + s.Queue)
+		}
+		handler := s.Subscriber.ServeDelivery(ch)
+		go func() {
+			for d := range deliveries {
+				handler(&d)
+			}
+		}()
+	}
+	return nil
+}
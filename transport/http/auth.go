@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/dkinzler/kit/errors"
+)
+
+// Header that contains the api key of a request, see NewAPIKeyHandler.
+const APIKeyHeader = "X-API-Key"
+
+// Returns the principal, e.g. a user or service account, that is identified by the given api key.
+// Should return an error with code Unauthenticated if the key is not valid.
+type APIKeyLookupFunc func(ctx context.Context, key string) (interface{}, error)
+
+// Returns the principal, e.g. a user or service account, with the given credentials.
+// Should return an error with code Unauthenticated if the credentials are not valid.
+// Passwords should be compared in constant time, e.g. using crypto/subtle.
+type BasicAuthFunc func(ctx context.Context, username, password string) (interface{}, error)
+
+type principalContextKey struct{}
+
+// Returns a new context that contains the given principal, see PrincipalFromContext.
+func ContextWithPrincipal(ctx context.Context, principal interface{}) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// Returns the principal stored in the context by NewAPIKeyHandler, NewBasicAuthHandler or ContextWithPrincipal.
+func PrincipalFromContext(ctx context.Context) (interface{}, bool) {
+	principal := ctx.Value(principalContextKey{})
+	return principal, principal != nil
+}
+
+// Http middleware that authenticates requests using the api key in the APIKeyHeader header.
+// The principal returned by lookup is stored in the request context, see PrincipalFromContext.
+//
+// If the header is missing, the request is answered with status 401 Unauthorized and the standard error body (see EncodeError).
+// Errors of type Error returned by lookup are encoded with EncodeError, e.g. to respond with 503 if the keys could not be loaded,
+// other errors result in status 401. The next handler is only called if the request is authenticated.
+func NewAPIKeyHandler(next http.Handler, lookup APIKeyLookupFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" {
+			EncodeError(r.Context(), newPublicTransportError(nil, errors.Unauthenticated, "missing api key"), w)
+			return
+		}
+		principal, err := lookup(r.Context(), key)
+		if err != nil {
+			EncodeError(r.Context(), authError(err, "invalid api key"), w)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)))
+	})
+}
+
+// Http middleware that authenticates requests using HTTP basic authentication (RFC 7617).
+// The principal returned by authenticate is stored in the request context, see PrincipalFromContext.
+//
+// Requests without or with invalid credentials are answered with status 401 Unauthorized, a "WWW-Authenticate" header
+// with the given realm and the standard error body (see EncodeError).
+// Like for NewAPIKeyHandler, errors of type Error returned by authenticate are encoded with EncodeError.
+func NewBasicAuthHandler(next http.Handler, realm string, authenticate BasicAuthFunc) http.Handler {
+	challenge := fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", challenge)
+			EncodeError(r.Context(), newPublicTransportError(nil, errors.Unauthenticated, "missing credentials"), w)
+			return
+		}
+		principal, err := authenticate(r.Context(), username, password)
+		if err != nil {
+			err = authError(err, "invalid credentials")
+			if errors.IsUnauthenticatedError(err) {
+				w.Header().Set("WWW-Authenticate", challenge)
+			}
+			EncodeError(r.Context(), err, w)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)))
+	})
+}
+
+func authError(err error, message string) error {
+	if _, ok := err.(errors.Error); ok {
+		return err
+	}
+	return newPublicTransportError(err, errors.Unauthenticated, message)
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func principalHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := PrincipalFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(principal.(string)))
+	})
+}
+
+func TestAPIKeyHandler(t *testing.T) {
+	a := assert.New(t)
+
+	handler := NewAPIKeyHandler(principalHandler(), func(ctx context.Context, key string) (interface{}, error) {
+		switch key {
+		case "key1":
+			return "service1", nil
+		case "unavailable":
+			return nil, errors.New(nil, "test", errors.Unavailable)
+		}
+		return nil, stderrors.New("unknown key")
+	})
+
+	request := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if key != "" {
+			r.Header.Set(APIKeyHeader, key)
+		}
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := request("key1")
+	a.Equal(http.StatusOK, w.Code)
+	a.Equal("service1", w.Body.String())
+
+	w = request("")
+	a.Equal(http.StatusUnauthorized, w.Code)
+	a.JSONEq(`{"error": {"message": "missing api key"}}`, w.Body.String())
+
+	w = request("key2")
+	a.Equal(http.StatusUnauthorized, w.Code)
+	a.JSONEq(`{"error": {"message": "invalid api key"}}`, w.Body.String())
+
+	w = request("unavailable")
+	a.Equal(http.StatusServiceUnavailable, w.Code)
+}
+
+func TestBasicAuthHandler(t *testing.T) {
+	a := assert.New(t)
+
+	handler := NewBasicAuthHandler(principalHandler(), "test", func(ctx context.Context, username, password string) (interface{}, error) {
+		if username == "user" && password == "pass" {
+			return "user", nil
+		}
+		return nil, errors.New(nil, "test", errors.Unauthenticated).WithPublicMessage("wrong password")
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("user", "pass")
+	handler.ServeHTTP(w, r)
+	a.Equal(http.StatusOK, w.Code)
+	a.Equal("user", w.Body.String())
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(w, r)
+	a.Equal(http.StatusUnauthorized, w.Code)
+	a.Equal(`Basic realm="test", charset="UTF-8"`, w.Header().Get("WWW-Authenticate"))
+	a.JSONEq(`{"error": {"message": "missing credentials"}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("user", "wrong")
+	handler.ServeHTTP(w, r)
+	a.Equal(http.StatusUnauthorized, w.Code)
+	a.NotEmpty(w.Header().Get("WWW-Authenticate"))
+	a.JSONEq(`{"error": {"message": "wrong password"}}`, w.Body.String())
+
+	_, ok := PrincipalFromContext(context.Background())
+	a.False(ok)
+}
@@ -0,0 +1,222 @@
+package http
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// A request and response captured by NewBodyCaptureHandler.
+type CapturedExchange struct {
+	Method string
+	URL    string
+	// Request id stored in the context by NewRequestIDHandler, empty if there is none.
+	RequestID     string
+	RequestHeader http.Header
+	// The part of the request body read by the handler, at most BodyCaptureConfig.MaxBodySize bytes.
+	// Empty if the content type of the body is not captured.
+	RequestBody          []byte
+	RequestBodyTruncated bool
+	StatusCode           int
+	ResponseHeader       http.Header
+	// At most BodyCaptureConfig.MaxBodySize bytes of the response body, empty if the content type of the body is not captured.
+	ResponseBody          []byte
+	ResponseBodyTruncated bool
+	Duration              time.Duration
+}
+
+// Configures NewBodyCaptureHandler.
+type BodyCaptureConfig struct {
+	// Fraction of requests that are captured, between 0 and 1. Defaults to 1 if 0, i.e. all requests are captured.
+	SampleRate float64
+	// Maximum number of bytes captured of each body, the rest of a body is not captured. Defaults to 64kb.
+	MaxBodySize int64
+	// Media types of bodies that are captured, e.g. "application/json". A type like "text/*" matches all subtypes.
+	// Bodies of other types, e.g. file uploads, are not captured. Defaults to JSON, form and text bodies.
+	ContentTypes []string
+	// Values of these headers are replaced with "REDACTED". Defaults to the "Authorization", "Cookie", "Set-Cookie"
+	// and APIKeyHeader headers.
+	RedactHeaders []string
+	// If not nil, captured requests are logged with keys "method", "url", "requestId", "requestHeader", "requestBody",
+	// "status", "responseHeader", "responseBody" and "duration".
+	Logger log.Logger
+	// If not nil, called with every captured request after the response was written.
+	OnCapture func(ctx context.Context, c CapturedExchange)
+}
+
+const defaultCaptureMaxBodySize = 64 * 1024
+
+var defaultCaptureContentTypes = []string{"application/json", "application/problem+json", "application/x-www-form-urlencoded", "text/*"}
+
+var defaultCaptureRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", APIKeyHeader}
+
+// Http middleware that captures the bodies of requests and responses, e.g. to debug problems in production.
+// The request body is captured while the next handler reads it, i.e. a body that is not read is not captured.
+// Captured requests are logged and/or passed to a callback, see BodyCaptureConfig.
+// Note that bodies might contain sensitive data, a low sample rate should be used in production.
+func NewBodyCaptureHandler(next http.Handler, config BodyCaptureConfig) http.Handler {
+	if config.Logger == nil && config.OnCapture == nil {
+		return next
+	}
+	sampleRate := config.SampleRate
+	if sampleRate == 0 {
+		sampleRate = 1
+	}
+	maxBodySize := config.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultCaptureMaxBodySize
+	}
+	contentTypes := config.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = defaultCaptureContentTypes
+	}
+	redactHeaders := config.RedactHeaders
+	if len(redactHeaders) == 0 {
+		redactHeaders = defaultCaptureRedactHeaders
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sampleRate < 1 && rand.Float64() >= sampleRate {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		begin := time.Now()
+		// take a copy of the header before the next handler can modify it
+		c := CapturedExchange{
+			Method:        r.Method,
+			URL:           r.URL.String(),
+			RequestHeader: redactHeader(r.Header, redactHeaders),
+		}
+		c.RequestID, _ = RequestIDFromContext(r.Context())
+
+		var body *captureReadCloser
+		if r.Body != nil && r.Body != http.NoBody && captureContentType(contentTypes, r.Header.Get("Content-Type")) {
+			body = &captureReadCloser{ReadCloser: r.Body, buf: captureBuffer{max: maxBodySize}}
+			r.Body = body
+		}
+		cw := &captureResponseWriter{
+			statusResponseWriter: statusResponseWriter{ResponseWriter: w},
+			buf:                  captureBuffer{max: maxBodySize},
+			contentTypes:         contentTypes,
+		}
+
+		defer func() {
+			c.Duration = time.Since(begin)
+			if body != nil {
+				c.RequestBody, c.RequestBodyTruncated = body.buf.data, body.buf.truncated
+			}
+			c.StatusCode = cw.statusCode()
+			c.ResponseHeader = redactHeader(w.Header(), redactHeaders)
+			c.ResponseBody, c.ResponseBodyTruncated = cw.buf.data, cw.buf.truncated
+
+			if config.Logger != nil {
+				config.Logger.Log(
+					"method", c.Method,
+					"url", c.URL,
+					"requestId", c.RequestID,
+					"requestHeader", c.RequestHeader,
+					"requestBody", string(c.RequestBody),
+					"status", c.StatusCode,
+					"responseHeader", c.ResponseHeader,
+					"responseBody", string(c.ResponseBody),
+					"duration", c.Duration,
+				)
+			}
+			if config.OnCapture != nil {
+				config.OnCapture(r.Context(), c)
+			}
+		}()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// Returns true if the media type of the given content type matches one of the types.
+func captureContentType(types []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if strings.EqualFold(t, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a copy of the header with the values of the given headers redacted.
+func redactHeader(h http.Header, redact []string) http.Header {
+	result := h.Clone()
+	if result == nil {
+		result = http.Header{}
+	}
+	for _, name := range redact {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := result[name]; ok {
+			result[name] = []string{"REDACTED"}
+		}
+	}
+	return result
+}
+
+// Records at most max bytes of the data written to it.
+type captureBuffer struct {
+	data      []byte
+	max       int64
+	truncated bool
+}
+
+func (b *captureBuffer) write(p []byte) {
+	if remaining := b.max - int64(len(b.data)); int64(len(p)) > remaining {
+		p = p[:remaining]
+		b.truncated = true
+	}
+	b.data = append(b.data, p...)
+}
+
+type captureReadCloser struct {
+	io.ReadCloser
+	buf captureBuffer
+}
+
+func (c *captureReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.buf.write(p[:n])
+	return n, err
+}
+
+type captureResponseWriter struct {
+	statusResponseWriter
+	buf          captureBuffer
+	contentTypes []string
+	// nil until the first write, when the content type of the response is known
+	capture *bool
+}
+
+func (w *captureResponseWriter) Write(b []byte) (int, error) {
+	if w.capture == nil {
+		// if the content type is not set, the response writer sets it based on the data written, use the same logic here
+		contentType := w.Header().Get("Content-Type")
+		if contentType == "" && w.Header()["Content-Type"] == nil {
+			contentType = http.DetectContentType(b)
+		}
+		capture := captureContentType(w.contentTypes, contentType)
+		w.capture = &capture
+	}
+	n, err := w.statusResponseWriter.Write(b)
+	if *w.capture {
+		w.buf.write(b[:n])
+	}
+	return n, err
+}
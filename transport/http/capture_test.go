@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyCaptureHandler(t *testing.T) {
+	a := assert.New(t)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=abc")
+		if r.URL.Path == "/file" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	})
+
+	var captured []CapturedExchange
+	config := BodyCaptureConfig{
+		MaxBodySize: 8,
+		OnCapture: func(ctx context.Context, c CapturedExchange) {
+			captured = append(captured, c)
+		},
+	}
+	handler := NewRequestIDHandler(NewBodyCaptureHandler(next, config))
+	request := func(path, contentType, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		r.Header.Set("Authorization", "Bearer token")
+		r.Header.Set(RequestIDHeader, "id")
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := request("/json?a=1", "application/json; charset=utf-8", `{"a":1}`)
+	a.Equal(`{"a":1}`, w.Body.String())
+	a.Len(captured, 1)
+	c := captured[0]
+	a.Equal(http.MethodPost, c.Method)
+	a.Equal("/json?a=1", c.URL)
+	a.Equal("id", c.RequestID)
+	a.Equal("REDACTED", c.RequestHeader.Get("Authorization"))
+	a.Equal(`{"a":1}`, string(c.RequestBody))
+	a.False(c.RequestBodyTruncated)
+	a.Equal(http.StatusCreated, c.StatusCode)
+	a.Equal("REDACTED", c.ResponseHeader.Get("Set-Cookie"))
+	a.Equal(`{"a":1}`, string(c.ResponseBody))
+	// headers of the request and response are not modified
+	a.Equal("session=abc", w.Header().Get("Set-Cookie"))
+
+	// bodies are truncated
+	w = request("/json", "text/plain", "0123456789")
+	a.Equal("0123456789", w.Body.String())
+	c = captured[1]
+	a.Equal("01234567", string(c.RequestBody))
+	a.True(c.RequestBodyTruncated)
+	a.Equal("01234567", string(c.ResponseBody))
+	a.True(c.ResponseBodyTruncated)
+
+	// other content types are not captured
+	request("/file", "application/octet-stream", "data")
+	c = captured[2]
+	a.Empty(c.RequestBody)
+	a.Empty(c.ResponseBody)
+	a.Equal(http.StatusCreated, c.StatusCode)
+
+	// no requests are captured without logger or callback
+	a.NotPanics(func() {
+		NewBodyCaptureHandler(next, BodyCaptureConfig{}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestCaptureContentType(t *testing.T) {
+	a := assert.New(t)
+
+	types := []string{"application/json", "text/*"}
+	a.True(captureContentType(types, "application/json"))
+	a.True(captureContentType(types, "Application/JSON; charset=utf-8"))
+	a.True(captureContentType(types, "text/html"))
+	a.False(captureContentType(types, "application/xml"))
+	a.False(captureContentType(types, "textual/plain"))
+	a.False(captureContentType(types, ""))
+}
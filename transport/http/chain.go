@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Returns a middleware that applies the given middlewares in order, i.e. the first middleware is the outermost one
+// and sees a request first. Chain(a, b, c)(h) is equivalent to a(b(c(h))).
+//
+// Example:
+//
+//	mw := Chain(
+//	  Use(PanicMiddleware, onPanic),
+//	  NewRequestIDHandler,
+//	  Use(CORSMiddleware, corsConfig),
+//	  Use(NewMaxRequestBodySizeHandler, int64(1<<20)),
+//	)
+//	r.Handle("/upload", mw(uploadHandler))
+func Chain(mws ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			h = mws[i](h)
+		}
+		return h
+	}
+}
+
+// Returns a middleware for a middleware constructor of this package that takes a configuration as second argument,
+// e.g. Use(CORSMiddleware, config) or Use(NewRateLimitHandler, config), so that it can be used with Chain and Mount.
+func Use[C any](mw func(http.Handler, C) http.Handler, config C) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return mw(h, config)
+	}
+}
+
+// Router is implemented by http.ServeMux and chi.Router of package "github.com/go-chi/chi/v5".
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Registers the given handler wrapped with the given middlewares (see Chain) for the given path,
+// so that middlewares can be applied per route instead of only globally.
+func Mount(router Router, path string, handler http.Handler, mws ...func(http.Handler) http.Handler) {
+	router.Handle(path, Chain(mws...)(handler))
+}
+
+// Works like Mount for routers of package "github.com/gorilla/mux".
+// Returns the route, e.g. to restrict the methods it matches.
+func MountMux(router *mux.Router, path string, handler http.Handler, mws ...func(http.Handler) http.Handler) *mux.Route {
+	return router.Handle(path, Chain(mws...)(handler))
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func tagMiddleware(tag string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Tags", tag)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	a := assert.New(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Tags", "handler")
+	})
+
+	w := httptest.NewRecorder()
+	Chain(tagMiddleware("a"), tagMiddleware("b"), tagMiddleware("c"))(handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal([]string{"a", "b", "c", "handler"}, w.Header().Values("X-Tags"))
+
+	w = httptest.NewRecorder()
+	Chain()(handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal([]string{"handler"}, w.Header().Values("X-Tags"))
+
+	// middlewares with configuration
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://example.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	Chain(tagMiddleware("a"), Use(CORSMiddleware, CORSConfig{Origins: []string{"*"}}))(handler).ServeHTTP(w, r)
+	a.Equal(http.StatusNoContent, w.Code)
+	a.Equal([]string{"a"}, w.Header().Values("X-Tags"))
+}
+
+func TestMount(t *testing.T) {
+	a := assert.New(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Tags", "handler")
+	})
+
+	serveMux := http.NewServeMux()
+	Mount(serveMux, "GET /a", handler, tagMiddleware("a"))
+	Mount(serveMux, "GET /b", handler)
+	chiRouter := chi.NewRouter()
+	Mount(chiRouter, "/a", handler, tagMiddleware("a"))
+	Mount(chiRouter, "/b", handler)
+	muxRouter := mux.NewRouter()
+	MountMux(muxRouter, "/a", handler, tagMiddleware("a")).Methods(http.MethodGet)
+	MountMux(muxRouter, "/b", handler)
+
+	for _, router := range []http.Handler{serveMux, chiRouter, muxRouter} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/a", nil))
+		a.Equal([]string{"a", "handler"}, w.Header().Values("X-Tags"))
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/b", nil))
+		a.Equal([]string{"handler"}, w.Header().Values("X-Tags"))
+	}
+}
@@ -0,0 +1,337 @@
+// Package client implements a http client for JSON APIs, e.g. services that use package "github.com/dkinzler/kit/transport/http".
+// Requests are retried with exponential backoff and error responses are converted into errors of type Error from package "github.com/dkinzler/kit/errors".
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+	transport "github.com/dkinzler/kit/transport/http"
+)
+
+const errorOrigin = "transport/http/client"
+
+// Maximum number of bytes of an error response body that are read to obtain the error code and message.
+const maxErrorBodyBytes = 64 * 1024
+
+// Configures a Client, zero values are replaced by defaults.
+type Config struct {
+	// Prepended to the paths of requests, e.g. "https://api.example.com/v1".
+	BaseURL string
+	// Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// Headers sent with every request, e.g. an api key.
+	Header http.Header
+	// Timeout of a single attempt of a request, not limited if 0.
+	Timeout time.Duration
+
+	// Maximum number of times a request is retried, requests are not retried if 0.
+	MaxRetries int
+	// Time to wait before the first retry, doubled for every further retry. Defaults to 100ms.
+	InitialBackoff time.Duration
+	// Maximum time to wait between retries, also limits the time given by Retry-After headers. Defaults to 5s.
+	MaxBackoff time.Duration
+	// Status codes of responses that are retried, defaults to 429, 502, 503 and 504.
+	RetryStatusCodes []int
+}
+
+var defaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Client sends JSON requests to a http API.
+//
+// If the response has a retryable status code (see Config.RetryStatusCodes), the request is retried.
+// Requests that fail without a response, e.g. because the connection was refused, are only retried if the method is idempotent
+// or the request has an "Idempotency-Key" header, since the server might have processed them.
+// This includes attempts that exceed the timeout of a single attempt (see Config.Timeout), requests are not retried once the context of the caller is done.
+//
+// Responses with status 4xx or 5xx are converted into errors of type Error, with the code given by errors.FromHTTPStatus.
+// The code and message of error bodies in the format written by EncodeError or EncodeProblemJSON of package
+// "github.com/dkinzler/kit/transport/http" are added to the internal message of the error and as key-value pairs "upstreamCode" and "upstreamMessage".
+// They are not used as public code and message, since they might not be meant for the clients of the calling service.
+//
+// If the context of a request contains a request id (see transport.RequestIDFromContext), it is sent in the RequestIDHeader header.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+}
+
+func New(config Config) *Client {
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 5 * time.Second
+	}
+	if len(config.RetryStatusCodes) == 0 {
+		config.RetryStatusCodes = defaultRetryStatusCodes
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{config: config, httpClient: httpClient}
+}
+
+type request struct {
+	header  http.Header
+	query   url.Values
+	timeout time.Duration
+}
+
+// Option configures a single request.
+type Option func(*request)
+
+// Sets a header of the request, overrides headers of the same name given by Config.Header.
+func Header(key, value string) Option {
+	return func(r *request) {
+		r.header.Set(key, value)
+	}
+}
+
+// Adds query parameters to the url of the request.
+func Query(values url.Values) Option {
+	return func(r *request) {
+		for key, vs := range values {
+			for _, v := range vs {
+				r.query.Add(key, v)
+			}
+		}
+	}
+}
+
+// Sets the timeout of a single attempt of the request, overrides Config.Timeout.
+func Timeout(timeout time.Duration) Option {
+	return func(r *request) {
+		r.timeout = timeout
+	}
+}
+
+func (c *Client) Get(ctx context.Context, path string, result interface{}, opts ...Option) error {
+	return c.Do(ctx, http.MethodGet, path, nil, result, opts...)
+}
+
+func (c *Client) Post(ctx context.Context, path string, body, result interface{}, opts ...Option) error {
+	return c.Do(ctx, http.MethodPost, path, body, result, opts...)
+}
+
+func (c *Client) Put(ctx context.Context, path string, body, result interface{}, opts ...Option) error {
+	return c.Do(ctx, http.MethodPut, path, body, result, opts...)
+}
+
+func (c *Client) Patch(ctx context.Context, path string, body, result interface{}, opts ...Option) error {
+	return c.Do(ctx, http.MethodPatch, path, body, result, opts...)
+}
+
+func (c *Client) Delete(ctx context.Context, path string, result interface{}, opts ...Option) error {
+	return c.Do(ctx, http.MethodDelete, path, nil, result, opts...)
+}
+
+// Sends a request with the given method to the base url joined with path.
+// If body is not nil, it is encoded as JSON. If result is not nil, the body of a successful response is decoded into it.
+func (c *Client) Do(ctx context.Context, method, path string, body, result interface{}, opts ...Option) error {
+	req := request{header: http.Header{}, query: url.Values{}, timeout: c.config.Timeout}
+	for _, opt := range opts {
+		opt(&req)
+	}
+
+	u, err := url.Parse(c.config.BaseURL + path)
+	if err != nil {
+		return errors.New(err, errorOrigin, errors.InvalidArgument).WithInternalMessage("invalid url")
+	}
+	if len(req.query) > 0 {
+		query := u.Query()
+		for key, vs := range req.query {
+			query[key] = append(query[key], vs...)
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	var bodyBytes []byte
+	if body != nil {
+		bodyBytes, err = json.Marshal(body)
+		if err != nil {
+			return errors.New(err, errorOrigin, errors.Internal).WithInternalMessage("could not encode request body")
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, wait, err := c.attempt(ctx, method, u.String(), bodyBytes, result, req)
+		if err == nil || !retry || attempt >= c.config.MaxRetries {
+			return err
+		}
+		if wait <= 0 {
+			wait = c.backoff(attempt)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// Sends the request once. Returns whether the request can be retried if it failed
+// and the time to wait before retrying given by a Retry-After header, 0 if there is none.
+func (c *Client) attempt(ctx context.Context, method, target string, body []byte, result interface{}, req request) (bool, time.Duration, error) {
+	attemptCtx := ctx
+	if req.timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, req.timeout)
+		defer cancel()
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	r, err := http.NewRequestWithContext(attemptCtx, method, target, bodyReader)
+	if err != nil {
+		return false, 0, errors.New(err, errorOrigin, errors.InvalidArgument).WithInternalMessage("could not create request")
+	}
+	for key, values := range c.config.Header {
+		r.Header[key] = append([]string(nil), values...)
+	}
+	for key, values := range req.header {
+		r.Header[key] = values
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if result != nil {
+		r.Header.Set("Accept", "application/json")
+	}
+	if id, ok := transport.RequestIDFromContext(ctx); ok && r.Header.Get(transport.RequestIDHeader) == "" {
+		r.Header.Set(transport.RequestIDHeader, id)
+	}
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		// an attempt that timed out can be retried, as long as the context of the caller is not done
+		retry := isIdempotent(r) && ctx.Err() == nil
+		return retry, 0, requestError(ctx, attemptCtx, err, method, target)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		retry := c.isRetryableStatus(resp.StatusCode)
+		return retry, retryAfter(resp.Header.Get("Retry-After"), c.config.MaxBackoff), responseError(resp, method, target, retry)
+	}
+
+	if result != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil && err != io.EOF {
+			return false, 0, errors.New(err, errorOrigin, errors.Internal).
+				WithInternalMessage("could not decode response body").
+				With("method", method).With("url", target)
+		}
+	}
+	return false, 0, nil
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	backoff := c.config.InitialBackoff << attempt
+	if backoff <= 0 || backoff > c.config.MaxBackoff {
+		backoff = c.config.MaxBackoff
+	}
+	// add jitter of up to 20%, so that clients that failed at the same time do not retry at the same time
+	return backoff - time.Duration(rand.Int63n(int64(backoff)/5+1))
+}
+
+func (c *Client) isRetryableStatus(status int) bool {
+	for _, s := range c.config.RetryStatusCodes {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdempotent(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.Header.Get("Idempotency-Key") != ""
+}
+
+// Parses a Retry-After header given in seconds, http dates are not supported.
+func retryAfter(value string, max time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	wait := time.Duration(seconds) * time.Second
+	if wait > max {
+		return max
+	}
+	return wait
+}
+
+// Returns the error of a request that could not be sent or did not receive a response.
+// ctx is the context of the caller and attemptCtx the context of the attempt, that is done earlier if a timeout is set (see Timeout).
+// The error is retryable unless the context of the caller is done, i.e. also if the attempt timed out.
+func requestError(ctx, attemptCtx context.Context, err error, method, target string) error {
+	code := errors.Unavailable
+	switch {
+	case stderrors.Is(ctx.Err(), context.Canceled):
+		code = errors.Cancelled
+	case stderrors.Is(ctx.Err(), context.DeadlineExceeded), stderrors.Is(attemptCtx.Err(), context.DeadlineExceeded):
+		code = errors.DeadlineExceeded
+	}
+	return errors.New(err, errorOrigin, code).
+		WithInternalMessage("request failed").
+		WithRetryable(ctx.Err() == nil).
+		With("method", method).With("url", target)
+}
+
+// Error body written by EncodeError or EncodeProblemJSON of package "github.com/dkinzler/kit/transport/http".
+type errorBody struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	// problem details
+	Code   int    `json:"code"`
+	Detail string `json:"detail"`
+}
+
+func responseError(resp *http.Response, method, target string, retryable bool) error {
+	e := errors.New(nil, errorOrigin, errors.FromHTTPStatus(resp.StatusCode)).
+		WithRetryable(retryable).
+		With("method", method).With("url", target).With("status", resp.StatusCode)
+
+	message := fmt.Sprintf("request failed with status %v", resp.StatusCode)
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	var body errorBody
+	if len(b) > 0 && json.Unmarshal(b, &body) == nil {
+		code, msg := body.Error.Code, body.Error.Message
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") {
+			code, msg = body.Code, body.Detail
+		}
+		if code != 0 {
+			e = e.With("upstreamCode", code)
+		}
+		if msg != "" {
+			e = e.With("upstreamMessage", msg)
+			message += ": " + msg
+		}
+	}
+	return e.WithInternalMessage(message)
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+	transport "github.com/dkinzler/kit/transport/http"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testItem struct {
+	Name string `json:"name"`
+}
+
+func TestClientRequests(t *testing.T) {
+	a := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/items":
+			a.Equal("key", r.Header.Get("X-API-Key"))
+			a.Equal("req1", r.Header.Get(transport.RequestIDHeader))
+			a.Equal("2", r.URL.Query().Get("limit"))
+			json.NewEncoder(w).Encode([]testItem{{Name: "a"}, {Name: "b"}})
+		case "/create":
+			a.Equal(http.MethodPost, r.Method)
+			a.Equal("application/json", r.Header.Get("Content-Type"))
+			var item testItem
+			a.Nil(json.NewDecoder(r.Body).Decode(&item))
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(item)
+		case "/delete":
+			w.WriteHeader(http.StatusNoContent)
+		case "/missing":
+			transport.EncodeError(r.Context(), errors.New(nil, "test", errors.NotFound).WithPublicCode(7).WithPublicMessage("item not found"), w)
+		case "/problem":
+			transport.EncodeProblemJSON(r.Context(), errors.New(nil, "test", errors.PermissionDenied).WithPublicMessage("not allowed"), w)
+		}
+	}))
+	defer server.Close()
+
+	c := New(Config{BaseURL: server.URL, Header: http.Header{"X-Api-Key": {"key"}}})
+	ctx := transport.ContextWithRequestID(context.Background(), "req1")
+
+	var items []testItem
+	err := c.Get(ctx, "/items", &items, Query(url.Values{"limit": {"2"}}))
+	a.Nil(err)
+	a.Equal([]testItem{{Name: "a"}, {Name: "b"}}, items)
+
+	var created testItem
+	err = c.Post(ctx, "/create", testItem{Name: "c"}, &created)
+	a.Nil(err)
+	a.Equal("c", created.Name)
+
+	err = c.Delete(ctx, "/delete", &created)
+	a.Nil(err)
+
+	err = c.Get(ctx, "/missing", nil)
+	a.True(errors.IsNotFoundError(err))
+	e := err.(errors.Error)
+	a.Equal(7, e.KeyVals["upstreamCode"])
+	a.Equal("item not found", e.KeyVals["upstreamMessage"])
+	a.Equal(http.StatusNotFound, e.KeyVals["status"])
+	// the upstream code does not replace the error code in the map representation
+	m := e.ToMap()
+	a.Equal("NotFound", m["code"])
+	a.Equal(7, m["upstreamCode"])
+	a.Empty(e.PublicMessage)
+
+	err = c.Get(ctx, "/problem", nil)
+	a.True(errors.IsPermissionDeniedError(err))
+	a.Equal("not allowed", err.(errors.Error).KeyVals["upstreamMessage"])
+}
+
+func TestClientRetries(t *testing.T) {
+	a := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/flaky":
+			if n < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"name": "ok"}`))
+		case "/unavailable":
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/invalid":
+			w.WriteHeader(http.StatusBadRequest)
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	c := New(Config{BaseURL: server.URL, MaxRetries: 3, InitialBackoff: time.Millisecond})
+
+	var item testItem
+	err := c.Get(context.Background(), "/flaky", &item)
+	a.Nil(err)
+	a.Equal("ok", item.Name)
+	a.Equal(int32(3), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	err = c.Post(context.Background(), "/unavailable", nil, nil)
+	a.True(errors.IsUnavailableError(err))
+	a.True(errors.IsRetryable(err))
+	a.Equal(int32(4), atomic.LoadInt32(&calls))
+
+	// client errors are not retried
+	atomic.StoreInt32(&calls, 0)
+	err = c.Get(context.Background(), "/invalid", nil)
+	a.True(errors.IsInvalidArgumentError(err))
+	a.False(errors.IsRetryable(err))
+	a.Equal(int32(1), atomic.LoadInt32(&calls))
+
+	// timeouts of single attempts are retried
+	atomic.StoreInt32(&calls, 0)
+	err = c.Get(context.Background(), "/slow", nil, Timeout(10*time.Millisecond))
+	a.True(errors.IsDeadlineExceededError(err))
+	a.True(errors.IsRetryable(err))
+	a.Equal(int32(4), atomic.LoadInt32(&calls))
+
+	// requests are not retried if the context of the caller is done
+	atomic.StoreInt32(&calls, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = c.Get(ctx, "/slow", nil, Timeout(time.Second))
+	a.True(errors.IsDeadlineExceededError(err))
+	a.False(errors.IsRetryable(err))
+	a.Equal(int32(1), atomic.LoadInt32(&calls))
+
+	// requests without a response are retried for idempotent methods
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	c = New(Config{BaseURL: closed.URL, MaxRetries: 1, InitialBackoff: time.Millisecond})
+	err = c.Get(context.Background(), "/", nil)
+	a.True(errors.IsUnavailableError(err))
+	a.True(errors.IsRetryable(err))
+}
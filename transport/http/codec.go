@@ -0,0 +1,246 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dkinzler/kit/errors"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes and decodes http bodies of a content type, see RegisterCodec.
+type Codec interface {
+	// Value of the Content-Type header of encoded bodies, e.g. "application/json; charset=utf-8".
+	// The media type without parameters, e.g. "application/json", is used to select a codec.
+	ContentType() string
+	Decode(r io.Reader, v interface{}) error
+	Encode(w io.Writer, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (jsonCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+func (xmlCodec) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+func (xmlCodec) Encode(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// Values must implement proto.Message.
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (protobufCodec) Decode(r io.Reader, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("value of type %T does not implement proto.Message", v)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, m)
+}
+
+func (protobufCodec) Encode(w io.Writer, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("value of type %T does not implement proto.Message", v)
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+var (
+	JSONCodec     Codec = jsonCodec{}
+	XMLCodec      Codec = xmlCodec{}
+	ProtobufCodec Codec = protobufCodec{}
+)
+
+var codecs = struct {
+	sync.RWMutex
+	byMediaType map[string]Codec
+	// media types in the order codecs were registered, used to resolve wildcards like "application/*"
+	order []string
+}{
+	byMediaType: map[string]Codec{
+		"application/json":       JSONCodec,
+		"application/xml":        XMLCodec,
+		"text/xml":               XMLCodec,
+		"application/x-protobuf": ProtobufCodec,
+	},
+	order: []string{"application/json", "application/xml", "text/xml", "application/x-protobuf"},
+}
+
+// Registers a codec for its media type, replacing any codec registered for the same media type.
+// Codecs for JSON, XML and protobuf are registered by default, other formats like msgpack can be added by implementing Codec.
+func RegisterCodec(codec Codec) {
+	mediaType := codecMediaType(codec)
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, ok := codecs.byMediaType[mediaType]; !ok {
+		codecs.order = append(codecs.order, mediaType)
+	}
+	codecs.byMediaType[mediaType] = codec
+}
+
+func codecMediaType(codec Codec) string {
+	mediaType, _, err := mime.ParseMediaType(codec.ContentType())
+	if err != nil {
+		return strings.ToLower(codec.ContentType())
+	}
+	return mediaType
+}
+
+// Returns the codec registered for the media type of the given Content-Type header value.
+// JSONCodec is returned for an empty value.
+func CodecForContentType(contentType string) (Codec, bool) {
+	if contentType == "" {
+		return JSONCodec, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codecs.RLock()
+	defer codecs.RUnlock()
+	codec, ok := codecs.byMediaType[mediaType]
+	return codec, ok
+}
+
+// Returns the codec to encode a response body with for the given Accept header value.
+// Media ranges are tried in the order of their quality values, wildcards like "application/*" match the codec registered first.
+// JSONCodec is returned if the header is empty or accepts any media type.
+// Returns false if no registered codec is acceptable.
+func NegotiateCodec(accept string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return JSONCodec, true
+	}
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+	for _, r := range ranges {
+		if r.mediaType == "*/*" {
+			return JSONCodec, true
+		}
+		if prefix, ok := strings.CutSuffix(r.mediaType, "/*"); ok {
+			for _, mediaType := range codecs.order {
+				if strings.HasPrefix(mediaType, prefix+"/") {
+					return codecs.byMediaType[mediaType], true
+				}
+			}
+			continue
+		}
+		if codec, ok := codecs.byMediaType[r.mediaType]; ok {
+			return codec, true
+		}
+	}
+	return nil, false
+}
+
+// Decodes the body of the given http request into target with the codec for the Content-Type header of the request,
+// see CodecForContentType. Works like DecodeJSONBody for requests without a Content-Type header.
+func DecodeBody(r *http.Request, target interface{}) error {
+	codec, ok := CodecForContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		return newPublicTransportError(nil, errors.InvalidArgument, "unsupported content type")
+	}
+	if err := codec.Decode(r.Body, target); err != nil {
+		return newRequestBodyError(err, "could not decode request body")
+	}
+	return validateDecoded(target)
+}
+
+// Encodes the given value with the codec negotiated for the Accept header of the request (see NegotiateCodec)
+// and writes it to the http response with the given status code.
+// If no codec is acceptable, the value is encoded as JSON.
+func EncodeBody(w http.ResponseWriter, r *http.Request, status int, source interface{}) error {
+	return encodeBody(w, r.Header.Get("Accept"), status, source)
+}
+
+func encodeBody(w http.ResponseWriter, accept string, status int, source interface{}) error {
+	codec, ok := NegotiateCodec(accept)
+	if !ok {
+		codec = JSONCodec
+	}
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.WriteHeader(status)
+	if source == nil {
+		return nil
+	}
+	if err := codec.Encode(w, source); err != nil {
+		return newInternalTransportError(err, errors.Internal, "could not encode response body")
+	}
+	return nil
+}
+
+// Works like MakeEnvelopeJSONEncodeFunc, but the response value is encoded with the codec negotiated for the Accept header of the request,
+// see NegotiateCodec. The Accept header is read from the context, it must be added with kithttp.PopulateRequestContext,
+// e.g. using the server option kithttp.ServerBefore(kithttp.PopulateRequestContext).
+// Responses are encoded as JSON if no codec is acceptable or the header is not in the context. Errors are encoded with EncodeError.
+func MakeGenericEncodeFunc(status int, headers func(response interface{}) http.Header, envelope Envelope) kithttp.EncodeResponseFunc {
+	return makeEncodeFunc(status, headers, envelope, func(ctx context.Context) string {
+		accept, _ := ctx.Value(kithttp.ContextKeyRequestAccept).(string)
+		return accept
+	})
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dkinzler/kit/endpoint"
+	"github.com/dkinzler/kit/errors"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type codecTestValue struct {
+	XMLName xml.Name `xml:"value" json:"-"`
+	A       string   `xml:"a" json:"a"`
+	B       int      `xml:"b" json:"b"`
+}
+
+type testMsgpackCodec struct{}
+
+func (testMsgpackCodec) ContentType() string                     { return "application/msgpack" }
+func (testMsgpackCodec) Decode(r io.Reader, v interface{}) error { return nil }
+func (testMsgpackCodec) Encode(w io.Writer, v interface{}) error {
+	_, err := w.Write([]byte("msgpack"))
+	return err
+}
+
+func TestNegotiateCodec(t *testing.T) {
+	a := assert.New(t)
+
+	for accept, expected := range map[string]Codec{
+		"":                JSONCodec,
+		"*/*":             JSONCodec,
+		"application/xml": XMLCodec,
+		"text/xml":        XMLCodec,
+		"application/xml;q=0.5, application/json": JSONCodec,
+		"text/html, application/x-protobuf;q=0.9": ProtobufCodec,
+		"application/*":        JSONCodec,
+		"text/*":               XMLCodec,
+		"text/html, */*;q=0.1": JSONCodec,
+	} {
+		codec, ok := NegotiateCodec(accept)
+		a.True(ok, accept)
+		a.Equal(expected, codec, accept)
+	}
+	for _, accept := range []string{"text/html", "application/json;q=0", "image/*"} {
+		_, ok := NegotiateCodec(accept)
+		a.False(ok, accept)
+	}
+
+	codec, ok := CodecForContentType("application/json; charset=utf-8")
+	a.True(ok)
+	a.Equal(JSONCodec, codec)
+	_, ok = CodecForContentType("text/plain")
+	a.False(ok)
+
+	RegisterCodec(testMsgpackCodec{})
+	defer func() {
+		codecs.Lock()
+		delete(codecs.byMediaType, "application/msgpack")
+		codecs.order = codecs.order[:len(codecs.order)-1]
+		codecs.Unlock()
+	}()
+	codec, ok = NegotiateCodec("application/msgpack")
+	a.True(ok)
+	a.Equal(testMsgpackCodec{}, codec)
+}
+
+func TestDecodeBody(t *testing.T) {
+	a := assert.New(t)
+
+	decode := func(contentType string, body []byte, target interface{}) error {
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		return DecodeBody(r, target)
+	}
+
+	var v codecTestValue
+	a.Nil(decode("", []byte(`{"a": "x", "b": 1}`), &v))
+	a.Equal("x", v.A)
+	v = codecTestValue{}
+	a.Nil(decode("application/xml", []byte(`<value><a>y</a><b>2</b></value>`), &v))
+	a.Equal(2, v.B)
+
+	b, err := proto.Marshal(wrapperspb.String("z"))
+	a.Nil(err)
+	var m wrapperspb.StringValue
+	a.Nil(decode("application/x-protobuf", b, &m))
+	a.Equal("z", m.Value)
+	// values that are not protobuf messages
+	a.True(errors.IsInvalidArgumentError(decode("application/x-protobuf", b, &v)))
+
+	err = decode("text/plain", []byte("x"), &v)
+	a.True(errors.IsInvalidArgumentError(err))
+	err = decode("application/json", []byte("{"), &v)
+	a.True(errors.IsInvalidArgumentError(err))
+}
+
+func TestEncodeBody(t *testing.T) {
+	a := assert.New(t)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+	a.Nil(EncodeBody(w, r, http.StatusCreated, codecTestValue{A: "x", B: 1}))
+	a.Equal(http.StatusCreated, w.Code)
+	a.Equal("application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	a.Equal("<value><a>x</a><b>1</b></value>", w.Body.String())
+
+	// not acceptable, encoded as json
+	r.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	a.Nil(EncodeBody(w, r, http.StatusOK, codecTestValue{A: "x", B: 1}))
+	a.Equal("application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	a.JSONEq(`{"a": "x", "b": 1}`, w.Body.String())
+}
+
+func TestGenericEncodeFunc(t *testing.T) {
+	a := assert.New(t)
+
+	encode := MakeGenericEncodeFunc(http.StatusOK, nil, nil)
+	contextWithAccept := func(accept string) context.Context {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", accept)
+		return kithttp.PopulateRequestContext(context.Background(), r)
+	}
+
+	w := httptest.NewRecorder()
+	a.Nil(encode(contextWithAccept("application/x-protobuf"), w, endpoint.Response{R: wrapperspb.String("z")}))
+	a.Equal("application/x-protobuf", w.Header().Get("Content-Type"))
+	var m wrapperspb.StringValue
+	a.Nil(proto.Unmarshal(w.Body.Bytes(), &m))
+	a.Equal("z", m.Value)
+
+	// json if the accept header is not in the context
+	w = httptest.NewRecorder()
+	a.Nil(encode(context.Background(), w, endpoint.Response{R: codecTestValue{A: "x"}}))
+	a.Equal("application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	a.JSONEq(`{"a": "x", "b": 0}`, w.Body.String())
+
+	// nil responses have no body, errors are encoded with EncodeError
+	w = httptest.NewRecorder()
+	a.Nil(encode(contextWithAccept("application/xml"), w, endpoint.Response{}))
+	a.Equal(http.StatusOK, w.Code)
+	a.Empty(w.Body.String())
+	w = httptest.NewRecorder()
+	a.Nil(encode(contextWithAccept("application/xml"), w, endpoint.Response{Err: errors.New(nil, "test", errors.NotFound).WithPublicMessage("not found")}))
+	a.Equal(http.StatusNotFound, w.Code)
+	a.True(strings.Contains(w.Body.String(), `"message":"not found"`))
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// Configures NewConcurrencyLimitHandler.
+type ConcurrencyLimitConfig struct {
+	// Maximum number of requests handled at the same time, at least 1.
+	MaxInFlight int
+	// Maximum number of requests that wait for another request to finish when MaxInFlight requests are being handled.
+	// If 0, requests are rejected immediately.
+	MaxQueued int
+	// Maximum time a request waits in the queue before it is rejected.
+	// If 0, requests wait until they can be handled or the request context is done, e.g. because of ServerConfig.RequestTimeout.
+	QueueTimeout time.Duration
+	// Value of the "Retry-After" header of rejected requests, rounded up to seconds. Defaults to 1s.
+	RetryAfter time.Duration
+
+	// Value of the "route" label of the metrics, e.g. the path template of the handler, see MetricsMiddleware.
+	Route string
+	// Number of requests being handled, with label "route". Not recorded if nil.
+	InFlight metrics.Gauge
+	// Number of requests waiting in the queue, with label "route". Not recorded if nil.
+	Queued metrics.Gauge
+	// Number of rejected requests, with label "route". Not recorded if nil.
+	Rejected metrics.Counter
+}
+
+// Http middleware that limits the number of requests handled at the same time, so that a service under load
+// answers some requests quickly instead of all requests slowly, see ConcurrencyLimitConfig.
+// Requests that exceed the limit are queued or answered with status 503 Service Unavailable, a "Retry-After" header
+// and the standard error body (see EncodeError), the next handler is not called.
+// Requests whose context is done while they are queued are not answered.
+//
+// Every handler returned by this function has its own limit, e.g. a global limit can be set with ServerConfig.ConcurrencyLimit
+// and lower limits for expensive routes by wrapping their handlers.
+func NewConcurrencyLimitHandler(next http.Handler, config ConcurrencyLimitConfig) http.Handler {
+	maxInFlight := config.MaxInFlight
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	retryAfter := config.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+	retryAfterHeader := strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10)
+
+	var inFlight, queued metrics.Gauge
+	if config.InFlight != nil {
+		inFlight = config.InFlight.With("route", config.Route)
+	}
+	if config.Queued != nil {
+		queued = config.Queued.With("route", config.Route)
+	}
+	var rejected metrics.Counter
+	if config.Rejected != nil {
+		rejected = config.Rejected.With("route", config.Route)
+	}
+
+	slots := make(chan struct{}, maxInFlight)
+	var queueLength int64
+
+	reject := func(w http.ResponseWriter, r *http.Request) {
+		if rejected != nil {
+			rejected.Add(1)
+		}
+		w.Header().Set("Retry-After", retryAfterHeader)
+		err := errors.New(nil, errorOrigin, errors.Unavailable).WithPublicMessage("server is overloaded, try again later")
+		EncodeError(r.Context(), err, w)
+	}
+
+	// Returns true if the request can be handled, false if it was rejected or its context is done.
+	acquire := func(w http.ResponseWriter, r *http.Request) bool {
+		select {
+		case slots <- struct{}{}:
+			return true
+		default:
+		}
+		if atomic.AddInt64(&queueLength, 1) > int64(config.MaxQueued) {
+			atomic.AddInt64(&queueLength, -1)
+			reject(w, r)
+			return false
+		}
+		if queued != nil {
+			queued.Add(1)
+		}
+		defer func() {
+			atomic.AddInt64(&queueLength, -1)
+			if queued != nil {
+				queued.Add(-1)
+			}
+		}()
+
+		var timeout <-chan time.Time
+		if config.QueueTimeout > 0 {
+			timer := time.NewTimer(config.QueueTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case slots <- struct{}{}:
+			return true
+		case <-timeout:
+			reject(w, r)
+			return false
+		case <-r.Context().Done():
+			return false
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acquire(w, r) {
+			return
+		}
+		defer func() { <-slots }()
+		if inFlight != nil {
+			inFlight.Add(1)
+			defer inFlight.Add(-1)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimitHandler(t *testing.T) {
+	a := assert.New(t)
+
+	started, block := make(chan struct{}, 10), make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-block
+		w.WriteHeader(http.StatusNoContent)
+	})
+	inFlight, queued, rejected := newTestMetric(), newTestMetric(), newTestMetric()
+	handler := NewConcurrencyLimitHandler(next, ConcurrencyLimitConfig{
+		MaxInFlight: 1,
+		MaxQueued:   1,
+		RetryAfter:  1500 * time.Millisecond,
+		Route:       "/",
+		InFlight:    testGauge{inFlight},
+		Queued:      testGauge{queued},
+		Rejected:    testCounter{rejected},
+	})
+
+	serve := func(ctx context.Context) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		return w
+	}
+	done := make(chan *httptest.ResponseRecorder, 2)
+	go func() { done <- serve(context.Background()) }()
+	<-started
+	a.Equal(1.0, inFlight.value("route", "/"))
+
+	// second request is queued
+	go func() { done <- serve(context.Background()) }()
+	for queued.value("route", "/") != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// third request is rejected
+	w := serve(context.Background())
+	a.Equal(http.StatusServiceUnavailable, w.Code)
+	a.Equal("2", w.Header().Get("Retry-After"))
+	a.JSONEq(`{"error": {"message": "server is overloaded, try again later"}}`, w.Body.String())
+	a.Equal(1.0, rejected.value("route", "/"))
+
+	// queued request is handled after the first one finished
+	block <- struct{}{}
+	a.Equal(http.StatusNoContent, (<-done).Code)
+	<-started
+	a.Equal(0.0, queued.value("route", "/"))
+	block <- struct{}{}
+	a.Equal(http.StatusNoContent, (<-done).Code)
+	a.Equal(0.0, inFlight.value("route", "/"))
+
+	// queued requests are rejected after the queue timeout or left when their context is done
+	handler = NewConcurrencyLimitHandler(next, ConcurrencyLimitConfig{MaxInFlight: 1, MaxQueued: 1, QueueTimeout: 10 * time.Millisecond})
+	go func() { done <- serve(context.Background()) }()
+	<-started
+	a.Equal(http.StatusServiceUnavailable, serve(context.Background()).Code)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = serve(ctx)
+	a.Equal(http.StatusOK, w.Code)
+	a.Empty(w.Body.String())
+	close(block)
+	a.Equal(http.StatusNoContent, (<-done).Code)
+}
+
+func TestServerHandlerConcurrencyLimit(t *testing.T) {
+	a := assert.New(t)
+
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-block
+	})
+	health := NewHealth()
+	h := serverHandler(handler, NewServerConfig().WithHealth(health).WithConcurrencyLimit(ConcurrencyLimitConfig{MaxInFlight: 1}))
+
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	<-started
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal(http.StatusServiceUnavailable, w.Code)
+
+	// health checks are not limited
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	a.Equal(http.StatusOK, w.Code)
+}
@@ -0,0 +1,273 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+)
+
+// Content of a download that can be read in parts, see ServeDownload.
+type Blob interface {
+	// Returns a reader for length bytes of the content starting at offset, a negative length reads until the end of the content.
+	NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+}
+
+// Adapts a function to the Blob interface, e.g. to serve an object from Firebase/Cloud Storage:
+//
+//	blob := BlobFunc(func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+//		return bucket.Object(name).NewRangeReader(ctx, offset, length)
+//	})
+type BlobFunc func(ctx context.Context, offset, length int64) (io.ReadCloser, error)
+
+func (f BlobFunc) NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	return f(ctx, offset, length)
+}
+
+// Returns a Blob that reads the first size bytes of r, e.g. an *os.File or *bytes.Reader.
+func ReaderAtBlob(r io.ReaderAt, size int64) Blob {
+	return BlobFunc(func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+		if length < 0 || offset+length > size {
+			length = size - offset
+		}
+		return io.NopCloser(io.NewSectionReader(r, offset, length)), nil
+	})
+}
+
+// Describes a download served by ServeDownload.
+type Download struct {
+	// File name suggested to the client in the "Content-Disposition" header, e.g. "export.csv".
+	// If empty, no file name is suggested.
+	Name string
+	// If empty, the content type is determined from the extension of Name or by sniffing the first 512 bytes of the content.
+	ContentType string
+	// Size of the content in bytes.
+	Size int64
+	// If not zero, used for the "Last-Modified" header and conditional requests.
+	ModTime time.Time
+	// If not empty, used for the "ETag" header and conditional requests, must be a quoted entity tag, see ETag.
+	ETag string
+	// If true, clients should display the content instead of downloading it, e.g. images in a browser.
+	Inline bool
+}
+
+type downloadRequest struct {
+	method          string
+	rangeHeader     string
+	ifRange         string
+	ifNoneMatch     string
+	ifModifiedSince string
+}
+
+func newDownloadRequest(r *http.Request) downloadRequest {
+	return downloadRequest{
+		method:          r.Method,
+		rangeHeader:     r.Header.Get("Range"),
+		ifRange:         r.Header.Get("If-Range"),
+		ifNoneMatch:     r.Header.Get("If-None-Match"),
+		ifModifiedSince: r.Header.Get("If-Modified-Since"),
+	}
+}
+
+type downloadRequestContextKey struct{}
+
+// Go kit request function that stores the method and the range and conditional headers of a request in the context,
+// so that they can be used by EncodeDownload.
+// Should be passed to kithttp.ServerBefore when creating a http handler with package "github.com/go-kit/kit/transport/http".
+func PopulateDownloadRequestContext(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, downloadRequestContextKey{}, newDownloadRequest(r))
+}
+
+// Works like ServeDownload, but uses the headers stored in the context by PopulateDownloadRequestContext, e.g. in a go kit encode function.
+// If the headers were not stored in the context, the full content is served.
+func EncodeDownload(ctx context.Context, w http.ResponseWriter, d Download, blob Blob) error {
+	dr, _ := ctx.Value(downloadRequestContextKey{}).(downloadRequest)
+	return serveDownload(ctx, w, dr, d, blob)
+}
+
+// Writes the content of a download to the response, with status 200 OK or 206 Partial Content if a single range of the content was requested
+// with a "Range" header. Requests for multiple ranges are answered with the full content.
+// Conditional requests with "If-None-Match", "If-Modified-Since" and "If-Range" headers are supported if d.ETag or d.ModTime is set.
+// Requests for a range that starts after the end of the content are answered with status 416 Range Not Satisfiable.
+//
+// If the content cannot be read, an error is returned before anything is written to the response, so that it can be encoded e.g. with EncodeError.
+// Errors of type Error returned by the blob are returned unchanged, other errors are wrapped in an error with code Internal.
+// Errors that occur after the response was started can only be logged.
+func ServeDownload(w http.ResponseWriter, r *http.Request, d Download, blob Blob) error {
+	return serveDownload(r.Context(), w, newDownloadRequest(r), d, blob)
+}
+
+func serveDownload(ctx context.Context, w http.ResponseWriter, dr downloadRequest, d Download, blob Blob) error {
+	if d.ETag != "" {
+		w.Header().Set("ETag", d.ETag)
+	}
+	if !d.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", d.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if dr.method == http.MethodGet || dr.method == http.MethodHead {
+		if notModified(dr, d) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	contentType := d.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(d.Name))
+	}
+	if contentType == "" {
+		var err error
+		contentType, err = sniffContentType(ctx, blob, d.Size)
+		if err != nil {
+			return err
+		}
+	}
+
+	offset, length, ok := int64(0), d.Size, true
+	if dr.rangeHeader != "" && ifRangeMatches(dr.ifRange, d) {
+		offset, length, ok = parseRange(dr.rangeHeader, d.Size)
+	}
+	if !ok {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", d.Size))
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return nil
+	}
+	partial := length != d.Size
+
+	var content io.ReadCloser
+	if dr.method != http.MethodHead {
+		var err error
+		content, err = blob.NewRangeReader(ctx, offset, length)
+		if err != nil {
+			return downloadError(err)
+		}
+		defer content.Close()
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Accept-Ranges", "bytes")
+	h.Set("Content-Length", strconv.FormatInt(length, 10))
+	disposition := "attachment"
+	if d.Inline {
+		disposition = "inline"
+	}
+	if d.Name != "" {
+		// FormatMediaType encodes names with non-ASCII characters as defined by RFC 2231
+		if v := mime.FormatMediaType(disposition, map[string]string{"filename": d.Name}); v != "" {
+			disposition = v
+		}
+	}
+	h.Set("Content-Disposition", disposition)
+
+	status := http.StatusOK
+	if partial {
+		status = http.StatusPartialContent
+		h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, d.Size))
+	}
+	w.WriteHeader(status)
+	if content == nil {
+		return nil
+	}
+	if _, err := io.CopyN(w, content, length); err != nil {
+		return newInternalTransportError(err, errors.Internal, "could not write download")
+	}
+	return nil
+}
+
+func downloadError(err error) error {
+	if _, ok := err.(errors.Error); ok {
+		return err
+	}
+	return newInternalTransportError(err, errors.Internal, "could not read download")
+}
+
+func sniffContentType(ctx context.Context, blob Blob, size int64) (string, error) {
+	r, err := blob.NewRangeReader(ctx, 0, min(size, 512))
+	if err != nil {
+		return "", downloadError(err)
+	}
+	defer r.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", downloadError(err)
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// Returns true if the response to the request would not have changed.
+// If-Modified-Since is only considered if the request has no If-None-Match header.
+func notModified(dr downloadRequest, d Download) bool {
+	if dr.ifNoneMatch != "" {
+		return d.ETag != "" && etagMatches(dr.ifNoneMatch, d.ETag, true)
+	}
+	if dr.ifModifiedSince == "" || d.ModTime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(dr.ifModifiedSince)
+	// the header has a resolution of seconds
+	return err == nil && !d.ModTime.Truncate(time.Second).After(t)
+}
+
+// Returns true if the range header should be used, i.e. there is no If-Range header or it matches the current version of the content.
+func ifRangeMatches(ifRange string, d Download) bool {
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return d.ETag != "" && etagMatches(ifRange, d.ETag, false)
+	}
+	t, err := http.ParseTime(ifRange)
+	return err == nil && !d.ModTime.IsZero() && d.ModTime.Truncate(time.Second).Equal(t)
+}
+
+// Parses a range header with a single range, e.g. "bytes=0-99", "bytes=100-" or "bytes=-100" for the last 100 bytes.
+// Returns the offset and length of the range, or the full content if the header is invalid or contains multiple ranges.
+// Returns false if the range is not satisfiable, i.e. it starts after the end of the content.
+func parseRange(header string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, size, true
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, size, true
+	}
+	if first == "" {
+		// suffix range
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, size, true
+		}
+		if n == 0 {
+			return 0, 0, false
+		}
+		n = min(n, size)
+		return size - n, n, true
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, size, true
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, size, true
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return 0, 0, false
+	}
+	return start, end - start + 1, true
+}
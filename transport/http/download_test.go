@@ -0,0 +1,143 @@
+package http
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeDownload(t *testing.T) {
+	a := assert.New(t)
+
+	content := "0123456789"
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	d := Download{
+		Name:    "export.csv",
+		Size:    int64(len(content)),
+		ModTime: modTime,
+		ETag:    `"v1"`,
+	}
+	blob := ReaderAtBlob(strings.NewReader(content), int64(len(content)))
+
+	serve := func(method string, d Download, headers map[string]string) (*httptest.ResponseRecorder, error) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		err := ServeDownload(w, r, d, blob)
+		return w, err
+	}
+
+	w, err := serve(http.MethodGet, d, nil)
+	a.Nil(err)
+	a.Equal(http.StatusOK, w.Code)
+	a.Equal(content, w.Body.String())
+	a.Equal("text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	a.Equal(`attachment; filename=export.csv`, w.Header().Get("Content-Disposition"))
+	a.Equal("10", w.Header().Get("Content-Length"))
+	a.Equal("bytes", w.Header().Get("Accept-Ranges"))
+	a.Equal(`"v1"`, w.Header().Get("ETag"))
+	a.Equal("Tue, 02 Jan 2024 03:04:05 GMT", w.Header().Get("Last-Modified"))
+
+	// ranges
+	for header, expected := range map[string]string{
+		"bytes=2-4":   "234",
+		"bytes=7-":    "789",
+		"bytes=-3":    "789",
+		"bytes=8-100": "89",
+	} {
+		w, err = serve(http.MethodGet, d, map[string]string{"Range": header})
+		a.Nil(err)
+		a.Equal(http.StatusPartialContent, w.Code, header)
+		a.Equal(expected, w.Body.String(), header)
+	}
+	w, _ = serve(http.MethodGet, d, map[string]string{"Range": "bytes=2-4"})
+	a.Equal("bytes 2-4/10", w.Header().Get("Content-Range"))
+	a.Equal("3", w.Header().Get("Content-Length"))
+
+	// multiple or invalid ranges are ignored
+	w, _ = serve(http.MethodGet, d, map[string]string{"Range": "bytes=0-1,3-4"})
+	a.Equal(http.StatusOK, w.Code)
+	w, _ = serve(http.MethodGet, d, map[string]string{"Range": "bytes=4-2"})
+	a.Equal(http.StatusOK, w.Code)
+
+	w, err = serve(http.MethodGet, d, map[string]string{"Range": "bytes=10-"})
+	a.Nil(err)
+	a.Equal(http.StatusRequestedRangeNotSatisfiable, w.Code)
+	a.Equal("bytes */10", w.Header().Get("Content-Range"))
+
+	// If-Range
+	w, _ = serve(http.MethodGet, d, map[string]string{"Range": "bytes=2-4", "If-Range": `"v1"`})
+	a.Equal(http.StatusPartialContent, w.Code)
+	w, _ = serve(http.MethodGet, d, map[string]string{"Range": "bytes=2-4", "If-Range": `"v0"`})
+	a.Equal(http.StatusOK, w.Code)
+	w, _ = serve(http.MethodGet, d, map[string]string{"Range": "bytes=2-4", "If-Range": "Tue, 02 Jan 2024 03:04:05 GMT"})
+	a.Equal(http.StatusPartialContent, w.Code)
+
+	// conditional requests
+	w, _ = serve(http.MethodGet, d, map[string]string{"If-None-Match": `"v1"`})
+	a.Equal(http.StatusNotModified, w.Code)
+	a.Empty(w.Body.String())
+	w, _ = serve(http.MethodGet, d, map[string]string{"If-Modified-Since": "Tue, 02 Jan 2024 03:04:05 GMT"})
+	a.Equal(http.StatusNotModified, w.Code)
+	w, _ = serve(http.MethodGet, d, map[string]string{"If-Modified-Since": "Tue, 02 Jan 2024 03:04:04 GMT"})
+	a.Equal(http.StatusOK, w.Code)
+
+	// HEAD requests get no body
+	w, _ = serve(http.MethodHead, d, nil)
+	a.Equal(http.StatusOK, w.Code)
+	a.Equal("10", w.Header().Get("Content-Length"))
+	a.Empty(w.Body.String())
+
+	// content type sniffing and inline disposition with non-ASCII name
+	w, _ = serve(http.MethodGet, Download{Name: "bericht ä", Size: int64(len(content)), Inline: true}, nil)
+	a.Equal("text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	a.Equal(`inline; filename*=utf-8''bericht%20%C3%A4`, w.Header().Get("Content-Disposition"))
+	a.Equal(content, w.Body.String())
+}
+
+func TestServeDownloadErrors(t *testing.T) {
+	a := assert.New(t)
+
+	d := Download{Name: "a.txt", Size: 10}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	w := httptest.NewRecorder()
+	err := ServeDownload(w, r, d, BlobFunc(func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+		return nil, stderrors.New("storage failed")
+	}))
+	a.True(errors.IsInternalError(err))
+	// nothing is written, so that the error can be encoded
+	a.False(w.Flushed)
+	a.Empty(w.Header().Get("Content-Length"))
+	a.Empty(w.Body.String())
+
+	notFound := errors.New(nil, "storage", errors.NotFound)
+	err = ServeDownload(httptest.NewRecorder(), r, d, BlobFunc(func(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+		return nil, notFound
+	}))
+	a.Equal(notFound, err)
+}
+
+func TestEncodeDownload(t *testing.T) {
+	a := assert.New(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Range", "bytes=0-1")
+	ctx := PopulateDownloadRequestContext(context.Background(), r)
+	w := httptest.NewRecorder()
+	err := EncodeDownload(ctx, w, Download{ContentType: "text/plain", Size: 3}, ReaderAtBlob(strings.NewReader("abc"), 3))
+	a.Nil(err)
+	a.Equal(http.StatusPartialContent, w.Code)
+	a.Equal("ab", w.Body.String())
+}
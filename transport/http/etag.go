@@ -0,0 +1,155 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/dkinzler/kit/errors"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// Returns a strong entity tag for the given response body, i.e. a quoted hash of the body.
+func ETag(body []byte) string {
+	h := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// Returns the entity tag of the JSON encoding of the given value, e.g. to compare it with the If-Match header of a request, see CheckIfMatch.
+func ComputeETag(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", newInternalTransportError(err, errors.Internal, "could not encode value to compute etag")
+	}
+	return ETag(b), nil
+}
+
+type conditionalHeaders struct {
+	method      string
+	ifMatch     string
+	ifNoneMatch string
+}
+
+type conditionalHeadersContextKey struct{}
+
+// Go kit request function that stores the method and the If-Match and If-None-Match headers of a request in the context,
+// so that they can be used by MakeConditionalEncodeFunc and CheckIfMatch.
+// Should be passed to kithttp.ServerBefore when creating a http handler with package "github.com/go-kit/kit/transport/http".
+func PopulateConditionalRequestContext(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, conditionalHeadersContextKey{}, conditionalHeaders{
+		method:      r.Method,
+		ifMatch:     r.Header.Get("If-Match"),
+		ifNoneMatch: r.Header.Get("If-None-Match"),
+	})
+}
+
+// Wraps the given encode function, e.g. one returned by MakeGenericJSONEncodeFunc, to support conditional GET requests.
+// The "ETag" header of successful responses with status 200 is set to the entity tag of the response body (see ETag).
+// If the If-None-Match header of a GET or HEAD request matches the entity tag, status 304 Not Modified is sent without a body.
+// The headers of the request must be stored in the context with PopulateConditionalRequestContext.
+//
+// If the wrapped function returns an error, nothing is written to the response writer, not even headers,
+// so that the error can still be encoded, e.g. by the error encoder of the go kit server.
+//
+// Note that the response is buffered to compute the entity tag, the wrapped function should not be used to stream large responses.
+func MakeConditionalEncodeFunc(encode kithttp.EncodeResponseFunc) kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		rw := &bufferedResponseWriter{header: w.Header().Clone(), status: http.StatusOK}
+		if err := encode(ctx, rw, response); err != nil {
+			return err
+		}
+		for key := range w.Header() {
+			if _, ok := rw.header[key]; !ok {
+				w.Header().Del(key)
+			}
+		}
+		for key, values := range rw.header {
+			w.Header()[key] = values
+		}
+
+		if rw.status == http.StatusOK {
+			etag := ETag(rw.body.Bytes())
+			w.Header().Set("ETag", etag)
+			h, _ := ctx.Value(conditionalHeadersContextKey{}).(conditionalHeaders)
+			if (h.method == http.MethodGet || h.method == http.MethodHead) && etagMatches(h.ifNoneMatch, etag, true) {
+				w.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+		}
+		w.WriteHeader(rw.status)
+		if _, err := w.Write(rw.body.Bytes()); err != nil {
+			return newInternalTransportError(err, errors.Internal, "could not write response body")
+		}
+		return nil
+	}
+}
+
+// Checks the If-Match header of the request against the current entity tag of the resource, e.g. before updating the resource,
+// so that clients do not overwrite changes made by other clients (optimistic concurrency control).
+// Returns an error with code FailedPrecondition if the header is set and does not match the given entity tag.
+// Returns nil if the header is not set or the headers were not stored in the context with PopulateConditionalRequestContext.
+//
+// The entity tag can e.g. be computed with ComputeETag from the current state of a resource that was read as part of a
+// Firestore transaction with TransactionExpectations, so that the resource cannot change between the check and the update.
+func CheckIfMatch(ctx context.Context, etag string) error {
+	h, _ := ctx.Value(conditionalHeadersContextKey{}).(conditionalHeaders)
+	if h.ifMatch == "" || etagMatches(h.ifMatch, etag, false) {
+		return nil
+	}
+	return errors.New(nil, errorOrigin, errors.FailedPrecondition).WithPublicMessage("resource was modified").With("etag", etag)
+}
+
+// Returns true if the given header value, a list of entity tags or "*", contains the entity tag.
+// Weak comparison ignores the "W/" prefix of weak entity tags, strong comparison only matches strong entity tags.
+func etagMatches(header, etag string, weak bool) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Buffers the status code and body of a response, headers are written to the underlying response writer directly.
+type bufferedResponseWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.body.Write(b)
+}
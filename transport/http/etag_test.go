@@ -0,0 +1,121 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkinzler/kit/endpoint"
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	a := assert.New(t)
+
+	etag, err := ComputeETag(map[string]string{"name": "a"})
+	a.Nil(err)
+	a.Equal(ETag([]byte(`{"name":"a"}`)), etag)
+	a.Len(etag, 34)
+	etag2, err := ComputeETag(map[string]string{"name": "b"})
+	a.Nil(err)
+	a.NotEqual(etag, etag2)
+
+	a.True(etagMatches(`"a", "b"`, `"b"`, false))
+	a.True(etagMatches(`*`, `"b"`, false))
+	a.False(etagMatches(``, `"b"`, false))
+	a.False(etagMatches(`"a"`, `"b"`, false))
+	a.True(etagMatches(`W/"b"`, `"b"`, true))
+	a.False(etagMatches(`W/"b"`, `"b"`, false))
+	a.False(etagMatches(`"b"`, `W/"b"`, false))
+}
+
+func TestConditionalEncodeFunc(t *testing.T) {
+	a := assert.New(t)
+
+	encode := MakeConditionalEncodeFunc(MakeGenericJSONEncodeFunc(http.StatusOK))
+	response := endpoint.Response{R: map[string]string{"name": "a"}}
+
+	request := func(method, ifNoneMatch string, response interface{}) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		ctx := PopulateConditionalRequestContext(context.Background(), r)
+		w := httptest.NewRecorder()
+		a.Nil(encode(ctx, w, response))
+		return w
+	}
+
+	w := request(http.MethodGet, "", response)
+	a.Equal(http.StatusOK, w.Code)
+	a.JSONEq(`{"name": "a"}`, w.Body.String())
+	a.Equal("application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	etag := w.Header().Get("ETag")
+	a.NotEmpty(etag)
+
+	w = request(http.MethodGet, etag, response)
+	a.Equal(http.StatusNotModified, w.Code)
+	a.Empty(w.Body.String())
+	a.Equal(etag, w.Header().Get("ETag"))
+
+	w = request(http.MethodGet, `"other", `+etag, response)
+	a.Equal(http.StatusNotModified, w.Code)
+
+	w = request(http.MethodGet, `"other"`, response)
+	a.Equal(http.StatusOK, w.Code)
+
+	// only GET and HEAD requests are conditional
+	w = request(http.MethodPost, etag, response)
+	a.Equal(http.StatusOK, w.Code)
+
+	// errors have no etag
+	w = request(http.MethodGet, etag, endpoint.Response{Err: errors.New(nil, "test", errors.NotFound).WithPublicMessage("not found")})
+	a.Equal(http.StatusNotFound, w.Code)
+	a.Empty(w.Header().Get("ETag"))
+	a.JSONEq(`{"error": {"message": "not found"}}`, w.Body.String())
+
+	// nothing is written if the wrapped function fails
+	failing := MakeConditionalEncodeFunc(func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name":`))
+		return errors.New(nil, "test", errors.Internal)
+	})
+	w = httptest.NewRecorder()
+	w.Header().Set("X-Request-Id", "1")
+	err := failing(context.Background(), w, response)
+	a.NotNil(err)
+	a.Equal(http.Header{"X-Request-Id": {"1"}}, w.Header())
+	a.Empty(w.Body.String())
+	// the error can still be encoded
+	EncodeError(context.Background(), err, w)
+	a.Equal(http.StatusInternalServerError, w.Code)
+	a.NotContains(w.Body.String(), `{"name":`)
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	a := assert.New(t)
+
+	etag, err := ComputeETag(map[string]string{"name": "a"})
+	a.Nil(err)
+
+	ctxWithIfMatch := func(ifMatch string) context.Context {
+		r := httptest.NewRequest(http.MethodPut, "/", nil)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		return PopulateConditionalRequestContext(context.Background(), r)
+	}
+
+	a.Nil(CheckIfMatch(ctxWithIfMatch(etag), etag))
+	a.Nil(CheckIfMatch(ctxWithIfMatch("*"), etag))
+	a.Nil(CheckIfMatch(ctxWithIfMatch(""), etag))
+	a.Nil(CheckIfMatch(context.Background(), etag))
+
+	err = CheckIfMatch(ctxWithIfMatch(`"outdated"`), etag)
+	a.True(errors.IsFailedPreconditionError(err))
+	a.Equal("resource was modified", err.(errors.Error).PublicMessage)
+}
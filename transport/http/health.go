@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+)
+
+// HealthCheck returns a non-nil error if a dependency or component of a service is not healthy, e.g. if a database cannot be reached.
+type HealthCheck func(ctx context.Context) error
+
+// Health contains the liveness and readiness checks of a service and provides http handlers to run them, e.g. for Kubernetes probes.
+// A service is live if it is running and does not need to be restarted, and ready if it can handle requests.
+// The handlers respond with status 200 OK if all checks succeed and with status 503 Service Unavailable otherwise.
+// The response body contains the results of the individual checks as JSON:
+//
+//	{"status": "error", "checks": {"database": {"status": "ok"}, "cache": {"status": "error", "error": "check failed"}}}
+//
+// Since the body is usually public, it contains the public message of a failed check if the error is of type Error
+// from package "github.com/dkinzler/kit/errors" and a generic message otherwise.
+// Checks can be added at any time and are safe for concurrent use.
+type Health struct {
+	// Maximum time to run the checks, defaults to 5 seconds if 0.
+	Timeout time.Duration
+
+	mu        sync.RWMutex
+	liveness  map[string]HealthCheck
+	readiness map[string]HealthCheck
+	draining  bool
+}
+
+func NewHealth() *Health {
+	return &Health{
+		Timeout:   5 * time.Second,
+		liveness:  make(map[string]HealthCheck),
+		readiness: make(map[string]HealthCheck),
+	}
+}
+
+// Adds a liveness check with the given name, replacing any check with the same name.
+func (h *Health) AddLivenessCheck(name string, check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness[name] = check
+}
+
+// Adds a readiness check with the given name, replacing any check with the same name.
+func (h *Health) AddReadinessCheck(name string, check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness[name] = check
+}
+
+// Lets the readiness handler fail without running any checks, e.g. when a server is about to shut down.
+// Liveness checks are not affected.
+func (h *Health) Drain() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.draining = true
+}
+
+// Http handler that runs the liveness checks.
+func (h *Health) LivenessHandler() http.Handler {
+	return h.handler(func() map[string]HealthCheck { return h.liveness }, false)
+}
+
+// Http handler that runs the readiness checks.
+func (h *Health) ReadinessHandler() http.Handler {
+	return h.handler(func() map[string]HealthCheck { return h.readiness }, true)
+}
+
+const (
+	healthStatusOK       = "ok"
+	healthStatusError    = "error"
+	healthStatusDraining = "draining"
+
+	healthCheckFailedMessage   = "check failed"
+	healthCheckTimedOutMessage = "check timed out"
+)
+
+type healthResponse struct {
+	Status string                       `json:"status"`
+	Checks map[string]healthCheckResult `json:"checks,omitempty"`
+}
+
+type healthCheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (h *Health) handler(checks func() map[string]HealthCheck, failWhenDraining bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		draining := h.draining
+		cs := make(map[string]HealthCheck, len(checks()))
+		for name, check := range checks() {
+			cs[name] = check
+		}
+		h.mu.RUnlock()
+
+		var resp healthResponse
+		if draining && failWhenDraining {
+			resp = healthResponse{Status: healthStatusDraining}
+		} else {
+			resp = h.runChecks(r.Context(), cs)
+		}
+		status := http.StatusOK
+		if resp.Status != healthStatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// Runs the checks concurrently, a check that does not return before the timeout fails.
+func (h *Health) runChecks(ctx context.Context, checks map[string]HealthCheck) healthResponse {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// buffered, so that checks that do not return before the timeout do not block forever
+	errs := make([]chan error, len(names))
+	for i, name := range names {
+		errs[i] = make(chan error, 1)
+		go func(check HealthCheck, c chan<- error) {
+			c <- check(ctx)
+		}(checks[name], errs[i])
+	}
+
+	resp := healthResponse{Status: healthStatusOK, Checks: make(map[string]healthCheckResult, len(names))}
+	for i, name := range names {
+		var err error
+		timedOut := false
+		select {
+		case err = <-errs[i]:
+		case <-ctx.Done():
+			// select chooses randomly if both are ready, a check that returned just before the timeout did not time out
+			select {
+			case err = <-errs[i]:
+			default:
+				timedOut = true
+			}
+		}
+		if timedOut {
+			resp.Status = healthStatusError
+			resp.Checks[name] = healthCheckResult{Status: healthStatusError, Error: healthCheckTimedOutMessage}
+		} else if err != nil {
+			resp.Status = healthStatusError
+			resp.Checks[name] = healthCheckResult{Status: healthStatusError, Error: healthCheckErrorMessage(err)}
+		} else {
+			resp.Checks[name] = healthCheckResult{Status: healthStatusOK}
+		}
+	}
+	return resp
+}
+
+// Returns the public message of the error or an error it wraps, or a generic message since the error of a check might contain internal details.
+func healthCheckErrorMessage(err error) string {
+	var e errors.Error
+	if stderrors.As(err, &e) && e.PublicMessage != "" {
+		return e.PublicMessage
+	}
+	return healthCheckFailedMessage
+}
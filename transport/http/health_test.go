@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	a := assert.New(t)
+
+	check := func(h http.Handler) (int, healthResponse) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		var resp healthResponse
+		a.Nil(json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+
+	health := NewHealth()
+	health.Timeout = 50 * time.Millisecond
+
+	// no checks
+	status, resp := check(health.LivenessHandler())
+	a.Equal(http.StatusOK, status)
+	a.Equal(healthResponse{Status: "ok"}, resp)
+
+	health.AddLivenessCheck("loop", func(ctx context.Context) error { return nil })
+	health.AddReadinessCheck("database", func(ctx context.Context) error { return nil })
+	status, resp = check(health.ReadinessHandler())
+	a.Equal(http.StatusOK, status)
+	a.Equal(healthResponse{Status: "ok", Checks: map[string]healthCheckResult{"database": {Status: "ok"}}}, resp)
+
+	health.AddReadinessCheck("cache", func(ctx context.Context) error { return stderrors.New("connection refused") })
+	health.AddReadinessCheck("queue", func(ctx context.Context) error {
+		return errors.New(nil, "test", errors.Unavailable).WithPublicMessage("queue unavailable")
+	})
+	health.AddReadinessCheck("index", func(ctx context.Context) error {
+		return fmt.Errorf("could not ping index: %w", errors.New(nil, "test", errors.Unavailable).WithPublicMessage("index unavailable"))
+	})
+	// checks are collected in the order of their names, the result of "storage" is already available
+	// when the timeout expired while waiting for "slow"
+	health.AddReadinessCheck("storage", func(ctx context.Context) error { return nil })
+	health.AddReadinessCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	status, resp = check(health.ReadinessHandler())
+	a.Equal(http.StatusServiceUnavailable, status)
+	a.Equal(healthResponse{Status: "error", Checks: map[string]healthCheckResult{
+		"database": {Status: "ok"},
+		"cache":    {Status: "error", Error: "check failed"},
+		"queue":    {Status: "error", Error: "queue unavailable"},
+		"index":    {Status: "error", Error: "index unavailable"},
+		"slow":     {Status: "error", Error: "check timed out"},
+		"storage":  {Status: "ok"},
+	}}, resp)
+
+	// liveness is not affected by readiness checks
+	status, resp = check(health.LivenessHandler())
+	a.Equal(http.StatusOK, status)
+	a.Equal(healthResponse{Status: "ok", Checks: map[string]healthCheckResult{"loop": {Status: "ok"}}}, resp)
+
+	// checks with the same name are replaced
+	health.AddReadinessCheck("cache", func(ctx context.Context) error { return nil })
+	health.AddReadinessCheck("slow", func(ctx context.Context) error { return nil })
+	health.AddReadinessCheck("queue", func(ctx context.Context) error { return nil })
+	health.AddReadinessCheck("index", func(ctx context.Context) error { return nil })
+	status, _ = check(health.ReadinessHandler())
+	a.Equal(http.StatusOK, status)
+
+	// readiness fails while draining
+	health.Drain()
+	status, resp = check(health.ReadinessHandler())
+	a.Equal(http.StatusServiceUnavailable, status)
+	a.Equal(healthResponse{Status: "draining"}, resp)
+	status, _ = check(health.LivenessHandler())
+	a.Equal(http.StatusOK, status)
+}
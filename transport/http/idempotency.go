@@ -0,0 +1,256 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+)
+
+// Header that contains the idempotency key of a request, see NewIdempotencyHandler.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Header set on responses that were replayed by NewIdempotencyHandler.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// Response stored for an idempotency key.
+type IdempotentResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// IdempotencyStore stores the responses of requests by idempotency key, see NewIdempotencyHandler.
+// The in-memory store returned by NewMemoryIdempotencyStore only detects duplicate requests handled by the same process,
+// package "github.com/dkinzler/kit/firebase/firestore" provides a store backed by Firestore that can be shared by multiple instances of a service.
+type IdempotencyStore interface {
+	// Starts the execution of a request with the given key.
+	// Returns true if no other execution of the key is in progress and no response is stored, the key is then locked until
+	// Complete or Release are called or the ttl expires. Otherwise returns the stored response,
+	// or nil if another execution is still in progress.
+	Begin(ctx context.Context, key string, ttl time.Duration) (*IdempotentResponse, bool, error)
+	// Stores the response of the execution of a request with the given key, it expires after ttl.
+	Complete(ctx context.Context, key string, response IdempotentResponse, ttl time.Duration) error
+	// Releases the lock on the given key without storing a response, so that the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+type idempotencyEntry struct {
+	response *IdempotentResponse
+	expires  time.Time
+}
+
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]idempotencyEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// Returns an IdempotencyStore that keeps responses in memory.
+// Expired entries are removed periodically.
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return newMemoryIdempotencyStore(time.Now)
+}
+
+func newMemoryIdempotencyStore(now func() time.Time) *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{
+		entries:   make(map[string]idempotencyEntry),
+		lastSweep: now(),
+		now:       now,
+	}
+}
+
+func (s *memoryIdempotencyStore) Begin(ctx context.Context, key string, ttl time.Duration) (*IdempotentResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		return e.response, false, nil
+	}
+	s.entries[key] = idempotencyEntry{expires: now.Add(ttl)}
+	return nil, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, key string, response IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = idempotencyEntry{response: &response, expires: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Removes expired entries, at most once a minute.
+func (s *memoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// Configures NewIdempotencyHandler.
+type IdempotencyConfig struct {
+	// Defaults to a store created with NewMemoryIdempotencyStore.
+	Store IdempotencyStore
+	// How long responses are stored, defaults to 24 hours.
+	TTL time.Duration
+	// How long a key is locked while a request is handled, should be longer than the request timeout. Defaults to 1 minute.
+	LockTTL time.Duration
+	// Methods of requests that are made idempotent, defaults to POST and PATCH.
+	// Requests with other methods are passed to the next handler unchanged.
+	Methods []string
+	// If true, requests without an idempotency key are rejected with status 400 Bad Request, otherwise they are passed to the next handler.
+	Required bool
+	// Returns a scope for the key of a request, e.g. the id of the authenticated principal (see PrincipalFromContext),
+	// so that keys of different clients do not collide. If nil, keys are only scoped by method and path.
+	Scope func(r *http.Request) string
+	// Called with errors returned by the store.
+	OnError func(error)
+}
+
+var defaultIdempotencyMethods = []string{http.MethodPost, http.MethodPatch}
+
+// Http middleware that makes requests with an IdempotencyKeyHeader header idempotent, e.g. for endpoints that create payments.
+//
+// The response of the first request with a key is stored and replayed for later requests with the same key, method and path
+// without calling the next handler, replayed responses have an IdempotentReplayedHeader header.
+// If a request with the same key is still being handled, the request is answered with status 409 Conflict and the standard error body (see EncodeError).
+// Responses with status 5xx are not stored, so that clients can retry the request with the same key.
+// If the store fails, the request is answered with status 503 Service Unavailable, since it cannot be handled safely.
+func NewIdempotencyHandler(next http.Handler, config IdempotencyConfig) http.Handler {
+	store := config.Store
+	if store == nil {
+		store = NewMemoryIdempotencyStore()
+	}
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	lockTTL := config.LockTTL
+	if lockTTL <= 0 {
+		lockTTL = time.Minute
+	}
+	methods := config.Methods
+	if len(methods) == 0 {
+		methods = defaultIdempotencyMethods
+	}
+	onError := func(err error) {
+		if config.OnError != nil {
+			config.OnError(err)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !containsString(methods, r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			if config.Required {
+				EncodeError(r.Context(), newPublicTransportError(nil, errors.InvalidArgument, "missing idempotency key"), w)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		key = r.Method + " " + r.URL.Path + " " + key
+		if config.Scope != nil {
+			key = config.Scope(r) + " " + key
+		}
+
+		response, ok, err := store.Begin(r.Context(), key, lockTTL)
+		if err != nil {
+			onError(err)
+			EncodeError(r.Context(), newInternalTransportError(err, errors.Unavailable, "idempotency store failed"), w)
+			return
+		}
+		if !ok {
+			if response == nil {
+				err := newPublicTransportError(nil, errors.Aborted, "a request with the same idempotency key is in progress")
+				EncodeError(r.Context(), err, w)
+				return
+			}
+			replayResponse(w, response)
+			return
+		}
+
+		rw := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			// release the key if the handler panics
+			if !completed {
+				if err := store.Release(context.WithoutCancel(r.Context()), key); err != nil {
+					onError(err)
+				}
+			}
+		}()
+		next.ServeHTTP(rw, r)
+		completed = true
+
+		ctx := context.WithoutCancel(r.Context())
+		if rw.status >= 500 {
+			err = store.Release(ctx, key)
+		} else {
+			err = store.Complete(ctx, key, IdempotentResponse{
+				StatusCode: rw.status,
+				Header:     w.Header().Clone(),
+				Body:       rw.body.Bytes(),
+			}, ttl)
+		}
+		if err != nil {
+			onError(err)
+		}
+	})
+}
+
+func replayResponse(w http.ResponseWriter, response *IdempotentResponse) {
+	for key, values := range response.Header {
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(response.StatusCode)
+	w.Write(response.Body)
+}
+
+// Writes a response and records its status code and body.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recordingResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
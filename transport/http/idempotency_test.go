@@ -0,0 +1,156 @@
+package http
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyHandler(t *testing.T) {
+	a := assert.New(t)
+
+	calls := 0
+	started, block := make(chan struct{}), make(chan struct{})
+	handler := NewIdempotencyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/block":
+			close(started)
+			<-block
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Call", fmt.Sprint(calls))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(fmt.Sprintf(`{"call": %v}`, calls)))
+	}), IdempotencyConfig{})
+
+	request := func(method, path, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			r.Header.Set(IdempotencyKeyHeader, key)
+		}
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := request(http.MethodPost, "/pay", "k1")
+	a.Equal(http.StatusCreated, w.Code)
+	a.Equal(`{"call": 1}`, w.Body.String())
+	a.Empty(w.Header().Get(IdempotentReplayedHeader))
+
+	// duplicate is replayed
+	w = request(http.MethodPost, "/pay", "k1")
+	a.Equal(http.StatusCreated, w.Code)
+	a.Equal(`{"call": 1}`, w.Body.String())
+	a.Equal("1", w.Header().Get("X-Call"))
+	a.Equal("true", w.Header().Get(IdempotentReplayedHeader))
+	a.Equal(1, calls)
+
+	// different key, path or no key
+	request(http.MethodPost, "/pay", "k2")
+	request(http.MethodPost, "/other", "k1")
+	request(http.MethodPost, "/pay", "")
+	a.Equal(4, calls)
+
+	// other methods are not affected
+	request(http.MethodGet, "/pay", "k1")
+	a.Equal(5, calls)
+
+	// server errors are not stored
+	w = request(http.MethodPost, "/fail", "k3")
+	a.Equal(http.StatusInternalServerError, w.Code)
+	request(http.MethodPost, "/fail", "k3")
+	a.Equal(7, calls)
+
+	// concurrent requests with the same key
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- request(http.MethodPost, "/block", "k4")
+	}()
+	<-started
+	w = request(http.MethodPost, "/block", "k4")
+	a.Equal(http.StatusConflict, w.Code)
+	a.JSONEq(`{"error": {"message": "a request with the same idempotency key is in progress"}}`, w.Body.String())
+	close(block)
+	a.Equal(http.StatusCreated, (<-done).Code)
+}
+
+type failingIdempotencyStore struct{}
+
+func (failingIdempotencyStore) Begin(ctx context.Context, key string, ttl time.Duration) (*IdempotentResponse, bool, error) {
+	return nil, false, stderrors.New("store failed")
+}
+
+func (failingIdempotencyStore) Complete(ctx context.Context, key string, response IdempotentResponse, ttl time.Duration) error {
+	return nil
+}
+
+func (failingIdempotencyStore) Release(ctx context.Context, key string) error {
+	return nil
+}
+
+func TestIdempotencyHandlerConfig(t *testing.T) {
+	a := assert.New(t)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	handler := NewIdempotencyHandler(next, IdempotencyConfig{Required: true})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	a.Equal(http.StatusBadRequest, w.Code)
+
+	var gotErr error
+	handler = NewIdempotencyHandler(next, IdempotencyConfig{
+		Store:   failingIdempotencyStore{},
+		OnError: func(err error) { gotErr = err },
+	})
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set(IdempotencyKeyHeader, "k")
+	handler.ServeHTTP(w, r)
+	a.Equal(http.StatusServiceUnavailable, w.Code)
+	a.NotNil(gotErr)
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Now()
+	s := newMemoryIdempotencyStore(func() time.Time { return now })
+	ctx := context.Background()
+
+	_, ok, err := s.Begin(ctx, "k", time.Minute)
+	a.Nil(err)
+	a.True(ok)
+	resp, ok, _ := s.Begin(ctx, "k", time.Minute)
+	a.False(ok)
+	a.Nil(resp)
+
+	// lock expired
+	now = now.Add(2 * time.Minute)
+	_, ok, _ = s.Begin(ctx, "k", time.Minute)
+	a.True(ok)
+
+	a.Nil(s.Complete(ctx, "k", IdempotentResponse{StatusCode: 200, Body: []byte("ok")}, time.Hour))
+	resp, ok, _ = s.Begin(ctx, "k", time.Minute)
+	a.False(ok)
+	a.Equal([]byte("ok"), resp.Body)
+
+	// expired entries are removed
+	now = now.Add(2 * time.Hour)
+	_, ok, _ = s.Begin(ctx, "other", time.Minute)
+	a.True(ok)
+	a.Len(s.entries, 1)
+
+	a.Nil(s.Release(ctx, "other"))
+	a.Len(s.entries, 0)
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dkinzler/kit/errors"
+)
+
+// Configures how DecodeJSONBodyWithOptions decodes request bodies.
+// The zero value decodes like encoding/json, i.e. unknown fields and trailing data are ignored.
+type JSONDecodeOptions struct {
+	// If true, the request fails if the body contains object keys that do not match a field of the target struct.
+	DisallowUnknownFields bool
+	// If true, the request fails if the body contains more data after the first JSON value.
+	DisallowTrailingData bool
+	// Maximum size of the request body in bytes, not limited if 0.
+	// Like for the limit set by NewMaxRequestBodySizeHandler, ErrToCode returns 413 for the error of a larger body.
+	MaxBytes int64
+	// Maximum nesting depth of objects and arrays, not limited if 0.
+	MaxDepth int
+	// If true, the public messages of decode errors contain the offset of syntax errors and the names of invalid or unknown fields,
+	// which are also added as BadRequest detail.
+	DetailedErrors bool
+}
+
+// Options used by DecodeJSONBody.
+// Can be replaced to make all handlers that use DecodeJSONBody, including generated code, decode request bodies strictly.
+var DefaultJSONDecodeOptions = JSONDecodeOptions{}
+
+// Tries to decode the body of the given http request into target using the given options.
+// Returns an error with code InvalidArgument and a public message if the body could not be decoded or violates one of the options.
+// Afterwards target is validated with ValidateFunc.
+func DecodeJSONBodyWithOptions(r *http.Request, target interface{}, opts JSONDecodeOptions) error {
+	var body io.Reader = r.Body
+	if opts.MaxBytes > 0 || opts.MaxDepth > 0 {
+		if opts.MaxBytes > 0 {
+			body = io.LimitReader(body, opts.MaxBytes+1)
+		}
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return newRequestBodyError(err, "could not read request body")
+		}
+		if opts.MaxBytes > 0 && int64(len(b)) > opts.MaxBytes {
+			return newRequestBodyError(&http.MaxBytesError{Limit: opts.MaxBytes}, "")
+		}
+		if opts.MaxDepth > 0 && jsonDepthExceeds(b, opts.MaxDepth) {
+			return newPublicTransportError(nil, errors.InvalidArgument, fmt.Sprintf("json request body must not be nested deeper than %v levels", opts.MaxDepth))
+		}
+		body = bytes.NewReader(b)
+	}
+
+	decoder := json.NewDecoder(body)
+	if opts.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(target); err != nil {
+		if opts.DetailedErrors {
+			return detailedJSONDecodeError(err)
+		}
+		return newRequestBodyError(err, "could not decode json request body")
+	}
+	if opts.DisallowTrailingData {
+		if _, err := decoder.Token(); err != io.EOF {
+			return newPublicTransportError(err, errors.InvalidArgument, "json request body contains data after the first value")
+		}
+	}
+	return validateDecoded(target)
+}
+
+// Returns true if objects and arrays in the given json are nested deeper than max levels.
+// Does not validate the json, invalid json is reported by the decoder.
+func jsonDepthExceeds(b []byte, max int) bool {
+	depth := 0
+	inString, escaped := false, false
+	for _, c := range b {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > max {
+				return true
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return false
+}
+
+func detailedJSONDecodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case stderrors.As(err, &syntaxErr):
+		return newPublicTransportError(err, errors.InvalidArgument, fmt.Sprintf("invalid json request body: syntax error at offset %v", syntaxErr.Offset))
+	case stderrors.As(err, &typeErr) && typeErr.Field != "":
+		return errors.New(err, errorOrigin, errors.InvalidArgument).
+			WithPublicMessage(fmt.Sprintf("invalid json request body: invalid value for field %v at offset %v", typeErr.Field, typeErr.Offset)).
+			WithDetails(errors.BadRequest{FieldViolations: []errors.FieldViolation{
+				{Field: typeErr.Field, Description: fmt.Sprintf("must be of type %v", typeErr.Type)},
+			}})
+	case stderrors.Is(err, io.ErrUnexpectedEOF) || stderrors.Is(err, io.EOF):
+		return newPublicTransportError(err, errors.InvalidArgument, "invalid json request body: unexpected end of input")
+	}
+	// encoding/json does not export an error type for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return errors.New(err, errorOrigin, errors.InvalidArgument).
+			WithPublicMessage(fmt.Sprintf("invalid json request body: unknown field %v", field)).
+			WithDetails(errors.BadRequest{FieldViolations: []errors.FieldViolation{
+				{Field: field, Description: "unknown field"},
+			}})
+	}
+	return newRequestBodyError(err, "could not decode json request body")
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type jsonTestStruct struct {
+	Name   string `json:"name"`
+	Count  int    `json:"count"`
+	Nested struct {
+		Values []int `json:"values"`
+	} `json:"nested"`
+}
+
+func TestDecodeJSONBodyWithOptions(t *testing.T) {
+	a := assert.New(t)
+
+	decode := func(body string, opts JSONDecodeOptions) (jsonTestStruct, error) {
+		var v jsonTestStruct
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		err := DecodeJSONBodyWithOptions(r, &v, opts)
+		return v, err
+	}
+
+	// the zero options are lenient
+	v, err := decode(`{"name": "a", "unknown": 1} {"more": true}`, JSONDecodeOptions{})
+	a.Nil(err)
+	a.Equal("a", v.Name)
+
+	strict := JSONDecodeOptions{
+		DisallowUnknownFields: true,
+		DisallowTrailingData:  true,
+		MaxBytes:              64,
+		MaxDepth:              2,
+		DetailedErrors:        true,
+	}
+	v, err = decode(`{"name": "a", "nested": {"values": [1, 2]}} `, JSONDecodeOptions{DisallowTrailingData: true})
+	a.Nil(err)
+	a.Equal([]int{1, 2}, v.Nested.Values)
+
+	_, err = decode(`{"name": "a", "unknown": 1}`, strict)
+	a.True(errors.IsInvalidArgumentError(err))
+	a.Equal("invalid json request body: unknown field unknown", err.(errors.Error).PublicMessage)
+	br, ok := errors.BadRequestOf(err)
+	a.True(ok)
+	a.Equal("unknown", br.FieldViolations[0].Field)
+
+	_, err = decode(`{"name": "a"} {}`, strict)
+	a.True(errors.IsInvalidArgumentError(err))
+	a.Equal("json request body contains data after the first value", err.(errors.Error).PublicMessage)
+
+	_, err = decode(`{"name": "`+strings.Repeat("a", 64)+`"}`, strict)
+	a.True(errors.IsInvalidArgumentError(err))
+	a.Equal("request body must not be larger than 64 bytes", err.(errors.Error).PublicMessage)
+	a.Equal(http.StatusRequestEntityTooLarge, ErrToCode(err))
+
+	_, err = decode(`{"nested": {"values": [1]}}`, strict)
+	a.True(errors.IsInvalidArgumentError(err))
+	a.Equal("json request body must not be nested deeper than 2 levels", err.(errors.Error).PublicMessage)
+
+	// brackets in strings do not count towards the depth
+	_, err = decode(`{"name": "[[[{\"{"}`, strict)
+	a.Nil(err)
+
+	_, err = decode(`{"count": "abc"}`, strict)
+	a.True(errors.IsInvalidArgumentError(err))
+	a.Equal("invalid json request body: invalid value for field count at offset 15", err.(errors.Error).PublicMessage)
+	br, ok = errors.BadRequestOf(err)
+	a.True(ok)
+	a.Equal([]errors.FieldViolation{{Field: "count", Description: "must be of type int"}}, br.FieldViolations)
+
+	_, err = decode(`{"name" "a"}`, strict)
+	a.True(errors.IsInvalidArgumentError(err))
+	a.Equal("invalid json request body: syntax error at offset 9", err.(errors.Error).PublicMessage)
+
+	_, err = decode(`{"name": `, strict)
+	a.True(errors.IsInvalidArgumentError(err))
+	a.Equal("invalid json request body: unexpected end of input", err.(errors.Error).PublicMessage)
+
+	// without detailed errors the message does not reveal details
+	_, err = decode(`{"count": "abc"}`, JSONDecodeOptions{})
+	a.Equal("could not decode json request body", err.(errors.Error).PublicMessage)
+	_, ok = errors.BadRequestOf(err)
+	a.False(ok)
+}
+
+func TestDecodeJSONBodyUsesDefaultOptions(t *testing.T) {
+	a := assert.New(t)
+
+	defer func(opts JSONDecodeOptions) { DefaultJSONDecodeOptions = opts }(DefaultJSONDecodeOptions)
+	DefaultJSONDecodeOptions = JSONDecodeOptions{DisallowUnknownFields: true}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"unknown": 1}`))
+	err := DecodeJSONBody(r, &jsonTestStruct{})
+	a.True(errors.IsInvalidArgumentError(err))
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// Metrics recorded by MetricsMiddleware, metrics that are nil are not recorded.
+// Metrics are abstracted with the interfaces of package "github.com/go-kit/kit/metrics", e.g. to use Prometheus
+// with package "github.com/go-kit/kit/metrics/prometheus":
+//
+//	labels := []string{"route", "method", "status"}
+//	m := HTTPMetrics{
+//		Requests: kitprometheus.NewCounterFrom(prometheus.CounterOpts{Name: "http_requests_total"}, labels),
+//		Duration: kitprometheus.NewHistogramFrom(prometheus.HistogramOpts{Name: "http_request_duration_seconds"}, labels),
+//		InFlight: kitprometheus.NewGaugeFrom(prometheus.GaugeOpts{Name: "http_requests_in_flight"}, []string{"route"}),
+//	}
+//
+// The metrics can then be exposed by setting ServerConfig.MetricsHandler to promhttp.Handler().
+type HTTPMetrics struct {
+	// Number of handled requests, with labels "route", "method" and "status".
+	Requests metrics.Counter
+	// Time it took to handle requests in seconds, with labels "route", "method" and "status".
+	Duration metrics.Histogram
+	// Number of requests currently being handled, with label "route".
+	InFlight metrics.Gauge
+}
+
+// Http middleware that records the given metrics for the requests handled by the next handler.
+// The route label should be the path template of the handler, e.g. "/users/{id}", and not the path of a request,
+// to keep the number of label values small. The status label is the response status code, e.g. "200".
+func MetricsMiddleware(next http.Handler, m HTTPMetrics, route string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.InFlight != nil {
+			inFlight := m.InFlight.With("route", route)
+			inFlight.Add(1)
+			defer inFlight.Add(-1)
+		}
+		begin := time.Now()
+		sw := &statusResponseWriter{ResponseWriter: w}
+		defer func() {
+			labels := []string{"route", route, "method", r.Method, "status", strconv.Itoa(sw.statusCode())}
+			if m.Requests != nil {
+				m.Requests.With(labels...).Add(1)
+			}
+			if m.Duration != nil {
+				m.Duration.With(labels...).Observe(time.Since(begin).Seconds())
+			}
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// Records the status code written to a http.ResponseWriter.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Status code written to the response, http.StatusOK if none was written explicitly.
+func (w *statusResponseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Required e.g. to upgrade websocket connections.
+func (w *statusResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not implement http.Hijacker")
+	}
+	// hijacked connections are reported with status 101 Switching Protocols
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Uses http.ResponseController, so that writers that only implement FlushError or wrap a flusher are flushed as well.
+func (w *statusResponseWriter) Flush() {
+	if err := http.NewResponseController(w.ResponseWriter).Flush(); err == nil && w.status == 0 {
+		w.status = http.StatusOK
+	}
+}
+
+// Used by http.ResponseController.
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,21 +1,182 @@
 package http
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	kittransport "github.com/go-kit/kit/transport"
 )
 
 // Http middleware that recovers and calls the provided onPanic function if the next http handler panics.
-// On panic status code 500 Internal Server Error is written to the response header.
+// On panic status code 500 Internal Server Error is written to the response header, together with the standard error body (see EncodeError).
+// Panics with value http.ErrAbortHandler are not recovered, since they are used to abort a response on purpose.
 func PanicMiddleware(next http.Handler, onPanic func(e interface{})) http.Handler {
+	return panicHandler(next, func(ctx context.Context, e interface{}, err error) {
+		if onPanic != nil {
+			onPanic(e)
+		}
+	})
+}
+
+// Works like PanicMiddleware, but calls the given error handler, e.g. a LogErrorHandler, with an error of type Error
+// with code Internal that contains the recovered value and the stack trace of the panic.
+func NewPanicHandler(next http.Handler, errorHandler kittransport.ErrorHandler) http.Handler {
+	return panicHandler(next, func(ctx context.Context, e interface{}, err error) {
+		if errorHandler != nil {
+			errorHandler.Handle(ctx, err)
+		}
+	})
+}
+
+func panicHandler(next http.Handler, onPanic func(ctx context.Context, e interface{}, err error)) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
-			if e := recover(); e != nil {
-				if onPanic != nil {
-					onPanic(e)
-				}
-				w.WriteHeader(http.StatusInternalServerError)
+			e := recover()
+			if e == nil {
+				return
+			}
+			if e == http.ErrAbortHandler {
+				panic(e)
 			}
+			err := panicError(e)
+			onPanic(r.Context(), e, err)
+			EncodeError(r.Context(), err, w)
 		}()
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Returns an error for the recovered value of a panic, the stack trace of the error contains the frames of the panicking goroutine.
+func panicError(e interface{}) errors.Error {
+	inner, _ := e.(error)
+	return errors.New(inner, errorOrigin, errors.Internal).
+		WithInternalMessage(fmt.Sprintf("panic: %v", e)).
+		WithPublicMessage("internal server error")
+}
+
+// Configures which cross-origin requests are allowed by CORSMiddleware.
+type CORSConfig struct {
+	// Allowed origins, e.g. "https://example.com". Use "*" to allow any origin.
+	Origins []string
+	// Allowed methods for preflight requests, defaults to GET, HEAD and POST if empty.
+	Methods []string
+	// Allowed request headers for preflight requests. Use "*" to allow any header.
+	Headers []string
+	// Response headers that the browser makes available to scripts, in addition to the CORS-safelisted ones like "Content-Type".
+	ExposedHeaders []string
+	// If true, browsers include credentials like cookies or authorization headers in cross-origin requests and make the response available to scripts.
+	AllowCredentials bool
+	// How long browsers may cache the result of a preflight request, not set if 0.
+	// Browsers limit this value, e.g. Chrome to 2 hours.
+	MaxAge time.Duration
+}
+
+var defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// Http middleware that handles cross-origin resource sharing (CORS) requests.
+// Preflight requests, i.e. OPTIONS requests with an "Access-Control-Request-Method" header, are answered with status 204 No Content
+// if the origin, method and headers are allowed and with status 403 Forbidden otherwise. The next handler is not called for preflight requests.
+// For other requests the "Access-Control-Allow-Origin" header is set if the origin is allowed before calling the next handler.
+// Requests without an "Origin" header are passed to the next handler unchanged.
+func CORSMiddleware(next http.Handler, config CORSConfig) http.Handler {
+	methods := config.Methods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		w.Header().Add("Vary", "Origin")
+		if !corsOriginAllowed(config.Origins, origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !preflight {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if config.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if len(config.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		method := r.Header.Get("Access-Control-Request-Method")
+		if !corsMethodAllowed(methods, method) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var headers []string
+		for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				headers = append(headers, h)
+			}
+		}
+		for _, h := range headers {
+			if !corsHeaderAllowed(config.Headers, h) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", method)
+		if config.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		if len(headers) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+		}
+		if config.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func corsOriginAllowed(origins []string, origin string) bool {
+	for _, o := range origins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func corsMethodAllowed(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func corsHeaderAllowed(headers []string, header string) bool {
+	for _, h := range headers {
+		if h == "*" || strings.EqualFold(h, header) {
+			return true
+		}
+	}
+	return false
+}
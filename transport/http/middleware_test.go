@@ -1,10 +1,19 @@
 package http
 
 import (
+	"context"
+	stderrors "errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/go-kit/kit/metrics"
+	kittransport "github.com/go-kit/kit/transport"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,4 +47,236 @@ func TestPanicCaught(t *testing.T) {
 	panicHandler.ServeHTTP(w, r)
 	a.True(called)
 	a.Equal(http.StatusInternalServerError, w.Result().StatusCode)
+	a.JSONEq(`{"error": {"message": "internal server error"}}`, w.Body.String())
+
+	// http.ErrAbortHandler is not recovered
+	panicHandler = PanicMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), nil)
+	a.PanicsWithValue(http.ErrAbortHandler, func() {
+		panicHandler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	})
+}
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic(stderrors.New("xyz"))
+}
+
+func TestPanicHandler(t *testing.T) {
+	a := assert.New(t)
+
+	var gotErr error
+	handler := NewPanicHandler(http.HandlerFunc(panickingHandler), kittransport.ErrorHandlerFunc(func(ctx context.Context, err error) {
+		gotErr = err
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	a.Equal(http.StatusInternalServerError, w.Code)
+	a.JSONEq(`{"error": {"message": "internal server error"}}`, w.Body.String())
+
+	e, ok := gotErr.(errors.Error)
+	a.True(ok)
+	a.True(errors.IsInternalError(e))
+	a.Equal("panic: xyz", e.InternalMessage)
+	a.Equal(stderrors.New("xyz"), e.Inner)
+	// the stack trace contains the function that panicked
+	a.Contains(e.StackTrace.String(), "panickingHandler")
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	a := assert.New(t)
+
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := CORSMiddleware(next, CORSConfig{
+		Origins: []string{"https://example.com"},
+		Methods: []string{"GET", "PUT"},
+		Headers: []string{"Authorization", "Content-Type"},
+	})
+
+	serve := func(method string, headers map[string]string) *http.Response {
+		called = false
+		r := httptest.NewRequest(method, "/test", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	// requests without origin are not changed
+	resp := serve("GET", nil)
+	a.True(called)
+	a.Empty(resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// allowed origin
+	resp = serve("GET", map[string]string{"Origin": "https://example.com"})
+	a.True(called)
+	a.Equal("https://example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// origin not allowed
+	resp = serve("GET", map[string]string{"Origin": "https://other.com"})
+	a.True(called)
+	a.Empty(resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// valid preflight request
+	resp = serve("OPTIONS", map[string]string{
+		"Origin":                         "https://example.com",
+		"Access-Control-Request-Method":  "PUT",
+		"Access-Control-Request-Headers": "content-type, authorization",
+	})
+	a.False(called)
+	a.Equal(http.StatusNoContent, resp.StatusCode)
+	a.Equal("https://example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	a.Equal("PUT", resp.Header.Get("Access-Control-Allow-Methods"))
+	a.Equal("content-type, authorization", resp.Header.Get("Access-Control-Allow-Headers"))
+
+	// preflight requests with method, header or origin that is not allowed
+	for _, headers := range []map[string]string{
+		{"Origin": "https://example.com", "Access-Control-Request-Method": "DELETE"},
+		{"Origin": "https://example.com", "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "X-Custom"},
+		{"Origin": "https://other.com", "Access-Control-Request-Method": "GET"},
+	} {
+		resp = serve("OPTIONS", headers)
+		a.False(called)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.Empty(resp.Header.Get("Access-Control-Allow-Origin"))
+	}
+
+	// any origin and header allowed, default methods
+	handler = CORSMiddleware(next, CORSConfig{Origins: []string{"*"}, Headers: []string{"*"}})
+	resp = serve("OPTIONS", map[string]string{
+		"Origin":                         "https://other.com",
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "X-Custom",
+	})
+	a.Equal(http.StatusNoContent, resp.StatusCode)
+	a.Equal("https://other.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	a.Empty(resp.Header.Get("Access-Control-Allow-Credentials"))
+	a.Empty(resp.Header.Get("Access-Control-Max-Age"))
+
+	// credentials, exposed headers and max age
+	handler = CORSMiddleware(next, CORSConfig{
+		Origins:          []string{"https://example.com"},
+		ExposedHeaders:   []string{"X-Request-Id", "X-Total-Count"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	resp = serve("OPTIONS", map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "GET"})
+	a.Equal(http.StatusNoContent, resp.StatusCode)
+	a.Equal("true", resp.Header.Get("Access-Control-Allow-Credentials"))
+	a.Equal("600", resp.Header.Get("Access-Control-Max-Age"))
+	a.Empty(resp.Header.Get("Access-Control-Expose-Headers"))
+	resp = serve("GET", map[string]string{"Origin": "https://example.com"})
+	a.True(called)
+	a.Equal("true", resp.Header.Get("Access-Control-Allow-Credentials"))
+	a.Equal("X-Request-Id, X-Total-Count", resp.Header.Get("Access-Control-Expose-Headers"))
+	a.Empty(resp.Header.Get("Access-Control-Max-Age"))
+	resp = serve("OPTIONS", map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "DELETE"})
+	a.Equal(http.StatusForbidden, resp.StatusCode)
+	a.Empty(resp.Header.Get("Access-Control-Allow-Credentials"))
+}
+
+// Records the values of a metric by label values, can be used as counter, gauge and histogram.
+type testMetric struct {
+	mu     *sync.Mutex
+	labels []string
+	values map[string]float64
+}
+
+func newTestMetric() *testMetric {
+	return &testMetric{mu: &sync.Mutex{}, values: make(map[string]float64)}
+}
+
+func (m *testMetric) With(labelValues ...string) *testMetric {
+	return &testMetric{mu: m.mu, labels: append(append([]string{}, m.labels...), labelValues...), values: m.values}
+}
+
+func (m *testMetric) key() string {
+	return strings.Join(m.labels, ",")
+}
+
+// Returns the value for the given label values, e.g. "route", "/users/{id}".
+func (m *testMetric) value(labelValues ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[strings.Join(labelValues, ",")]
+}
+
+func (m *testMetric) Add(delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[m.key()] += delta
+}
+
+func (m *testMetric) Set(value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[m.key()] = value
+}
+
+func (m *testMetric) Observe(value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[m.key()]++
+}
+
+type testCounter struct{ *testMetric }
+
+func (c testCounter) With(labelValues ...string) metrics.Counter {
+	return testCounter{c.testMetric.With(labelValues...)}
+}
+
+type testGauge struct{ *testMetric }
+
+func (g testGauge) With(labelValues ...string) metrics.Gauge {
+	return testGauge{g.testMetric.With(labelValues...)}
+}
+
+type testHistogram struct{ *testMetric }
+
+func (h testHistogram) With(labelValues ...string) metrics.Histogram {
+	return testHistogram{h.testMetric.With(labelValues...)}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	a := assert.New(t)
+
+	requests, duration, inFlight := newTestMetric(), newTestMetric(), newTestMetric()
+	m := HTTPMetrics{Requests: testCounter{requests}, Duration: testHistogram{duration}, InFlight: testGauge{inFlight}}
+
+	var inFlightDuringRequest float64
+	handler := MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlightDuringRequest = inFlight.values["route,/users/{id}"]
+		if r.URL.Path == "/users/1" {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.Write([]byte("ok"))
+		}
+	}), m, "/users/{id}")
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/3"} {
+		r := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+	}
+
+	a.Equal(float64(1), inFlightDuringRequest)
+	a.Equal(map[string]float64{"route,/users/{id}": 0}, inFlight.values)
+	expected := map[string]float64{
+		"route,/users/{id},method,GET,status,404": 1,
+		"route,/users/{id},method,GET,status,200": 2,
+	}
+	a.Equal(expected, requests.values)
+	a.Equal(expected, duration.values)
+
+	// nil metrics are not recorded
+	handler = MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), HTTPMetrics{}, "/")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	a.Equal(http.StatusOK, w.Result().StatusCode)
 }
@@ -0,0 +1,167 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dkinzler/kit/errors"
+)
+
+// Pagination parameters of a request for a list of items, see DecodePagination.
+// Either offset based pagination, where Offset is the number of items to skip, or cursor based pagination can be used.
+type Pagination struct {
+	// Maximum number of items to return.
+	Limit  int
+	Offset int
+	// Opaque value returned with a previous page that identifies the position to continue from.
+	Cursor string
+	// Fields to sort items by, a "-" prefix denotes descending order, e.g. "-createdAt".
+	Sort []string
+}
+
+// Configures DecodePagination.
+type PaginationOptions struct {
+	// Limit used if the request does not contain one, defaults to 20.
+	DefaultLimit int
+	// Maximum limit a request can ask for, defaults to 100.
+	MaxLimit int
+	// Fields items can be sorted by, requests with other sort fields are rejected.
+	// If empty, requests must not contain sort fields.
+	SortFields []string
+}
+
+// Decodes the pagination parameters from the query parameters "limit", "offset", "cursor" and "sort" of the given request,
+// e.g. "?limit=10&offset=20&sort=name,-createdAt". The sort parameter can also be repeated, e.g. "?sort=name&sort=-createdAt".
+// Returns an error with code InvalidArgument and a public message if a parameter is invalid,
+// e.g. if the limit is not between 1 and the maximum limit or both offset and cursor are given.
+func DecodePagination(r *http.Request, opts PaginationOptions) (Pagination, error) {
+	defaultLimit, maxLimit := opts.DefaultLimit, opts.MaxLimit
+	if defaultLimit <= 0 {
+		defaultLimit = 20
+	}
+	if maxLimit <= 0 {
+		maxLimit = 100
+	}
+	query := r.URL.Query()
+
+	p := Pagination{Limit: defaultLimit, Cursor: query.Get("cursor")}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return Pagination{}, newPublicTransportError(err, errors.InvalidArgument, fmt.Sprintf("limit must be a number between 1 and %v", maxLimit))
+		}
+		p.Limit = limit
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return Pagination{}, newPublicTransportError(err, errors.InvalidArgument, "offset must be a non-negative number")
+		}
+		p.Offset = offset
+		if p.Cursor != "" {
+			return Pagination{}, newPublicTransportError(nil, errors.InvalidArgument, "offset and cursor cannot be used together")
+		}
+	}
+
+	for _, v := range query["sort"] {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if !containsString(opts.SortFields, strings.TrimPrefix(field, "-")) {
+				return Pagination{}, newPublicTransportError(nil, errors.InvalidArgument, fmt.Sprintf("cannot sort by %v", strings.TrimPrefix(field, "-")))
+			}
+			p.Sort = append(p.Sort, field)
+		}
+	}
+	return p, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// A page of items returned for a request with the given pagination parameters, see EncodePaginatedResponse.
+type Page struct {
+	Pagination Pagination
+	// Total number of items, -1 if unknown.
+	Total int
+	// Cursor of the next page for cursor based pagination, empty if there is no next page.
+	NextCursor string
+}
+
+// Encodes the given items like EncodeBody and adds headers that describe the page:
+//   - "X-Total-Count" with the total number of items, if known
+//   - "Link" with links to other pages as defined by RFC 5988, e.g. <https://example.com/items?limit=10&offset=20>; rel="next"
+//
+// For offset based pagination the links to the "first", "prev", "next" and "last" pages are added if they exist,
+// the "next" and "last" links only if the total number of items is known.
+// For cursor based pagination the "next" link is added if the page has a next cursor.
+// Links are relative to the host of the request and keep all other query parameters of the request.
+func EncodePaginatedResponse(w http.ResponseWriter, r *http.Request, status int, page Page, items interface{}) error {
+	if page.Total >= 0 {
+		w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	}
+	if links := paginationLinks(r.URL, page); len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	return EncodeBody(w, r, status, items)
+}
+
+func paginationLinks(u *url.URL, page Page) []string {
+	p := page.Pagination
+	link := func(rel string, set map[string]string) string {
+		query := u.Query()
+		query.Set("limit", strconv.Itoa(p.Limit))
+		for key, value := range set {
+			if value == "" {
+				query.Del(key)
+			} else {
+				query.Set(key, value)
+			}
+		}
+		return fmt.Sprintf("<%v?%v>; rel=\"%v\"", u.Path, query.Encode(), rel)
+	}
+	offsetLink := func(rel string, offset int) string {
+		return link(rel, map[string]string{"offset": strconv.Itoa(offset), "cursor": ""})
+	}
+
+	var links []string
+	if p.Cursor != "" || page.NextCursor != "" {
+		if page.NextCursor != "" {
+			links = append(links, link("next", map[string]string{"cursor": page.NextCursor, "offset": ""}))
+		}
+		return links
+	}
+	if p.Limit <= 0 {
+		return nil
+	}
+	links = append(links, offsetLink("first", 0))
+	if p.Offset > 0 {
+		prev := p.Offset - p.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, offsetLink("prev", prev))
+	}
+	if page.Total >= 0 {
+		if p.Offset+p.Limit < page.Total {
+			links = append(links, offsetLink("next", p.Offset+p.Limit))
+		}
+		last := 0
+		if page.Total > 0 {
+			last = (page.Total - 1) / p.Limit * p.Limit
+		}
+		links = append(links, offsetLink("last", last))
+	}
+	return links
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodePagination(t *testing.T) {
+	a := assert.New(t)
+
+	opts := PaginationOptions{DefaultLimit: 10, MaxLimit: 50, SortFields: []string{"name", "createdAt"}}
+
+	r := httptest.NewRequest(http.MethodGet, "/items", nil)
+	p, err := DecodePagination(r, opts)
+	a.Nil(err)
+	a.Equal(Pagination{Limit: 10}, p)
+
+	r = httptest.NewRequest(http.MethodGet, "/items?limit=25&offset=50&sort=name,-createdAt", nil)
+	p, err = DecodePagination(r, opts)
+	a.Nil(err)
+	a.Equal(Pagination{Limit: 25, Offset: 50, Sort: []string{"name", "-createdAt"}}, p)
+
+	r = httptest.NewRequest(http.MethodGet, "/items?cursor=abc&sort=name&sort=-createdAt", nil)
+	p, err = DecodePagination(r, opts)
+	a.Nil(err)
+	a.Equal(Pagination{Limit: 10, Cursor: "abc", Sort: []string{"name", "-createdAt"}}, p)
+
+	// defaults are used if options are not set
+	r = httptest.NewRequest(http.MethodGet, "/items?limit=100", nil)
+	p, err = DecodePagination(r, PaginationOptions{})
+	a.Nil(err)
+	a.Equal(100, p.Limit)
+
+	invalid := []string{
+		"/items?limit=0",
+		"/items?limit=51",
+		"/items?limit=abc",
+		"/items?offset=-1",
+		"/items?offset=abc",
+		"/items?offset=10&cursor=abc",
+		"/items?sort=unknown",
+		"/items?sort=-unknown",
+	}
+	for _, target := range invalid {
+		r = httptest.NewRequest(http.MethodGet, target, nil)
+		_, err = DecodePagination(r, opts)
+		a.True(errors.IsInvalidArgumentError(err), target)
+		a.NotEmpty(err.(errors.Error).PublicMessage, target)
+	}
+
+	// sorting is not allowed if no sort fields are configured
+	r = httptest.NewRequest(http.MethodGet, "/items?sort=name", nil)
+	_, err = DecodePagination(r, PaginationOptions{})
+	a.True(errors.IsInvalidArgumentError(err))
+}
+
+func TestEncodePaginatedResponse(t *testing.T) {
+	a := assert.New(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/items?limit=10&offset=20&q=x", nil)
+	w := httptest.NewRecorder()
+	err := EncodePaginatedResponse(w, r, http.StatusOK, Page{
+		Pagination: Pagination{Limit: 10, Offset: 20},
+		Total:      45,
+	}, []string{"a", "b"})
+	a.Nil(err)
+	a.Equal(http.StatusOK, w.Code)
+	a.Equal("45", w.Header().Get("X-Total-Count"))
+	a.Equal(`</items?limit=10&offset=0&q=x>; rel="first", `+
+		`</items?limit=10&offset=10&q=x>; rel="prev", `+
+		`</items?limit=10&offset=30&q=x>; rel="next", `+
+		`</items?limit=10&offset=40&q=x>; rel="last"`, w.Header().Get("Link"))
+	a.JSONEq(`["a","b"]`, w.Body.String())
+
+	// last page, total unknown
+	r = httptest.NewRequest(http.MethodGet, "/items?limit=10&offset=5", nil)
+	w = httptest.NewRecorder()
+	err = EncodePaginatedResponse(w, r, http.StatusOK, Page{
+		Pagination: Pagination{Limit: 10, Offset: 5},
+		Total:      -1,
+	}, []string{})
+	a.Nil(err)
+	a.Empty(w.Header().Get("X-Total-Count"))
+	a.Equal(`</items?limit=10&offset=0>; rel="first", </items?limit=10&offset=0>; rel="prev"`, w.Header().Get("Link"))
+
+	// cursor based pagination
+	r = httptest.NewRequest(http.MethodGet, "/items?cursor=abc", nil)
+	w = httptest.NewRecorder()
+	err = EncodePaginatedResponse(w, r, http.StatusOK, Page{
+		Pagination: Pagination{Limit: 20, Cursor: "abc"},
+		Total:      -1,
+		NextCursor: "def",
+	}, []string{})
+	a.Nil(err)
+	a.Equal(`</items?cursor=def&limit=20>; rel="next"`, w.Header().Get("Link"))
+
+	// no next cursor
+	w = httptest.NewRecorder()
+	err = EncodePaginatedResponse(w, r, http.StatusOK, Page{
+		Pagination: Pagination{Limit: 20, Cursor: "abc"},
+		Total:      -1,
+	}, []string{})
+	a.Nil(err)
+	a.Empty(w.Header().Get("Link"))
+}
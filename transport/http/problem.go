@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/dkinzler/kit/errors"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// Format of the response bodies written by EncodeError.
+type ErrorFormat int
+
+const (
+	// A json object {"error": {"code": ..., "message": ...}} with the public code and message of an error, see EncodeError.
+	ErrorFormatJSON ErrorFormat = iota
+	// Problem details as defined by RFC 7807, see EncodeProblemJSON.
+	ErrorFormatProblemJSON
+)
+
+// Format used by EncodeError and therefore by all the encode functions of this package and generated http handlers.
+// Should be set when a program is initialized, before any requests are handled.
+var DefaultErrorFormat = ErrorFormatJSON
+
+// Sends an appropriate http status code and a body with content type "application/problem+json" based on the error,
+// as defined by RFC 7807 "Problem Details for HTTP APIs".
+//
+// The "type" member is always "about:blank", i.e. the problem has no semantics beyond the status code, and the "title" is the
+// status text of the status code. If the error is of type Error from package "github.com/dkinzler/kit/errors", the "detail" member
+// is the public message of the error and the public code and key-value pairs of the error are added as extension members.
+// Note that key-values are therefore sent to clients, sensitive values are redacted (see errors.Error.WithSensitive).
+// If the request path was added to the context with kithttp.PopulateRequestContext, it is used as the "instance" member.
+//
+// Example:
+//
+//	{
+//	  "type": "about:blank",
+//	  "title": "Not Found",
+//	  "status": 404,
+//	  "detail": "user not found",
+//	  "instance": "/users/42",
+//	  "code": 7
+//	}
+func EncodeProblemJSON(ctx context.Context, err error, w http.ResponseWriter) error {
+	status := ErrToCode(err)
+	body := map[string]interface{}{}
+	if e, ok := err.(errors.Error); ok {
+		for key, value := range e.Redact().KeyVals {
+			body[key] = value
+		}
+		if e.PublicCode != 0 {
+			body["code"] = e.PublicCode
+		}
+		if e.PublicMessage != "" {
+			body["detail"] = e.PublicMessage
+		}
+	}
+	// standard members take precedence over key-values with the same name
+	body["type"] = "about:blank"
+	body["title"] = http.StatusText(status)
+	body["status"] = status
+	if path, ok := ctx.Value(kithttp.ContextKeyRequestPath).(string); ok && path != "" {
+		body["instance"] = path
+	} else {
+		delete(body, "instance")
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		return newInternalTransportError(err, errors.Internal, "could not encode problem json")
+	}
+	return nil
+}
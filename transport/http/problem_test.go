@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkinzler/kit/errors"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeProblemJSON(t *testing.T) {
+	a := assert.New(t)
+
+	encode := func(ctx context.Context, err error, encoder func(context.Context, error, http.ResponseWriter) error) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		a.Nil(encoder(ctx, err, w))
+		var body map[string]interface{}
+		a.Nil(json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+
+	err := errors.New(nil, "test", errors.NotFound).
+		WithPublicCode(7).
+		WithPublicMessage("user not found").
+		WithInternalMessage("internal").
+		With("userId", "42").
+		With("status", 1).
+		WithSensitive("email", "a@b.c")
+	r := httptest.NewRequest("GET", "/users/42", nil)
+	ctx := kithttp.PopulateRequestContext(context.Background(), r)
+	w, body := encode(ctx, err, EncodeProblemJSON)
+	a.Equal(http.StatusNotFound, w.Code)
+	a.Equal("application/problem+json", w.Header().Get("Content-Type"))
+	a.Equal(map[string]interface{}{
+		"type":     "about:blank",
+		"title":    "Not Found",
+		"status":   float64(404),
+		"detail":   "user not found",
+		"instance": "/users/42",
+		"code":     float64(7),
+		"userId":   "42",
+		"email":    errors.RedactedValue,
+	}, body)
+
+	// errors of other types
+	w, body = encode(context.Background(), stderrors.New("xyz"), EncodeProblemJSON)
+	a.Equal(http.StatusInternalServerError, w.Code)
+	a.Equal(map[string]interface{}{
+		"type":   "about:blank",
+		"title":  "Internal Server Error",
+		"status": float64(500),
+	}, body)
+
+	// EncodeError uses the default error format
+	DefaultErrorFormat = ErrorFormatProblemJSON
+	defer func() {
+		DefaultErrorFormat = ErrorFormatJSON
+	}()
+	w, body = encode(context.Background(), errors.New(nil, "test", errors.InvalidArgument), EncodeError)
+	a.Equal(http.StatusBadRequest, w.Code)
+	a.Equal("application/problem+json", w.Header().Get("Content-Type"))
+	a.Equal("Bad Request", body["title"])
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/gorilla/schema"
+)
+
+// Configures a QueryDecoder.
+type QueryDecoderConfig struct {
+	// If true, query parameters that do not correspond to a struct field are ignored, otherwise decoding fails.
+	IgnoreUnknownKeys bool
+	// If true, empty query parameters set fields to their zero value, otherwise empty parameters are ignored.
+	ZeroEmpty bool
+	// Layouts used to decode time.Time fields, tried in order.
+	// If empty, time.Time fields are decoded in RFC 3339 format.
+	TimeLayouts []string
+	// Functions used to decode fields of custom types, the returned value must be invalid if the query parameter could not be decoded.
+	// E.g. the function for type time.Duration could be registered with key reflect.TypeOf(time.Duration(0)).
+	Converters map[reflect.Type]func(value string) reflect.Value
+}
+
+// Decodes query parameters into structs, see DecodeQueryParameters.
+// Wraps a decoder of the "github.com/gorilla/schema" package with its own configuration,
+// so that different handlers can decode query parameters differently.
+// Fields of types that implement encoding.TextUnmarshaler, e.g. uuid.UUID of the "github.com/google/uuid" package, are decoded with UnmarshalText.
+// A QueryDecoder is safe for concurrent use.
+type QueryDecoder struct {
+	decoder *schema.Decoder
+}
+
+func NewQueryDecoder(config QueryDecoderConfig) *QueryDecoder {
+	d := schema.NewDecoder()
+	d.IgnoreUnknownKeys(config.IgnoreUnknownKeys)
+	d.ZeroEmpty(config.ZeroEmpty)
+	if len(config.TimeLayouts) > 0 {
+		layouts := config.TimeLayouts
+		d.RegisterConverter(time.Time{}, func(value string) reflect.Value {
+			for _, layout := range layouts {
+				if t, err := time.Parse(layout, value); err == nil {
+					return reflect.ValueOf(t)
+				}
+			}
+			return reflect.Value{}
+		})
+	}
+	for t, converter := range config.Converters {
+		d.RegisterConverter(reflect.Zero(t).Interface(), converter)
+	}
+	return &QueryDecoder{decoder: d}
+}
+
+// Decodes the query parameters in the url of the given request into v, which should be a pointer to a struct.
+// Afterwards v is validated with ValidateFunc.
+func (d *QueryDecoder) Decode(r *http.Request, v interface{}) error {
+	err := r.ParseForm()
+	if err != nil {
+		return newRequestBodyError(err, "could not parse query parameters")
+	}
+	return d.DecodeValues(r.Form, v)
+}
+
+// Decodes the given values into v, which should be a pointer to a struct.
+// Afterwards v is validated with ValidateFunc.
+func (d *QueryDecoder) DecodeValues(values url.Values, v interface{}) error {
+	err := d.decoder.Decode(v, values)
+	if err != nil {
+		return newPublicTransportError(err, errors.InvalidArgument, "could not decode query parameters")
+	}
+	return validateDecoded(v)
+}
+
+// Decoder used by DecodeQueryParameters, created with the zero QueryDecoderConfig.
+// Can be replaced to change how query parameters are decoded by all handlers that use DecodeQueryParameters, including generated code.
+var DefaultQueryDecoder = NewQueryDecoder(QueryDecoderConfig{})
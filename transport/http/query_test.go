@@ -0,0 +1,81 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type queryTestStruct struct {
+	Name  string        `schema:"name"`
+	Count int           `schema:"count"`
+	Since time.Time     `schema:"since"`
+	Until *time.Time    `schema:"until"`
+	ID    uuid.UUID     `schema:"id"`
+	Wait  time.Duration `schema:"wait"`
+}
+
+func TestQueryDecoder(t *testing.T) {
+	a := assert.New(t)
+
+	// default decoder
+	var v queryTestStruct
+	r := httptest.NewRequest(http.MethodGet, "/?name=abc&since=2023-01-02T03:04:05Z&until=2023-02-01T00:00:00Z&id=8c4f4b7e-1b7a-4f47-9a36-5a9d5b8f2c1e", nil)
+	err := DecodeQueryParameters(r, &v)
+	a.Nil(err)
+	a.Equal("abc", v.Name)
+	a.Equal(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), v.Since)
+	a.Equal(time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), *v.Until)
+	a.Equal(uuid.MustParse("8c4f4b7e-1b7a-4f47-9a36-5a9d5b8f2c1e"), v.ID)
+
+	r = httptest.NewRequest(http.MethodGet, "/?since=yesterday", nil)
+	err = DecodeQueryParameters(r, &queryTestStruct{})
+	a.True(errors.IsInvalidArgumentError(err))
+	r = httptest.NewRequest(http.MethodGet, "/?id=abc", nil)
+	err = DecodeQueryParameters(r, &queryTestStruct{})
+	a.True(errors.IsInvalidArgumentError(err))
+	r = httptest.NewRequest(http.MethodGet, "/?unknown=1", nil)
+	err = DecodeQueryParameters(r, &queryTestStruct{})
+	a.True(errors.IsInvalidArgumentError(err))
+
+	d := NewQueryDecoder(QueryDecoderConfig{
+		IgnoreUnknownKeys: true,
+		ZeroEmpty:         true,
+		TimeLayouts:       []string{"2006-01-02"},
+		Converters: map[reflect.Type]func(string) reflect.Value{
+			reflect.TypeOf(time.Duration(0)): func(value string) reflect.Value {
+				seconds, err := strconv.Atoi(value)
+				if err != nil {
+					return reflect.Value{}
+				}
+				return reflect.ValueOf(time.Duration(seconds) * time.Second)
+			},
+		},
+	})
+	v = queryTestStruct{Count: 5}
+	r = httptest.NewRequest(http.MethodGet, "/?since=2023-01-02&wait=30&count=&unknown=1", nil)
+	err = d.Decode(r, &v)
+	a.Nil(err)
+	a.Equal(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), v.Since)
+	a.Equal(30*time.Second, v.Wait)
+	a.Equal(0, v.Count)
+
+	// other time formats are not accepted if layouts are configured
+	r = httptest.NewRequest(http.MethodGet, "/?since=2023-01-02T03:04:05Z", nil)
+	err = d.Decode(r, &queryTestStruct{})
+	a.True(errors.IsInvalidArgumentError(err))
+
+	// values are validated
+	err = d.DecodeValues(map[string][]string{"name": {""}}, &validatedRequest{})
+	a.True(errors.IsInvalidArgumentError(err))
+	_, ok := errors.BadRequestOf(err)
+	a.True(ok)
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+)
+
+// RateLimitStore stores the token buckets used by NewRateLimitHandler.
+// The in-memory store returned by NewMemoryRateLimitStore limits requests per process,
+// an implementation backed by e.g. Redis can be used to share limits between multiple instances of a service.
+type RateLimitStore interface {
+	// Takes a token from the bucket of the given key, that is refilled with rate tokens per second and holds at most burst tokens.
+	// A bucket that does not exist yet is full.
+	// Returns true if a token was available and otherwise how long it takes until a token will be available,
+	// or 0 if that is unknown, e.g. because the bucket is never refilled if rate is not positive.
+	Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// Returns a RateLimitStore that keeps the token buckets in memory.
+// Buckets that are full are removed periodically, so that the memory used depends only on the number of recently active keys.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return newMemoryRateLimitStore(time.Now)
+}
+
+func newMemoryRateLimitStore(now func() time.Time) *memoryRateLimitStore {
+	return &memoryRateLimitStore{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: now(),
+		now:       now,
+	}
+}
+
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now, rate, burst)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	if rate <= 0 {
+		// the bucket is never refilled
+		return false, 0, nil
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second)), nil
+}
+
+// Removes buckets that would be full by now, at most once a minute.
+func (s *memoryRateLimitStore) sweep(now time.Time, rate float64, burst int) {
+	if now.Sub(s.lastSweep) < time.Minute || rate <= 0 {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= float64(burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Configures NewRateLimitHandler.
+type RateLimitConfig struct {
+	// Number of requests per second allowed on average.
+	Rate float64
+	// Maximum number of requests allowed in a burst, at least 1.
+	Burst int
+	// Returns the key of a request, requests with the same key share a token bucket, e.g. KeyByIP to limit requests per client.
+	// If nil, all requests share a global token bucket.
+	Key func(r *http.Request) string
+	// Defaults to a store created with NewMemoryRateLimitStore.
+	Store RateLimitStore
+	// Called with errors returned by the store, requests are allowed if the store fails.
+	OnError func(error)
+}
+
+// Returns the IP address of the client that made the request, without the port.
+// Note that this is the address of the last proxy if the server runs behind a proxy or load balancer.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Returns a key function that uses the value of the given request header, e.g. an API key header.
+func KeyByHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// Http middleware that limits the rate of requests using token buckets, see RateLimitConfig.
+// Requests that exceed the limit are answered with an error with code ResourceExhausted, i.e. status 429 Too Many Requests
+// and the standard error body (see EncodeError), and a "Retry-After" header with the number of seconds until a request
+// will be allowed again if the store returns it, the next handler is not called.
+func NewRateLimitHandler(next http.Handler, config RateLimitConfig) http.Handler {
+	store := config.Store
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+	burst := config.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var key string
+		if config.Key != nil {
+			key = config.Key(r)
+		}
+		ok, retryAfter, err := store.Take(r.Context(), key, config.Rate, burst)
+		if err != nil {
+			if config.OnError != nil {
+				config.OnError(err)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !ok {
+			if retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+			}
+			err := errors.New(nil, errorOrigin, errors.ResourceExhausted).WithPublicMessage("rate limit exceeded, try again later")
+			EncodeError(r.Context(), err, w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
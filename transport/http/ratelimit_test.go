@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Now()
+	store := newMemoryRateLimitStore(func() time.Time { return now })
+	take := func(key string) (bool, time.Duration) {
+		ok, retryAfter, err := store.Take(context.Background(), key, 2, 3)
+		a.Nil(err)
+		return ok, retryAfter
+	}
+
+	// burst of 3 requests
+	for i := 0; i < 3; i++ {
+		ok, _ := take("a")
+		a.True(ok)
+	}
+	ok, retryAfter := take("a")
+	a.False(ok)
+	a.Equal(500*time.Millisecond, retryAfter)
+	// other keys have their own bucket
+	ok, _ = take("b")
+	a.True(ok)
+
+	// refilled with 2 tokens per second
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		ok, _ := take("a")
+		a.True(ok)
+	}
+	ok, _ = take("a")
+	a.False(ok)
+
+	// full buckets are removed
+	now = now.Add(2 * time.Minute)
+	take("c")
+	a.Len(store.buckets, 1)
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	return false, 0, errors.New("store unavailable")
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	a := assert.New(t)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(h http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// per ip
+	handler := NewRateLimitHandler(next, RateLimitConfig{Rate: 0.5, Burst: 1, Key: KeyByIP})
+	a.Equal(http.StatusOK, serve(handler, "1.2.3.4:1000").Code)
+	w := serve(handler, "1.2.3.4:1001")
+	a.Equal(http.StatusTooManyRequests, w.Code)
+	a.Equal("2", w.Header().Get("Retry-After"))
+	a.JSONEq(`{"error": {"message": "rate limit exceeded, try again later"}}`, w.Body.String())
+	a.Equal(http.StatusOK, serve(handler, "5.6.7.8:1000").Code)
+
+	// global
+	handler = NewRateLimitHandler(next, RateLimitConfig{Rate: 1, Burst: 2})
+	a.Equal(http.StatusOK, serve(handler, "1.2.3.4:1000").Code)
+	a.Equal(http.StatusOK, serve(handler, "5.6.7.8:1000").Code)
+	a.Equal(http.StatusTooManyRequests, serve(handler, "9.9.9.9:1000").Code)
+
+	// without a refill rate it is unknown when a request will be allowed again
+	handler = NewRateLimitHandler(next, RateLimitConfig{Rate: 0, Burst: 1})
+	a.Equal(http.StatusOK, serve(handler, "1.2.3.4:1000").Code)
+	w = serve(handler, "1.2.3.4:1000")
+	a.Equal(http.StatusTooManyRequests, w.Code)
+	_, ok := w.Header()["Retry-After"]
+	a.False(ok)
+
+	// requests are allowed if the store fails
+	var storeErr error
+	handler = NewRateLimitHandler(next, RateLimitConfig{Rate: 1, Store: failingRateLimitStore{}, OnError: func(err error) {
+		storeErr = err
+	}})
+	a.Equal(http.StatusOK, serve(handler, "1.2.3.4:1000").Code)
+	a.NotNil(storeErr)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Api-Key", "abc")
+	a.Equal("abc", KeyByHeader("X-Api-Key")(r))
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dkinzler/kit/uuid"
+)
+
+// Header used to pass the id of a request between services, see NewRequestIDHandler.
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDContextKey struct{}
+
+// Returns a new context that contains the given request id, see RequestIDFromContext.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// Returns the request id stored in the context by NewRequestIDHandler or ContextWithRequestID.
+// The http client of package "github.com/dkinzler/kit/transport/http/client" sends it in the RequestIDHeader header of outgoing requests.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDContextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Http middleware that stores the id of a request in the request context, see RequestIDFromContext.
+// The id is taken from the RequestIDHeader header of the request, a random UUID is used if the header is missing.
+// The id is also set in the RequestIDHeader header of the response, so that clients can refer to requests e.g. when reporting problems.
+func NewRequestIDHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			// NewUUID only fails if the random source fails, the request can be handled without an id then
+			id, _ = uuid.NewUUID()
+		}
+		if id != "" {
+			w.Header().Set(RequestIDHeader, id)
+			r = r.WithContext(ContextWithRequestID(r.Context(), id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
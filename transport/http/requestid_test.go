@@ -0,0 +1,35 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDHandler(t *testing.T) {
+	a := assert.New(t)
+
+	var gotID string
+	handler := NewRequestIDHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID, _ = RequestIDFromContext(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "abc")
+	handler.ServeHTTP(w, r)
+	a.Equal("abc", gotID)
+	a.Equal("abc", w.Header().Get(RequestIDHeader))
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(w, r)
+	a.Len(gotID, 36)
+	a.Equal(gotID, w.Header().Get(RequestIDHeader))
+
+	_, ok := RequestIDFromContext(context.Background())
+	a.False(ok)
+}
@@ -0,0 +1,227 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/gorilla/mux"
+)
+
+// Maps names of routes to their path templates, so that urls can be built from the name of a route instead of hardcoding paths,
+// e.g. for "Location" headers, redirects or links in responses.
+// Path templates can use the syntax of gorilla/mux, chi or http.ServeMux, e.g. "/users/{id}", "/users/{id:[0-9]+}", "/files/{path...}" or "/files/*".
+// Routes registered by generated code can be added with the generated RegisterHttpRoutes function.
+type Routes struct {
+	mu     sync.RWMutex
+	routes map[string]routeTemplate
+}
+
+func NewRoutes() *Routes {
+	return &Routes{routes: make(map[string]routeTemplate)}
+}
+
+// Routes used by RegisterRoute, URLFor and Redirect.
+var DefaultRoutes = NewRoutes()
+
+// Registers a route with DefaultRoutes, see Routes.Register.
+func RegisterRoute(name, template string) error {
+	return DefaultRoutes.Register(name, template)
+}
+
+// Returns the url of a route registered with DefaultRoutes, see Routes.URLFor.
+func URLFor(name string, params ...string) (string, error) {
+	return DefaultRoutes.URLFor(name, params...)
+}
+
+// Redirects to a route registered with DefaultRoutes, see Routes.Redirect.
+func Redirect(w http.ResponseWriter, r *http.Request, status int, name string, params ...string) error {
+	return DefaultRoutes.Redirect(w, r, status, name, params...)
+}
+
+// Registers the path template of a route.
+// Returns an error if the template is invalid or another template was already registered with the same name.
+func (rs *Routes) Register(name, template string) error {
+	if name == "" {
+		return errors.New(nil, errorOrigin, errors.InvalidArgument).WithInternalMessage("route name must not be empty")
+	}
+	t, err := parseRouteTemplate(template)
+	if err != nil {
+		return errors.New(err, errorOrigin, errors.InvalidArgument).WithInternalMessage("invalid route template").With("route", name)
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if existing, ok := rs.routes[name]; ok && existing.template != template {
+		return errors.New(nil, errorOrigin, errors.AlreadyExists).WithInternalMessage("route already registered").With("route", name)
+	}
+	rs.routes[name] = t
+	return nil
+}
+
+// Registers the path templates of all routes of the router that have a name, e.g. routes created with
+//
+//	router.Handle("/users/{id}", handler).Methods("GET").Name("getUser")
+//
+// Routes without a path template, e.g. routes that only match a host, are ignored.
+func (rs *Routes) RegisterMuxRoutes(router *mux.Router) error {
+	return router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		name := route.GetName()
+		if name == "" {
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		return rs.Register(name, template)
+	})
+}
+
+// Returns the url path of the route with the given name, with the variables of the path template replaced by the given parameters,
+// e.g. URLFor("getUser", "id", "123") returns "/users/123" for a route with template "/users/{id}".
+// Parameters are key-value pairs, values are escaped. Parameters that are not variables of the path template are added
+// as query parameters, e.g. URLFor("listUsers", "limit", "10") returns "/users?limit=10".
+//
+// Returns an error with code Internal if the route does not exist, a variable is missing or the value of a variable
+// does not match the regular expression of the variable.
+func (rs *Routes) URLFor(name string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", newInternalTransportError(nil, errors.Internal, "odd number of route parameters")
+	}
+	rs.mu.RLock()
+	t, ok := rs.routes[name]
+	rs.mu.RUnlock()
+	if !ok {
+		return "", newInternalTransportError(nil, errors.Internal, fmt.Sprintf("unknown route %v", name))
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	var b strings.Builder
+	for _, s := range t.segments {
+		if s.param == "" {
+			b.WriteString(s.literal)
+			continue
+		}
+		v, ok := values[s.param]
+		if !ok {
+			return "", newInternalTransportError(nil, errors.Internal, fmt.Sprintf("missing parameter %v of route %v", s.param, name))
+		}
+		delete(values, s.param)
+		if s.re != nil && !s.re.MatchString(v) {
+			return "", newInternalTransportError(nil, errors.Internal, fmt.Sprintf("parameter %v of route %v does not match %v", s.param, name, s.re))
+		}
+		if s.wildcard {
+			// wildcards match multiple path segments, slashes are not escaped
+			parts := strings.Split(v, "/")
+			for i, p := range parts {
+				parts[i] = url.PathEscape(p)
+			}
+			b.WriteString(strings.Join(parts, "/"))
+		} else {
+			b.WriteString(url.PathEscape(v))
+		}
+	}
+
+	if len(values) > 0 {
+		query := make(url.Values, len(values))
+		for k, v := range values {
+			query.Set(k, v)
+		}
+		b.WriteString("?")
+		b.WriteString(query.Encode())
+	}
+	return b.String(), nil
+}
+
+// Replies to the request with a redirect to the url of the route with the given name, see URLFor.
+// The status should be a redirect status, e.g. http.StatusSeeOther after a POST request.
+// If the url cannot be built, the error is returned and nothing is written to the response.
+func (rs *Routes) Redirect(w http.ResponseWriter, r *http.Request, status int, name string, params ...string) error {
+	target, err := rs.URLFor(name, params...)
+	if err != nil {
+		return err
+	}
+	http.Redirect(w, r, target, status)
+	return nil
+}
+
+type routeTemplate struct {
+	template string
+	segments []routeSegment
+}
+
+// Either a literal part of a path template or a variable.
+type routeSegment struct {
+	literal string
+	param   string
+	// nil if any value is allowed
+	re       *regexp.Regexp
+	wildcard bool
+}
+
+func parseRouteTemplate(template string) (routeTemplate, error) {
+	result := routeTemplate{template: template}
+	rest := template
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			if literal, ok := strings.CutSuffix(rest, "*"); ok && strings.HasSuffix(literal, "/") {
+				// chi catch-all, the value is stored in url parameter "*"
+				result.segments = append(result.segments, routeSegment{literal: literal}, routeSegment{param: "*", wildcard: true})
+			} else {
+				result.segments = append(result.segments, routeSegment{literal: rest})
+			}
+			break
+		}
+		if start > 0 {
+			result.segments = append(result.segments, routeSegment{literal: rest[:start]})
+		}
+		// find the matching brace, regular expressions can contain braces, e.g. "{id:[0-9]{3}}"
+		depth, end := 0, -1
+		for i := start; i < len(rest) && end < 0; i++ {
+			switch rest[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			return routeTemplate{}, fmt.Errorf("unbalanced braces in route template %v", template)
+		}
+		variable := rest[start+1 : end]
+		rest = rest[end+1:]
+		if variable == "$" {
+			// http.ServeMux pattern that only matches the path up to the trailing slash
+			continue
+		}
+
+		s := routeSegment{param: variable}
+		if name, pattern, ok := strings.Cut(variable, ":"); ok {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return routeTemplate{}, fmt.Errorf("invalid regular expression in route template %v: %w", template, err)
+			}
+			s.param, s.re = name, re
+		} else if name, ok := strings.CutSuffix(variable, "..."); ok {
+			s.param, s.wildcard = name, true
+		}
+		if s.param == "" {
+			return routeTemplate{}, fmt.Errorf("variable without name in route template %v", template)
+		}
+		result.segments = append(result.segments, s)
+	}
+	return result, nil
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutes(t *testing.T) {
+	a := assert.New(t)
+
+	rs := NewRoutes()
+	a.Nil(rs.Register("getUser", "/users/{id}"))
+	a.Nil(rs.Register("getItem", "/items/{id:[0-9]{3}}/details"))
+	a.Nil(rs.Register("getFile", "/files/{path...}"))
+	a.Nil(rs.Register("getChiFile", "/static/*"))
+	a.Nil(rs.Register("listUsers", "/users/{$}"))
+	// registering the same template again is allowed
+	a.Nil(rs.Register("getUser", "/users/{id}"))
+
+	a.True(errors.IsAlreadyExistsError(rs.Register("getUser", "/other/{id}")))
+	a.True(errors.IsInvalidArgumentError(rs.Register("x", "/users/{id")))
+	a.True(errors.IsInvalidArgumentError(rs.Register("x", "/users/{id:[0-9}")))
+	a.True(errors.IsInvalidArgumentError(rs.Register("x", "/users/{}")))
+	a.True(errors.IsInvalidArgumentError(rs.Register("", "/users")))
+
+	for _, tc := range []struct {
+		name     string
+		params   []string
+		expected string
+	}{
+		{"getUser", []string{"id", "123"}, "/users/123"},
+		{"getUser", []string{"id", "a b/c"}, "/users/a%20b%2Fc"},
+		{"getUser", []string{"id", "1", "expand", "groups", "a", "x&y"}, "/users/1?a=x%26y&expand=groups"},
+		{"getItem", []string{"id", "123"}, "/items/123/details"},
+		{"getFile", []string{"path", "a/b c.txt"}, "/files/a/b%20c.txt"},
+		{"getChiFile", []string{"*", "css/app.css"}, "/static/css/app.css"},
+		{"listUsers", nil, "/users/"},
+	} {
+		u, err := rs.URLFor(tc.name, tc.params...)
+		a.Nil(err, tc.expected)
+		a.Equal(tc.expected, u)
+	}
+
+	_, err := rs.URLFor("unknown")
+	a.True(errors.IsInternalError(err))
+	_, err = rs.URLFor("getUser")
+	a.True(errors.IsInternalError(err))
+	_, err = rs.URLFor("getUser", "id")
+	a.True(errors.IsInternalError(err))
+	_, err = rs.URLFor("getItem", "id", "12")
+	a.True(errors.IsInternalError(err))
+}
+
+func TestRoutesRedirect(t *testing.T) {
+	a := assert.New(t)
+
+	rs := NewRoutes()
+	a.Nil(rs.Register("getUser", "/users/{id}"))
+
+	w := httptest.NewRecorder()
+	err := rs.Redirect(w, httptest.NewRequest(http.MethodPost, "/users", nil), http.StatusSeeOther, "getUser", "id", "1")
+	a.Nil(err)
+	a.Equal(http.StatusSeeOther, w.Code)
+	a.Equal("/users/1", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	err = rs.Redirect(w, httptest.NewRequest(http.MethodPost, "/users", nil), http.StatusSeeOther, "unknown")
+	a.NotNil(err)
+	a.Empty(w.Header().Get("Location"))
+}
+
+func TestRegisterMuxRoutes(t *testing.T) {
+	a := assert.New(t)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	router := mux.NewRouter()
+	router.Handle("/users/{id}", h).Methods(http.MethodGet).Name("getUser")
+	router.Handle("/users", h).Methods(http.MethodGet)
+	sub := router.PathPrefix("/api").Subrouter()
+	sub.Handle("/items/{id:[0-9]+}", h).Name("getItem")
+
+	rs := NewRoutes()
+	a.Nil(rs.RegisterMuxRoutes(router))
+
+	u, err := rs.URLFor("getUser", "id", "1")
+	a.Nil(err)
+	a.Equal("/users/1", u)
+	u, err = rs.URLFor("getItem", "id", "2")
+	a.Nil(err)
+	a.Equal("/api/items/2", u)
+	// the url matches the same route as the one built by gorilla/mux
+	expected, _ := router.Get("getItem").URL("id", "2")
+	a.Equal(expected.String(), u)
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Configures the headers set by NewSecurityHeadersHandler, headers with a zero value are not set.
+// DefaultSecurityHeadersConfig returns a baseline suitable for JSON APIs.
+type SecurityHeadersConfig struct {
+	// Max age of the "Strict-Transport-Security" header, i.e. how long browsers should only connect to the host using HTTPS.
+	// Browsers ignore the header in responses to plain HTTP requests.
+	HSTSMaxAge time.Duration
+	// Adds the "includeSubDomains" directive to the "Strict-Transport-Security" header.
+	HSTSIncludeSubdomains bool
+	// Adds the "preload" directive to the "Strict-Transport-Security" header, see https://hstspreload.org.
+	HSTSPreload bool
+	// If true, the "X-Content-Type-Options" header is set to "nosniff", so that browsers do not guess the content type of responses.
+	NoSniff bool
+	// Value of the "X-Frame-Options" header, e.g. "DENY" or "SAMEORIGIN".
+	FrameOptions string
+	// Value of the "Referrer-Policy" header, e.g. "no-referrer".
+	ReferrerPolicy string
+	// Value of the "Content-Security-Policy" header, e.g. "default-src 'self'".
+	ContentSecurityPolicy string
+}
+
+// Returns a config with HSTS for 1 year including subdomains, "nosniff", "X-Frame-Options: DENY",
+// "Referrer-Policy: strict-origin-when-cross-origin" and a content security policy that does not allow loading
+// any resources or framing the response, which is suitable for responses of JSON APIs.
+func DefaultSecurityHeadersConfig() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		NoSniff:               true,
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+	}
+}
+
+func (c SecurityHeadersConfig) WithHSTS(maxAge time.Duration, includeSubdomains, preload bool) SecurityHeadersConfig {
+	c.HSTSMaxAge = maxAge
+	c.HSTSIncludeSubdomains = includeSubdomains
+	c.HSTSPreload = preload
+	return c
+}
+
+func (c SecurityHeadersConfig) WithFrameOptions(frameOptions string) SecurityHeadersConfig {
+	c.FrameOptions = frameOptions
+	return c
+}
+
+func (c SecurityHeadersConfig) WithReferrerPolicy(policy string) SecurityHeadersConfig {
+	c.ReferrerPolicy = policy
+	return c
+}
+
+func (c SecurityHeadersConfig) WithContentSecurityPolicy(policy string) SecurityHeadersConfig {
+	c.ContentSecurityPolicy = policy
+	return c
+}
+
+// Returns the header values for the config, a value is empty if the header should not be set.
+func (c SecurityHeadersConfig) headers() [][2]string {
+	var hsts string
+	if c.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(c.HSTSMaxAge.Seconds()), 10)
+		if c.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if c.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	var noSniff string
+	if c.NoSniff {
+		noSniff = "nosniff"
+	}
+	return [][2]string{
+		{"Strict-Transport-Security", hsts},
+		{"X-Content-Type-Options", noSniff},
+		{"X-Frame-Options", c.FrameOptions},
+		{"Referrer-Policy", c.ReferrerPolicy},
+		{"Content-Security-Policy", c.ContentSecurityPolicy},
+	}
+}
+
+// Http middleware that sets the security headers of the config on every response before calling the next handler.
+// Headers that are not set by the config are removed, so that the headers set for all requests, e.g. with ServerConfig.SecurityHeaders,
+// can be replaced for individual routes by wrapping their handlers with another config, e.g. to allow a page to be framed:
+//
+//	h = NewSecurityHeadersHandler(h, DefaultSecurityHeadersConfig().WithFrameOptions("SAMEORIGIN").WithContentSecurityPolicy("frame-ancestors 'self'"))
+//
+// The next handler can also change the headers of a response directly.
+func NewSecurityHeadersHandler(next http.Handler, config SecurityHeadersConfig) http.Handler {
+	headers := config.headers()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		for _, header := range headers {
+			if header[1] == "" {
+				h.Del(header[0])
+			} else {
+				h.Set(header[0], header[1])
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeadersHandler(t *testing.T) {
+	a := assert.New(t)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	serve := func(h http.Handler) http.Header {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Header()
+	}
+
+	h := serve(NewSecurityHeadersHandler(next, DefaultSecurityHeadersConfig()))
+	a.Equal("max-age=31536000; includeSubDomains", h.Get("Strict-Transport-Security"))
+	a.Equal("nosniff", h.Get("X-Content-Type-Options"))
+	a.Equal("DENY", h.Get("X-Frame-Options"))
+	a.Equal("strict-origin-when-cross-origin", h.Get("Referrer-Policy"))
+	a.Equal("default-src 'none'; frame-ancestors 'none'", h.Get("Content-Security-Policy"))
+
+	h = serve(NewSecurityHeadersHandler(next, SecurityHeadersConfig{}.WithHSTS(time.Hour, false, true)))
+	a.Equal("max-age=3600; preload", h.Get("Strict-Transport-Security"))
+	a.Empty(h.Get("X-Content-Type-Options"))
+	a.Empty(h.Get("X-Frame-Options"))
+
+	// a route can replace the headers set for all requests
+	route := NewSecurityHeadersHandler(next, DefaultSecurityHeadersConfig().WithFrameOptions("SAMEORIGIN").WithContentSecurityPolicy(""))
+	h = serve(NewSecurityHeadersHandler(route, DefaultSecurityHeadersConfig()))
+	a.Equal("SAMEORIGIN", h.Get("X-Frame-Options"))
+	a.Empty(h.Values("Content-Security-Policy"))
+	a.Equal("nosniff", h.Get("X-Content-Type-Options"))
+}
+
+func TestServerHandlerSecurityHeaders(t *testing.T) {
+	a := assert.New(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("xyz")
+	})
+	config := NewServerConfig()
+
+	w := httptest.NewRecorder()
+	serverHandler(handler, config).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Empty(w.Header().Get("X-Frame-Options"))
+
+	// headers are also set on error responses
+	w = httptest.NewRecorder()
+	serverHandler(handler, config.WithSecurityHeaders(DefaultSecurityHeadersConfig())).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal(http.StatusInternalServerError, w.Code)
+	a.Equal("DENY", w.Header().Get("X-Frame-Options"))
+}
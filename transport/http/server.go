@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"syscall"
 	"time"
+
+	kittransport "github.com/go-kit/kit/transport"
 )
 
 /*
@@ -64,9 +66,12 @@ type ServerConfig struct {
 
 	// Maximum size of request body in bytes, 0 = no limit, defaults to 128kb
 	RequestMaxBodyBytes int
+	// If true, requests with a Content-Length header larger than RequestMaxBodyBytes are rejected before the handler runs,
+	// see NewStrictMaxRequestBodySizeHandler.
+	RequestMaxBodyCheckContentLength bool
 	// Defaults to 128kb
 	RequestMaxHeaderBytes int
-	// Defaults to 7s
+	// Requests are cancelled and answered with status 504 after this time, see NewTimeoutHandler. Defaults to 7s
 	RequestTimeout time.Duration
 	// Defaults to 10s
 	WriteTimeout time.Duration
@@ -75,8 +80,38 @@ type ServerConfig struct {
 
 	// Called when a panic is caught in a http handler
 	OnPanicFunc func(interface{})
+	// Called with an error that contains the value and stack trace of a panic caught in a http handler, e.g. a LogErrorHandler.
+	// See NewPanicHandler.
+	PanicErrorHandler kittransport.ErrorHandler
 	// Called when the server is shut down with the error returned by the Shutdown() method
 	OnShutdownFunc func(error)
+	// Run in order after the server is shut down and OnShutdownFunc was called, e.g. to close database clients or flush loggers.
+	ShutdownHooks []ShutdownHook
+	// Called with the name and error of shutdown hooks that fail.
+	OnShutdownHookErrorFunc func(name string, err error)
+	// Time to wait after a shutdown signal is received before the server is shut down.
+	// If Health is set, readiness checks fail during this time, so that e.g. a load balancer can stop sending requests
+	// to the server before it stops accepting connections. Defaults to 0.
+	DrainDelay time.Duration
+
+	// If not nil, requests to MetricsPath are handled by this handler, e.g. promhttp.Handler() to expose Prometheus metrics.
+	// See also MetricsMiddleware.
+	MetricsHandler http.Handler
+	// Defaults to /metrics
+	MetricsPath string
+
+	// If not nil, the liveness and readiness handlers of Health are mounted at LivenessPath and ReadinessPath.
+	Health *Health
+	// Defaults to /healthz
+	LivenessPath string
+	// Defaults to /readyz
+	ReadinessPath string
+
+	// If not nil, security headers are set on all responses, see NewSecurityHeadersHandler and DefaultSecurityHeadersConfig.
+	SecurityHeaders *SecurityHeadersConfig
+	// If not nil, the number of requests handled at the same time is limited, see NewConcurrencyLimitHandler.
+	// Requests to the metrics and health handlers are not limited.
+	ConcurrencyLimit *ConcurrencyLimitConfig
 }
 
 func NewServerConfig() ServerConfig {
@@ -88,6 +123,9 @@ func NewServerConfig() ServerConfig {
 		RequestTimeout:        7 * time.Second,
 		WriteTimeout:          10 * time.Second,
 		ReadTimeout:           10 * time.Second,
+		MetricsPath:           "/metrics",
+		LivenessPath:          "/healthz",
+		ReadinessPath:         "/readyz",
 	}
 }
 
@@ -106,6 +144,11 @@ func (s ServerConfig) WithRequestMaxBodyBytes(maxBytes int) ServerConfig {
 	return s
 }
 
+func (s ServerConfig) WithRequestMaxBodyCheckContentLength(check bool) ServerConfig {
+	s.RequestMaxBodyCheckContentLength = check
+	return s
+}
+
 func (s ServerConfig) WithRequestMaxHeaderBytes(maxBytes int) ServerConfig {
 	s.RequestMaxHeaderBytes = maxBytes
 	return s
@@ -131,13 +174,70 @@ func (s ServerConfig) WithOnPanicFunc(onPanic func(interface{})) ServerConfig {
 	return s
 }
 
+func (s ServerConfig) WithPanicErrorHandler(errorHandler kittransport.ErrorHandler) ServerConfig {
+	s.PanicErrorHandler = errorHandler
+	return s
+}
+
 func (s ServerConfig) WithOnShutdownFunc(onShutdown func(error)) ServerConfig {
 	s.OnShutdownFunc = onShutdown
 	return s
 }
 
+// Appends the given hooks to the shutdown hooks.
+func (s ServerConfig) WithShutdownHooks(hooks ...ShutdownHook) ServerConfig {
+	s.ShutdownHooks = append(append([]ShutdownHook{}, s.ShutdownHooks...), hooks...)
+	return s
+}
+
+func (s ServerConfig) WithOnShutdownHookErrorFunc(onError func(name string, err error)) ServerConfig {
+	s.OnShutdownHookErrorFunc = onError
+	return s
+}
+
+func (s ServerConfig) WithDrainDelay(delay time.Duration) ServerConfig {
+	s.DrainDelay = delay
+	return s
+}
+
+func (s ServerConfig) WithMetricsHandler(handler http.Handler) ServerConfig {
+	s.MetricsHandler = handler
+	return s
+}
+
+func (s ServerConfig) WithMetricsPath(path string) ServerConfig {
+	s.MetricsPath = path
+	return s
+}
+
+func (s ServerConfig) WithHealth(health *Health) ServerConfig {
+	s.Health = health
+	return s
+}
+
+func (s ServerConfig) WithLivenessPath(path string) ServerConfig {
+	s.LivenessPath = path
+	return s
+}
+
+func (s ServerConfig) WithReadinessPath(path string) ServerConfig {
+	s.ReadinessPath = path
+	return s
+}
+
+func (s ServerConfig) WithSecurityHeaders(config SecurityHeadersConfig) ServerConfig {
+	s.SecurityHeaders = &config
+	return s
+}
+
+func (s ServerConfig) WithConcurrencyLimit(config ConcurrencyLimitConfig) ServerConfig {
+	s.ConcurrencyLimit = &config
+	return s
+}
+
 // Creates a new http server and starts listening with the given handler, config and useful defaults.
 // Middlewares to catch panics and to timeout requests are added and server shutdown is handled gracefully.
+// If a metrics handler or health checks are configured, they are mounted at the configured paths.
 //
 // This function blocks until a signal to shutdown the server is received, it then tries
 // to gracefully shutdown the server and eventually returns. We wait for open connections/requests to complete for 10 seconds.
@@ -148,21 +248,43 @@ func (s ServerConfig) WithOnShutdownFunc(onShutdown func(error)) ServerConfig {
 //
 // When using a close channel, make sure to send any values in a non-blocking way.
 //
+// On a shutdown signal the health checks of the config are set to draining and the server waits for DrainDelay,
+// then it is shut down and finally OnShutdownFunc and the shutdown hooks are called in order.
+//
 // Returns any errors from ListenAndServer() that are not http.ErrServerClosed.
 func RunDefaultServer(handler http.Handler, closeChan <-chan struct{}, config ServerConfig) error {
-	var h http.Handler = handler
-
-	if config.RequestMaxBodyBytes > 0 {
-		h = NewMaxRequestBodySizeHandler(h, int64(config.RequestMaxBodyBytes))
-	}
-
-	// catch panics
-	h = PanicMiddleware(h, config.OnPanicFunc)
+	// There are multiple sources that can cause a shutdown,
+	// a SIGINT or SIGTERM signal to the process or a value sent on closeChan.
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	go func() {
+		select {
+		case <-closeChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return RunServerContext(ctx, handler, config)
+}
 
-	// request timeout
-	if config.RequestTimeout > 0 {
-		h = http.TimeoutHandler(h, config.RequestTimeout, "request timed out")
-	}
+// Works like RunDefaultServer, but the server is shut down when the given context is cancelled instead of on signals or a value sent on a channel.
+// This makes it easy to run the server together with other components of a service, e.g. with package "golang.org/x/sync/errgroup".
+// Use signal.NotifyContext to also shut down the server on signals.
+//
+// Example:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	g, ctx := errgroup.WithContext(ctx)
+//	g.Go(func() error {
+//	  return RunServerContext(ctx, handler, config)
+//	})
+//	g.Go(func() error {
+//	  return runWorker(ctx)
+//	})
+//	err := g.Wait()
+func RunServerContext(ctx context.Context, handler http.Handler, config ServerConfig) error {
+	h := serverHandler(handler, config)
 
 	srv := &http.Server{
 		Handler:      h,
@@ -177,32 +299,31 @@ func RunDefaultServer(handler http.Handler, closeChan <-chan struct{}, config Se
 	// Sending a value on this channel will shutdown the server.
 	c := make(chan struct{}, 1)
 
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	onShutdown := func(err error) {
+		if config.OnShutdownFunc != nil {
+			config.OnShutdownFunc(err)
+		}
+		runShutdownHooks(config.ShutdownHooks, config.OnShutdownHookErrorFunc)
+	}
+	shutdown := HandleShutdown(srv, c, onShutdown, 10*time.Second)
+
 	go func() {
-		// There are multiple sources that can cause a shutdown,
-		// a SIGINT or SIGTERM signal to the process or a value sent on closeChan.
-		// Since the server can be shutdown only once, only the first shutdown event
-		// needs to be processed.
 		select {
-		case <-sig:
-			// Perfrom a non-blocking send.
-			// If a send would block, that just means that a value has already been sent, which
-			// will already cause the server to shutdown.
-			select {
-			case c <- struct{}{}:
-			default:
-			}
-		case <-closeChan:
-			select {
-			case c <- struct{}{}:
-			default:
-			}
+		case <-ctx.Done():
+		case <-shutdown:
+			// the server failed to start and was shut down without the context being cancelled
+			return
+		}
+		drain(config)
+		// Perfrom a non-blocking send.
+		// If a send would block, that just means that a value has already been sent, which
+		// will already cause the server to shutdown.
+		select {
+		case c <- struct{}{}:
+		default:
 		}
 	}()
 
-	shutdown := HandleShutdown(srv, c, config.OnShutdownFunc, 10*time.Second)
-
 	var returnError error
 
 	// When Shutdown is called ListenAndServe returns immediately with http.ErrServerClosed.
@@ -222,3 +343,120 @@ func RunDefaultServer(handler http.Handler, closeChan <-chan struct{}, config Se
 	<-shutdown
 	return returnError
 }
+
+// Returns the handler of the server created by RunDefaultServer, i.e. the given handler wrapped with the middlewares enabled by the config.
+func serverHandler(handler http.Handler, config ServerConfig) http.Handler {
+	var h http.Handler = handler
+	if h == nil {
+		// like http.Server
+		h = http.DefaultServeMux
+	}
+
+	// limit the handler before the metrics and health handlers are mounted, e.g. so that liveness checks do not fail under load
+	if config.ConcurrencyLimit != nil {
+		h = NewConcurrencyLimitHandler(h, *config.ConcurrencyLimit)
+	}
+
+	mounted := make(map[string]http.Handler)
+	if config.MetricsHandler != nil {
+		mounted[pathOrDefault(config.MetricsPath, "/metrics")] = config.MetricsHandler
+	}
+	if config.Health != nil {
+		mounted[pathOrDefault(config.LivenessPath, "/healthz")] = config.Health.LivenessHandler()
+		mounted[pathOrDefault(config.ReadinessPath, "/readyz")] = config.Health.ReadinessHandler()
+	}
+	if len(mounted) > 0 {
+		// only the exact paths are dispatched, all other requests are passed to the handler unchanged,
+		// a http.ServeMux would e.g. clean paths and redirect requests
+		next := h
+		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mh, ok := mounted[r.URL.Path]; ok {
+				mh.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	if config.RequestMaxBodyBytes > 0 {
+		if config.RequestMaxBodyCheckContentLength {
+			h = NewStrictMaxRequestBodySizeHandler(h, int64(config.RequestMaxBodyBytes))
+		} else {
+			h = NewMaxRequestBodySizeHandler(h, int64(config.RequestMaxBodyBytes))
+		}
+	}
+
+	// catch panics
+	h = panicHandler(h, func(ctx context.Context, e interface{}, err error) {
+		if config.OnPanicFunc != nil {
+			config.OnPanicFunc(e)
+		}
+		if config.PanicErrorHandler != nil {
+			config.PanicErrorHandler.Handle(ctx, err)
+		}
+	})
+
+	// request timeout
+	if config.RequestTimeout > 0 {
+		h = NewTimeoutHandler(h, config.RequestTimeout)
+	}
+
+	// set security headers first, so that they are also part of timeout and panic responses
+	if config.SecurityHeaders != nil {
+		h = NewSecurityHeadersHandler(h, *config.SecurityHeaders)
+	}
+	return h
+}
+
+func pathOrDefault(path, def string) string {
+	if path == "" {
+		return def
+	}
+	return path
+}
+
+// A function run when a server created by RunDefaultServer is shut down, see ServerConfig.ShutdownHooks.
+type ShutdownHook struct {
+	// Used to report errors.
+	Name string
+	// The context is cancelled after Timeout.
+	Fn func(ctx context.Context) error
+	// Defaults to 5s
+	Timeout time.Duration
+}
+
+// Runs the hooks in order, a hook that does not return before its timeout fails with the context error and the next hook is run.
+func runShutdownHooks(hooks []ShutdownHook, onError func(name string, err error)) {
+	for _, hook := range hooks {
+		timeout := hook.Timeout
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		// buffered, so that a hook that does not return before the timeout does not block forever
+		done := make(chan error, 1)
+		go func(fn func(context.Context) error) {
+			done <- fn(ctx)
+		}(hook.Fn)
+		var err error
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		cancel()
+		if err != nil && onError != nil {
+			onError(hook.Name, err)
+		}
+	}
+}
+
+// Lets readiness checks fail and waits for the drain delay before the server is shut down.
+func drain(config ServerConfig) {
+	if config.Health != nil {
+		config.Health.Drain()
+	}
+	if config.DrainDelay > 0 {
+		time.Sleep(config.DrainDelay)
+	}
+}
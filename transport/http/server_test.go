@@ -1,7 +1,11 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -63,3 +67,150 @@ func TestDefaultServerShutdown(t *testing.T) {
 	a.NotNil(err)
 	a.True(onShutdownCalled)
 }
+
+func TestServerHandlerMountedHandlers(t *testing.T) {
+	a := assert.New(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	metricsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("metrics"))
+	})
+
+	serve := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	config := NewServerConfig()
+	h := serverHandler(handler, config)
+	a.Equal(http.StatusTeapot, serve(h, "/metrics").Code)
+
+	h = serverHandler(handler, config.WithMetricsHandler(metricsHandler))
+	w := serve(h, "/metrics")
+	a.Equal(http.StatusOK, w.Code)
+	a.Equal("metrics", w.Body.String())
+	a.Equal(http.StatusTeapot, serve(h, "/other").Code)
+
+	h = serverHandler(handler, config.WithMetricsHandler(metricsHandler).WithMetricsPath("/internal/metrics"))
+	a.Equal("metrics", serve(h, "/internal/metrics").Body.String())
+	a.Equal(http.StatusTeapot, serve(h, "/metrics").Code)
+
+	health := NewHealth()
+	health.AddReadinessCheck("failing", func(ctx context.Context) error { return errors.New("not ready") })
+	h = serverHandler(handler, config.WithMetricsHandler(metricsHandler).WithHealth(health))
+	a.Equal("metrics", serve(h, "/metrics").Body.String())
+	a.Equal(http.StatusOK, serve(h, "/healthz").Code)
+	a.Equal(http.StatusServiceUnavailable, serve(h, "/readyz").Code)
+	a.Equal(http.StatusTeapot, serve(h, "/other").Code)
+
+	// other requests reach the handler unchanged, e.g. paths are not cleaned and routes of the handler are not shadowed
+	var paths []string
+	handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h = serverHandler(handler, config.WithMetricsHandler(metricsHandler))
+	a.Equal(http.StatusTeapot, serve(h, "/a//b").Code)
+	a.Equal(http.StatusTeapot, serve(h, "/a/../metrics").Code)
+	a.Equal(http.StatusTeapot, serve(h, "/metrics/").Code)
+	a.Equal([]string{"/a//b", "/a/../metrics", "/metrics/"}, paths)
+}
+
+func TestShutdownHooks(t *testing.T) {
+	a := assert.New(t)
+
+	var mu sync.Mutex
+	var calls []string
+	called := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, name)
+	}
+	hook := func(name string, err error) ShutdownHook {
+		return ShutdownHook{Name: name, Fn: func(ctx context.Context) error {
+			called(name)
+			return err
+		}}
+	}
+	slow := ShutdownHook{Name: "slow", Timeout: 10 * time.Millisecond, Fn: func(ctx context.Context) error {
+		called("slow")
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}}
+	failed := make(map[string]error)
+	runShutdownHooks([]ShutdownHook{hook("db", nil), slow, hook("logger", errors.New("flush failed"))}, func(name string, err error) {
+		failed[name] = err
+	})
+	mu.Lock()
+	a.Equal([]string{"db", "slow", "logger"}, calls)
+	calls = nil
+	mu.Unlock()
+	a.Equal(map[string]error{"slow": context.DeadlineExceeded, "logger": errors.New("flush failed")}, failed)
+
+	// hooks are run after the server is shut down, readiness fails while draining
+	health := NewHealth()
+	config := NewServerConfig().WithPort(9002).WithHealth(health).WithDrainDelay(20*time.Millisecond).
+		WithOnShutdownFunc(func(err error) {
+			called("onShutdown")
+		}).
+		WithShutdownHooks(hook("db", nil), hook("logger", nil))
+	c := make(chan struct{})
+	readyDuringDrain := make(chan int, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c <- struct{}{}
+		time.Sleep(10 * time.Millisecond)
+		w := httptest.NewRecorder()
+		health.ReadinessHandler().ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+		readyDuringDrain <- w.Code
+	}()
+	a.Nil(RunDefaultServer(http.NotFoundHandler(), c, config))
+	mu.Lock()
+	a.Equal([]string{"onShutdown", "db", "logger"}, calls)
+	mu.Unlock()
+	a.Equal(http.StatusServiceUnavailable, <-readyDuringDrain)
+}
+
+func TestRunServerContext(t *testing.T) {
+	a := assert.New(t)
+
+	onShutdownCalled := make(chan struct{}, 1)
+	config := NewServerConfig().WithPort(9003).WithOnShutdownFunc(func(err error) {
+		onShutdownCalled <- struct{}{}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- RunServerContext(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}), config)
+	}()
+
+	// wait until the server accepts requests
+	var resp *http.Response
+	var err error
+	for i := 0; i < 100; i++ {
+		resp, err = http.Get("http://localhost:9003/")
+		if err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	a.Nil(err)
+	a.Equal(http.StatusTeapot, resp.StatusCode)
+	resp.Body.Close()
+
+	cancel()
+	a.Nil(<-done)
+	a.Len(onShutdownCalled, 1)
+	<-onShutdownCalled
+
+	// errors from ListenAndServe are returned without cancelling the context
+	err = RunServerContext(context.Background(), nil, config.WithAddress("::::::"))
+	a.NotNil(err)
+}
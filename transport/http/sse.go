@@ -0,0 +1,231 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+)
+
+// An event sent to a client with Server-Sent Events.
+type SSEEvent struct {
+	// Optional id of the event, sent back by clients that reconnect in the "Last-Event-ID" header.
+	// Must not contain newlines, otherwise the event is not sent.
+	ID string
+	// Optional type of the event, clients treat events without a type as "message" events.
+	// Must not contain newlines, otherwise the event is not sent.
+	Event string
+	// Strings and byte slices are sent as is, other values are encoded as JSON.
+	Data interface{}
+	// Optional time clients should wait before reconnecting if the connection is lost.
+	Retry time.Duration
+}
+
+// SSEStreamFunc streams events to a client by calling send for every event.
+// The stream is closed once the function returns.
+// The context is cancelled if the client closes the connection.
+// A client that reconnects sends the id of the last event it received, which can be used to resume the stream, see SSELastEventID.
+type SSEStreamFunc func(ctx context.Context, send func(e SSEEvent) error) error
+
+type sseLastEventIDContextKey struct{}
+
+// Returns the id of the last event a reconnecting client received, i.e. the value of the "Last-Event-ID" header.
+// Returns an empty string for a new connection.
+func SSELastEventID(ctx context.Context) string {
+	id, _ := ctx.Value(sseLastEventIDContextKey{}).(string)
+	return id
+}
+
+// SSEDecodeFunc decodes a http request before the stream is started and returns the function that streams events to the client.
+// If an error is returned, no stream is started and the error is encoded using EncodeError.
+type SSEDecodeFunc func(ctx context.Context, r *http.Request) (SSEStreamFunc, error)
+
+type sseHandler struct {
+	decode    SSEDecodeFunc
+	heartbeat time.Duration
+}
+
+// Interval of the heartbeats sent by handlers returned by NewSSEHandler.
+const DefaultSSEHeartbeat = 15 * time.Second
+
+// Returns a http handler that streams the events produced by the given function to clients with Server-Sent Events,
+// i.e. as a response with content type "text/event-stream". Every event is flushed immediately.
+// A heartbeat comment is sent whenever no event was sent for DefaultSSEHeartbeat,
+// so that proxies do not close idle connections and a closed connection is detected.
+//
+// If the stream function returns an error, an event with type "error" is sent before the stream is closed.
+// If the error is or wraps an error of type Error from package "github.com/dkinzler/kit/errors", its public message is used as the data of the event.
+//
+// The response writer must support flushing, which the writer of NewTimeoutHandler does, but e.g. the one of http.TimeoutHandler does not.
+// The stream ends when the context of the request is done, so when using RunDefaultServer, set ServerConfig.RequestTimeout to 0
+// and the write timeout to a value larger than the expected stream duration.
+func NewSSEHandler(stream SSEStreamFunc) http.Handler {
+	return NewSSEDecodeHandler(func(ctx context.Context, r *http.Request) (SSEStreamFunc, error) {
+		return stream, nil
+	}, DefaultSSEHeartbeat)
+}
+
+// Like NewSSEHandler, but the request is decoded before the stream is started, e.g. to validate parameters
+// and respond with an error status instead of an error event.
+// If heartbeat is greater than 0, a heartbeat comment is sent whenever no event was sent for that long.
+//
+// If the stream function returns an error, an event with type "error" is sent before the stream is closed.
+// If the error is or wraps an error of type Error from package "github.com/dkinzler/kit/errors", its public message is used as the data of the event.
+//
+// The response writer must support flushing, which the writer of NewTimeoutHandler does, but e.g. the one of http.TimeoutHandler does not.
+// The stream ends when the context of the request is done, so when using RunDefaultServer, set ServerConfig.RequestTimeout to 0
+// and the write timeout to a value larger than the expected stream duration.
+func NewSSEDecodeHandler(decode SSEDecodeFunc, heartbeat time.Duration) http.Handler {
+	return &sseHandler{
+		decode:    decode,
+		heartbeat: heartbeat,
+	}
+}
+
+func (h *sseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stream, err := h.decode(r.Context(), r)
+	if err != nil {
+		EncodeError(r.Context(), err, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	sw := &sseWriter{w: w, rc: http.NewResponseController(w)}
+	if err := sw.flush(); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(r.Context(), sseLastEventIDContextKey{}, r.Header.Get("Last-Event-ID")))
+	defer cancel()
+
+	var wg sync.WaitGroup
+	if h.heartbeat > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sw.sendHeartbeats(ctx, h.heartbeat)
+		}()
+	}
+
+	err = stream(ctx, func(e SSEEvent) error {
+		if err := ctx.Err(); err != nil {
+			return newInternalTransportError(err, errors.Unavailable, "sse stream closed")
+		}
+		b, err := formatSSEEvent(e)
+		if err != nil {
+			return newInternalTransportError(err, errors.Internal, "could not encode sse event")
+		}
+		if err := sw.write(b); err != nil {
+			return newInternalTransportError(err, errors.Unavailable, "could not write sse event")
+		}
+		return nil
+	})
+	if err != nil {
+		var message string
+		var e errors.Error
+		if stderrors.As(err, &e) {
+			message = e.PublicMessage
+		}
+		b, _ := formatSSEEvent(SSEEvent{Event: "error", Data: message})
+		sw.write(b)
+	}
+
+	// the response writer must not be used after the handler returns
+	cancel()
+	wg.Wait()
+}
+
+// Writes to a response, writes are serialized since heartbeats are sent concurrently.
+type sseWriter struct {
+	mu        sync.Mutex
+	w         http.ResponseWriter
+	rc        *http.ResponseController
+	lastWrite time.Time
+}
+
+func (s *sseWriter) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+	s.lastWrite = time.Now()
+	return s.rc.Flush()
+}
+
+func (s *sseWriter) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastWrite = time.Now()
+	return s.rc.Flush()
+}
+
+func (s *sseWriter) sendHeartbeats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			idle := time.Since(s.lastWrite)
+			s.mu.Unlock()
+			if idle >= interval {
+				s.write([]byte(": heartbeat\n\n"))
+			}
+		}
+	}
+}
+
+// Formats an event in the text/event-stream format.
+// Returns an error if the id or type contain newlines, which would end the field and could be used to inject other fields.
+func formatSSEEvent(e SSEEvent) ([]byte, error) {
+	if strings.ContainsAny(e.ID, "\r\n") {
+		return nil, fmt.Errorf("id of sse event contains a newline")
+	}
+	if strings.ContainsAny(e.Event, "\r\n") {
+		return nil, fmt.Errorf("type of sse event contains a newline")
+	}
+	var b strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %v\n", e.ID)
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %v\n", e.Event)
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %v\n", e.Retry.Milliseconds())
+	}
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		encoded, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		data = string(encoded)
+	}
+	// every line of the data is sent as a separate data field, clients join them with newlines
+	// clients also treat "\r" as the end of a line
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %v\n", line)
+	}
+	b.WriteString("\n")
+	return []byte(b.String()), nil
+}
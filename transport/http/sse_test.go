@@ -0,0 +1,144 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEHandler(t *testing.T) {
+	a := assert.New(t)
+
+	cancelled := make(chan struct{})
+	handler := NewSSEDecodeHandler(func(ctx context.Context, r *http.Request) (SSEStreamFunc, error) {
+		mode := r.URL.Query().Get("mode")
+		if mode == "" {
+			return nil, errors.New(nil, "test", errors.InvalidArgument)
+		}
+		return func(ctx context.Context, send func(e SSEEvent) error) error {
+			if err := send(SSEEvent{ID: "1", Data: "resumed after " + SSELastEventID(ctx)}); err != nil {
+				return err
+			}
+			if err := send(SSEEvent{ID: "2", Event: "update", Data: map[string]int{"a": 1}, Retry: time.Second}); err != nil {
+				return err
+			}
+			switch mode {
+			case "fail":
+				// the public message of a wrapped error is sent as well
+				return fmt.Errorf("stream: %w", errors.New(nil, "test", errors.Internal).WithPublicMessage("stream failed"))
+			case "wait":
+				<-ctx.Done()
+				close(cancelled)
+			}
+			return nil
+		}, nil
+	}, 10*time.Millisecond)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(mode string) *http.Response {
+		r, err := http.NewRequest("GET", server.URL+"?mode="+mode, nil)
+		a.Nil(err)
+		r.Header.Set("Last-Event-ID", "0")
+		resp, err := http.DefaultClient.Do(r)
+		a.Nil(err)
+		return resp
+	}
+	readEvent := func(reader *bufio.Reader) string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil || line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	// decode error, no stream is started
+	resp, err := http.Get(server.URL)
+	a.Nil(err)
+	a.Equal(http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = get("ok")
+	a.Equal(http.StatusOK, resp.StatusCode)
+	a.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+	a.Equal("id: 1\ndata: resumed after 0\n", readEvent(reader))
+	a.Equal("id: 2\nevent: update\nretry: 1000\ndata: {\"a\":1}\n", readEvent(reader))
+	a.Equal("", readEvent(reader))
+	resp.Body.Close()
+
+	// stream function returns error
+	resp = get("fail")
+	reader = bufio.NewReader(resp.Body)
+	readEvent(reader)
+	readEvent(reader)
+	a.Equal("event: error\ndata: stream failed\n", readEvent(reader))
+	resp.Body.Close()
+
+	// heartbeats are sent and the stream is cancelled when the client closes the connection
+	resp = get("wait")
+	reader = bufio.NewReader(resp.Body)
+	readEvent(reader)
+	readEvent(reader)
+	a.Equal(": heartbeat\n", readEvent(reader))
+	resp.Body.Close()
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		a.Fail("stream not cancelled")
+	}
+}
+
+func TestSSEHandlerStreamFunc(t *testing.T) {
+	a := assert.New(t)
+
+	handler := NewSSEHandler(func(ctx context.Context, send func(e SSEEvent) error) error {
+		if err := send(SSEEvent{ID: "1\nevent: admin", Data: "x"}); err == nil {
+			return errors.New(nil, "test", errors.Internal).WithPublicMessage("id not rejected")
+		}
+		return send(SSEEvent{ID: "2", Data: "after " + SSELastEventID(ctx)})
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Last-Event-ID", "1")
+	handler.ServeHTTP(w, r)
+	a.Equal(http.StatusOK, w.Code)
+	a.Equal("text/event-stream", w.Header().Get("Content-Type"))
+	a.Equal("id: 2\ndata: after 1\n\n", w.Body.String())
+	a.True(w.Flushed)
+}
+
+func TestFormatSSEEvent(t *testing.T) {
+	a := assert.New(t)
+
+	b, err := formatSSEEvent(SSEEvent{Data: "line1\nline2"})
+	a.Nil(err)
+	a.Equal("data: line1\ndata: line2\n\n", string(b))
+	b, err = formatSSEEvent(SSEEvent{Event: "ping"})
+	a.Nil(err)
+	a.Equal("event: ping\ndata: \n\n", string(b))
+	_, err = formatSSEEvent(SSEEvent{Data: make(chan int)})
+	a.NotNil(err)
+
+	// newlines in data can not inject fields
+	b, err = formatSSEEvent(SSEEvent{Data: "a\rid: 1\r\nb"})
+	a.Nil(err)
+	a.Equal("data: a\ndata: id: 1\ndata: b\n\n", string(b))
+	_, err = formatSSEEvent(SSEEvent{ID: "1\rretry: 1"})
+	a.NotNil(err)
+	_, err = formatSSEEvent(SSEEvent{Event: "update\ndata: x"})
+	a.NotNil(err)
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+)
+
+// Http middleware that cancels the context of a request after the given timeout.
+// If the next handler has not started writing the response when the timeout expires, the request is answered with
+// status 504 Gateway Timeout and the standard error body (see EncodeError), writes of the handler after the timeout return http.ErrHandlerTimeout.
+// This way encode functions that run after an endpoint returned a context error do not write a second response.
+// If the handler has already started writing the response, it can finish it.
+//
+// In contrast to http.TimeoutHandler, the response is not buffered and can be flushed, e.g. for Server-Sent Events.
+// Hijacking the connection is not supported.
+func NewTimeoutHandler(next http.Handler, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		tw := &timeoutResponseWriter{w: w, header: w.Header().Clone(), ctx: ctx}
+		done := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if e := recover(); e != nil {
+					// http.ErrAbortHandler is propagated unchanged, so that it is still recognized, e.g. by http.Server and panicHandler
+					if e == http.ErrAbortHandler {
+						panicChan <- e
+						return
+					}
+					panicChan <- panicWithStack{value: e, stack: stack()}
+				}
+			}()
+			next.ServeHTTP(tw, r)
+			close(done)
+		}()
+
+		finished := false
+		select {
+		case e := <-panicChan:
+			panic(e)
+		case <-done:
+			finished = true
+		case <-ctx.Done():
+		}
+
+		tw.mu.Lock()
+		// if the request was cancelled for another reason, e.g. because the client closed the connection, no response is written
+		if tw.wroteHeader || ctx.Err() != context.DeadlineExceeded {
+			if finished && !tw.wroteHeader {
+				// the handler returned without writing, the headers it set must still be sent like the server would
+				tw.writeHeaderLocked(http.StatusOK)
+			}
+			tw.mu.Unlock()
+			if !finished {
+				// the handler might have started the response, it must finish it before this function returns
+				select {
+				case e := <-panicChan:
+					panic(e)
+				case <-done:
+				}
+			}
+			return
+		}
+		tw.timedOut = true
+		tw.mu.Unlock()
+		err := errors.New(ctx.Err(), errorOrigin, errors.DeadlineExceeded).WithPublicMessage("request timed out")
+		EncodeError(ctx, err, w)
+	})
+}
+
+type panicWithStack struct {
+	value interface{}
+	stack []byte
+}
+
+func (p panicWithStack) String() string {
+	return fmt.Sprintf("%v\n\ngoroutine stack:\n%s", p.value, p.stack)
+}
+
+func stack() []byte {
+	buf := make([]byte, 64<<10)
+	return buf[:runtime.Stack(buf, false)]
+}
+
+// Guards a response writer, so that the handler cannot start writing a response after the timeout expired.
+// The handler gets its own copy of the header, since it might still modify it while the timeout response is written.
+type timeoutResponseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	ctx         context.Context
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutResponseWriter) Header() http.Header {
+	return tw.header
+}
+
+// Returns true if the handler cannot write to the response, must be called with the lock held.
+func (tw *timeoutResponseWriter) timedOutLocked() bool {
+	return tw.timedOut || (!tw.wroteHeader && tw.ctx.Err() == context.DeadlineExceeded)
+}
+
+// Must be called with the lock held.
+func (tw *timeoutResponseWriter) writeHeaderLocked(status int) {
+	if tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	dst := tw.w.Header()
+	for key := range dst {
+		if _, ok := tw.header[key]; !ok {
+			delete(dst, key)
+		}
+	}
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+	tw.w.WriteHeader(status)
+}
+
+func (tw *timeoutResponseWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOutLocked() {
+		return
+	}
+	tw.writeHeaderLocked(status)
+}
+
+func (tw *timeoutResponseWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOutLocked() {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	return tw.w.Write(b)
+}
+
+// Implements http.Flusher for writers that wrap this one and type-assert the interface, see FlushError.
+func (tw *timeoutResponseWriter) Flush() {
+	tw.FlushError()
+}
+
+func (tw *timeoutResponseWriter) FlushError() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOutLocked() {
+		return http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	return http.NewResponseController(tw.w).Flush()
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dkinzler/kit/endpoint"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutHandler(t *testing.T) {
+	a := assert.New(t)
+
+	// handler that finishes in time
+	handler := NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Context().Deadline()
+		a.True(ok)
+		w.Header().Set("X-Test", "1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("ok"))
+	}), time.Second)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal(http.StatusCreated, w.Code)
+	a.Equal("1", w.Header().Get("X-Test"))
+	a.Equal("ok", w.Body.String())
+
+	// handler that only sets headers
+	handler = NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Foo", "bar")
+	}), time.Second)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal(http.StatusOK, w.Code)
+	a.Equal("bar", w.Header().Get("X-Foo"))
+
+	// handler that times out, the encode func runs after the timeout response was written
+	writeErr := make(chan error, 1)
+	encode := MakeGenericJSONEncodeFunc(http.StatusOK)
+	handler = NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Header().Set("X-Test", "1")
+		writeErr <- encode(r.Context(), w, endpoint.Response{Err: r.Context().Err()})
+	}), 10*time.Millisecond)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal(http.StatusGatewayTimeout, w.Code)
+	a.JSONEq(`{"error": {"message": "request timed out"}}`, w.Body.String())
+	<-writeErr
+	a.Empty(w.Header().Get("X-Test"))
+
+	// handler that started the response before the timeout can finish it
+	handler = NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("a"))
+		http.NewResponseController(w).Flush()
+		<-r.Context().Done()
+		w.Write([]byte("b"))
+	}), 10*time.Millisecond)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal(http.StatusOK, w.Code)
+	a.True(w.Flushed)
+	a.Equal("ab", w.Body.String())
+
+	// flushes of wrapping writers reach the response, e.g. for the writer of MetricsMiddleware
+	handler = NewTimeoutHandler(MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a"))
+		a.Nil(http.NewResponseController(w).Flush())
+		w.(http.Flusher).Flush()
+	}), HTTPMetrics{}, "/"), time.Second)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.True(w.Flushed)
+	a.Equal("a", w.Body.String())
+
+	// panics are propagated
+	handler = NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("xyz")
+	}), time.Second)
+	a.Panics(func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	// http.ErrAbortHandler is not wrapped
+	handler = NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), time.Second)
+	a.PanicsWithValue(http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+
+	a.Equal(http.StatusGatewayTimeout, ErrToCode(context.DeadlineExceeded))
+}
@@ -3,11 +3,16 @@ package http
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/dkinzler/kit/endpoint"
 	"github.com/dkinzler/kit/errors"
 
+	"github.com/go-chi/chi/v5"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/go-kit/log"
 	"github.com/gorilla/mux"
@@ -24,16 +29,24 @@ func newInternalTransportError(inner error, code errors.ErrorCode, message strin
 	return errors.New(inner, errorOrigin, code).WithInternalMessage(message)
 }
 
+// Returns an error for a request body that could not be read or decoded.
+// If the body is larger than the limit set by NewMaxRequestBodySizeHandler, the public message says so and ErrToCode returns 413.
+func newRequestBodyError(err error, message string) error {
+	var maxBytesErr *http.MaxBytesError
+	if stderrors.As(err, &maxBytesErr) {
+		return newPublicTransportError(err, errors.InvalidArgument, fmt.Sprintf("request body must not be larger than %v bytes", maxBytesErr.Limit))
+	}
+	return newPublicTransportError(err, errors.InvalidArgument, message)
+}
+
 // Tries to decode the body of the given http request into target.
+// Afterwards target is validated with ValidateFunc, e.g. by calling its Validate method if it implements Validator.
+// Uses DefaultJSONDecodeOptions, see DecodeJSONBodyWithOptions to decode a body with different options.
 func DecodeJSONBody(r *http.Request, target interface{}) error {
-	err := json.NewDecoder(r.Body).Decode(target)
-	if err != nil {
-		// If the request body could not be decoded, there is probably a problem/bug in the client that made the request.
-		// It makes sense to inform the client of the reason the request failed.
-		// Therefore we return an error with a public error message that can be sent back to the client in the http response.
-		return newPublicTransportError(err, errors.InvalidArgument, "could not decode json request body")
-	}
-	return nil
+	// If the request body could not be decoded, there is probably a problem/bug in the client that made the request.
+	// It makes sense to inform the client of the reason the request failed.
+	// Therefore the returned error has a public error message that can be sent back to the client in the http response.
+	return DecodeJSONBodyWithOptions(r, target, DefaultJSONDecodeOptions)
 }
 
 // Encodes the given value as JSON and writes it to the http response.
@@ -70,10 +83,98 @@ func DecodeURLParameter(r *http.Request, name string) (string, error) {
 	return value, nil
 }
 
-var schemaDecoder = schema.NewDecoder()
+// Returns the value of the given url parameter.
+// Works like DecodeURLParameter, but for routes registered with the "github.com/go-chi/chi/v5" package.
+//
+// Example:
+//
+//	r := chi.NewRouter()
+//	r.Get("/somepath/{xyz}", func(w http.ResponseWriter, r *http.Request) {
+//	  v, err := DecodeChiURLParameter(r, "xyz")
+//	  ...
+//	})
+func DecodeChiURLParameter(r *http.Request, name string) (string, error) {
+	// chi.URLParam() returns an empty string if the parameter does not exist, which we cannot distinguish from an empty value.
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		for i, key := range rctx.URLParams.Keys {
+			if key == name {
+				return rctx.URLParams.Values[i], nil
+			}
+		}
+	}
+	return "", newInternalTransportError(nil, errors.Internal, "url parameter not found, this is probably a bug")
+}
+
+// Returns the value of the given path wildcard.
+// Works like DecodeURLParameter, but for routes registered with a http.ServeMux using the path patterns introduced in Go 1.22.
+//
+// Example:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("GET /somepath/{xyz}", func(w http.ResponseWriter, r *http.Request) {
+//	  v, err := DecodePathValue(r, "xyz")
+//	  ...
+//	})
+func DecodePathValue(r *http.Request, name string) (string, error) {
+	value := r.PathValue(name)
+	// A wildcard like {xyz} always matches a non-empty path segment, an empty value means that the pattern does not contain the wildcard.
+	if value == "" {
+		return "", newInternalTransportError(nil, errors.Internal, "path value not found, this is probably a bug")
+	}
+	return value, nil
+}
+
+// Default maximum number of bytes of a multipart request body that are stored in memory, remaining parts of files are stored on disk.
+// Same as the default used by http.Request.FormFile.
+const DefaultMultipartMaxMemory int64 = 32 << 20
+
+// Returns the file with the given form field name from a multipart/form-data request.
+// The request body is parsed on the first call, maxMemory bytes of file parts are stored in memory (see http.Request.ParseMultipartForm).
+//
+// Example:
+//
+//	handlerFunc := func(w http.ResponseWriter, r *http.Request) {
+//	  fh, err := DecodeMultipartFile(r, "file", DefaultMultipartMaxMemory)
+//	  ...
+//	  f, err := fh.Open()
+//	  ...
+//	}
+func DecodeMultipartFile(r *http.Request, name string, maxMemory int64) (*multipart.FileHeader, error) {
+	if r.MultipartForm == nil {
+		err := r.ParseMultipartForm(maxMemory)
+		if err != nil {
+			return nil, newRequestBodyError(err, "could not parse multipart form")
+		}
+	}
+	files := r.MultipartForm.File[name]
+	if len(files) == 0 {
+		return nil, newPublicTransportError(nil, errors.InvalidArgument, fmt.Sprintf("missing file %v in multipart form", name))
+	}
+	return files[0], nil
+}
+
+// Works like DecodeMultipartFile, but returns the contents of the file.
+func DecodeMultipartFileBytes(r *http.Request, name string, maxMemory int64) ([]byte, error) {
+	fh, err := DecodeMultipartFile(r, name, maxMemory)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, newInternalTransportError(err, errors.Internal, "could not open multipart file")
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, newRequestBodyError(err, "could not read multipart file")
+	}
+	return b, nil
+}
 
 // Decodes the query parameters in the url of the given request into v, which should be a pointer to a struct.
 // Struct tags can be used to define custom field names or ignore struct fields (see the "github.com/gorilla/schema" package for more information).
+// Like DecodeJSONBody, v is validated with ValidateFunc after decoding.
+// Uses DefaultQueryDecoder, create a QueryDecoder to decode query parameters with a different configuration.
 //
 // Example:
 //
@@ -84,20 +185,85 @@ var schemaDecoder = schema.NewDecoder()
 //	  C int `schema:"-"`
 //	}
 func DecodeQueryParameters(r *http.Request, v interface{}) error {
-	err := r.ParseForm()
+	return DefaultQueryDecoder.Decode(r, v)
+}
+
+var headerDecoder = newHeaderDecoder()
+
+func newHeaderDecoder() *schema.Decoder {
+	d := schema.NewDecoder()
+	d.SetAliasTag("header")
+	// requests usually contain many headers that are not decoded
+	d.IgnoreUnknownKeys(true)
+	return d
+}
+
+// Decodes the headers of the given request into v, which should be a pointer to a struct.
+// Works like DecodeQueryParameters, but field names are given with "header" struct tags and are matched case-insensitively.
+//
+// Example:
+//
+//	type X struct {
+//	  RequestID string `header:"X-Request-Id"`
+//	  Version int `header:"X-Api-Version"`
+//	  // Ignore this field
+//	  C int `header:"-"`
+//	}
+func DecodeHeaderParameters(r *http.Request, v interface{}) error {
+	err := headerDecoder.Decode(v, map[string][]string(r.Header))
 	if err != nil {
-		return newPublicTransportError(nil, errors.InvalidArgument, "could not parse query parameters")
+		return newPublicTransportError(err, errors.InvalidArgument, "could not decode headers")
 	}
-	err = schemaDecoder.Decode(v, r.Form)
+	return nil
+}
+
+// Returns the value of the cookie with the given name.
+func DecodeCookie(r *http.Request, name string) (string, error) {
+	c, err := r.Cookie(name)
 	if err != nil {
-		return newPublicTransportError(err, errors.InvalidArgument, "could not decode query parameters")
+		return "", newPublicTransportError(err, errors.InvalidArgument, fmt.Sprintf("missing cookie %v", name))
 	}
-	return nil
+	return c.Value, nil
 }
 
 // A generic response encoder function for Go kit (github.com/go-kit/kit).
 // Use this function only if the response value returned by the endpoint implements the Responder interface from package "github.com/dkinzler/kit/endpoint".
 func MakeGenericJSONEncodeFunc(status int) kithttp.EncodeResponseFunc {
+	return MakeGenericJSONEncodeFuncWithHeaders(status, nil)
+}
+
+// Works like MakeGenericJSONEncodeFunc, but additionally sets the headers returned by the given function if the request was successful.
+// The function is called with the response value of the endpoint, i.e. the value returned by Responder.Response().
+// This can be used e.g. to set a "Location" header for a newly created resource.
+func MakeGenericJSONEncodeFuncWithHeaders(status int, headers func(response interface{}) http.Header) kithttp.EncodeResponseFunc {
+	return MakeEnvelopeJSONEncodeFunc(status, headers, nil)
+}
+
+// Envelope wraps the response value of an endpoint before it is encoded as json, e.g. to return responses
+// of the form {"data": ..., "meta": ...} from all endpoints of an api.
+type Envelope func(ctx context.Context, response interface{}) interface{}
+
+// Envelope that wraps a response value in an object {"data": response}.
+func DataEnvelope(_ context.Context, response interface{}) interface{} {
+	return dataEnvelope{Data: response}
+}
+
+type dataEnvelope struct {
+	Data interface{} `json:"data"`
+}
+
+// Works like MakeGenericJSONEncodeFuncWithHeaders, but the response value is wrapped with the given envelope before it is encoded.
+// The envelope is also called if the response value is nil, so that all successful responses have the same shape.
+// Errors are not wrapped, they are encoded with EncodeError.
+// The headers function can be nil and the response value is passed to it unwrapped.
+// If envelope is nil, the response value is encoded as is.
+func MakeEnvelopeJSONEncodeFunc(status int, headers func(response interface{}) http.Header, envelope Envelope) kithttp.EncodeResponseFunc {
+	return makeEncodeFunc(status, headers, envelope, nil)
+}
+
+// Returns an encode function for Responder values, the response is encoded with the codec negotiated for the value returned by accept, see NegotiateCodec.
+// If accept is nil, responses are encoded as JSON.
+func makeEncodeFunc(status int, headers func(response interface{}) http.Header, envelope Envelope, accept func(ctx context.Context) string) kithttp.EncodeResponseFunc {
 	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 		resp, ok := response.(endpoint.Responder)
 		if !ok {
@@ -107,34 +273,47 @@ func MakeGenericJSONEncodeFunc(status int) kithttp.EncodeResponseFunc {
 		if resp.Error() != nil {
 			return EncodeError(ctx, resp.Error(), w)
 		}
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.WriteHeader(status)
-		if resp.Response() != nil {
-			return EncodeJSONBody(w, resp.Response())
+		if headers != nil {
+			for key, values := range headers(resp.Response()) {
+				for _, value := range values {
+					w.Header().Add(key, value)
+				}
+			}
+		}
+		var source interface{}
+		if envelope != nil {
+			source = envelope(ctx, resp.Response())
+		} else {
+			source = resp.Response()
+		}
+		if accept == nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(status)
+			if source != nil {
+				return EncodeJSONBody(w, source)
+			}
+			return nil
 		}
-		return nil
+		return encodeBody(w, accept(ctx), status, source)
 	}
 }
 
 // Determines an appropriate http response code for the given error.
-// If the error is of type Error from package "github.com/dkinzler/kit/errors", the response code is based on the error code of the error.
-// Otherwise http.StatusInternalServerError is returned.
+// If the error is of type Error from package "github.com/dkinzler/kit/errors", the response code is based on the error code of the error,
+// see errors.ErrorCode.HTTPStatus. Otherwise http.StatusInternalServerError is returned.
+// If the request body was larger than the limit set by NewMaxRequestBodySizeHandler, i.e. the error wraps a *http.MaxBytesError,
+// http.StatusRequestEntityTooLarge is returned. Errors of other types that wrap context.DeadlineExceeded, e.g. because the request
+// timed out (see NewTimeoutHandler), are mapped to http.StatusGatewayTimeout.
 func ErrToCode(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if stderrors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
 	if e, ok := err.(errors.Error); ok {
-		switch e.Code {
-		case errors.InvalidArgument:
-			return http.StatusBadRequest
-		case errors.FailedPrecondition:
-			return http.StatusBadRequest
-		case errors.PermissionDenied:
-			return http.StatusForbidden
-		case errors.Unauthenticated:
-			return http.StatusUnauthorized
-		case errors.NotFound:
-			return http.StatusNotFound
-		default:
-			return http.StatusInternalServerError
-		}
+		return e.Code.HTTPStatus()
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return errors.DeadlineExceeded.HTTPStatus()
 	}
 	return http.StatusInternalServerError
 }
@@ -151,7 +330,12 @@ func ErrToCode(err error) int {
 //		"message": "this is an example error message"
 //	  }
 //	}
-func EncodeError(_ context.Context, err error, w http.ResponseWriter) error {
+//
+// If DefaultErrorFormat is ErrorFormatProblemJSON, the error is encoded with EncodeProblemJSON instead.
+func EncodeError(ctx context.Context, err error, w http.ResponseWriter) error {
+	if DefaultErrorFormat == ErrorFormatProblemJSON {
+		return EncodeProblemJSON(ctx, err, w)
+	}
 	w.WriteHeader(ErrToCode(err))
 	if e, ok := err.(errors.Error); ok {
 		if e.PublicCode != 0 || e.PublicMessage != "" {
@@ -201,20 +385,39 @@ func (h *LogErrorHandler) Handle(ctx context.Context, err error) {
 }
 
 type maxRequestBodySizeHandler struct {
-	next http.Handler
-	n    int64
+	next               http.Handler
+	n                  int64
+	checkContentLength bool
 }
 
 func (m *maxRequestBodySizeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.checkContentLength && r.ContentLength > m.n {
+		err := newRequestBodyError(&http.MaxBytesError{Limit: m.n}, "")
+		EncodeError(r.Context(), err, w)
+		return
+	}
 	r.Body = http.MaxBytesReader(w, r.Body, m.n)
 	m.next.ServeHTTP(w, r)
 }
 
 // Limit the request body size of the given http handler to the specified number of bytes.
-// If the request body is larger, reading beyond the limit will return an error.
+// If the request body is larger, reading beyond the limit will return an error that wraps a *http.MaxBytesError.
+// The decode functions of this package, e.g. DecodeJSONBody, return errors for which ErrToCode returns 413 Request Entity Too Large in this case,
+// so that EncodeError responds with status 413 and a public error message.
 func NewMaxRequestBodySizeHandler(next http.Handler, maxBytes int64) http.Handler {
 	return &maxRequestBodySizeHandler{
 		next: next,
 		n:    maxBytes,
 	}
 }
+
+// Works like NewMaxRequestBodySizeHandler, but requests with a Content-Length header larger than maxBytes
+// are answered with status 413 Request Entity Too Large and the standard error body (see EncodeError) before the next handler runs.
+// Note that this also rejects requests whose handlers would not read the body.
+func NewStrictMaxRequestBodySizeHandler(next http.Handler, maxBytes int64) http.Handler {
+	return &maxRequestBodySizeHandler{
+		next:               next,
+		n:                  maxBytes,
+		checkContentLength: true,
+	}
+}
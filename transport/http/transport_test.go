@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	stderrors "errors"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/dkinzler/kit/endpoint"
 	"github.com/dkinzler/kit/errors"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
@@ -163,6 +165,95 @@ func TestDecodeURLParameterReturnsErrorOnMissingParam(t *testing.T) {
 	a.Empty(actual)
 }
 
+func TestDecodeChiURLParameter(t *testing.T) {
+	a := assert.New(t)
+
+	r := httptest.NewRequest("GET", "http://example.com/events/e-1234-5678", nil)
+	w := httptest.NewRecorder()
+	var actual string
+	var err error
+	router := chi.NewRouter()
+	router.Get("/events/{eventid}", func(w http.ResponseWriter, r *http.Request) {
+		actual, err = DecodeChiURLParameter(r, "eventid")
+	})
+	router.ServeHTTP(w, r)
+	a.Nil(err)
+	a.Equal("e-1234-5678", actual)
+
+	// missing parameter
+	r = httptest.NewRequest("GET", "http://example.com/events", nil)
+	router = chi.NewRouter()
+	router.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+		actual, err = DecodeChiURLParameter(r, "eventid")
+	})
+	router.ServeHTTP(w, r)
+	a.NotNil(err)
+	a.True(errors.IsInternalError(err))
+	a.Empty(actual)
+}
+
+func TestDecodePathValue(t *testing.T) {
+	a := assert.New(t)
+
+	r := httptest.NewRequest("GET", "http://example.com/events/e-1234-5678", nil)
+	w := httptest.NewRecorder()
+	var actual string
+	var err error
+	router := http.NewServeMux()
+	router.HandleFunc("GET /events/{eventid}", func(w http.ResponseWriter, r *http.Request) {
+		actual, err = DecodePathValue(r, "eventid")
+	})
+	router.ServeHTTP(w, r)
+	a.Nil(err)
+	a.Equal("e-1234-5678", actual)
+
+	// missing path value
+	r = httptest.NewRequest("GET", "http://example.com/events", nil)
+	router = http.NewServeMux()
+	router.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		actual, err = DecodePathValue(r, "eventid")
+	})
+	router.ServeHTTP(w, r)
+	a.NotNil(err)
+	a.True(errors.IsInternalError(err))
+	a.Empty(actual)
+}
+
+func TestDecodeMultipartFile(t *testing.T) {
+	a := assert.New(t)
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	fw, err := mw.CreateFormFile("file", "test.txt")
+	a.Nil(err)
+	_, err = fw.Write([]byte("file content"))
+	a.Nil(err)
+	a.Nil(mw.Close())
+
+	r := httptest.NewRequest("POST", "http://example.com/upload", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	fh, err := DecodeMultipartFile(r, "file", DefaultMultipartMaxMemory)
+	a.Nil(err)
+	a.Equal("test.txt", fh.Filename)
+
+	b, err := DecodeMultipartFileBytes(r, "file", DefaultMultipartMaxMemory)
+	a.Nil(err)
+	a.Equal("file content", string(b))
+
+	// missing file
+	_, err = DecodeMultipartFile(r, "other", DefaultMultipartMaxMemory)
+	a.NotNil(err)
+	a.True(errors.IsInvalidArgumentError(err))
+
+	// not a multipart request
+	r = httptest.NewRequest("POST", "http://example.com/upload", strings.NewReader("{}"))
+	r.Header.Set("Content-Type", "application/json")
+	_, err = DecodeMultipartFileBytes(r, "file", DefaultMultipartMaxMemory)
+	a.NotNil(err)
+	a.True(errors.IsInvalidArgumentError(err))
+}
+
 type DecodeQueryStruct struct {
 	From   string   `schema:"from"`
 	To     int      `schema:"to"`
@@ -184,6 +275,45 @@ func TestDecodeQueryParameters(t *testing.T) {
 	a.Equal(expected, actual)
 }
 
+func TestDecodeHeaderParameters(t *testing.T) {
+	a := assert.New(t)
+
+	type headers struct {
+		RequestID string   `header:"X-Request-Id"`
+		Version   int      `header:"x-api-version"`
+		Tags      []string `header:"X-Tag"`
+		Ignored   string   `header:"-"`
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	r.Header.Set("X-Api-Version", "2")
+	r.Header.Add("X-Tag", "a")
+	r.Header.Add("X-Tag", "b")
+	r.Header.Set("User-Agent", "test")
+	var actual headers
+	a.Nil(DecodeHeaderParameters(r, &actual))
+	a.Equal(headers{RequestID: "abc", Version: 2, Tags: []string{"a", "b"}}, actual)
+
+	r.Header.Set("X-Api-Version", "two")
+	err := DecodeHeaderParameters(r, &actual)
+	a.True(errors.IsInvalidArgumentError(err))
+}
+
+func TestDecodeCookie(t *testing.T) {
+	a := assert.New(t)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: "xyz"})
+	v, err := DecodeCookie(r, "session")
+	a.Nil(err)
+	a.Equal("xyz", v)
+
+	_, err = DecodeCookie(r, "other")
+	a.True(errors.IsInvalidArgumentError(err))
+	a.Equal("missing cookie other", err.(errors.Error).PublicMessage)
+}
+
 func TestEncodeErrorWorks(t *testing.T) {
 	a := assert.New(t)
 
@@ -301,6 +431,70 @@ func TestGenericJSONEncodeFunc(t *testing.T) {
 	a.Equal(expected, u)
 }
 
+func TestGenericJSONEncodeFuncWithHeaders(t *testing.T) {
+	a := assert.New(t)
+
+	headers := func(response interface{}) http.Header {
+		h := make(http.Header)
+		h.Set("Location", "/items/"+response.(string))
+		return h
+	}
+
+	// headers set on success
+	w := httptest.NewRecorder()
+	err := MakeGenericJSONEncodeFuncWithHeaders(http.StatusCreated, headers)(context.Background(), w, endpoint.Response{R: "abc"})
+	a.Nil(err)
+	a.Equal(http.StatusCreated, w.Result().StatusCode)
+	a.Equal("/items/abc", w.Result().Header.Get("Location"))
+
+	// headers not set on error
+	w = httptest.NewRecorder()
+	err = MakeGenericJSONEncodeFuncWithHeaders(http.StatusCreated, headers)(context.Background(), w, endpoint.Response{Err: errors.New(nil, "test", errors.NotFound)})
+	a.Nil(err)
+	a.Equal(http.StatusNotFound, w.Result().StatusCode)
+	a.Empty(w.Result().Header.Get("Location"))
+}
+
+func TestEnvelopeJSONEncodeFunc(t *testing.T) {
+	a := assert.New(t)
+
+	// response values are wrapped
+	w := httptest.NewRecorder()
+	err := MakeEnvelopeJSONEncodeFunc(http.StatusOK, nil, DataEnvelope)(context.Background(), w, endpoint.Response{R: map[string]string{"a": "b"}})
+	a.Nil(err)
+	a.Equal(http.StatusOK, w.Result().StatusCode)
+	a.JSONEq(`{"data": {"a": "b"}}`, w.Body.String())
+
+	// nil response values are wrapped too
+	w = httptest.NewRecorder()
+	err = MakeEnvelopeJSONEncodeFunc(http.StatusCreated, nil, DataEnvelope)(context.Background(), w, endpoint.Response{})
+	a.Nil(err)
+	a.Equal(http.StatusCreated, w.Result().StatusCode)
+	a.JSONEq(`{"data": null}`, w.Body.String())
+
+	// custom envelope, headers are computed from the unwrapped response
+	envelope := func(ctx context.Context, response interface{}) interface{} {
+		return map[string]interface{}{"data": response, "meta": map[string]string{"version": "1"}}
+	}
+	headers := func(response interface{}) http.Header {
+		h := make(http.Header)
+		h.Set("Location", "/items/"+response.(string))
+		return h
+	}
+	w = httptest.NewRecorder()
+	err = MakeEnvelopeJSONEncodeFunc(http.StatusCreated, headers, envelope)(context.Background(), w, endpoint.Response{R: "abc"})
+	a.Nil(err)
+	a.Equal("/items/abc", w.Result().Header.Get("Location"))
+	a.JSONEq(`{"data": "abc", "meta": {"version": "1"}}`, w.Body.String())
+
+	// errors are not wrapped
+	w = httptest.NewRecorder()
+	err = MakeEnvelopeJSONEncodeFunc(http.StatusOK, nil, DataEnvelope)(context.Background(), w, endpoint.Response{Err: errors.New(nil, "test", errors.NotFound).WithPublicMessage("not found")})
+	a.Nil(err)
+	a.Equal(http.StatusNotFound, w.Result().StatusCode)
+	a.JSONEq(`{"error": {"message": "not found"}}`, w.Body.String())
+}
+
 func TestMaxRequestBodySizeHandler(t *testing.T) {
 	a := assert.New(t)
 
@@ -345,4 +539,56 @@ func TestMaxRequestBodySizeHandler(t *testing.T) {
 	handler.ServeHTTP(w, r)
 	a.False(gotError)
 	a.Equal(http.StatusCreated, w.Result().StatusCode)
+
+	// requests decoded with the functions of this package are answered with 413 and the standard error body
+	called := false
+	handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		var x map[string]interface{}
+		err := DecodeJSONBody(r, &x)
+		if err != nil {
+			EncodeError(r.Context(), err, w)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+	})
+	bodyBytes, err = json.Marshal(map[string]interface{}{"x": strings.Repeat("a", 2000)})
+	a.Nil(err)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/test", bytes.NewReader(bodyBytes))
+	NewMaxRequestBodySizeHandler(handler, 1000).ServeHTTP(w, r)
+	a.True(called)
+	a.Equal(http.StatusRequestEntityTooLarge, w.Result().StatusCode)
+	a.JSONEq(`{"error": {"message": "request body must not be larger than 1000 bytes"}}`, w.Body.String())
+
+	// the strict handler rejects the request before the handler runs
+	called = false
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/test", bytes.NewReader(bodyBytes))
+	NewStrictMaxRequestBodySizeHandler(handler, 1000).ServeHTTP(w, r)
+	a.False(called)
+	a.Equal(http.StatusRequestEntityTooLarge, w.Result().StatusCode)
+	a.JSONEq(`{"error": {"message": "request body must not be larger than 1000 bytes"}}`, w.Body.String())
+
+	// without a Content-Length header the body is limited while reading
+	called = false
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/test", bytes.NewReader(bodyBytes))
+	r.ContentLength = -1
+	NewStrictMaxRequestBodySizeHandler(handler, 1000).ServeHTTP(w, r)
+	a.True(called)
+	a.Equal(http.StatusRequestEntityTooLarge, w.Result().StatusCode)
+
+	// request body < 1000 bytes, request should work
+	bodyBytes, err = json.Marshal(map[string]interface{}{"x": "this is not that long"})
+	a.Nil(err)
+	for _, h := range []http.Handler{NewMaxRequestBodySizeHandler(handler, 1000), NewStrictMaxRequestBodySizeHandler(handler, 1000)} {
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest("POST", "/test", bytes.NewReader(bodyBytes))
+		h.ServeHTTP(w, r)
+		a.Equal(http.StatusCreated, w.Result().StatusCode)
+	}
+
+	a.Equal(http.StatusRequestEntityTooLarge, ErrToCode(errors.New(&http.MaxBytesError{Limit: 1}, "test", errors.Internal)))
 }
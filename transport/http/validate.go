@@ -0,0 +1,62 @@
+package http
+
+import (
+	"github.com/dkinzler/kit/errors"
+)
+
+// Validator can be implemented by request types to validate requests after they were decoded,
+// see DecodeJSONBody, DecodeQueryParameters and DecodeBody.
+//
+// Validate can return an error of type Error, which is returned unchanged by the decode functions,
+// FieldErrors to describe which fields are invalid, or any other error, whose message is then sent to the client.
+type Validator interface {
+	Validate() error
+}
+
+// Function called on every value decoded by DecodeJSONBody, DecodeQueryParameters and DecodeBody.
+// By default values that implement Validator are validated, values of other types are not.
+// Can be replaced e.g. to use a validation library, errors returned by the function are handled like errors returned by Validator.
+var ValidateFunc func(v interface{}) error = validateValidator
+
+func validateValidator(v interface{}) error {
+	if validator, ok := v.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+// Error that describes the invalid fields of a request, can e.g. be returned by Validate methods.
+// Decode functions convert it to an error with code InvalidArgument and a BadRequest detail containing the field violations.
+type FieldErrors []errors.FieldViolation
+
+func (f FieldErrors) Error() string {
+	if len(f) == 0 {
+		return "invalid request"
+	}
+	msg := "invalid request: " + f[0].Field + ": " + f[0].Description
+	if len(f) > 1 {
+		msg += " (and more)"
+	}
+	return msg
+}
+
+// Validates the given decoded value using ValidateFunc and converts failures into errors with code InvalidArgument.
+func validateDecoded(v interface{}) error {
+	if ValidateFunc == nil {
+		return nil
+	}
+	err := ValidateFunc(v)
+	if err == nil {
+		return nil
+	}
+	switch e := err.(type) {
+	case errors.Error:
+		return e
+	case FieldErrors:
+		return errors.New(e, errorOrigin, errors.InvalidArgument).
+			WithPublicMessage("invalid request").
+			WithDetails(errors.BadRequest{FieldViolations: e})
+	default:
+		return newPublicTransportError(err, errors.InvalidArgument, err.Error())
+	}
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validatedRequest struct {
+	Name string `json:"name" schema:"name"`
+	Age  int    `json:"age" schema:"age"`
+}
+
+func (v validatedRequest) Validate() error {
+	var f FieldErrors
+	if v.Name == "" {
+		f = append(f, errors.FieldViolation{Field: "name", Description: "must not be empty"})
+	}
+	if v.Age < 0 {
+		f = append(f, errors.FieldViolation{Field: "age", Description: "must not be negative"})
+	}
+	if len(f) > 0 {
+		return f
+	}
+	if v.Name == "plain" {
+		return stderrors.New("plain is not a valid name")
+	}
+	if v.Name == "conflict" {
+		return errors.New(nil, "test", errors.AlreadyExists)
+	}
+	return nil
+}
+
+func TestDecodeValidatesRequests(t *testing.T) {
+	a := assert.New(t)
+
+	var v validatedRequest
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "a", "age": 1}`))
+	a.Nil(DecodeJSONBody(r, &v))
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "", "age": -1}`))
+	err := DecodeJSONBody(r, &v)
+	a.True(errors.IsInvalidArgumentError(err))
+	br, ok := errors.BadRequestOf(err)
+	a.True(ok)
+	a.Equal([]errors.FieldViolation{
+		{Field: "name", Description: "must not be empty"},
+		{Field: "age", Description: "must not be negative"},
+	}, br.FieldViolations)
+
+	r = httptest.NewRequest(http.MethodGet, "/?name=plain", nil)
+	err = DecodeQueryParameters(r, &validatedRequest{})
+	a.True(errors.IsInvalidArgumentError(err))
+	a.Equal("plain is not a valid name", err.(errors.Error).PublicMessage)
+
+	// errors of type Error are returned unchanged
+	r = httptest.NewRequest(http.MethodGet, "/?name=conflict", nil)
+	err = DecodeQueryParameters(r, &validatedRequest{})
+	a.True(errors.IsAlreadyExistsError(err))
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": ""}`))
+	r.Header.Set("Content-Type", "application/json")
+	err = DecodeBody(r, &validatedRequest{})
+	a.True(errors.IsInvalidArgumentError(err))
+
+	// custom validate function
+	defer func(f func(interface{}) error) { ValidateFunc = f }(ValidateFunc)
+	ValidateFunc = func(v interface{}) error {
+		return FieldErrors{{Field: "x", Description: "invalid"}}
+	}
+	var m map[string]interface{}
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	err = DecodeJSONBody(r, &m)
+	a.True(errors.IsInvalidArgumentError(err))
+	br, ok = errors.BadRequestOf(err)
+	a.True(ok)
+	a.Len(br.FieldViolations, 1)
+
+	ValidateFunc = nil
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": ""}`))
+	a.Nil(DecodeJSONBody(r, &validatedRequest{}))
+}
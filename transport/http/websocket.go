@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebsocketStreamFunc streams values to a client by calling send for every value.
+// The websocket connection is closed once the function returns.
+// The context is cancelled if the client closes the connection.
+type WebsocketStreamFunc func(ctx context.Context, send func(v interface{}) error) error
+
+// WebsocketDecodeFunc decodes a http request before the connection is upgraded and returns the function that streams values to the client.
+// If an error is returned, the connection is not upgraded and the error is encoded using EncodeError.
+type WebsocketDecodeFunc func(ctx context.Context, r *http.Request) (WebsocketStreamFunc, error)
+
+type websocketStreamHandler struct {
+	decode   WebsocketDecodeFunc
+	upgrader *websocket.Upgrader
+}
+
+// Returns a http handler that upgrades requests to websocket connections and sends the values produced by a WebsocketStreamFunc as JSON messages.
+// Messages sent by the client are discarded.
+//
+// When the stream function returns, a close message is sent to the client.
+// The close code is websocket.CloseNormalClosure if the stream function returned nil and websocket.CloseInternalServerErr otherwise.
+// If the error is or wraps an error of type Error from package "github.com/dkinzler/kit/errors", its public message is used as the close reason.
+//
+// If upgrader is nil, a websocket.Upgrader with default options is used.
+func NewWebsocketStreamHandler(decode WebsocketDecodeFunc, upgrader *websocket.Upgrader) http.Handler {
+	if upgrader == nil {
+		upgrader = &websocket.Upgrader{}
+	}
+	return &websocketStreamHandler{
+		decode:   decode,
+		upgrader: upgrader,
+	}
+}
+
+func (h *websocketStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stream, err := h.decode(r.Context(), r)
+	if err != nil {
+		EncodeError(r.Context(), err, w)
+		return
+	}
+
+	// Upgrade() already replies with an error response if the upgrade fails.
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Control messages like close and ping are processed while reading from the connection.
+	// Reading returns an error once the connection is closed, in which case the stream is cancelled.
+	go func() {
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	err = stream(ctx, func(v interface{}) error {
+		err := conn.WriteJSON(v)
+		if err != nil {
+			return newInternalTransportError(err, errors.Unavailable, "could not write websocket message")
+		}
+		return nil
+	})
+
+	code, reason := websocket.CloseNormalClosure, ""
+	if err != nil {
+		code = websocket.CloseInternalServerErr
+		var e errors.Error
+		if stderrors.As(err, &e) {
+			reason = e.PublicMessage
+		}
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebsocketStreamHandler(t *testing.T) {
+	a := assert.New(t)
+
+	handler := NewWebsocketStreamHandler(func(ctx context.Context, r *http.Request) (WebsocketStreamFunc, error) {
+		n := r.URL.Query().Get("n")
+		if n == "" {
+			return nil, errors.New(nil, "test", errors.InvalidArgument)
+		}
+		return func(ctx context.Context, send func(v interface{}) error) error {
+			for _, v := range []string{"a", "b", n} {
+				if err := send(v); err != nil {
+					return err
+				}
+			}
+			if n == "fail" {
+				// the public message of a wrapped error is used as well
+				return fmt.Errorf("stream: %w", errors.New(nil, "test", errors.Internal).WithPublicMessage("stream failed"))
+			}
+			return nil
+		}, nil
+	}, nil)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	// decode error, connection is not upgraded
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	a.NotNil(err)
+	a.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?n=c", nil)
+	a.Nil(err)
+	defer conn.Close()
+	for _, expected := range []string{"a", "b", "c"} {
+		var v string
+		a.Nil(conn.ReadJSON(&v))
+		a.Equal(expected, v)
+	}
+	_, _, err = conn.ReadMessage()
+	a.True(websocket.IsCloseError(err, websocket.CloseNormalClosure))
+
+	// stream function returns error
+	conn2, _, err := websocket.DefaultDialer.Dial(url+"?n=fail", nil)
+	a.Nil(err)
+	defer conn2.Close()
+	for i := 0; i < 3; i++ {
+		var v string
+		a.Nil(conn2.ReadJSON(&v))
+	}
+	_, _, err = conn2.ReadMessage()
+	a.True(websocket.IsCloseError(err, websocket.CloseInternalServerErr))
+	a.Contains(err.Error(), "stream failed")
+}
@@ -0,0 +1,95 @@
+// Package message implements the JSON message format used for responses by the message based transports, e.g. NATS and AMQP.
+//
+// Since there are no status codes like in http, a response message contains either the result or the error returned by an endpoint:
+//
+//	{
+//	  "result": {...},
+//	  "error": {
+//	    "code": "InvalidArgument",
+//	    "publicCode": 42,
+//	    "message": "this is an example error message"
+//	  }
+//	}
+package message
+
+import (
+	"encoding/json"
+
+	"github.com/dkinzler/kit/endpoint"
+	"github.com/dkinzler/kit/errors"
+)
+
+const errorOrigin = "transport/message"
+
+type response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *responseError  `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code       string `json:"code"`
+	PublicCode int    `json:"publicCode,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// Encodes the value returned by an endpoint, which should implement endpoint.Responder.
+func EncodeResponse(resp interface{}) ([]byte, error) {
+	r, ok := resp.(endpoint.Responder)
+	if !ok {
+		return nil, errors.New(nil, errorOrigin, errors.Internal).WithInternalMessage("response type does not implement Responder, this is probably a bug")
+	}
+	if r.Error() != nil {
+		return EncodeError(r.Error())
+	}
+	var msg response
+	if r.Response() != nil {
+		result, err := json.Marshal(r.Response())
+		if err != nil {
+			return nil, errors.New(err, errorOrigin, errors.Internal).WithInternalMessage("could not encode response")
+		}
+		msg.Result = result
+	}
+	return json.Marshal(msg)
+}
+
+// Encodes an error, only the error code and public code and message are included.
+// Errors that are not of type Error from package "github.com/dkinzler/kit/errors" are encoded with code Unknown.
+func EncodeError(err error) ([]byte, error) {
+	re := &responseError{Code: errors.Unknown.String()}
+	if e, ok := err.(errors.Error); ok {
+		re.Code = e.Code.String()
+		re.PublicCode = e.PublicCode
+		re.Message = e.PublicMessage
+	}
+	return json.Marshal(response{Error: re})
+}
+
+// Decodes a response message into a value of type endpoint.Response, the result is decoded into a value of type T.
+// An error in the response message is returned as an error of type Error from package "github.com/dkinzler/kit/errors"
+// in the Err field of the endpoint.Response.
+func DecodeResponse[T any](data []byte, origin string) (endpoint.Response, error) {
+	var msg response
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return endpoint.Response{}, errors.New(err, errorOrigin, errors.Internal).WithInternalMessage("could not decode response message")
+	}
+	if msg.Error != nil {
+		return endpoint.Response{
+			Err: errors.New(nil, origin, codeFromString(msg.Error.Code)).
+				WithPublicCode(msg.Error.PublicCode).
+				WithPublicMessage(msg.Error.Message),
+		}, nil
+	}
+	var result T
+	if len(msg.Result) > 0 {
+		if err := json.Unmarshal(msg.Result, &result); err != nil {
+			return endpoint.Response{}, errors.New(err, errorOrigin, errors.Internal).WithInternalMessage("could not decode result")
+		}
+	}
+	return endpoint.Response{R: result}, nil
+}
+
+func codeFromString(s string) errors.ErrorCode {
+	// unknown codes are decoded as Unknown
+	c, _ := errors.ParseErrorCode(s)
+	return c
+}
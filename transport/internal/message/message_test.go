@@ -0,0 +1,62 @@
+package message
+
+import (
+	stderrors "errors"
+	"testing"
+
+	"github.com/dkinzler/kit/endpoint"
+	"github.com/dkinzler/kit/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testResult struct {
+	A string
+	B int
+}
+
+func TestEncodeDecodeResponse(t *testing.T) {
+	a := assert.New(t)
+
+	b, err := EncodeResponse(endpoint.Response{R: testResult{A: "abc", B: 42}})
+	a.Nil(err)
+	resp, err := DecodeResponse[testResult](b, "test")
+	a.Nil(err)
+	a.Nil(resp.Err)
+	a.Equal(testResult{A: "abc", B: 42}, resp.R)
+
+	// no result
+	b, err = EncodeResponse(endpoint.Response{})
+	a.Nil(err)
+	resp, err = DecodeResponse[interface{}](b, "test")
+	a.Nil(err)
+	a.Nil(resp.Err)
+	a.Nil(resp.R)
+
+	// error
+	b, err = EncodeResponse(endpoint.Response{Err: errors.New(nil, "x", errors.NotFound).WithPublicCode(7).WithPublicMessage("not found").WithInternalMessage("secret")})
+	a.Nil(err)
+	a.NotContains(string(b), "secret")
+	resp, err = DecodeResponse[testResult](b, "test")
+	a.Nil(err)
+	a.Nil(resp.R)
+	e, ok := resp.Err.(errors.Error)
+	a.True(ok)
+	a.Equal("test", e.Origin)
+	a.Equal(errors.NotFound, e.Code)
+	a.Equal(7, e.PublicCode)
+	a.Equal("not found", e.PublicMessage)
+
+	// errors not of type Error are encoded with code Unknown
+	b, err = EncodeError(stderrors.New("xyz"))
+	a.Nil(err)
+	resp, err = DecodeResponse[testResult](b, "test")
+	a.Nil(err)
+	a.True(errors.IsUnknownError(resp.Err))
+
+	_, err = EncodeResponse("not a responder")
+	a.NotNil(err)
+
+	_, err = DecodeResponse[testResult]([]byte("{invalid"), "test")
+	a.NotNil(err)
+}
@@ -0,0 +1,82 @@
+// Package nats provides helpers to build Go kit (github.com/go-kit/kit) NATS subscribers and publishers
+// for endpoints that return values implementing endpoint.Responder (see package "github.com/dkinzler/kit/endpoint").
+//
+// Requests are encoded as JSON. Response messages contain either the result or the error returned by an endpoint, e.g.
+//
+//	{"result": {...}}
+//	{"error": {"code": "InvalidArgument", "publicCode": 42, "message": "this is an example error message"}}
+package nats
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dkinzler/kit/errors"
+	"github.com/dkinzler/kit/transport/internal/message"
+
+	kitnats "github.com/go-kit/kit/transport/nats"
+	"github.com/nats-io/nats.go"
+)
+
+const errorOrigin = "transport/nats"
+
+// Tries to decode the data of the given message into target.
+func DecodeJSONRequest(msg *nats.Msg, target interface{}) error {
+	err := json.Unmarshal(msg.Data, target)
+	if err != nil {
+		return errors.New(err, errorOrigin, errors.InvalidArgument).WithPublicMessage("could not decode json request")
+	}
+	return nil
+}
+
+// A generic response encoder function for Go kit subscribers.
+// Use this function only if the response value returned by the endpoint implements the Responder interface from package "github.com/dkinzler/kit/endpoint".
+func EncodeGenericJSONResponse(_ context.Context, reply string, nc *nats.Conn, response interface{}) error {
+	b, err := message.EncodeResponse(response)
+	if err != nil {
+		return err
+	}
+	return nc.Publish(reply, b)
+}
+
+// Encodes errors returned by decode functions, endpoints or encode functions in the same format as errors returned by the service.
+// Can be used with kitnats.SubscriberErrorEncoder.
+func EncodeError(_ context.Context, err error, reply string, nc *nats.Conn) {
+	b, err := message.EncodeError(err)
+	if err != nil {
+		return
+	}
+	nc.Publish(reply, b)
+}
+
+// Decodes a response message encoded with EncodeGenericJSONResponse or EncodeError into a value of type endpoint.Response,
+// where the result is of type T. Errors are of type Error from package "github.com/dkinzler/kit/errors".
+// For endpoints without a result use interface{} as type parameter.
+func DecodeJSONResponse[T any](_ context.Context, msg *nats.Msg) (interface{}, error) {
+	return message.DecodeResponse[T](msg.Data, errorOrigin)
+}
+
+// Subscription of a Go kit subscriber to a subject.
+type Subscription struct {
+	Subject string
+	// Optional, if set, subscribers with the same queue name form a queue group and each message is only delivered to one of them.
+	Queue      string
+	Subscriber *kitnats.Subscriber
+}
+
+// Subscribes to the subjects of all the given subscriptions.
+// If a subscription fails, the subscriptions already created are unsubscribed.
+func Subscribe(nc *nats.Conn, subscriptions []Subscription) ([]*nats.Subscription, error) {
+	var result []*nats.Subscription
+	for _, s := range subscriptions {
+		sub, err := nc.QueueSubscribe(s.Subject, s.Queue, s.Subscriber.ServeMsg(nc))
+		if err != nil {
+			for _, sub := range result {
+				sub.Unsubscribe()
+			}
+			return nil, errors.New(err, errorOrigin, errors.Unavailable).WithInternalMessage("could not subscribe to subject " + s.Subject)
+		}
+		result = append(result, sub)
+	}
+	return result, nil
+}